@@ -0,0 +1,13 @@
+package adminapi_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestAdminapi(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Adminapi Suite")
+}
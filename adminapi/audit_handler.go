@@ -0,0 +1,75 @@
+package adminapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/auditlog"
+)
+
+const AuditPath = "/audit"
+
+type AuditHandler struct {
+	logger   lager.Logger
+	auditLog *auditlog.FileLog
+}
+
+func NewAuditHandler(logger lager.Logger, auditLog *auditlog.FileLog) *AuditHandler {
+	return &AuditHandler{
+		logger:   logger.Session("audit-handler"),
+		auditLog: auditLog,
+	}
+}
+
+// ServeHTTP serves GET /audit with the most recent audit entries as JSON
+// lines, oldest first. The process_guid and source query parameters filter
+// the entries, lines limits how many of the remaining ones are returned.
+func (h *AuditHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	logger := h.logger.Session("serve", lager.Data{"query": req.URL.RawQuery})
+
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := req.URL.Query()
+
+	lines := 0
+	if value := query.Get("lines"); value != "" {
+		var err error
+		lines, err = strconv.Atoi(value)
+		if err != nil || lines < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	processGUID := query.Get("process_guid")
+	source := query.Get("source")
+
+	entries := []auditlog.Entry{}
+	for _, entry := range h.auditLog.Entries() {
+		if processGUID != "" && entry.ProcessGUID != processGUID {
+			continue
+		}
+		if source != "" && entry.Source != source {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if lines > 0 && len(entries) > lines {
+		entries = entries[len(entries)-lines:]
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	for _, entry := range entries {
+		err := encoder.Encode(entry)
+		if err != nil {
+			logger.Error("failed-to-encode-entry", err)
+			return
+		}
+	}
+}
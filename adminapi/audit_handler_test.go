@@ -0,0 +1,133 @@
+package adminapi_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/adminapi"
+	"code.cloudfoundry.org/route-emitter/auditlog"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AuditHandler", func() {
+	var (
+		handler  *adminapi.AuditHandler
+		recorder *httptest.ResponseRecorder
+		tmpDir   string
+		method   string
+		path     string
+	)
+
+	responseEntries := func() []auditlog.Entry {
+		entries := []auditlog.Entry{}
+		scanner := bufio.NewScanner(recorder.Body)
+		for scanner.Scan() {
+			var entry auditlog.Entry
+			Expect(json.Unmarshal(scanner.Bytes(), &entry)).To(Succeed())
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "audit-handler")
+		Expect(err).NotTo(HaveOccurred())
+
+		logger := lagertest.NewTestLogger("test")
+		auditLog, err := auditlog.NewFileLog(logger, fakeclock.NewFakeClock(time.Now()), filepath.Join(tmpDir, "audit.jsonl"), 0, 0, 10)
+		Expect(err).NotTo(HaveOccurred())
+
+		auditLog.Record(auditlog.Entry{Source: auditlog.SourceEvent, EventType: "actual_lrp_created", ProcessGUID: "process-guid-1"})
+		auditLog.Record(auditlog.Entry{Source: auditlog.SourceEvent, EventType: "actual_lrp_removed", ProcessGUID: "process-guid-2"})
+		auditLog.Record(auditlog.Entry{Source: auditlog.SourceSync})
+
+		handler = adminapi.NewAuditHandler(logger, auditLog)
+		recorder = httptest.NewRecorder()
+		method = "GET"
+		path = "/audit"
+	})
+
+	JustBeforeEach(func() {
+		request, err := http.NewRequest(method, path, nil)
+		Expect(err).NotTo(HaveOccurred())
+		handler.ServeHTTP(recorder, request)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	It("returns the entries as json lines, oldest first", func() {
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		entries := responseEntries()
+		Expect(entries).To(HaveLen(3))
+		Expect(entries[0].ProcessGUID).To(Equal("process-guid-1"))
+		Expect(entries[2].Source).To(Equal(auditlog.SourceSync))
+	})
+
+	Context("when limited to a number of lines", func() {
+		BeforeEach(func() {
+			path = "/audit?lines=2"
+		})
+
+		It("returns the most recent entries", func() {
+			entries := responseEntries()
+			Expect(entries).To(HaveLen(2))
+			Expect(entries[0].ProcessGUID).To(Equal("process-guid-2"))
+		})
+	})
+
+	Context("when filtered by process guid", func() {
+		BeforeEach(func() {
+			path = "/audit?process_guid=process-guid-2"
+		})
+
+		It("returns only the entries of that process", func() {
+			entries := responseEntries()
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].EventType).To(Equal("actual_lrp_removed"))
+		})
+	})
+
+	Context("when filtered by source", func() {
+		BeforeEach(func() {
+			path = "/audit?source=sync"
+		})
+
+		It("returns only the entries of that source", func() {
+			entries := responseEntries()
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Source).To(Equal(auditlog.SourceSync))
+		})
+	})
+
+	Context("when the number of lines is invalid", func() {
+		BeforeEach(func() {
+			path = "/audit?lines=many"
+		})
+
+		It("returns 400", func() {
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Context("when the request is not a GET", func() {
+		BeforeEach(func() {
+			method = "POST"
+		})
+
+		It("returns 405", func() {
+			Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+})
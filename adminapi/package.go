@@ -0,0 +1 @@
+package adminapi // import "code.cloudfoundry.org/route-emitter/adminapi"
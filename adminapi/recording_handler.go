@@ -0,0 +1,53 @@
+package adminapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/emitter"
+)
+
+const RecordingPath = "/recording"
+
+type RecordingHandler struct {
+	logger   lager.Logger
+	recorder *emitter.RecordingEmitter
+}
+
+func NewRecordingHandler(logger lager.Logger, recorder *emitter.RecordingEmitter) *RecordingHandler {
+	return &RecordingHandler{
+		logger:   logger.Session("recording-handler"),
+		recorder: recorder,
+	}
+}
+
+// ServeHTTP serves GET /recording with the per-subject counts and the
+// buffered messages of a dry-run emitter. The subject query parameter limits
+// the buffered messages to a single subject, the counts are always complete.
+func (h *RecordingHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	logger := h.logger.Session("serve", lager.Data{"query": req.URL.RawQuery})
+
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	recording := h.recorder.Recording()
+
+	if subject := req.URL.Query().Get("subject"); subject != "" {
+		messages := []emitter.RecordedMessage{}
+		for _, message := range recording.Messages {
+			if message.Subject == subject {
+				messages = append(messages, message)
+			}
+		}
+		recording.Messages = messages
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(recording)
+	if err != nil {
+		logger.Error("failed-to-encode-recording", err)
+	}
+}
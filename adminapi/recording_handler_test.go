@@ -0,0 +1,87 @@
+package adminapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/adminapi"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecordingHandler", func() {
+	var (
+		handler  *adminapi.RecordingHandler
+		recorder *httptest.ResponseRecorder
+		method   string
+		path     string
+	)
+
+	BeforeEach(func() {
+		logger := lagertest.NewTestLogger("test")
+		recordingEmitter := emitter.NewRecordingEmitter(logger, fakeclock.NewFakeClock(time.Now()), 10, false)
+		err := recordingEmitter.NATSEmitter().Emit(routingtable.MessagesToEmit{
+			RegistrationMessages: []routingtable.RegistryMessage{
+				{Host: "1.1.1.1", Port: 61001, URIs: []string{"foo.example.com"}},
+			},
+			UnregistrationMessages: []routingtable.RegistryMessage{
+				{Host: "2.2.2.2", Port: 61002, URIs: []string{"bar.example.com"}},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		handler = adminapi.NewRecordingHandler(logger, recordingEmitter)
+		recorder = httptest.NewRecorder()
+		method = "GET"
+		path = "/recording"
+	})
+
+	JustBeforeEach(func() {
+		request, err := http.NewRequest(method, path, nil)
+		Expect(err).NotTo(HaveOccurred())
+		handler.ServeHTTP(recorder, request)
+	})
+
+	It("returns the counts and the recorded messages", func() {
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+
+		var recording emitter.Recording
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &recording)).To(Succeed())
+		Expect(recording.Counts).To(Equal(map[string]uint64{
+			emitter.RouterRegisterSubject:   1,
+			emitter.RouterUnregisterSubject: 1,
+		}))
+		Expect(recording.Messages).To(HaveLen(2))
+		Expect(recording.Messages[0].RegistryMessage.URIs).To(ConsistOf("foo.example.com"))
+	})
+
+	Context("when filtered by subject", func() {
+		BeforeEach(func() {
+			path = "/recording?subject=router.unregister"
+		})
+
+		It("returns only the messages for that subject", func() {
+			var recording emitter.Recording
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &recording)).To(Succeed())
+			Expect(recording.Counts).To(HaveLen(2))
+			Expect(recording.Messages).To(HaveLen(1))
+			Expect(recording.Messages[0].RegistryMessage.URIs).To(ConsistOf("bar.example.com"))
+		})
+	})
+
+	Context("when the request is not a GET", func() {
+		BeforeEach(func() {
+			method = "POST"
+		})
+
+		It("returns 405", func() {
+			Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+})
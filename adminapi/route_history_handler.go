@@ -0,0 +1,62 @@
+package adminapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+const RouteHistoryPath = "/route_history"
+
+type RouteHistory struct {
+	Records []routingtable.ChangeRecord `json:"records"`
+}
+
+type RouteHistoryHandler struct {
+	logger  lager.Logger
+	history *routingtable.History
+}
+
+func NewRouteHistoryHandler(logger lager.Logger, history *routingtable.History) *RouteHistoryHandler {
+	return &RouteHistoryHandler{
+		logger:  logger.Session("route-history-handler"),
+		history: history,
+	}
+}
+
+// ServeHTTP serves GET /route_history with the recorded route changes, oldest
+// first. The hostname, process_guid, router_group and external_port query
+// parameters filter the records.
+func (h *RouteHistoryHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	logger := h.logger.Session("serve", lager.Data{"query": req.URL.RawQuery})
+
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := req.URL.Query()
+
+	filter := routingtable.HistoryFilter{
+		ProcessGUID:     query.Get("process_guid"),
+		Hostname:        query.Get("hostname"),
+		RouterGroupGUID: query.Get("router_group"),
+	}
+	if value := query.Get("external_port"); value != "" {
+		port, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || port == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		filter.ExternalPort = uint32(port)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(RouteHistory{Records: h.history.Records(filter)})
+	if err != nil {
+		logger.Error("failed-to-encode-route-history", err)
+	}
+}
@@ -0,0 +1,157 @@
+package adminapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/adminapi"
+	"code.cloudfoundry.org/route-emitter/cfroutes"
+	metricsfakes "code.cloudfoundry.org/route-emitter/metrics/fakes"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/routing-info/tcp_routes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RouteHistoryHandler", func() {
+	var (
+		table    routingtable.RoutingTable
+		handler  *adminapi.RouteHistoryHandler
+		recorder *httptest.ResponseRecorder
+		method   string
+		path     string
+		tag      models.ModificationTag
+	)
+
+	desire := func(processGuid string, port uint32, hostname string, externalPort uint32, routerGroup string) {
+		routes := cfroutes.CFRoutes{{Hostnames: []string{hostname}, Port: port}}.RoutingInfo()
+		tcpRoutes := tcp_routes.TCPRoutes{{RouterGroupGuid: routerGroup, ExternalPort: externalPort, ContainerPort: port}}.RoutingInfo()
+		for key, message := range *tcpRoutes {
+			routes[key] = message
+		}
+
+		schedulingInfo := models.NewDesiredLRPSchedulingInfo(
+			models.NewDesiredLRPKey(processGuid, "domain", "log-guid"),
+			"", 1, models.NewDesiredLRPResource(0, 0, 0, ""), routes, tag, nil, nil,
+		)
+		table.SetRoutes(nil, &schedulingInfo)
+	}
+
+	start := func(processGuid, instanceGuid, host string, hostPort, containerPort uint32) {
+		table.AddEndpoint(&routingtable.ActualLRPRoutingInfo{
+			ActualLRP: &models.ActualLRP{
+				ActualLRPKey:         models.NewActualLRPKey(processGuid, 0, "domain"),
+				ActualLRPInstanceKey: models.NewActualLRPInstanceKey(instanceGuid, "cell-id"),
+				ActualLRPNetInfo:     models.NewActualLRPNetInfo(host, "10.0.0.1", models.NewPortMapping(hostPort, containerPort)),
+				State:                models.ActualLRPStateRunning,
+				ModificationTag:      tag,
+			},
+		})
+	}
+
+	responseRecords := func() []routingtable.ChangeRecord {
+		var history adminapi.RouteHistory
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &history)).To(Succeed())
+		return history.Records
+	}
+
+	BeforeEach(func() {
+		logger := lagertest.NewTestLogger("test")
+		clock := fakeclock.NewFakeClock(time.Now())
+		history := routingtable.NewHistory(clock, 10, 10)
+		table = routingtable.NewDrainingRoutingTable(logger, false, &metricsfakes.FakeSink{}, clock, 0, history)
+		handler = adminapi.NewRouteHistoryHandler(logger, history)
+		recorder = httptest.NewRecorder()
+		method = "GET"
+		path = "/route_history"
+		tag = models.ModificationTag{Epoch: "abc", Index: 1}
+
+		desire("process-guid-1", 8080, "foo.example.com", 5222, "router-group-1")
+		desire("process-guid-2", 8080, "bar.example.com", 5223, "router-group-2")
+		start("process-guid-1", "instance-guid-1", "1.1.1.1", 61001, 8080)
+		start("process-guid-2", "instance-guid-2", "2.2.2.2", 61002, 8080)
+	})
+
+	JustBeforeEach(func() {
+		request, err := http.NewRequest(method, path, nil)
+		Expect(err).NotTo(HaveOccurred())
+		handler.ServeHTTP(recorder, request)
+	})
+
+	It("returns every record, oldest first", func() {
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+
+		records := responseRecords()
+		Expect(records).To(HaveLen(4))
+		Expect(records[0].ProcessGUID).To(Equal("process-guid-1"))
+		Expect(records[3].ProcessGUID).To(Equal("process-guid-2"))
+	})
+
+	Context("when filtered by hostname", func() {
+		BeforeEach(func() {
+			path = "/route_history?hostname=bar.example.com"
+		})
+
+		It("returns the records of that hostname", func() {
+			records := responseRecords()
+			Expect(records).To(HaveLen(1))
+			Expect(records[0].Hostname).To(Equal("bar.example.com"))
+			Expect(records[0].InstanceGUID).To(Equal("instance-guid-2"))
+			Expect(records[0].Change).To(Equal(routingtable.ChangeAdded))
+		})
+	})
+
+	Context("when filtered by process guid", func() {
+		BeforeEach(func() {
+			path = "/route_history?process_guid=process-guid-1"
+		})
+
+		It("returns the records of that process", func() {
+			records := responseRecords()
+			Expect(records).To(HaveLen(2))
+			for _, record := range records {
+				Expect(record.ProcessGUID).To(Equal("process-guid-1"))
+			}
+		})
+	})
+
+	Context("when filtered by router group and external port", func() {
+		BeforeEach(func() {
+			path = "/route_history?router_group=router-group-1&external_port=5222"
+		})
+
+		It("returns the records of that tcp route", func() {
+			records := responseRecords()
+			Expect(records).To(HaveLen(1))
+			Expect(records[0].Table).To(Equal("tcp"))
+			Expect(records[0].RouterGroupGUID).To(Equal("router-group-1"))
+			Expect(records[0].ExternalPort).To(BeEquivalentTo(5222))
+		})
+	})
+
+	Context("when the external port is invalid", func() {
+		BeforeEach(func() {
+			path = "/route_history?external_port=not-a-port"
+		})
+
+		It("responds with a bad request", func() {
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Context("when the method is not GET", func() {
+		BeforeEach(func() {
+			method = "POST"
+		})
+
+		It("responds with method not allowed", func() {
+			Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+})
@@ -0,0 +1,278 @@
+package adminapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+const (
+	RoutingTablePath = "/routing_table"
+
+	HTTPTable     = "http"
+	TCPTable      = "tcp"
+	InternalTable = "internal"
+)
+
+type Route struct {
+	Hostname         string `json:"hostname,omitempty"`
+	RouteServiceUrl  string `json:"route_service_url,omitempty"`
+	IsolationSegment string `json:"isolation_segment,omitempty"`
+	LogGUID          string `json:"log_guid,omitempty"`
+	RouterGroupGUID  string `json:"router_group_guid,omitempty"`
+	ExternalPort     uint32 `json:"external_port,omitempty"`
+	Weight           uint32 `json:"weight,omitempty"`
+}
+
+type Endpoint struct {
+	InstanceGUID          string                  `json:"instance_guid"`
+	Index                 int32                   `json:"index"`
+	Host                  string                  `json:"host"`
+	ContainerIP           string                  `json:"container_ip"`
+	Port                  uint32                  `json:"port"`
+	ContainerPort         uint32                  `json:"container_port"`
+	TlsProxyPort          uint32                  `json:"tls_proxy_port,omitempty"`
+	ContainerTlsProxyPort uint32                  `json:"container_tls_proxy_port,omitempty"`
+	Evacuating            bool                    `json:"evacuating"`
+	Since                 int64                   `json:"since"`
+	ModificationTag       *models.ModificationTag `json:"modification_tag,omitempty"`
+}
+
+type Entry struct {
+	ProcessGUID      string                  `json:"process_guid"`
+	ContainerPort    uint32                  `json:"container_port"`
+	Domain           string                  `json:"domain"`
+	DesiredInstances int32                   `json:"desired_instances"`
+	ModificationTag  *models.ModificationTag `json:"modification_tag,omitempty"`
+	Routes           []Route                 `json:"routes"`
+	Endpoints        []Endpoint              `json:"endpoints"`
+}
+
+type AddressEntry struct {
+	Host         string `json:"host"`
+	Port         uint32 `json:"port"`
+	InstanceGUID string `json:"instance_guid"`
+	Evacuating   bool   `json:"evacuating"`
+}
+
+type Table struct {
+	Entries        []Entry        `json:"entries"`
+	AddressEntries []AddressEntry `json:"address_entries,omitempty"`
+}
+
+type RoutingTableHandler struct {
+	logger lager.Logger
+	table  routingtable.RoutingTable
+}
+
+func NewRoutingTableHandler(logger lager.Logger, table routingtable.RoutingTable) *RoutingTableHandler {
+	return &RoutingTableHandler{
+		logger: logger.Session("routing-table-handler"),
+		table:  table,
+	}
+}
+
+// ServeHTTP serves GET /routing_table, which dumps every sub-table, and
+// GET /routing_table/{http,tcp,internal} for a single sub-table. Entries can
+// be narrowed down with the process_guid, hostname and router_group query
+// parameters.
+func (h *RoutingTableHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	logger := h.logger.Session("serve", lager.Data{"path": req.URL.Path, "query": req.URL.RawQuery})
+
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter := newEntryFilter(req.URL.Query())
+	tableName := strings.Trim(strings.TrimPrefix(req.URL.Path, RoutingTablePath), "/")
+
+	var response interface{}
+	switch tableName {
+	case "":
+		response = map[string]Table{
+			HTTPTable:     h.httpTable(filter),
+			TCPTable:      newTable(h.table.TCPEntries(), filter),
+			InternalTable: newTable(h.table.InternalEntries(), filter),
+		}
+	case HTTPTable:
+		response = h.httpTable(filter)
+	case TCPTable:
+		response = newTable(h.table.TCPEntries(), filter)
+	case InternalTable:
+		response = newTable(h.table.InternalEntries(), filter)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		logger.Error("failed-to-encode-routing-table", err)
+	}
+}
+
+func (h *RoutingTableHandler) httpTable(filter entryFilter) Table {
+	table := newTable(h.table.HTTPEntries(), filter)
+
+	instanceGUIDs := map[string]struct{}{}
+	for _, entry := range table.Entries {
+		for _, endpoint := range entry.Endpoints {
+			instanceGUIDs[endpoint.InstanceGUID] = struct{}{}
+		}
+	}
+
+	for address, endpointKey := range h.table.AddressEntries() {
+		if !filter.empty() {
+			if _, ok := instanceGUIDs[endpointKey.InstanceGUID]; !ok {
+				continue
+			}
+		}
+		table.AddressEntries = append(table.AddressEntries, AddressEntry{
+			Host:         address.Host,
+			Port:         address.Port,
+			InstanceGUID: endpointKey.InstanceGUID,
+			Evacuating:   endpointKey.Evacuating,
+		})
+	}
+
+	sort.Slice(table.AddressEntries, func(i, j int) bool {
+		if table.AddressEntries[i].Host != table.AddressEntries[j].Host {
+			return table.AddressEntries[i].Host < table.AddressEntries[j].Host
+		}
+		return table.AddressEntries[i].Port < table.AddressEntries[j].Port
+	})
+
+	return table
+}
+
+func newTable(entries map[routingtable.RoutingKey]routingtable.RoutableEndpoints, filter entryFilter) Table {
+	table := Table{Entries: []Entry{}}
+
+	for key, routableEndpoints := range entries {
+		entry := newEntry(key, routableEndpoints)
+		if !filter.matches(entry) {
+			continue
+		}
+		table.Entries = append(table.Entries, entry)
+	}
+
+	sort.Slice(table.Entries, func(i, j int) bool {
+		if table.Entries[i].ProcessGUID != table.Entries[j].ProcessGUID {
+			return table.Entries[i].ProcessGUID < table.Entries[j].ProcessGUID
+		}
+		return table.Entries[i].ContainerPort < table.Entries[j].ContainerPort
+	})
+
+	return table
+}
+
+func newEntry(key routingtable.RoutingKey, routableEndpoints routingtable.RoutableEndpoints) Entry {
+	entry := Entry{
+		ProcessGUID:      key.ProcessGUID,
+		ContainerPort:    key.ContainerPort,
+		Domain:           routableEndpoints.Domain,
+		DesiredInstances: routableEndpoints.DesiredInstances,
+		ModificationTag:  routableEndpoints.ModificationTag,
+		Routes:           []Route{},
+		Endpoints:        []Endpoint{},
+	}
+
+	for _, route := range routableEndpoints.Routes {
+		switch route := route.(type) {
+		case routingtable.Route:
+			entry.Routes = append(entry.Routes, Route{
+				Hostname:         route.Hostname,
+				RouteServiceUrl:  route.RouteServiceUrl,
+				IsolationSegment: route.IsolationSegment,
+				LogGUID:          route.LogGUID,
+				Weight:           route.Weight,
+			})
+		case routingtable.InternalRoute:
+			entry.Routes = append(entry.Routes, Route{
+				Hostname: route.Hostname,
+				LogGUID:  route.LogGUID,
+			})
+		case routingtable.ExternalEndpointInfo:
+			entry.Routes = append(entry.Routes, Route{
+				RouterGroupGUID: route.RouterGroupGUID,
+				ExternalPort:    route.Port,
+			})
+		}
+	}
+
+	for _, endpoint := range routableEndpoints.Endpoints {
+		entry.Endpoints = append(entry.Endpoints, Endpoint{
+			InstanceGUID:          endpoint.InstanceGUID,
+			Index:                 endpoint.Index,
+			Host:                  endpoint.Host,
+			ContainerIP:           endpoint.ContainerIP,
+			Port:                  endpoint.Port,
+			ContainerPort:         endpoint.ContainerPort,
+			TlsProxyPort:          endpoint.TlsProxyPort,
+			ContainerTlsProxyPort: endpoint.ContainerTlsProxyPort,
+			Evacuating:            endpoint.Evacuating,
+			Since:                 endpoint.Since,
+			ModificationTag:       endpoint.ModificationTag,
+		})
+	}
+
+	sort.Slice(entry.Endpoints, func(i, j int) bool {
+		if entry.Endpoints[i].Index != entry.Endpoints[j].Index {
+			return entry.Endpoints[i].Index < entry.Endpoints[j].Index
+		}
+		return entry.Endpoints[i].InstanceGUID < entry.Endpoints[j].InstanceGUID
+	})
+
+	return entry
+}
+
+type entryFilter struct {
+	processGUID string
+	hostname    string
+	routerGroup string
+}
+
+func newEntryFilter(query url.Values) entryFilter {
+	return entryFilter{
+		processGUID: query.Get("process_guid"),
+		hostname:    query.Get("hostname"),
+		routerGroup: query.Get("router_group"),
+	}
+}
+
+func (f entryFilter) empty() bool {
+	return f.processGUID == "" && f.hostname == "" && f.routerGroup == ""
+}
+
+func (f entryFilter) matches(entry Entry) bool {
+	if f.processGUID != "" && entry.ProcessGUID != f.processGUID {
+		return false
+	}
+
+	if f.hostname != "" && !entry.hasRoute(func(r Route) bool { return r.Hostname == f.hostname }) {
+		return false
+	}
+
+	if f.routerGroup != "" && !entry.hasRoute(func(r Route) bool { return r.RouterGroupGUID == f.routerGroup }) {
+		return false
+	}
+
+	return true
+}
+
+func (e Entry) hasRoute(predicate func(Route) bool) bool {
+	for _, route := range e.Routes {
+		if predicate(route) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+package adminapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/adminapi"
+	"code.cloudfoundry.org/route-emitter/cfroutes"
+	metricsfakes "code.cloudfoundry.org/route-emitter/metrics/fakes"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/routing-info/internalroutes"
+	"code.cloudfoundry.org/routing-info/tcp_routes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RoutingTableHandler", func() {
+	var (
+		table    routingtable.RoutingTable
+		handler  *adminapi.RoutingTableHandler
+		recorder *httptest.ResponseRecorder
+		method   string
+		path     string
+		tag      models.ModificationTag
+	)
+
+	desire := func(processGuid string, port uint32, hostnames []string, internalHostname string, externalPort uint32, routerGroup string) {
+		routes := cfroutes.CFRoutes{{Hostnames: hostnames, Port: port}}.RoutingInfo()
+		tcpRoutes := tcp_routes.TCPRoutes{{RouterGroupGuid: routerGroup, ExternalPort: externalPort, ContainerPort: port}}.RoutingInfo()
+		for key, message := range *tcpRoutes {
+			routes[key] = message
+		}
+		internalRoutes := internalroutes.InternalRoutes{{Hostname: internalHostname}}.RoutingInfo()
+		for key, message := range internalRoutes {
+			routes[key] = message
+		}
+
+		schedulingInfo := models.NewDesiredLRPSchedulingInfo(
+			models.NewDesiredLRPKey(processGuid, "domain", "log-guid"),
+			"", 1, models.NewDesiredLRPResource(0, 0, 0, ""), routes, tag, nil, nil,
+		)
+		table.SetRoutes(nil, &schedulingInfo)
+	}
+
+	start := func(processGuid, instanceGuid, host string, hostPort, containerPort uint32) {
+		table.AddEndpoint(&routingtable.ActualLRPRoutingInfo{
+			ActualLRP: &models.ActualLRP{
+				ActualLRPKey:         models.NewActualLRPKey(processGuid, 0, "domain"),
+				ActualLRPInstanceKey: models.NewActualLRPInstanceKey(instanceGuid, "cell-id"),
+				ActualLRPNetInfo:     models.NewActualLRPNetInfo(host, "10.0.0.1", models.NewPortMapping(hostPort, containerPort)),
+				State:                models.ActualLRPStateRunning,
+				ModificationTag:      tag,
+			},
+		})
+	}
+
+	BeforeEach(func() {
+		logger := lagertest.NewTestLogger("test")
+		table = routingtable.NewRoutingTable(logger, false, &metricsfakes.FakeSink{})
+		handler = adminapi.NewRoutingTableHandler(logger, table)
+		recorder = httptest.NewRecorder()
+		method = "GET"
+		tag = models.ModificationTag{Epoch: "abc", Index: 1}
+
+		desire("process-guid-1", 8080, []string{"foo.example.com"}, "foo.apps.internal", 5222, "router-group-1")
+		desire("process-guid-2", 8080, []string{"bar.example.com"}, "bar.apps.internal", 5223, "router-group-2")
+		start("process-guid-1", "instance-guid-1", "1.1.1.1", 61001, 8080)
+		start("process-guid-2", "instance-guid-2", "2.2.2.2", 61002, 8080)
+	})
+
+	JustBeforeEach(func() {
+		request, err := http.NewRequest(method, path, nil)
+		Expect(err).NotTo(HaveOccurred())
+		handler.ServeHTTP(recorder, request)
+	})
+
+	Context("when all sub-tables are requested", func() {
+		BeforeEach(func() {
+			path = "/routing_table"
+		})
+
+		It("returns the http, tcp and internal tables", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+
+			var tables map[string]adminapi.Table
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &tables)).To(Succeed())
+			Expect(tables).To(HaveKey("http"))
+			Expect(tables).To(HaveKey("tcp"))
+			Expect(tables).To(HaveKey("internal"))
+			Expect(tables["http"].Entries).To(HaveLen(2))
+			Expect(tables["tcp"].Entries).To(HaveLen(2))
+			Expect(tables["internal"].Entries).To(HaveLen(2))
+		})
+	})
+
+	Context("when the http table is requested", func() {
+		BeforeEach(func() {
+			path = "/routing_table/http"
+		})
+
+		It("returns the entries with their routes, endpoints and address entries", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+
+			var table adminapi.Table
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &table)).To(Succeed())
+			Expect(table.Entries).To(HaveLen(2))
+
+			entry := table.Entries[0]
+			Expect(entry.ProcessGUID).To(Equal("process-guid-1"))
+			Expect(entry.ContainerPort).To(BeEquivalentTo(8080))
+			Expect(entry.Domain).To(Equal("domain"))
+			Expect(entry.DesiredInstances).To(BeEquivalentTo(1))
+			Expect(entry.ModificationTag).To(Equal(&tag))
+			Expect(entry.Routes).To(ConsistOf(adminapi.Route{Hostname: "foo.example.com", LogGUID: "log-guid"}))
+			Expect(entry.Endpoints).To(HaveLen(1))
+			Expect(entry.Endpoints[0].InstanceGUID).To(Equal("instance-guid-1"))
+			Expect(entry.Endpoints[0].Host).To(Equal("1.1.1.1"))
+			Expect(entry.Endpoints[0].Port).To(BeEquivalentTo(61001))
+
+			Expect(table.AddressEntries).To(Equal([]adminapi.AddressEntry{
+				{Host: "1.1.1.1", Port: 61001, InstanceGUID: "instance-guid-1"},
+				{Host: "2.2.2.2", Port: 61002, InstanceGUID: "instance-guid-2"},
+			}))
+		})
+
+		Context("and filtered by hostname", func() {
+			BeforeEach(func() {
+				path = "/routing_table/http?hostname=bar.example.com"
+			})
+
+			It("returns only the matching entries and address entries", func() {
+				var table adminapi.Table
+				Expect(json.Unmarshal(recorder.Body.Bytes(), &table)).To(Succeed())
+				Expect(table.Entries).To(HaveLen(1))
+				Expect(table.Entries[0].ProcessGUID).To(Equal("process-guid-2"))
+				Expect(table.AddressEntries).To(Equal([]adminapi.AddressEntry{
+					{Host: "2.2.2.2", Port: 61002, InstanceGUID: "instance-guid-2"},
+				}))
+			})
+		})
+	})
+
+	Context("when the tcp table is filtered by router group", func() {
+		BeforeEach(func() {
+			path = "/routing_table/tcp?router_group=router-group-1"
+		})
+
+		It("returns only the matching entries", func() {
+			var table adminapi.Table
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &table)).To(Succeed())
+			Expect(table.Entries).To(HaveLen(1))
+			Expect(table.Entries[0].ProcessGUID).To(Equal("process-guid-1"))
+			Expect(table.Entries[0].Routes).To(ConsistOf(adminapi.Route{RouterGroupGUID: "router-group-1", ExternalPort: 5222}))
+		})
+	})
+
+	Context("when the internal table is filtered by process guid", func() {
+		BeforeEach(func() {
+			path = "/routing_table/internal?process_guid=process-guid-2"
+		})
+
+		It("returns only the matching entries", func() {
+			var table adminapi.Table
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &table)).To(Succeed())
+			Expect(table.Entries).To(HaveLen(1))
+			Expect(table.Entries[0].Routes).To(ConsistOf(adminapi.Route{Hostname: "bar.apps.internal", LogGUID: "log-guid"}))
+		})
+	})
+
+	Context("when an unknown table is requested", func() {
+		BeforeEach(func() {
+			path = "/routing_table/foo"
+		})
+
+		It("returns 404", func() {
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Context("when the request is not a GET", func() {
+		BeforeEach(func() {
+			path = "/routing_table"
+			method = "DELETE"
+		})
+
+		It("returns 405", func() {
+			Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+})
@@ -0,0 +1,56 @@
+package auditlog
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	tcpmodels "code.cloudfoundry.org/routing-api/models"
+)
+
+// The sources of route changes. Event driven changes also record the type
+// of the triggering BBS event.
+const (
+	SourceEvent   = "event"
+	SourceSync    = "sync"
+	SourceRefresh = "refresh"
+	SourceDrain   = "drain"
+)
+
+// Entry is a single route change, the registrations and unregistrations it
+// resulted in are exactly the ones that were emitted.
+type Entry struct {
+	Time         time.Time `json:"time"`
+	Source       string    `json:"source"`
+	EventType    string    `json:"event_type,omitempty"`
+	ProcessGUID  string    `json:"process_guid,omitempty"`
+	InstanceGUID string    `json:"instance_guid,omitempty"`
+
+	Registrations           []routingtable.RegistryMessage `json:"registrations,omitempty"`
+	Unregistrations         []routingtable.RegistryMessage `json:"unregistrations,omitempty"`
+	InternalRegistrations   []routingtable.RegistryMessage `json:"internal_registrations,omitempty"`
+	InternalUnregistrations []routingtable.RegistryMessage `json:"internal_unregistrations,omitempty"`
+	TCPRegistrations        []tcpmodels.TcpRouteMapping    `json:"tcp_registrations,omitempty"`
+	TCPUnregistrations      []tcpmodels.TcpRouteMapping    `json:"tcp_unregistrations,omitempty"`
+}
+
+// WithChanges returns a copy of the entry recording messagesToEmit and
+// routeMappings.
+func (e Entry) WithChanges(messagesToEmit routingtable.MessagesToEmit, routeMappings routingtable.TCPRouteMappings) Entry {
+	e.Registrations = messagesToEmit.RegistrationMessages
+	e.Unregistrations = messagesToEmit.UnregistrationMessages
+	e.InternalRegistrations = messagesToEmit.InternalRegistrationMessages
+	e.InternalUnregistrations = messagesToEmit.InternalUnregistrationMessages
+	e.TCPRegistrations = routeMappings.Registrations
+	e.TCPUnregistrations = routeMappings.Unregistrations
+	return e
+}
+
+// Empty is true when the entry records no change at all.
+func (e Entry) Empty() bool {
+	return len(e.Registrations) == 0 &&
+		len(e.Unregistrations) == 0 &&
+		len(e.InternalRegistrations) == 0 &&
+		len(e.InternalUnregistrations) == 0 &&
+		len(e.TCPRegistrations) == 0 &&
+		len(e.TCPUnregistrations) == 0
+}
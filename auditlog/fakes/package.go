@@ -0,0 +1 @@
+package fakes // import "code.cloudfoundry.org/route-emitter/auditlog/fakes"
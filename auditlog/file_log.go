@@ -0,0 +1,176 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+)
+
+//go:generate counterfeiter -o fakes/fake_recorder.go . Recorder
+
+type Recorder interface {
+	Record(entry Entry)
+}
+
+// FileLog appends every entry as a JSON line to the file at path. Once the
+// file would grow past maxSize bytes it is rotated to path.1, the previous
+// path.1 to path.2 and so on, keeping at most maxBackups rotated files. The
+// most recent entries are also kept in a fixed size buffer to be tailed.
+type FileLog struct {
+	logger     lager.Logger
+	clock      clock.Clock
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	lock    sync.Mutex
+	file    *os.File
+	size    int64
+	entries []Entry
+	next    int
+	full    bool
+}
+
+var _ Recorder = new(FileLog)
+
+func NewFileLog(logger lager.Logger, clock clock.Clock, path string, maxSize int64, maxBackups, bufferSize int) (*FileLog, error) {
+	l := &FileLog{
+		logger:     logger.Session("audit-log", lager.Data{"path": path}),
+		clock:      clock,
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+		entries:    make([]Entry, bufferSize),
+	}
+
+	err := l.open()
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Record stamps the entry with the current time, buffers it and appends it
+// to the file. Failing to write is logged, route changes are never held up
+// by the audit log.
+func (l *FileLog) Record(entry Entry) {
+	entry.Time = l.clock.Now()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		l.logger.Error("failed-to-marshal-entry", err)
+		return
+	}
+	data = append(data, '\n')
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.buffer(entry)
+
+	// the file is only missing when reopening it during a rotation failed
+	if l.file == nil {
+		err = l.open()
+		if err != nil {
+			l.logger.Error("failed-to-reopen", err)
+			return
+		}
+	}
+
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(data)) > l.maxSize {
+		err = l.rotate()
+		if err != nil {
+			l.logger.Error("failed-to-rotate", err)
+			if l.file == nil {
+				return
+			}
+		}
+	}
+
+	n, err := l.file.Write(data)
+	l.size += int64(n)
+	if err != nil {
+		l.logger.Error("failed-to-write-entry", err)
+	}
+}
+
+// Entries returns a copy of the buffered entries, oldest first.
+func (l *FileLog) Entries() []Entry {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	entries := []Entry{}
+	if l.full {
+		entries = append(entries, l.entries[l.next:]...)
+	}
+	return append(entries, l.entries[:l.next]...)
+}
+
+func (l *FileLog) buffer(entry Entry) {
+	if len(l.entries) == 0 {
+		return
+	}
+
+	l.entries[l.next] = entry
+	l.next++
+	if l.next == len(l.entries) {
+		l.next = 0
+		l.full = true
+	}
+}
+
+func (l *FileLog) open() error {
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+// rotate reopens the file even when moving it aside failed, the entries then
+// keep being appended to it.
+func (l *FileLog) rotate() error {
+	err := l.file.Close()
+	l.file = nil
+	if err != nil {
+		return err
+	}
+
+	err = l.moveAside()
+	openErr := l.open()
+	if openErr != nil {
+		return openErr
+	}
+	return err
+}
+
+func (l *FileLog) moveAside() error {
+	if l.maxBackups == 0 {
+		return os.Remove(l.path)
+	}
+
+	for i := l.maxBackups - 1; i > 0; i-- {
+		err := os.Rename(l.backupPath(i), l.backupPath(i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(l.path, l.backupPath(1))
+}
+
+func (l *FileLog) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", l.path, i)
+}
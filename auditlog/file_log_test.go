@@ -0,0 +1,131 @@
+package auditlog_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/auditlog"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FileLog", func() {
+	var (
+		clock      *fakeclock.FakeClock
+		tmpDir     string
+		path       string
+		maxSize    int64
+		maxBackups int
+		fileLog    *auditlog.FileLog
+		entry      auditlog.Entry
+	)
+
+	readEntries := func(path string) []auditlog.Entry {
+		file, err := os.Open(path)
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+
+		entries := []auditlog.Entry{}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var entry auditlog.Entry
+			Expect(json.Unmarshal(scanner.Bytes(), &entry)).To(Succeed())
+			entries = append(entries, entry)
+		}
+		Expect(scanner.Err()).NotTo(HaveOccurred())
+		return entries
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "auditlog")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(tmpDir, "audit.jsonl")
+
+		clock = fakeclock.NewFakeClock(time.Unix(1000, 0).UTC())
+		maxSize = 1024 * 1024
+		maxBackups = 2
+
+		entry = auditlog.Entry{
+			Source:       auditlog.SourceEvent,
+			EventType:    "actual_lrp_removed",
+			ProcessGUID:  "process-guid",
+			InstanceGUID: "instance-guid",
+			Unregistrations: []routingtable.RegistryMessage{
+				{Host: "1.1.1.1", Port: 61001, URIs: []string{"foo.example.com"}},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		fileLog, err = auditlog.NewFileLog(lagertest.NewTestLogger("test"), clock, path, maxSize, maxBackups, 2)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	It("appends the entries stamped with the current time to the file", func() {
+		fileLog.Record(entry)
+		clock.Increment(time.Second)
+		fileLog.Record(entry)
+
+		entries := readEntries(path)
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].Time).To(Equal(time.Unix(1000, 0).UTC()))
+		Expect(entries[1].Time).To(Equal(time.Unix(1001, 0).UTC()))
+		Expect(entries[1].ProcessGUID).To(Equal("process-guid"))
+		Expect(entries[1].Unregistrations).To(Equal(entry.Unregistrations))
+	})
+
+	It("keeps the most recent entries in the buffer, oldest first", func() {
+		for i := 0; i < 3; i++ {
+			fileLog.Record(entry)
+			clock.Increment(time.Second)
+		}
+
+		entries := fileLog.Entries()
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].Time).To(Equal(time.Unix(1001, 0).UTC()))
+		Expect(entries[1].Time).To(Equal(time.Unix(1002, 0).UTC()))
+	})
+
+	Context("when the file already exists", func() {
+		BeforeEach(func() {
+			Expect(ioutil.WriteFile(path, []byte("{}\n"), 0644)).To(Succeed())
+		})
+
+		It("appends to it", func() {
+			fileLog.Record(entry)
+			Expect(readEntries(path)).To(HaveLen(2))
+		})
+	})
+
+	Context("when the file would grow past the max size", func() {
+		BeforeEach(func() {
+			maxSize = 1
+		})
+
+		It("rotates it, keeping at most max backups", func() {
+			for i := 0; i < 4; i++ {
+				fileLog.Record(entry)
+				clock.Increment(time.Second)
+			}
+
+			Expect(readEntries(path)[0].Time).To(Equal(time.Unix(1003, 0).UTC()))
+			Expect(readEntries(path + ".1")[0].Time).To(Equal(time.Unix(1002, 0).UTC()))
+			Expect(readEntries(path + ".2")[0].Time).To(Equal(time.Unix(1001, 0).UTC()))
+			Expect(path + ".3").NotTo(BeAnExistingFile())
+		})
+	})
+})
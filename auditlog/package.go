@@ -0,0 +1 @@
+package auditlog // import "code.cloudfoundry.org/route-emitter/auditlog"
@@ -10,10 +10,23 @@ const CF_ROUTER = "cf-router"
 
 type CFRoutes []CFRoute
 
+// CFRoute is a route of the cf-router routing info. Weight is the share of
+// the requests to Hostnames the route gets relative to the other routes for
+// the same hostnames, 0 leaves it to the router.
 type CFRoute struct {
-	Hostnames       []string `json:"hostnames"`
-	Port            uint32   `json:"port"`
-	RouteServiceUrl string   `json:"route_service_url,omitempty"`
+	Hostnames        []string `json:"hostnames"`
+	Port             uint32   `json:"port"`
+	RouteServiceUrl  string   `json:"route_service_url,omitempty"`
+	IsolationSegment string   `json:"isolation_segment,omitempty"`
+	Weight           uint32   `json:"weight,omitempty"`
+	Options          *Options `json:"options,omitempty"`
+}
+
+// Options are the per-route settings passed on to the router.
+type Options struct {
+	LoadBalancingAlgorithm   string   `json:"loadbalancing,omitempty"`
+	StickySessionCookieNames []string `json:"sticky_session_cookie_names,omitempty"`
+	RequestTimeoutMs         uint32   `json:"request_timeout_ms,omitempty"`
 }
 
 func (c CFRoutes) RoutingInfo() models.Routes {
@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 
 	"code.cloudfoundry.org/bbs/models"
-	"code.cloudfoundry.org/routing-info/cfroutes"
+	"code.cloudfoundry.org/route-emitter/cfroutes"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -29,9 +29,11 @@ var _ = Describe("RoutingInfoHelpers", func() {
 			Port:      22222,
 		}
 		route3 = cfroutes.CFRoute{
-			Hostnames:       []string{"foo3.example.com", "bar3.examaple.com"},
-			Port:            33333,
-			RouteServiceUrl: "rs.example.com",
+			Hostnames:        []string{"foo3.example.com", "bar3.examaple.com"},
+			Port:             33333,
+			RouteServiceUrl:  "rs.example.com",
+			IsolationSegment: "some-isolation-segment",
+			Weight:           25,
 		}
 
 		routes = cfroutes.CFRoutes{route1, route2, route3}
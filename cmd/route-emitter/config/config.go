@@ -2,12 +2,14 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"time"
 
 	"code.cloudfoundry.org/debugserver"
 	loggingclient "code.cloudfoundry.org/diego-logging-client"
 	"code.cloudfoundry.org/durationjson"
+	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagerflags"
 	"code.cloudfoundry.org/locket"
 )
@@ -26,6 +28,69 @@ type OAuthConfig struct {
 	SkipCertVerify bool   `json:"skip_cert_verify"`
 }
 
+type XDSConfig struct {
+	ListenAddress    string `json:"listen_address"`
+	HTTPListenerPort uint32 `json:"http_listener_port"`
+}
+
+// DNSConfig configures the authoritative DNS server for the hostnames of
+// internal routes in Domains.
+type DNSConfig struct {
+	ListenAddress   string                `json:"listen_address"`
+	Domains         []string              `json:"domains"`
+	TTL             durationjson.Duration `json:"ttl"`
+	RefreshInterval durationjson.Duration `json:"refresh_interval"`
+}
+
+// KubernetesConfig selects where the Services and EndpointSlices mirroring
+// http routes are written: the files in OutputDir if it is set, the cluster
+// of Kubeconfig otherwise. An empty Kubeconfig uses the in-cluster config.
+type KubernetesConfig struct {
+	Kubeconfig string `json:"kubeconfig"`
+	Namespace  string `json:"namespace"`
+	OutputDir  string `json:"output_dir"`
+}
+
+// AuditLogConfig configures the audit log of route changes, written as JSON
+// lines to Path and rotated once it grows past MaxSize bytes. The last
+// BufferSize entries are served by the admin API.
+type AuditLogConfig struct {
+	Path       string `json:"path"`
+	MaxSize    int64  `json:"max_size"`
+	MaxBackups int    `json:"max_backups"`
+	BufferSize int    `json:"buffer_size"`
+}
+
+// RouteHistoryConfig bounds the in-memory history of route changes served by
+// the admin API: at most MaxRecordsPerKey changes are kept for each of at most
+// MaxKeys routing keys.
+type RouteHistoryConfig struct {
+	MaxRecordsPerKey int `json:"max_records_per_key"`
+	MaxKeys          int `json:"max_keys"`
+}
+
+// RouteFilterConfig restricts the emitted routes to the processes of some
+// isolation segments, domains or process guid prefixes. Empty include lists
+// include everything, excludes take precedence over includes. Processes
+// without placement tags are in the "shared" isolation segment.
+type RouteFilterConfig struct {
+	IncludeIsolationSegments   []string `json:"include_isolation_segments"`
+	ExcludeIsolationSegments   []string `json:"exclude_isolation_segments"`
+	IncludeDomains             []string `json:"include_domains"`
+	ExcludeDomains             []string `json:"exclude_domains"`
+	IncludeProcessGuidPrefixes []string `json:"include_process_guid_prefixes"`
+	ExcludeProcessGuidPrefixes []string `json:"exclude_process_guid_prefixes"`
+}
+
+// RetryConfig bounds the queues retrying failed NATS and routing API
+// publishes, a MaxSize of 0 disables retries.
+type RetryConfig struct {
+	MaxSize     int                   `json:"max_size"`
+	MaxAttempts int                   `json:"max_attempts"`
+	MinBackoff  durationjson.Duration `json:"min_backoff"`
+	MaxBackoff  durationjson.Duration `json:"max_backoff"`
+}
+
 type RouteEmitterConfig struct {
 	BBSAddress                         string                `json:"bbs_address"`
 	BBSCACertFile                      string                `json:"bbs_ca_cert_file"`
@@ -36,6 +101,8 @@ type RouteEmitterConfig struct {
 	CellID                             string                `json:"cell_id,omitempty"`
 	UUID                               string                `json:"uuid,omitempty"`
 	RegisterDirectInstanceRoutes       bool                  `json:"register_direct_instance_routes",omitempty`
+	IgnoreRoutability                  bool                  `json:"ignore_routability,omitempty"`
+	DrainWindow                        durationjson.Duration `json:"drain_window,omitempty"`
 	CommunicationTimeout               durationjson.Duration `json:"communication_timeout,omitempty"`
 	ConsulCluster                      string                `json:"consul_cluster,omitempty"`
 	ConsulDownModeNotificationInterval durationjson.Duration `json:"consul_down_mode_notification_interval,omitempty"`
@@ -47,8 +114,21 @@ type RouteEmitterConfig struct {
 	NATSAddresses                      string                `json:"nats_addresses,omitempty"`
 	NATSUsername                       string                `json:"nats_username,omitempty"`
 	NATSPassword                       string                `json:"nats_password,omitempty"`
+	NATSTLSEnabled                     bool                  `json:"nats_tls_enabled"`
+	NATSCACertFile                     string                `json:"nats_ca_cert_file,omitempty"`
+	NATSClientCertFile                 string                `json:"nats_client_cert_file,omitempty"`
+	NATSClientKeyFile                  string                `json:"nats_client_key_file,omitempty"`
+	NATSTLSServerName                  string                `json:"nats_tls_server_name,omitempty"`
+	NATSCredentialsFile                string                `json:"nats_credentials_file,omitempty"`
+	NATSNKeySeedFile                   string                `json:"nats_nkey_seed_file,omitempty"`
+	NATSCredentialsPollInterval        durationjson.Duration `json:"nats_credentials_poll_interval,omitempty"`
 	RouteEmittingWorkers               int                   `json:"route_emitting_workers,omitempty"`
 	SyncInterval                       durationjson.Duration `json:"sync_interval,omitempty"`
+	EnableIncrementalSync              bool                  `json:"enable_incremental_sync"`
+	FullSyncCycles                     int                   `json:"full_sync_cycles,omitempty"`
+	EventResubscribeMinBackoff         durationjson.Duration `json:"event_resubscribe_min_backoff,omitempty"`
+	EventResubscribeMaxBackoff         durationjson.Duration `json:"event_resubscribe_max_backoff,omitempty"`
+	EventWorkers                       int                   `json:"event_workers,omitempty"`
 	TCPRouteTTL                        durationjson.Duration `json:"tcp_route_ttl,omitempty"`
 	OAuth                              OAuthConfig           `json:"oauth"`
 	RoutingAPI                         RoutingAPIConfig      `json:"routing_api"`
@@ -56,8 +136,31 @@ type RouteEmitterConfig struct {
 	LoggregatorConfig                  loggingclient.Config  `json:"loggregator"`
 	ReportInterval                     durationjson.Duration `json:"report_interval,omitempty"`
 	EnableInternalEmitter              bool                  `json:"enable_internal_emitter"`
+	EnableXDSEmitter                   bool                  `json:"enable_xds_emitter"`
+	XDS                                XDSConfig             `json:"xds"`
+	EnableDNSServer                    bool                  `json:"enable_dns_server"`
+	DNS                                DNSConfig             `json:"dns"`
+	EnableKubernetesEmitter            bool                  `json:"enable_kubernetes_emitter"`
+	Kubernetes                         KubernetesConfig      `json:"kubernetes"`
+	DryRun                             bool                  `json:"dry_run"`
+	DryRunBufferSize                   int                   `json:"dry_run_buffer_size,omitempty"`
+	EnablePrometheusMetrics            bool                  `json:"enable_prometheus_metrics"`
+	EnableAuditLog                     bool                  `json:"enable_audit_log"`
+	AuditLog                           AuditLogConfig        `json:"audit_log"`
+	EnableRouteHistory                 bool                  `json:"enable_route_history"`
+	RouteHistory                       RouteHistoryConfig    `json:"route_history"`
+	Retry                              RetryConfig           `json:"retry"`
+	RouteFilter                        RouteFilterConfig     `json:"route_filter"`
+	EnableBatchedRegistration          bool                  `json:"enable_batched_registration"`
+	BatchedRegistrationSize            int                   `json:"batched_registration_size,omitempty"`
 	ConsulEnabled                      bool                  `json:"consul_enabled"`
 	LocketEnabled                      bool                  `json:"locket_enabled"`
+	EnableSharding                     bool                  `json:"enable_sharding"`
+	ShardMembershipPollInterval        durationjson.Duration `json:"shard_membership_poll_interval,omitempty"`
+	EnableConsulInternalEmitter        bool                  `json:"enable_consul_internal_emitter"`
+	ConsulServiceTTL                   durationjson.Duration `json:"consul_service_ttl,omitempty"`
+	RoutingTableSnapshotPath           string                `json:"routing_table_snapshot_path,omitempty"`
+	RoutingTableSnapshotInterval       durationjson.Duration `json:"routing_table_snapshot_interval,omitempty"`
 	lagerflags.LagerConfig
 	debugserver.DebugServerConfig
 	locket.ClientLocketConfig
@@ -74,13 +177,45 @@ func DefaultRouteEmitterConfig() RouteEmitterConfig {
 		NATSAddresses:                      "nats://127.0.0.1:4222",
 		NATSUsername:                       "nats",
 		NATSPassword:                       "nats",
+		NATSCredentialsPollInterval:        durationjson.Duration(10 * time.Second),
 		RouteEmittingWorkers:               20,
 		SyncInterval:                       durationjson.Duration(time.Minute),
+		FullSyncCycles:                     10,
+		EventResubscribeMinBackoff:         durationjson.Duration(500 * time.Millisecond),
+		EventResubscribeMaxBackoff:         durationjson.Duration(30 * time.Second),
+		EventWorkers:                       8,
 		TCPRouteTTL:                        durationjson.Duration(2 * time.Minute),
 		LagerConfig:                        lagerflags.DefaultLagerConfig(),
 		EnableTCPEmitter:                   false,
 		EnableInternalEmitter:              false,
 		RegisterDirectInstanceRoutes:       false,
+		RoutingTableSnapshotInterval:       durationjson.Duration(30 * time.Second),
+		DryRunBufferSize:                   1000,
+		BatchedRegistrationSize:            100,
+		ShardMembershipPollInterval:        durationjson.Duration(5 * time.Second),
+		ConsulServiceTTL:                   durationjson.Duration(time.Minute),
+		DNS: DNSConfig{
+			Domains:         []string{"apps.internal"},
+			RefreshInterval: durationjson.Duration(time.Second),
+		},
+		Kubernetes: KubernetesConfig{
+			Namespace: "default",
+		},
+		AuditLog: AuditLogConfig{
+			MaxSize:    100 * 1024 * 1024,
+			MaxBackups: 5,
+			BufferSize: 1000,
+		},
+		RouteHistory: RouteHistoryConfig{
+			MaxRecordsPerKey: 100,
+			MaxKeys:          10000,
+		},
+		Retry: RetryConfig{
+			MaxSize:     1000,
+			MaxAttempts: 10,
+			MinBackoff:  durationjson.Duration(500 * time.Millisecond),
+			MaxBackoff:  durationjson.Duration(30 * time.Second),
+		},
 	}
 }
 
@@ -102,3 +237,19 @@ func NewRouteEmitterConfig(configPath string) (RouteEmitterConfig, error) {
 
 	return routeEmitterConfig, nil
 }
+
+// MinLogLevel returns the lager level for the configured log_level.
+func (c RouteEmitterConfig) MinLogLevel() (lager.LogLevel, error) {
+	switch c.LogLevel {
+	case lagerflags.DEBUG:
+		return lager.DEBUG, nil
+	case lagerflags.INFO:
+		return lager.INFO, nil
+	case lagerflags.ERROR:
+		return lager.ERROR, nil
+	case lagerflags.FATAL:
+		return lager.FATAL, nil
+	default:
+		return lager.INFO, fmt.Errorf("unknown log_level %q", c.LogLevel)
+	}
+}
@@ -30,6 +30,11 @@ var _ = Describe("Config", func() {
 			"communication_timeout":"2s",
 			"consul_down_mode_notification_interval": "2m",
 			"sync_interval": "4s",
+			"enable_incremental_sync": true,
+			"full_sync_cycles": 5,
+			"event_resubscribe_min_backoff": "1s",
+			"event_resubscribe_max_backoff": "1m",
+			"event_workers": 4,
 			"bbs_address": "1.1.1.1:9091",
 			"bbs_ca_cert_file": "/tmp/bbs_ca_cert",
 			"bbs_client_cert_file": "/tmp/bbs_client_cert",
@@ -40,20 +45,67 @@ var _ = Describe("Config", func() {
 			"nats_addresses": "http://127.0.0.2:4222",
 			"nats_username": "user",
 			"nats_password": "password",
+			"nats_tls_enabled": true,
+			"nats_ca_cert_file": "/tmp/nats_ca_cert",
+			"nats_client_cert_file": "/tmp/nats_client_cert",
+			"nats_client_key_file": "/tmp/nats_client_key",
+			"nats_tls_server_name": "nats.service.cf.internal",
+			"nats_credentials_file": "/tmp/nats_user.creds",
+			"nats_credentials_poll_interval": "30s",
 			"lock_retry_interval": "15s",
 			"lock_ttl": "20s",
 			"log_level": "debug",
 			"debug_address": "127.0.0.1:9999",
 			"enable_tcp_emitter": true,
 			"enable_internal_emitter": true,
+			"enable_xds_emitter": true,
 			"register_direct_instance_routes": true,
+			"ignore_routability": true,
+			"drain_window": "10s",
 			"consul_enabled": true,
 			"locket_enabled": true,
+			"enable_sharding": true,
+			"shard_membership_poll_interval": "10s",
+			"enable_consul_internal_emitter": true,
+			"consul_service_ttl": "30s",
 			"locket_address": "127.0.0.1:18018",
 			"locket_ca_cert_file": "locket-ca-cert",
 			"report_interval": "1m",
 			"locket_client_cert_file": "locket-client-cert",
 			"locket_client_key_file": "locket-client-key",
+			"routing_table_snapshot_path": "/var/vcap/data/route-emitter/routing-table.json",
+			"routing_table_snapshot_interval": "10s",
+			"dry_run": true,
+			"dry_run_buffer_size": 500,
+			"enable_prometheus_metrics": true,
+			"enable_audit_log": true,
+			"audit_log": {
+				"path": "/var/vcap/sys/log/route_emitter/audit.jsonl",
+				"max_size": 1048576,
+				"max_backups": 3,
+				"buffer_size": 200
+			},
+			"enable_route_history": true,
+			"route_history": {
+				"max_records_per_key": 20,
+				"max_keys": 500
+			},
+			"retry": {
+				"max_size": 50,
+				"max_attempts": 5,
+				"min_backoff": "1s",
+				"max_backoff": "1m"
+			},
+			"route_filter": {
+				"include_isolation_segments": ["isolation-segment-1"],
+				"exclude_isolation_segments": ["shared"],
+				"include_domains": ["cf-apps"],
+				"exclude_domains": ["cf-tasks"],
+				"include_process_guid_prefixes": ["team-a-"],
+				"exclude_process_guid_prefixes": ["team-a-internal-"]
+			},
+			"enable_batched_registration": true,
+			"batched_registration_size": 250,
 			"oauth": {
 				"uaa_url": "https://uaa.cf.service.internal:8443",
 				"client_name": "someclient",
@@ -61,6 +113,23 @@ var _ = Describe("Config", func() {
 				"ca_certs": "some-cert",
 				"skip_cert_verify": true
 			},
+			"xds": {
+				"listen_address": "127.0.0.1:18000",
+				"http_listener_port": 8080
+			},
+			"enable_dns_server": true,
+			"dns": {
+				"listen_address": "127.0.0.1:8053",
+				"domains": ["apps.internal", "internal.example.com"],
+				"ttl": "5s",
+				"refresh_interval": "2s"
+			},
+			"enable_kubernetes_emitter": true,
+			"kubernetes": {
+				"kubeconfig": "/var/vcap/jobs/route_emitter/config/kubeconfig",
+				"namespace": "cf-routes",
+				"output_dir": "/var/vcap/data/route-emitter/kubernetes"
+			},
 			"loggregator": {
 			  "loggregator_use_v2_api": true,
 			  "loggregator_api_port": 1234,
@@ -106,6 +175,11 @@ var _ = Describe("Config", func() {
 			UUID:                               "bosh-boshy-bosh-bosh",
 			CommunicationTimeout:               durationjson.Duration(2 * time.Second),
 			SyncInterval:                       durationjson.Duration(4 * time.Second),
+			EnableIncrementalSync:              true,
+			FullSyncCycles:                     5,
+			EventResubscribeMinBackoff:         durationjson.Duration(time.Second),
+			EventResubscribeMaxBackoff:         durationjson.Duration(time.Minute),
+			EventWorkers:                       4,
 			ConsulDownModeNotificationInterval: durationjson.Duration(2 * time.Minute),
 			BBSAddress:                         "1.1.1.1:9091",
 			BBSCACertFile:                      "/tmp/bbs_ca_cert",
@@ -116,6 +190,13 @@ var _ = Describe("Config", func() {
 			NATSAddresses:                      "http://127.0.0.2:4222",
 			NATSUsername:                       "user",
 			NATSPassword:                       "password",
+			NATSTLSEnabled:                     true,
+			NATSCACertFile:                     "/tmp/nats_ca_cert",
+			NATSClientCertFile:                 "/tmp/nats_client_cert",
+			NATSClientKeyFile:                  "/tmp/nats_client_key",
+			NATSTLSServerName:                  "nats.service.cf.internal",
+			NATSCredentialsFile:                "/tmp/nats_user.creds",
+			NATSCredentialsPollInterval:        durationjson.Duration(30 * time.Second),
 			LockRetryInterval:                  durationjson.Duration(15 * time.Second),
 			LockTTL:                            durationjson.Duration(20 * time.Second),
 			ConsulSessionName:                  "myconsulsession",
@@ -124,9 +205,49 @@ var _ = Describe("Config", func() {
 			ReportInterval:                     durationjson.Duration(1 * time.Minute),
 			EnableTCPEmitter:                   true,
 			EnableInternalEmitter:              true,
+			EnableXDSEmitter:                   true,
 			RegisterDirectInstanceRoutes:       true,
+			IgnoreRoutability:                  true,
+			DrainWindow:                        durationjson.Duration(10 * time.Second),
 			ConsulEnabled:                      true,
 			LocketEnabled:                      true,
+			EnableSharding:                     true,
+			ShardMembershipPollInterval:        durationjson.Duration(10 * time.Second),
+			EnableConsulInternalEmitter:        true,
+			ConsulServiceTTL:                   durationjson.Duration(30 * time.Second),
+			RoutingTableSnapshotPath:           "/var/vcap/data/route-emitter/routing-table.json",
+			RoutingTableSnapshotInterval:       durationjson.Duration(10 * time.Second),
+			DryRun:                             true,
+			DryRunBufferSize:                   500,
+			EnablePrometheusMetrics:            true,
+			EnableAuditLog:                     true,
+			AuditLog: config.AuditLogConfig{
+				Path:       "/var/vcap/sys/log/route_emitter/audit.jsonl",
+				MaxSize:    1048576,
+				MaxBackups: 3,
+				BufferSize: 200,
+			},
+			EnableRouteHistory: true,
+			RouteHistory: config.RouteHistoryConfig{
+				MaxRecordsPerKey: 20,
+				MaxKeys:          500,
+			},
+			Retry: config.RetryConfig{
+				MaxSize:     50,
+				MaxAttempts: 5,
+				MinBackoff:  durationjson.Duration(time.Second),
+				MaxBackoff:  durationjson.Duration(time.Minute),
+			},
+			RouteFilter: config.RouteFilterConfig{
+				IncludeIsolationSegments:   []string{"isolation-segment-1"},
+				ExcludeIsolationSegments:   []string{"shared"},
+				IncludeDomains:             []string{"cf-apps"},
+				ExcludeDomains:             []string{"cf-tasks"},
+				IncludeProcessGuidPrefixes: []string{"team-a-"},
+				ExcludeProcessGuidPrefixes: []string{"team-a-internal-"},
+			},
+			EnableBatchedRegistration: true,
+			BatchedRegistrationSize:   250,
 			DebugServerConfig: debugserver.DebugServerConfig{
 				DebugAddress: "127.0.0.1:9999",
 			},
@@ -140,6 +261,23 @@ var _ = Describe("Config", func() {
 				CACerts:        "some-cert",
 				SkipCertVerify: true,
 			},
+			XDS: config.XDSConfig{
+				ListenAddress:    "127.0.0.1:18000",
+				HTTPListenerPort: 8080,
+			},
+			EnableDNSServer: true,
+			DNS: config.DNSConfig{
+				ListenAddress:   "127.0.0.1:8053",
+				Domains:         []string{"apps.internal", "internal.example.com"},
+				TTL:             durationjson.Duration(5 * time.Second),
+				RefreshInterval: durationjson.Duration(2 * time.Second),
+			},
+			EnableKubernetesEmitter: true,
+			Kubernetes: config.KubernetesConfig{
+				Kubeconfig: "/var/vcap/jobs/route_emitter/config/kubeconfig",
+				Namespace:  "cf-routes",
+				OutputDir:  "/var/vcap/data/route-emitter/kubernetes",
+			},
 			LoggregatorConfig: loggingclient.Config{
 				UseV2API:      true,
 				APIPort:       1234,
@@ -200,12 +338,44 @@ var _ = Describe("Config", func() {
 				NATSAddresses:                      "nats://127.0.0.1:4222",
 				NATSUsername:                       "nats",
 				NATSPassword:                       "nats",
+				NATSCredentialsPollInterval:        durationjson.Duration(10 * time.Second),
 				RouteEmittingWorkers:               20,
 				SyncInterval:                       durationjson.Duration(time.Minute),
+				FullSyncCycles:                     10,
+				EventResubscribeMinBackoff:         durationjson.Duration(500 * time.Millisecond),
+				EventResubscribeMaxBackoff:         durationjson.Duration(30 * time.Second),
+				EventWorkers:                       8,
 				TCPRouteTTL:                        durationjson.Duration(2 * time.Minute),
 				EnableTCPEmitter:                   false,
 				EnableInternalEmitter:              false,
 				RegisterDirectInstanceRoutes:       false,
+				RoutingTableSnapshotInterval:       durationjson.Duration(30 * time.Second),
+				DryRunBufferSize:                   1000,
+				BatchedRegistrationSize:            100,
+				ShardMembershipPollInterval:        durationjson.Duration(5 * time.Second),
+				ConsulServiceTTL:                   durationjson.Duration(time.Minute),
+				DNS: config.DNSConfig{
+					Domains:         []string{"apps.internal"},
+					RefreshInterval: durationjson.Duration(time.Second),
+				},
+				Kubernetes: config.KubernetesConfig{
+					Namespace: "default",
+				},
+				AuditLog: config.AuditLogConfig{
+					MaxSize:    100 * 1024 * 1024,
+					MaxBackups: 5,
+					BufferSize: 1000,
+				},
+				RouteHistory: config.RouteHistoryConfig{
+					MaxRecordsPerKey: 100,
+					MaxKeys:          10000,
+				},
+				Retry: config.RetryConfig{
+					MaxSize:     1000,
+					MaxAttempts: 10,
+					MinBackoff:  durationjson.Duration(500 * time.Millisecond),
+					MaxBackoff:  durationjson.Duration(30 * time.Second),
+				},
 				LagerConfig: lagerflags.LagerConfig{
 					LogLevel: "info",
 				},
@@ -214,4 +384,349 @@ var _ = Describe("Config", func() {
 			Expect(routeEmitterConfig).To(Equal(config))
 		})
 	})
+
+	Describe("Validate", func() {
+		var cfg config.RouteEmitterConfig
+
+		BeforeEach(func() {
+			cfg = config.DefaultRouteEmitterConfig()
+			cfg.CellID = "cell-id"
+		})
+
+		It("accepts the defaults in local mode", func() {
+			Expect(cfg.Validate()).To(Succeed())
+		})
+
+		It("rejects a non-positive number of workers", func() {
+			cfg.RouteEmittingWorkers = 0
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring("route_emitting_workers")))
+		})
+
+		It("rejects a non-positive sync interval", func() {
+			cfg.SyncInterval = 0
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring("sync_interval")))
+		})
+
+		It("rejects a tcp route ttl that is too large", func() {
+			cfg.TCPRouteTTL = durationjson.Duration(24 * time.Hour)
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring("tcp_route_ttl")))
+		})
+
+		It("rejects an unknown log level", func() {
+			cfg.LogLevel = "loud"
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring("log_level")))
+		})
+
+		It("rejects a non-positive batch size when batching registrations", func() {
+			cfg.EnableBatchedRegistration = true
+			cfg.BatchedRegistrationSize = 0
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring("batched_registration_size")))
+		})
+
+		It("requires a ca cert when nats tls is enabled", func() {
+			cfg.NATSTLSEnabled = true
+			Expect(cfg.Validate()).To(ConsistOf(
+				config.ValidationError{Path: "nats_ca_cert_file", Message: "must be set when nats_tls_enabled is true"},
+			))
+		})
+
+		It("requires the nats client cert and key together", func() {
+			cfg.NATSTLSEnabled = true
+			cfg.NATSCACertFile = "/tmp/nats_ca_cert"
+			cfg.NATSClientCertFile = "/tmp/nats_client_cert"
+			Expect(cfg.Validate()).To(ConsistOf(
+				config.ValidationError{Path: "nats_client_key_file", Message: "must be set when nats_client_cert_file is set"},
+			))
+
+			cfg.NATSClientCertFile = ""
+			cfg.NATSClientKeyFile = "/tmp/nats_client_key"
+			Expect(cfg.Validate()).To(ConsistOf(
+				config.ValidationError{Path: "nats_client_cert_file", Message: "must be set when nats_client_key_file is set"},
+			))
+		})
+
+		It("accepts only one nats credentials file", func() {
+			cfg.NATSCredentialsFile = "/tmp/nats_user.creds"
+			cfg.NATSNKeySeedFile = "/tmp/nats_user.nk"
+			Expect(cfg.Validate()).To(ConsistOf(
+				config.ValidationError{Path: "nats_nkey_seed_file", Message: "must not be set when nats_credentials_file is set"},
+			))
+		})
+
+		It("rejects a non-positive nats credentials poll interval", func() {
+			cfg.NATSNKeySeedFile = "/tmp/nats_user.nk"
+			cfg.NATSCredentialsPollInterval = 0
+			Expect(cfg.Validate()).To(MatchError(ContainSubstring("nats_credentials_poll_interval")))
+		})
+
+		It("rejects lock settings in local mode", func() {
+			cfg.ConsulEnabled = true
+			cfg.LocketEnabled = true
+			cfg.UUID = "some-uuid"
+			Expect(cfg.Validate()).To(ConsistOf(
+				config.ValidationError{Path: "consul_enabled", Message: "must not be true when cell_id is set"},
+				config.ValidationError{Path: "locket_enabled", Message: "must not be true when cell_id is set"},
+			))
+		})
+
+		Context("in global mode", func() {
+			BeforeEach(func() {
+				cfg.CellID = ""
+			})
+
+			It("requires a lock backend", func() {
+				Expect(cfg.Validate()).To(ConsistOf(
+					config.ValidationError{Path: "cell_id", Message: "must be set unless consul_enabled or locket_enabled is true"},
+				))
+			})
+
+			It("does not require a lock backend for a dry run", func() {
+				cfg.DryRun = true
+				Expect(cfg.Validate()).To(Succeed())
+			})
+
+			It("requires a uuid for locket", func() {
+				cfg.LocketEnabled = true
+				Expect(cfg.Validate()).To(ConsistOf(
+					config.ValidationError{Path: "uuid", Message: "must be set when locket_enabled is true"},
+				))
+			})
+
+			It("accepts sharding with locket", func() {
+				cfg.EnableSharding = true
+				cfg.LocketEnabled = true
+				cfg.UUID = "some-uuid"
+				Expect(cfg.Validate()).To(Succeed())
+			})
+
+			It("requires locket and no consul for sharding", func() {
+				cfg.EnableSharding = true
+				cfg.ConsulEnabled = true
+				Expect(cfg.Validate()).To(ConsistOf(
+					config.ValidationError{Path: "locket_enabled", Message: "must be true when enable_sharding is true"},
+					config.ValidationError{Path: "consul_enabled", Message: "must not be true when enable_sharding is true"},
+				))
+			})
+		})
+
+		It("rejects sharding in local mode", func() {
+			cfg.EnableSharding = true
+			cfg.LocketEnabled = false
+			Expect(cfg.Validate()).To(ContainElement(
+				config.ValidationError{Path: "enable_sharding", Message: "must not be true when cell_id is set"},
+			))
+		})
+
+		It("requires a routing api url for the tcp emitter", func() {
+			cfg.EnableTCPEmitter = true
+			Expect(cfg.Validate()).To(ConsistOf(
+				config.ValidationError{Path: "routing_api.url", Message: "must be set when enable_tcp_emitter is true"},
+			))
+		})
+
+		It("rejects a negative drain window", func() {
+			cfg.DrainWindow = durationjson.Duration(-time.Second)
+			Expect(cfg.Validate()).To(ConsistOf(
+				config.ValidationError{Path: "drain_window", Message: "must not be negative"},
+			))
+		})
+
+		It("requires a listen address for the dns server", func() {
+			cfg.EnableDNSServer = true
+			cfg.DNS.TTL = durationjson.Duration(-time.Second)
+			Expect(cfg.Validate()).To(ConsistOf(
+				config.ValidationError{Path: "dns.listen_address", Message: "must be set when enable_dns_server is true"},
+				config.ValidationError{Path: "dns.ttl", Message: "must not be negative"},
+			))
+		})
+
+		It("requires a path and a positive max size for the audit log", func() {
+			cfg.EnableAuditLog = true
+			cfg.AuditLog.MaxSize = 0
+			Expect(cfg.Validate()).To(ConsistOf(
+				config.ValidationError{Path: "audit_log.path", Message: "must be set when enable_audit_log is true"},
+				config.ValidationError{Path: "audit_log.max_size", Message: "must be positive"},
+			))
+		})
+
+		It("requires a positive number of full sync cycles for incremental syncs", func() {
+			cfg.EnableIncrementalSync = true
+			cfg.FullSyncCycles = 0
+			Expect(cfg.Validate()).To(ConsistOf(
+				config.ValidationError{Path: "full_sync_cycles", Message: "must be positive"},
+			))
+		})
+
+		It("requires a positive event resubscription backoff", func() {
+			cfg.EventResubscribeMinBackoff = 0
+			Expect(cfg.Validate()).To(ConsistOf(
+				config.ValidationError{Path: "event_resubscribe_min_backoff", Message: "must be positive"},
+			))
+		})
+
+		It("requires the maximum event resubscription backoff to be at least the minimum", func() {
+			cfg.EventResubscribeMaxBackoff = durationjson.Duration(100 * time.Millisecond)
+			Expect(cfg.Validate()).To(ConsistOf(
+				config.ValidationError{Path: "event_resubscribe_max_backoff", Message: "must not be less than event_resubscribe_min_backoff"},
+			))
+		})
+
+		It("allows handling events without workers", func() {
+			cfg.EventWorkers = 0
+			Expect(cfg.Validate()).To(Succeed())
+		})
+
+		It("requires a non-negative number of event workers", func() {
+			cfg.EventWorkers = -1
+			Expect(cfg.Validate()).To(ConsistOf(
+				config.ValidationError{Path: "event_workers", Message: "must not be negative"},
+			))
+		})
+
+		It("requires non-empty route filter prefixes", func() {
+			cfg.RouteFilter.IncludeProcessGuidPrefixes = []string{"team-a-", ""}
+			cfg.RouteFilter.ExcludeProcessGuidPrefixes = []string{""}
+			Expect(cfg.Validate()).To(ConsistOf(
+				config.ValidationError{Path: "route_filter.include_process_guid_prefixes", Message: "must not contain empty prefixes"},
+				config.ValidationError{Path: "route_filter.exclude_process_guid_prefixes", Message: "must not contain empty prefixes"},
+			))
+		})
+
+		It("requires positive retention limits for the route history", func() {
+			cfg.EnableRouteHistory = true
+			cfg.RouteHistory.MaxRecordsPerKey = 0
+			cfg.RouteHistory.MaxKeys = -1
+			Expect(cfg.Validate()).To(ConsistOf(
+				config.ValidationError{Path: "route_history.max_records_per_key", Message: "must be positive"},
+				config.ValidationError{Path: "route_history.max_keys", Message: "must be positive"},
+			))
+		})
+
+		It("requires a namespace for the kubernetes emitter", func() {
+			cfg.EnableKubernetesEmitter = true
+			cfg.Kubernetes.Namespace = ""
+			Expect(cfg.Validate()).To(ConsistOf(
+				config.ValidationError{Path: "kubernetes.namespace", Message: "must be set when enable_kubernetes_emitter is true"},
+			))
+		})
+
+		It("requires a consul cluster and service ttl for the consul internal emitter", func() {
+			cfg.EnableConsulInternalEmitter = true
+			cfg.EnableInternalEmitter = true
+			cfg.ConsulServiceTTL = 0
+			Expect(cfg.Validate()).To(ConsistOf(
+				config.ValidationError{Path: "consul_cluster", Message: "must be set when enable_consul_internal_emitter is true"},
+				config.ValidationError{Path: "consul_service_ttl", Message: "must be at least 1s"},
+			))
+		})
+
+		It("rejects a consul service ttl below a second", func() {
+			cfg.EnableConsulInternalEmitter = true
+			cfg.EnableInternalEmitter = true
+			cfg.ConsulCluster = "http://127.0.0.1:8500"
+			cfg.ConsulServiceTTL = durationjson.Duration(2 * time.Nanosecond)
+			Expect(cfg.Validate()).To(ConsistOf(
+				config.ValidationError{Path: "consul_service_ttl", Message: "must be at least 1s"},
+			))
+		})
+
+		It("requires internal broadcasts for the consul internal emitter", func() {
+			cfg.EnableConsulInternalEmitter = true
+			cfg.ConsulCluster = "http://127.0.0.1:8500"
+			cfg.EnableInternalEmitter = false
+			Expect(cfg.Validate()).To(ConsistOf(
+				config.ValidationError{Path: "enable_internal_emitter", Message: "must be true when enable_consul_internal_emitter is true"},
+			))
+		})
+	})
+
+	Describe("ValidateFile", func() {
+		It("knows every setting of a complete config", func() {
+			errs, _ := config.ValidateFile(configPath).(config.ValidationErrors)
+			for _, err := range errs {
+				Expect(err.Message).NotTo(Equal("unknown field"), err.Path)
+			}
+		})
+
+		It("accepts a valid config", func() {
+			configData = `{"cell_id": "cell-id", "sync_interval": "4s"}`
+			Expect(ioutil.WriteFile(configPath, []byte(configData), 0644)).To(Succeed())
+			Expect(config.ValidateFile(configPath)).To(Succeed())
+		})
+
+		It("reports every problem with its json path", func() {
+			configData = `{
+				"cell_id": "cell-id",
+				"consul_enabled": true,
+				"tcp_route_ttl": "24h",
+				"unknown_key": 1,
+				"routing_api": {"uri": "http://example.com"},
+				"log_level": "debug"
+			}`
+			Expect(ioutil.WriteFile(configPath, []byte(configData), 0644)).To(Succeed())
+
+			Expect(config.ValidateFile(configPath)).To(ConsistOf(
+				config.ValidationError{Path: "routing_api.uri", Message: "unknown field"},
+				config.ValidationError{Path: "unknown_key", Message: "unknown field"},
+				config.ValidationError{Path: "tcp_route_ttl", Message: "must not be more than 18h12m15s"},
+				config.ValidationError{Path: "consul_enabled", Message: "must not be true when cell_id is set"},
+			))
+		})
+
+		It("reports values of the wrong type", func() {
+			configData = `{"cell_id": "cell-id", "route_emitting_workers": "many"}`
+			Expect(ioutil.WriteFile(configPath, []byte(configData), 0644)).To(Succeed())
+
+			err := config.ValidateFile(configPath)
+			Expect(err).To(HaveLen(1))
+			Expect(err.Error()).To(ContainSubstring("route_emitting_workers"))
+		})
+
+		It("reports invalid json", func() {
+			configData = `{`
+			Expect(ioutil.WriteFile(configPath, []byte(configData), 0644)).To(Succeed())
+			Expect(config.ValidateFile(configPath)).To(HaveLen(1))
+		})
+
+		It("returns an error when the file cannot be read", func() {
+			err := config.ValidateFile("/does/not/exist")
+			Expect(err).To(HaveOccurred())
+			Expect(err).NotTo(BeAssignableToTypeOf(config.ValidationErrors{}))
+		})
+	})
+
+	Describe("ChangedFields", func() {
+		var current, next config.RouteEmitterConfig
+
+		BeforeEach(func() {
+			current = config.DefaultRouteEmitterConfig()
+			next = config.DefaultRouteEmitterConfig()
+		})
+
+		It("returns the json names of the changed fields", func() {
+			next.SyncInterval = durationjson.Duration(time.Hour)
+			next.LogLevel = "debug"
+			next.RoutingAPI.Port = 3000
+
+			Expect(config.ChangedFields(current, next)).To(ConsistOf("sync_interval", "log_level", "routing_api"))
+		})
+
+		It("returns nothing when the configs are the same", func() {
+			Expect(config.ChangedFields(current, next)).To(BeEmpty())
+		})
+
+		It("only copies the reloadable fields", func() {
+			next.SyncInterval = durationjson.Duration(time.Hour)
+			next.NATSPassword = "secret"
+			next.RouteFilter.ExcludeDomains = []string{"cf-tasks"}
+			next.CellID = "cell-id"
+
+			updated := current.WithReloadable(next)
+			Expect(config.ChangedFields(current, updated)).To(ConsistOf("sync_interval", "nats_password", "route_filter"))
+			for _, field := range config.ChangedFields(current, updated) {
+				Expect(config.IsReloadable(field)).To(BeTrue())
+			}
+			Expect(config.IsReloadable("cell_id")).To(BeFalse())
+		})
+	})
 })
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// ReloadableFields are the settings, by JSON name, that a running route
+// emitter applies on SIGHUP. Any other change needs a restart.
+var ReloadableFields = []string{
+	"route_emitting_workers",
+	"sync_interval",
+	"tcp_route_ttl",
+	"nats_addresses",
+	"nats_username",
+	"nats_password",
+	"log_level",
+	"route_filter",
+}
+
+func IsReloadable(field string) bool {
+	for _, reloadable := range ReloadableFields {
+		if field == reloadable {
+			return true
+		}
+	}
+	return false
+}
+
+// WithReloadable returns c with the reloadable settings taken from next.
+func (c RouteEmitterConfig) WithReloadable(next RouteEmitterConfig) RouteEmitterConfig {
+	c.RouteEmittingWorkers = next.RouteEmittingWorkers
+	c.SyncInterval = next.SyncInterval
+	c.TCPRouteTTL = next.TCPRouteTTL
+	c.NATSAddresses = next.NATSAddresses
+	c.NATSUsername = next.NATSUsername
+	c.NATSPassword = next.NATSPassword
+	c.LogLevel = next.LogLevel
+	c.RouteFilter = next.RouteFilter
+	return c
+}
+
+// ChangedFields returns the JSON names of the settings that differ between
+// current and next. Fields of embedded configs are reported individually,
+// other nested configs as a whole.
+func ChangedFields(current, next RouteEmitterConfig) []string {
+	return changedFields(reflect.ValueOf(current), reflect.ValueOf(next))
+}
+
+func changedFields(current, next reflect.Value) []string {
+	changed := []string{}
+	for i := 0; i < current.NumField(); i++ {
+		field := current.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			changed = append(changed, changedFields(current.Field(i), next.Field(i))...)
+			continue
+		}
+
+		if !reflect.DeepEqual(current.Field(i).Interface(), next.Field(i).Interface()) {
+			changed = append(changed, jsonName(field))
+		}
+	}
+	return changed
+}
+
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
@@ -0,0 +1,329 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxTCPRouteTTL is the largest TTL the routing API accepts for a TCP route.
+const MaxTCPRouteTTL = 65535 * time.Second
+
+// MinConsulServiceTTL is the shortest TTL Consul accepts for a check.
+const MinConsulServiceTTL = time.Second
+
+// ValidationError is a single problem with a config, Path is the JSON path of
+// the offending setting.
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// ValidateFile parses the config at configPath strictly and reports every
+// problem with it at once. Unlike NewRouteEmitterConfig unknown keys are an
+// error. The returned error is a ValidationErrors unless the file could not be
+// read.
+func ValidateFile(configPath string) error {
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+
+	var raw interface{}
+	err = json.Unmarshal(data, &raw)
+	if err != nil {
+		return ValidationErrors{{Message: err.Error()}}
+	}
+
+	errs := unknownFields("", raw, reflect.TypeOf(RouteEmitterConfig{}))
+
+	cfg := DefaultRouteEmitterConfig()
+	err = json.NewDecoder(bytes.NewReader(data)).Decode(&cfg)
+	if err != nil {
+		if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+			errs = append(errs, ValidationError{
+				Path:    typeErr.Field,
+				Message: fmt.Sprintf("cannot use %s as %s", typeErr.Value, typeErr.Type),
+			})
+		} else {
+			errs = append(errs, ValidationError{Message: err.Error()})
+		}
+		return errs
+	}
+
+	errs = append(errs, cfg.validate()...)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Validate checks the settings, and the combinations of settings, that would
+// otherwise only fail once the route emitter is running. The returned error is
+// a ValidationErrors.
+func (c RouteEmitterConfig) Validate() error {
+	errs := c.validate()
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (c RouteEmitterConfig) validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	if c.RouteEmittingWorkers <= 0 {
+		errs = append(errs, ValidationError{"route_emitting_workers", "must be positive"})
+	}
+	if c.SyncInterval <= 0 {
+		errs = append(errs, ValidationError{"sync_interval", "must be positive"})
+	}
+	if time.Duration(c.TCPRouteTTL) > MaxTCPRouteTTL {
+		errs = append(errs, ValidationError{"tcp_route_ttl", fmt.Sprintf("must not be more than %s", MaxTCPRouteTTL)})
+	}
+	if _, err := c.MinLogLevel(); err != nil {
+		errs = append(errs, ValidationError{"log_level", fmt.Sprintf("unknown level %q", c.LogLevel)})
+	}
+
+	if c.Retry.MaxSize < 0 {
+		errs = append(errs, ValidationError{"retry.max_size", "must not be negative"})
+	}
+	if c.Retry.MaxSize > 0 {
+		if c.Retry.MaxAttempts <= 0 {
+			errs = append(errs, ValidationError{"retry.max_attempts", "must be positive"})
+		}
+		if c.Retry.MinBackoff <= 0 {
+			errs = append(errs, ValidationError{"retry.min_backoff", "must be positive"})
+		}
+		if c.Retry.MaxBackoff < c.Retry.MinBackoff {
+			errs = append(errs, ValidationError{"retry.max_backoff", "must not be less than retry.min_backoff"})
+		}
+	}
+
+	if c.NATSTLSEnabled {
+		if c.NATSCACertFile == "" {
+			errs = append(errs, ValidationError{"nats_ca_cert_file", "must be set when nats_tls_enabled is true"})
+		}
+		if c.NATSClientCertFile != "" && c.NATSClientKeyFile == "" {
+			errs = append(errs, ValidationError{"nats_client_key_file", "must be set when nats_client_cert_file is set"})
+		}
+		if c.NATSClientKeyFile != "" && c.NATSClientCertFile == "" {
+			errs = append(errs, ValidationError{"nats_client_cert_file", "must be set when nats_client_key_file is set"})
+		}
+	}
+
+	if c.NATSCredentialsFile != "" && c.NATSNKeySeedFile != "" {
+		errs = append(errs, ValidationError{"nats_nkey_seed_file", "must not be set when nats_credentials_file is set"})
+	}
+	if (c.NATSCredentialsFile != "" || c.NATSNKeySeedFile != "") && c.NATSCredentialsPollInterval <= 0 {
+		errs = append(errs, ValidationError{"nats_credentials_poll_interval", "must be positive"})
+	}
+
+	if c.DrainWindow < 0 {
+		errs = append(errs, ValidationError{"drain_window", "must not be negative"})
+	}
+
+	if c.EnableBatchedRegistration && c.BatchedRegistrationSize <= 0 {
+		errs = append(errs, ValidationError{"batched_registration_size", "must be positive"})
+	}
+
+	if c.LocketEnabled && c.UUID == "" {
+		errs = append(errs, ValidationError{"uuid", "must be set when locket_enabled is true"})
+	}
+	if c.EnableTCPEmitter && c.RoutingAPI.URL == "" {
+		errs = append(errs, ValidationError{"routing_api.url", "must be set when enable_tcp_emitter is true"})
+	}
+	if c.EnableXDSEmitter && c.XDS.ListenAddress == "" {
+		errs = append(errs, ValidationError{"xds.listen_address", "must be set when enable_xds_emitter is true"})
+	}
+	if c.EnableDNSServer {
+		if c.DNS.ListenAddress == "" {
+			errs = append(errs, ValidationError{"dns.listen_address", "must be set when enable_dns_server is true"})
+		}
+		if len(c.DNS.Domains) == 0 {
+			errs = append(errs, ValidationError{"dns.domains", "must not be empty when enable_dns_server is true"})
+		}
+		if c.DNS.TTL < 0 {
+			errs = append(errs, ValidationError{"dns.ttl", "must not be negative"})
+		}
+		if c.DNS.RefreshInterval <= 0 {
+			errs = append(errs, ValidationError{"dns.refresh_interval", "must be positive"})
+		}
+	}
+	if c.EnableAuditLog {
+		if c.AuditLog.Path == "" {
+			errs = append(errs, ValidationError{"audit_log.path", "must be set when enable_audit_log is true"})
+		}
+		if c.AuditLog.MaxSize <= 0 {
+			errs = append(errs, ValidationError{"audit_log.max_size", "must be positive"})
+		}
+		if c.AuditLog.MaxBackups < 0 {
+			errs = append(errs, ValidationError{"audit_log.max_backups", "must not be negative"})
+		}
+		if c.AuditLog.BufferSize < 0 {
+			errs = append(errs, ValidationError{"audit_log.buffer_size", "must not be negative"})
+		}
+	}
+	if c.EnableIncrementalSync && c.FullSyncCycles <= 0 {
+		errs = append(errs, ValidationError{"full_sync_cycles", "must be positive"})
+	}
+	if c.EventResubscribeMinBackoff <= 0 {
+		errs = append(errs, ValidationError{"event_resubscribe_min_backoff", "must be positive"})
+	}
+	if c.EventResubscribeMaxBackoff < c.EventResubscribeMinBackoff {
+		errs = append(errs, ValidationError{"event_resubscribe_max_backoff", "must not be less than event_resubscribe_min_backoff"})
+	}
+	// without workers the events are handled serially by the watcher
+	if c.EventWorkers < 0 {
+		errs = append(errs, ValidationError{"event_workers", "must not be negative"})
+	}
+	// an empty prefix matches every process guid
+	for _, prefix := range c.RouteFilter.IncludeProcessGuidPrefixes {
+		if prefix == "" {
+			errs = append(errs, ValidationError{"route_filter.include_process_guid_prefixes", "must not contain empty prefixes"})
+			break
+		}
+	}
+	for _, prefix := range c.RouteFilter.ExcludeProcessGuidPrefixes {
+		if prefix == "" {
+			errs = append(errs, ValidationError{"route_filter.exclude_process_guid_prefixes", "must not contain empty prefixes"})
+			break
+		}
+	}
+	if c.EnableRouteHistory {
+		if c.RouteHistory.MaxRecordsPerKey <= 0 {
+			errs = append(errs, ValidationError{"route_history.max_records_per_key", "must be positive"})
+		}
+		if c.RouteHistory.MaxKeys <= 0 {
+			errs = append(errs, ValidationError{"route_history.max_keys", "must be positive"})
+		}
+	}
+	if c.EnableKubernetesEmitter && c.Kubernetes.Namespace == "" {
+		errs = append(errs, ValidationError{"kubernetes.namespace", "must be set when enable_kubernetes_emitter is true"})
+	}
+	if c.EnableConsulInternalEmitter {
+		if c.ConsulCluster == "" {
+			errs = append(errs, ValidationError{"consul_cluster", "must be set when enable_consul_internal_emitter is true"})
+		}
+		if time.Duration(c.ConsulServiceTTL) < MinConsulServiceTTL {
+			errs = append(errs, ValidationError{"consul_service_ttl", fmt.Sprintf("must be at least %s", MinConsulServiceTTL)})
+		}
+		// the ttl checks are passed on the internal broadcasts
+		if !c.EnableInternalEmitter {
+			errs = append(errs, ValidationError{"enable_internal_emitter", "must be true when enable_consul_internal_emitter is true"})
+		}
+	}
+
+	// a dry run never takes a lock, local mode (a cell id) must not
+	if c.CellID == "" && !c.DryRun && !c.ConsulEnabled && !c.LocketEnabled {
+		errs = append(errs, ValidationError{"cell_id", "must be set unless consul_enabled or locket_enabled is true"})
+	}
+	if c.CellID != "" && c.ConsulEnabled {
+		errs = append(errs, ValidationError{"consul_enabled", "must not be true when cell_id is set"})
+	}
+	if c.CellID != "" && c.LocketEnabled {
+		errs = append(errs, ValidationError{"locket_enabled", "must not be true when cell_id is set"})
+	}
+
+	// sharded emitters find each other through locket presences instead of
+	// competing for a lock
+	if c.EnableSharding {
+		if c.CellID != "" {
+			errs = append(errs, ValidationError{"enable_sharding", "must not be true when cell_id is set"})
+		}
+		if !c.LocketEnabled {
+			errs = append(errs, ValidationError{"locket_enabled", "must be true when enable_sharding is true"})
+		}
+		if c.ConsulEnabled {
+			errs = append(errs, ValidationError{"consul_enabled", "must not be true when enable_sharding is true"})
+		}
+		if c.ShardMembershipPollInterval <= 0 {
+			errs = append(errs, ValidationError{"shard_membership_poll_interval", "must be positive"})
+		}
+	}
+
+	return errs
+}
+
+// unknownFields returns an error for every key in value that does not match a
+// field of t, descending into nested objects. Keys are matched the way
+// encoding/json matches them.
+func unknownFields(path string, value interface{}, t reflect.Type) ValidationErrors {
+	object, ok := value.(map[string]interface{})
+	if !ok || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := map[string]reflect.Type{}
+	collectFields(t, fields)
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	errs := ValidationErrors{}
+	for _, key := range keys {
+		fieldPath := key
+		if path != "" {
+			fieldPath = path + "." + key
+		}
+
+		fieldType, ok := lookupField(fields, key)
+		if !ok {
+			errs = append(errs, ValidationError{fieldPath, "unknown field"})
+			continue
+		}
+		errs = append(errs, unknownFields(fieldPath, object[key], fieldType)...)
+	}
+	return errs
+}
+
+func collectFields(t reflect.Type, fields map[string]reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			collectFields(field.Type, fields)
+			continue
+		}
+		fields[jsonName(field)] = field.Type
+	}
+}
+
+func lookupField(fields map[string]reflect.Type, key string) (reflect.Type, bool) {
+	if fieldType, ok := fields[key]; ok {
+		return fieldType, true
+	}
+	for name, fieldType := range fields {
+		if strings.EqualFold(name, key) {
+			return fieldType, true
+		}
+	}
+	return nil, false
+}
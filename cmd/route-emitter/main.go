@@ -1,11 +1,13 @@
 package main
 
 import (
-	"errors"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
 	"time"
 
 	"code.cloudfoundry.org/bbs"
@@ -22,26 +24,39 @@ import (
 	"code.cloudfoundry.org/locket/lock"
 	locketmodels "code.cloudfoundry.org/locket/models"
 	route_emitter "code.cloudfoundry.org/route-emitter"
+	"code.cloudfoundry.org/route-emitter/adminapi"
+	"code.cloudfoundry.org/route-emitter/auditlog"
 	"code.cloudfoundry.org/route-emitter/cmd/route-emitter/config"
 	"code.cloudfoundry.org/route-emitter/consuldownchecker"
 	"code.cloudfoundry.org/route-emitter/consuldownmodenotifier"
 	"code.cloudfoundry.org/route-emitter/diegonats"
+	"code.cloudfoundry.org/route-emitter/dnsserver"
 	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/metrics"
+	"code.cloudfoundry.org/route-emitter/reloader"
 	"code.cloudfoundry.org/route-emitter/routehandlers"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/route-emitter/scheduler"
+	"code.cloudfoundry.org/route-emitter/shard"
+	"code.cloudfoundry.org/route-emitter/snapshotter"
 	"code.cloudfoundry.org/route-emitter/syncer"
 	"code.cloudfoundry.org/route-emitter/watcher"
+	"code.cloudfoundry.org/route-emitter/xdsserver"
 	"code.cloudfoundry.org/routing-api"
 	uaaclient "code.cloudfoundry.org/uaa-go-client"
 	uaaconfig "code.cloudfoundry.org/uaa-go-client/config"
 	"code.cloudfoundry.org/workpool"
 	"github.com/cloudfoundry/dropsonde"
+	"github.com/envoyproxy/go-control-plane/pkg/cache"
 	"github.com/nu7hatch/gouuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/tedsuo/ifrit"
 	"github.com/tedsuo/ifrit/grouper"
 	"github.com/tedsuo/ifrit/http_server"
 	"github.com/tedsuo/ifrit/sigmon"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 var configFilePath = flag.String(
@@ -53,9 +68,14 @@ var configFilePath = flag.String(
 const (
 	dropsondeOrigin     = "route_emitter"
 	routeEmitterLockKey = "route_emitter"
+	drainCheckInterval  = time.Second
 )
 
 func main() {
+	if isValidateCommand() {
+		os.Exit(runValidate(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	flag.Parse()
 
 	cfg, err := config.NewRouteEmitterConfig(*configFilePath)
@@ -67,19 +87,46 @@ func main() {
 	cfhttp.Initialize(time.Duration(cfg.CommunicationTimeout))
 
 	logger, reconfigurableSink := lagerflags.NewFromConfig(cfg.ConsulSessionName, cfg.LagerConfig)
+	checkConfig(logger, cfg)
+
 	natsClient := diegonats.NewClient()
 
 	natsPingDuration := 20 * time.Second
 	logger.Info("setting-nats-ping-interval", lager.Data{"duration-in-seconds": natsPingDuration.Seconds()})
 	natsClient.SetPingInterval(natsPingDuration)
 
+	if cfg.NATSTLSEnabled {
+		natsTLSConfig, err := diegonats.NewTLSConfig(cfg.NATSCACertFile, cfg.NATSClientCertFile, cfg.NATSClientKeyFile, cfg.NATSTLSServerName)
+		if err != nil {
+			logger.Fatal("failed-to-load-nats-tls-config", err)
+		}
+		natsClient.SetTLSConfig(natsTLSConfig)
+	}
+
 	clock := clock.NewClock()
 
+	natsCredentials := initializeNATSCredentials(logger, clock, cfg)
+	if natsCredentials != nil {
+		natsClient.SetCredentials(natsCredentials)
+	}
+
 	externalChan := make(chan struct{}, 1)
 	internalChan := make(chan struct{}, 1)
 	syncer := syncer.NewSyncer(clock, time.Duration(cfg.SyncInterval), logger)
-	externalScheduler := scheduler.NewRouteBroadcastScheduler(clock, natsClient, logger, "router", externalChan)
-	internalScheduler := scheduler.NewRouteBroadcastScheduler(clock, natsClient, logger, "service-discovery", internalChan)
+
+	// routers only receive batched registrations once all of them have
+	// advertised support for it in their greeting
+	var (
+		registrationBatcher *emitter.RegistrationBatcher
+		greetingObserver    scheduler.GreetingObserver
+	)
+	if cfg.EnableBatchedRegistration && !cfg.DryRun {
+		registrationBatcher = emitter.NewRegistrationBatcher(logger, clock, cfg.BatchedRegistrationSize)
+		greetingObserver = registrationBatcher
+	}
+
+	externalScheduler := scheduler.NewRouteBroadcastScheduler(clock, natsClient, logger, "router", externalChan, greetingObserver)
+	internalScheduler := scheduler.NewRouteBroadcastScheduler(clock, natsClient, logger, "service-discovery", internalChan, nil)
 
 	metronClient, err := initializeMetron(logger, cfg)
 	if err != nil {
@@ -87,54 +134,225 @@ func main() {
 		os.Exit(1)
 	}
 
-	natsClientRunner := diegonats.NewClientRunner(cfg.NATSAddresses, cfg.NATSUsername, cfg.NATSPassword, logger, natsClient)
+	metricsSink := metrics.NewLoggregatorSink(metronClient)
+	var metricsRegistry *prometheus.Registry
+	if cfg.EnablePrometheusMetrics {
+		metricsRegistry = prometheus.NewRegistry()
+		metricsSink = metrics.NewMultiSink(metricsSink, metrics.NewPrometheusSink(metricsRegistry))
+	}
+
+	natsUsername, natsPassword := natsUserPassword(cfg)
+	natsClientRunner := diegonats.NewClientRunner(cfg.NATSAddresses, natsUsername, natsPassword, logger, natsClient)
+	if natsCredentials != nil {
+		natsClientRunner = natsClientRunner.WithKeyChanges(natsCredentials.KeyChanges())
+	}
 
 	bbsClient := initializeBBSClient(logger, cfg)
 
 	localMode := cfg.CellID != ""
-	table := routingtable.NewRoutingTable(logger, cfg.RegisterDirectInstanceRoutes, metronClient)
-	natsEmitter := initializeNatsEmitter(logger, natsClient, cfg.RouteEmittingWorkers, metronClient, cfg.EnableInternalEmitter)
+	var routeHistory *routingtable.History
+	if cfg.EnableRouteHistory {
+		routeHistory = routingtable.NewHistory(clock, cfg.RouteHistory.MaxRecordsPerKey, cfg.RouteHistory.MaxKeys)
+	}
+	table := routingtable.NewDrainingRoutingTable(logger, cfg.RegisterDirectInstanceRoutes, metricsSink, clock, time.Duration(cfg.DrainWindow), routeHistory)
 
-	routeTTL := time.Duration(cfg.TCPRouteTTL)
-	if routeTTL.Seconds() > 65535 {
-		logger.Fatal("invalid-route-ttl", errors.New("route TTL value too large"), lager.Data{"ttl": routeTTL.Seconds()})
+	// in dry-run mode nothing is published, the recorder takes the place of
+	// the nats and routing api emitters
+	var (
+		recorder    *emitter.RecordingEmitter
+		natsEmitter emitter.NATSEmitter
+
+		natsRetryQueue       *emitter.RetryQueue
+		routingAPIRetryQueue *emitter.RetryQueue
+	)
+	if cfg.DryRun {
+		logger.Info("running-in-dry-run-mode", lager.Data{"buffer-size": cfg.DryRunBufferSize})
+		recorder = emitter.NewRecordingEmitter(logger, clock, cfg.DryRunBufferSize, cfg.EnableInternalEmitter)
+		natsEmitter = recorder.NATSEmitter()
+	} else {
+		if cfg.Retry.MaxSize > 0 {
+			natsRetryQueue = initializeRetryQueue(logger, clock, metricsSink, "nats", cfg.Retry)
+		}
+		natsEmitter = initializeNatsEmitter(logger, natsClient, cfg.RouteEmittingWorkers, metricsSink, natsRetryQueue, registrationBatcher, cfg.EnableInternalEmitter)
+	}
+
+	// internal routes can also be published to the consul catalog and http
+	// routes mirrored into kubernetes, alongside nats
+	routeEmitters := []emitter.NATSEmitter{natsEmitter}
+	if cfg.EnableConsulInternalEmitter && !cfg.DryRun {
+		consulEmitter := emitter.NewConsulEmitter(
+			logger,
+			initializeConsulClient(logger, cfg.ConsulCluster),
+			time.Duration(cfg.ConsulServiceTTL),
+		)
+		routeEmitters = append(routeEmitters, consulEmitter)
+	}
+	if cfg.EnableKubernetesEmitter && !cfg.DryRun {
+		kubeEmitter := emitter.NewKubeEmitter(
+			logger,
+			table,
+			initializeKubeObjectWriter(logger, cfg.Kubernetes),
+			cfg.Kubernetes.Namespace,
+			cfg.RegisterDirectInstanceRoutes,
+		)
+		routeEmitters = append(routeEmitters, kubeEmitter)
+	}
+	routeEmitter := natsEmitter
+	if len(routeEmitters) > 1 {
+		routeEmitter = emitter.NewMultiNATSEmitter(routeEmitters...)
 	}
 
+	var tableSnapshotter ifrit.Runner
+	if cfg.RoutingTableSnapshotPath != "" {
+		restoreRoutingTable(logger, table, cfg.RoutingTableSnapshotPath)
+		tableSnapshotter = snapshotter.NewSnapshotter(
+			logger,
+			clock,
+			table,
+			routeEmitter,
+			cfg.RoutingTableSnapshotPath,
+			time.Duration(cfg.RoutingTableSnapshotInterval),
+		)
+	}
+
+	routeTTL := time.Duration(cfg.TCPRouteTTL)
+
 	var routingAPIEmitter emitter.RoutingAPIEmitter
-	if cfg.EnableTCPEmitter {
+	if cfg.EnableTCPEmitter && recorder != nil {
+		routingAPIEmitter = recorder.RoutingAPIEmitter()
+	} else if cfg.EnableTCPEmitter {
 		tcpLogger := logger.Session("tcp")
 		uaaClient := newUaaClient(tcpLogger, &cfg, clock)
 		routingAPIAddress := fmt.Sprintf("%s:%d", cfg.RoutingAPI.URL, cfg.RoutingAPI.Port)
 		logger.Debug("creating-routing-api-client", lager.Data{"api-location": routingAPIAddress})
 		routingAPIClient := routing_api.NewClient(routingAPIAddress, false)
-		routingAPIEmitter = emitter.NewRoutingAPIEmitter(tcpLogger, routingAPIClient, uaaClient, int(routeTTL.Seconds()))
+		if cfg.Retry.MaxSize > 0 {
+			routingAPIRetryQueue = initializeRetryQueue(tcpLogger, clock, metricsSink, "routing-api", cfg.Retry)
+		}
+		routingAPIEmitter = emitter.NewRoutingAPIEmitter(tcpLogger, routingAPIClient, uaaClient, int(routeTTL.Seconds()), routingAPIRetryQueue)
 	}
 
-	handler := routehandlers.NewHandler(table, natsEmitter, routingAPIEmitter, localMode, metronClient)
+	var (
+		xdsEmitter emitter.XDSEmitter
+		xdsServer  ifrit.Runner
+	)
+	if cfg.EnableXDSEmitter && !cfg.DryRun {
+		xdsLogger := logger.Session("xds")
+		snapshotCache := cache.NewSnapshotCache(true, emitter.XDSNodeHash{}, nil)
+		xdsEmitter = emitter.NewXDSEmitter(xdsLogger, snapshotCache, cfg.XDS.HTTPListenerPort)
+		xdsServer = xdsserver.NewServer(xdsLogger, cfg.XDS.ListenAddress, snapshotCache)
+		seedXDSSnapshot(xdsLogger, table, xdsEmitter)
+	}
+
+	var dnsServer ifrit.Runner
+	if cfg.EnableDNSServer && !cfg.DryRun {
+		resolver := dnsserver.NewResolver(table, cfg.DNS.Domains, time.Duration(cfg.DNS.TTL))
+		dnsServer = dnsserver.NewServer(logger, clock, cfg.DNS.ListenAddress, resolver, time.Duration(cfg.DNS.RefreshInterval))
+	}
+
+	// sharded global emitters each handle the process guids assigned to them
+	// by a ring of the emitters present in locket, instead of one of them
+	// holding the lock
+	var (
+		locketClient    locketmodels.LocketClient
+		shardMembership *shard.Membership
+		shardFilter     watcher.ShardFilter
+	)
+	if cfg.EnableSharding && cfg.CellID == "" && !cfg.DryRun {
+		locketClient = initializeLocketClient(logger, cfg)
+		shardMembership = shard.NewMembership(
+			logger,
+			clock,
+			locketClient,
+			cfg.UUID,
+			time.Duration(cfg.ShardMembershipPollInterval),
+			syncer.SyncCh(),
+		)
+		shardFilter = shardMembership
+	}
+
+	var (
+		auditLog      *auditlog.FileLog
+		auditRecorder auditlog.Recorder
+	)
+	if cfg.EnableAuditLog {
+		auditLog, err = auditlog.NewFileLog(
+			logger,
+			clock,
+			cfg.AuditLog.Path,
+			cfg.AuditLog.MaxSize,
+			cfg.AuditLog.MaxBackups,
+			cfg.AuditLog.BufferSize,
+		)
+		if err != nil {
+			logger.Fatal("failed-to-open-audit-log", err, lager.Data{"path": cfg.AuditLog.Path})
+		}
+		auditRecorder = auditLog
+	}
+
+	handler := routehandlers.NewHandler(table, routeEmitter, routingAPIEmitter, xdsEmitter, localMode, cfg.IgnoreRoutability, shardFilter, metricsSink, auditRecorder)
+	handler.SetRouteFilter(newRouteFilter(cfg.RouteFilter))
 
 	watcher := watcher.NewWatcher(
 		cfg.CellID,
+		shardFilter,
+		cfg.IgnoreRoutability,
 		bbsClient,
 		clock,
 		handler,
 		syncer.SyncCh(),
 		externalScheduler.EmitCh(),
 		internalScheduler.EmitCh(),
+		watcherDrainCheckInterval(cfg),
+		watcherFullSyncCycles(cfg),
+		time.Duration(cfg.EventResubscribeMinBackoff),
+		time.Duration(cfg.EventResubscribeMaxBackoff),
+		cfg.EventWorkers,
 		logger,
-		metronClient,
+		metricsSink,
 	)
 
 	healthHandler := func(resp http.ResponseWriter, req *http.Request) {
 		resp.WriteHeader(http.StatusOK)
 	}
-	healthCheckServer := http_server.New(cfg.HealthCheckAddress, http.HandlerFunc(healthHandler))
+	routingTableHandler := adminapi.NewRoutingTableHandler(logger, table)
+	mux := http.NewServeMux()
+	mux.Handle(adminapi.RoutingTablePath, routingTableHandler)
+	mux.Handle(adminapi.RoutingTablePath+"/", routingTableHandler)
+	if recorder != nil {
+		mux.Handle(adminapi.RecordingPath, adminapi.NewRecordingHandler(logger, recorder))
+	}
+	if auditLog != nil {
+		mux.Handle(adminapi.AuditPath, adminapi.NewAuditHandler(logger, auditLog))
+	}
+	if routeHistory != nil {
+		mux.Handle(adminapi.RouteHistoryPath, adminapi.NewRouteHistoryHandler(logger, routeHistory))
+	}
+	if metricsRegistry != nil {
+		mux.Handle("/metrics", promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
+	}
+	mux.HandleFunc("/", healthHandler)
+	healthCheckServer := http_server.New(cfg.HealthCheckAddress, mux)
+
+	reloadSignals := make(chan os.Signal, 1)
+	signal.Notify(reloadSignals, syscall.SIGHUP)
+	configReloader := reloader.NewReloader(
+		logger,
+		*configFilePath,
+		cfg,
+		reloadSignals,
+		newConfigApplier(reconfigurableSink, natsClientRunner, natsEmitter, routingAPIEmitter, syncer, handler, watcher),
+	)
+
 	members := grouper.Members{
 		{"nats-client", natsClientRunner},
 		{"healthcheck", healthCheckServer},
 	}
 
+	// a dry-run emitter shadows the live one, it must never take the lock
+	// away from it
 	lockMembers := []grouper.Member{}
-	if cfg.CellID == "" {
+	if cfg.CellID == "" && !cfg.DryRun && shardMembership == nil {
 		if cfg.ConsulEnabled {
 			consulClient := initializeConsulClient(logger, cfg.ConsulCluster)
 
@@ -145,7 +363,7 @@ func main() {
 				time.Duration(cfg.LockTTL),
 				time.Duration(cfg.LockRetryInterval),
 				clock,
-				metronClient,
+				metricsSink,
 			)
 
 			consulDownModeNotifier := consuldownmodenotifier.NewConsulDownModeNotifier(
@@ -153,7 +371,7 @@ func main() {
 				0,
 				clock,
 				time.Duration(cfg.ConsulDownModeNotificationInterval),
-				metronClient,
+				metricsSink,
 			)
 
 			// we are running in global mode
@@ -162,14 +380,7 @@ func main() {
 		}
 
 		if cfg.LocketEnabled {
-			locketClient, err := locket.NewClient(logger, cfg.ClientLocketConfig)
-			if err != nil {
-				logger.Fatal("failed-to-create-locket-client", err)
-			}
-
-			if cfg.UUID == "" {
-				logger.Fatal("invalid-uuid", errors.New("invalid-uuid-from-config"))
-			}
+			locketClient := initializeLocketClient(logger, cfg)
 
 			lockIdentifier := &locketmodels.Resource{
 				Key:      routeEmitterLockKey,
@@ -189,20 +400,70 @@ func main() {
 		}
 
 		members = append(members,
-			grouper.Member{"lock", lockRunner(logger, clock, lockMembers)},
+			grouper.Member{"lock", lockRunner(clock, lockMembers)},
 		)
 	}
 
+	if shardMembership != nil {
+		presence := &locketmodels.Resource{
+			Key:      shard.PresenceKey(cfg.UUID),
+			Owner:    cfg.UUID,
+			TypeCode: locketmodels.PRESENCE,
+			Type:     locketmodels.PresenceType,
+		}
+
+		members = append(members,
+			grouper.Member{"shard-presence", lock.NewPresenceRunner(
+				logger,
+				locketClient,
+				presence,
+				locket.DefaultSessionTTLInSeconds,
+				clock,
+				locket.SQLRetryInterval,
+			)},
+			grouper.Member{"shard-membership", shardMembership},
+		)
+	}
+
+	if tableSnapshotter != nil {
+		members = append(members, grouper.Member{"snapshotter", tableSnapshotter})
+	}
+
+	if xdsServer != nil {
+		members = append(members, grouper.Member{"xds-server", xdsServer})
+	}
+	if dnsServer != nil {
+		members = append(members, grouper.Member{"dns-server", dnsServer})
+	}
+
+	if natsCredentials != nil {
+		members = append(members, grouper.Member{"nats-credentials", natsCredentials})
+	}
+
+	if natsRetryQueue != nil {
+		members = append(members, grouper.Member{"nats-retry-queue", natsRetryQueue})
+	}
+
+	if routingAPIRetryQueue != nil {
+		members = append(members, grouper.Member{"routing-api-retry-queue", routingAPIRetryQueue})
+	}
+
 	members = append(members,
 		grouper.Member{"watcher", watcher},
-		grouper.Member{"external-scheduler", externalScheduler},
 		grouper.Member{"syncer", syncer},
 	)
 
-	if cfg.EnableInternalEmitter {
-		members = append(members, grouper.Member{"internal-scheduler", internalScheduler})
+	// the schedulers greet the routers and service discovery, a dry run must
+	// stay invisible to them
+	if !cfg.DryRun {
+		members = append(members, grouper.Member{"external-scheduler", externalScheduler})
+		if cfg.EnableInternalEmitter || cfg.EnableConsulInternalEmitter {
+			members = append(members, grouper.Member{"internal-scheduler", internalScheduler})
+		}
 	}
 
+	members = append(members, grouper.Member{"config-reloader", configReloader})
+
 	if cfg.DebugAddress != "" {
 		members = append(grouper.Members{
 			{"debug-server", debugserver.Runner(cfg.DebugAddress, reconfigurableSink)},
@@ -222,7 +483,7 @@ func main() {
 		logger.Info("finished")
 	}
 
-	if cfg.ConsulEnabled && cfg.CellID == "" {
+	if cfg.ConsulEnabled && cfg.CellID == "" && !cfg.DryRun {
 		// ConsulDown mode
 		logger = logger.Session("consul-down-mode")
 
@@ -239,7 +500,7 @@ func main() {
 			1,
 			clock,
 			time.Duration(cfg.ConsulDownModeNotificationInterval),
-			metronClient,
+			metricsSink,
 		)
 
 		// we are running in global mode
@@ -247,15 +508,43 @@ func main() {
 			{"nats-client", natsClientRunner},
 			{"consul-down-checker", consulDownChecker},
 			{"consul-down-mode-notifier", consulDownModeNotifier},
-			{"watcher", watcher},
-			{"external-scheduler", externalScheduler},
-			{"syncer", syncer},
 		}
 
-		if cfg.EnableInternalEmitter {
+		if tableSnapshotter != nil {
+			members = append(members, grouper.Member{"snapshotter", tableSnapshotter})
+		}
+
+		if xdsServer != nil {
+			members = append(members, grouper.Member{"xds-server", xdsServer})
+		}
+		if dnsServer != nil {
+			members = append(members, grouper.Member{"dns-server", dnsServer})
+		}
+
+		if natsCredentials != nil {
+			members = append(members, grouper.Member{"nats-credentials", natsCredentials})
+		}
+
+		if natsRetryQueue != nil {
+			members = append(members, grouper.Member{"nats-retry-queue", natsRetryQueue})
+		}
+
+		if routingAPIRetryQueue != nil {
+			members = append(members, grouper.Member{"routing-api-retry-queue", routingAPIRetryQueue})
+		}
+
+		members = append(members,
+			grouper.Member{"watcher", watcher},
+			grouper.Member{"external-scheduler", externalScheduler},
+			grouper.Member{"syncer", syncer},
+		)
+
+		if cfg.EnableInternalEmitter || cfg.EnableConsulInternalEmitter {
 			members = append(members, grouper.Member{"internal-scheduler", internalScheduler})
 		}
 
+		members = append(members, grouper.Member{"config-reloader", configReloader})
+
 		group = grouper.NewOrdered(os.Interrupt, members)
 
 		logger.Info("starting")
@@ -273,16 +562,176 @@ func main() {
 	logger.Info("exited")
 }
 
-func lockRunner(logger lager.Logger, clk clock.Clock, locks []grouper.Member) ifrit.Runner {
-	switch len(locks) {
-	case 0:
-		logger.Fatal("no-locks-configured", errors.New("Lock configuration must be provided"))
+// newConfigApplier applies the settings that can change without a restart.
+// Only the components using the settings are updated, the routing table is
+// left to a full sync when the route filter changed.
+func newConfigApplier(
+	reconfigurableSink *lager.ReconfigurableSink,
+	natsClientRunner diegonats.NATSClientRunner,
+	natsEmitter emitter.NATSEmitter,
+	routingAPIEmitter emitter.RoutingAPIEmitter,
+	natsSyncer *syncer.NatsSyncer,
+	handler *routehandlers.Handler,
+	routeWatcher *watcher.Watcher,
+) reloader.ApplyFunc {
+	return func(logger lager.Logger, current, next config.RouteEmitterConfig) error {
+		if next.LogLevel != current.LogLevel {
+			level, err := next.MinLogLevel()
+			if err != nil {
+				return err
+			}
+			reconfigurableSink.SetMinLevel(level)
+		}
+
+		if next.SyncInterval != current.SyncInterval {
+			natsSyncer.SetSyncInterval(time.Duration(next.SyncInterval))
+		}
+
+		if next.RouteEmittingWorkers != current.RouteEmittingWorkers {
+			if setter, ok := natsEmitter.(emitter.WorkPoolSetter); ok {
+				workPool, err := workpool.NewWorkPool(next.RouteEmittingWorkers)
+				if err != nil {
+					return err
+				}
+				setter.SetWorkPool(workPool)
+			}
+		}
+
+		if next.TCPRouteTTL != current.TCPRouteTTL {
+			if setter, ok := routingAPIEmitter.(emitter.RouteTTLSetter); ok {
+				setter.SetRouteTTL(int(time.Duration(next.TCPRouteTTL).Seconds()))
+			}
+		}
+
+		if next.NATSAddresses != current.NATSAddresses ||
+			next.NATSUsername != current.NATSUsername ||
+			next.NATSPassword != current.NATSPassword {
+			logger.Info("reconnecting-to-nats")
+			natsUsername, natsPassword := natsUserPassword(next)
+			natsClientRunner.Reconnect(next.NATSAddresses, natsUsername, natsPassword)
+		}
+
+		if !reflect.DeepEqual(next.RouteFilter, current.RouteFilter) {
+			logger.Info("applying-route-filter")
+			handler.SetRouteFilter(newRouteFilter(next.RouteFilter))
+			routeWatcher.RequestFullSync()
+		}
+
 		return nil
-	case 1:
-		return locks[0]
+	}
+}
+
+// initializeNATSCredentials returns the watched credentials file or NKey seed
+// file of cfg, nil when NATS authenticates with the user and password.
+func initializeNATSCredentials(logger lager.Logger, clk clock.Clock, cfg config.RouteEmitterConfig) *diegonats.Credentials {
+	var (
+		credentials *diegonats.Credentials
+		err         error
+	)
+
+	pollInterval := time.Duration(cfg.NATSCredentialsPollInterval)
+	switch {
+	case cfg.NATSCredentialsFile != "":
+		credentials, err = diegonats.NewUserCredentials(logger, clk, cfg.NATSCredentialsFile, pollInterval)
+	case cfg.NATSNKeySeedFile != "":
+		credentials, err = diegonats.NewNKeySeedCredentials(logger, clk, cfg.NATSNKeySeedFile, pollInterval)
 	default:
-		return jointlock.NewJointLock(clk, locket.DefaultSessionTTL, locks...)
+		return nil
+	}
+	if err != nil {
+		logger.Fatal("failed-to-load-nats-credentials", err)
+	}
+
+	return credentials
+}
+
+// natsUserPassword returns the user and password put in the NATS urls, none
+// when a credentials file authenticates the connections.
+// startupFailures are the settings the emitter always refused to start with,
+// along with the errors it fails with. Configs breaking any other rule of the
+// validate command started before those rules existed and still do.
+var startupFailures = map[string]string{
+	"tcp_route_ttl":      "invalid-route-ttl",
+	"uuid":               "invalid-uuid",
+	"cell_id":            "no-locks-configured",
+	"xds.listen_address": "invalid-xds-listen-address",
+}
+
+func checkConfig(logger lager.Logger, cfg config.RouteEmitterConfig) {
+	errs, ok := cfg.Validate().(config.ValidationErrors)
+	if !ok {
+		return
+	}
+
+	for _, err := range errs {
+		if _, fails := startupFailures[err.Path]; !fails {
+			logger.Error("ignoring-invalid-config", err)
+		}
+	}
+	for _, err := range errs {
+		if action, fails := startupFailures[err.Path]; fails {
+			logger.Fatal(action, err)
+		}
+	}
+}
+
+func natsUserPassword(cfg config.RouteEmitterConfig) (string, string) {
+	if cfg.NATSCredentialsFile != "" || cfg.NATSNKeySeedFile != "" {
+		return "", ""
 	}
+	return cfg.NATSUsername, cfg.NATSPassword
+}
+
+func initializeLocketClient(logger lager.Logger, cfg config.RouteEmitterConfig) locketmodels.LocketClient {
+	locketClient, err := locket.NewClient(logger, cfg.ClientLocketConfig)
+	if err != nil {
+		logger.Fatal("failed-to-create-locket-client", err)
+	}
+
+	return locketClient
+}
+
+func lockRunner(clk clock.Clock, locks []grouper.Member) ifrit.Runner {
+	if len(locks) == 1 {
+		return locks[0]
+	}
+	return jointlock.NewJointLock(clk, locket.DefaultSessionTTL, locks...)
+}
+
+func restoreRoutingTable(logger lager.Logger, table routingtable.RoutingTable, snapshotPath string) {
+	logger = logger.Session("restore-routing-table", lager.Data{"path": snapshotPath})
+
+	snapshot, err := snapshotter.Load(snapshotPath)
+	if err != nil {
+		logger.Info("no-usable-snapshot", lager.Data{"error": err.Error()})
+		return
+	}
+
+	err = table.Restore(snapshot)
+	if err != nil {
+		logger.Error("failed-to-restore-snapshot", err)
+		return
+	}
+
+	logger.Info("restored-snapshot", lager.Data{"table-size": table.TableSize()})
+}
+
+// seedXDSSnapshot publishes the external routes already in the table, such as
+// the ones restored from a snapshot. The sync only emits what changed, so they
+// would otherwise not reach the proxies until the next emit interval.
+func seedXDSSnapshot(logger lager.Logger, table routingtable.RoutingTable, xdsEmitter emitter.XDSEmitter) {
+	if table.TableSize() == 0 {
+		return
+	}
+
+	routeMappings, messagesToEmit := table.GetExternalRoutingEvents()
+	err := xdsEmitter.Emit(messagesToEmit, routeMappings)
+	if err != nil {
+		logger.Error("failed-to-seed-snapshot", err)
+		return
+	}
+
+	logger.Info("seeded-snapshot", lager.Data{"table-size": table.TableSize()})
 }
 
 func newUaaClient(logger lager.Logger, c *config.RouteEmitterConfig, klok clock.Clock) uaaclient.Client {
@@ -337,11 +786,32 @@ func initializeMetron(logger lager.Logger, locketConfig config.RouteEmitterConfi
 	return client, nil
 }
 
+func initializeRetryQueue(
+	logger lager.Logger,
+	clock clock.Clock,
+	metricsSink metrics.Sink,
+	name string,
+	cfg config.RetryConfig,
+) *emitter.RetryQueue {
+	return emitter.NewRetryQueue(
+		logger,
+		clock,
+		metricsSink,
+		name,
+		cfg.MaxSize,
+		cfg.MaxAttempts,
+		time.Duration(cfg.MinBackoff),
+		time.Duration(cfg.MaxBackoff),
+	)
+}
+
 func initializeNatsEmitter(
 	logger lager.Logger,
 	natsClient diegonats.NATSClient,
 	routeEmittingWorkers int,
-	metronClient loggingclient.IngressClient,
+	metricsSink metrics.Sink,
+	retryQueue *emitter.RetryQueue,
+	registrationBatcher *emitter.RegistrationBatcher,
 	emitInternalRoutes bool,
 ) emitter.NATSEmitter {
 	workPool, err := workpool.NewWorkPool(routeEmittingWorkers)
@@ -349,7 +819,24 @@ func initializeNatsEmitter(
 		logger.Fatal("failed-to-construct-nats-emitter-workpool", err, lager.Data{"num-workers": routeEmittingWorkers}) // should never happen
 	}
 
-	return emitter.NewNATSEmitter(natsClient, workPool, logger, metronClient, emitInternalRoutes)
+	return emitter.NewNATSEmitter(natsClient, workPool, logger, metricsSink, retryQueue, registrationBatcher, emitInternalRoutes)
+}
+
+func initializeKubeObjectWriter(logger lager.Logger, kubeConfig config.KubernetesConfig) emitter.KubeObjectWriter {
+	if kubeConfig.OutputDir != "" {
+		logger.Info("writing-kubernetes-objects-to-files", lager.Data{"output-dir": kubeConfig.OutputDir})
+		return emitter.NewKubeFileWriter(kubeConfig.OutputDir)
+	}
+
+	restConfig, err := clientcmd.BuildConfigFromFlags("", kubeConfig.Kubeconfig)
+	if err != nil {
+		logger.Fatal("failed-to-load-kubeconfig", err)
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		logger.Fatal("failed-to-create-kubernetes-client", err)
+	}
+	return emitter.NewKubeAPIWriter(client, kubeConfig.Namespace)
 }
 
 func initializeConsulClient(logger lager.Logger, consulCluster string) consuladapter.Client {
@@ -366,7 +853,7 @@ func initializeLockMaintainer(
 	sessionName string,
 	lockTTL, lockRetryInterval time.Duration,
 	clock clock.Clock,
-	metronClient loggingclient.IngressClient,
+	metricsSink metrics.Sink,
 ) ifrit.Runner {
 	uuid, err := uuid.NewV4()
 	if err != nil {
@@ -375,7 +862,7 @@ func initializeLockMaintainer(
 
 	serviceClient := route_emitter.NewServiceClient(consulClient, clock)
 
-	return serviceClient.NewRouteEmitterLockRunner(logger, uuid.String(), lockRetryInterval, lockTTL, metronClient)
+	return serviceClient.NewRouteEmitterLockRunner(logger, uuid.String(), lockRetryInterval, lockTTL, metrics.NewSinkIngressClient(metricsSink))
 }
 
 func initializeBBSClient(
@@ -395,3 +882,30 @@ func initializeBBSClient(
 	}
 	return bbsClient
 }
+
+func watcherDrainCheckInterval(cfg config.RouteEmitterConfig) time.Duration {
+	if cfg.DrainWindow <= 0 {
+		return 0
+	}
+	return drainCheckInterval
+}
+
+func newRouteFilter(cfg config.RouteFilterConfig) routehandlers.RouteFilter {
+	return routehandlers.RouteFilter{
+		IncludeIsolationSegments:   cfg.IncludeIsolationSegments,
+		ExcludeIsolationSegments:   cfg.ExcludeIsolationSegments,
+		IncludeDomains:             cfg.IncludeDomains,
+		ExcludeDomains:             cfg.ExcludeDomains,
+		IncludeProcessGuidPrefixes: cfg.IncludeProcessGuidPrefixes,
+		ExcludeProcessGuidPrefixes: cfg.ExcludeProcessGuidPrefixes,
+	}
+}
+
+// watcherFullSyncCycles returns 0, a full sync every time, unless incremental
+// syncs are enabled.
+func watcherFullSyncCycles(cfg config.RouteEmitterConfig) int {
+	if !cfg.EnableIncrementalSync {
+		return 0
+	}
+	return cfg.FullSyncCycles
+}
@@ -32,12 +32,12 @@ import (
 	"code.cloudfoundry.org/lager/lagerflags"
 	"code.cloudfoundry.org/lager/lagertest"
 	"code.cloudfoundry.org/locket"
+	"code.cloudfoundry.org/route-emitter/cfroutes"
 	"code.cloudfoundry.org/route-emitter/cmd/route-emitter/config"
 	"code.cloudfoundry.org/route-emitter/cmd/route-emitter/runners"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	. "code.cloudfoundry.org/route-emitter/routingtable/matchers"
 	apimodels "code.cloudfoundry.org/routing-api/models"
-	"code.cloudfoundry.org/routing-info/cfroutes"
 	"code.cloudfoundry.org/routing-info/internalroutes"
 	"code.cloudfoundry.org/routing-info/tcp_routes"
 	"github.com/cloudfoundry/sonde-go/events"
@@ -1169,6 +1169,199 @@ var _ = Describe("Route Emitter", func() {
 					Expect(err).NotTo(HaveOccurred())
 				})
 
+				It("exposes the routing table on the healthcheck server", func() {
+					client := http.Client{
+						Timeout: time.Second,
+					}
+					Eventually(func() ([]string, error) {
+						resp, err := client.Get("http://" + healthCheckAddress + "/routing_table/http?process_guid=" + processGuid)
+						if err != nil {
+							return nil, err
+						}
+						defer resp.Body.Close()
+
+						var table struct {
+							Entries []struct {
+								Routes []struct {
+									Hostname string `json:"hostname"`
+								} `json:"routes"`
+								Endpoints []struct {
+									InstanceGUID string `json:"instance_guid"`
+								} `json:"endpoints"`
+							} `json:"entries"`
+						}
+						err = json.NewDecoder(resp.Body).Decode(&table)
+						if err != nil {
+							return nil, err
+						}
+
+						hostnames := []string{}
+						for _, entry := range table.Entries {
+							if len(entry.Endpoints) == 0 {
+								continue
+							}
+							for _, route := range entry.Routes {
+								hostnames = append(hostnames, route.Hostname)
+							}
+						}
+						return hostnames, nil
+					}).Should(ConsistOf(hostnames))
+				})
+
+				Context("when running in dry-run mode", func() {
+					var greetings chan struct{}
+
+					BeforeEach(func() {
+						cfgs = append(cfgs, func(cfg *config.RouteEmitterConfig) {
+							cfg.DryRun = true
+						})
+
+						greetings = make(chan struct{}, 1)
+						natsClient.Subscribe("router.greet", func(msg *nats.Msg) {
+							select {
+							case greetings <- struct{}{}:
+							default:
+							}
+						})
+					})
+
+					It("records the routes instead of publishing them", func() {
+						client := http.Client{
+							Timeout: time.Second,
+						}
+						Eventually(func() (map[string]uint64, error) {
+							resp, err := client.Get("http://" + healthCheckAddress + "/recording")
+							if err != nil {
+								return nil, err
+							}
+							defer resp.Body.Close()
+
+							var recording struct {
+								Counts map[string]uint64 `json:"counts"`
+							}
+							err = json.NewDecoder(resp.Body).Decode(&recording)
+							return recording.Counts, err
+						}).Should(HaveKeyWithValue("router.register", BeNumerically(">=", 2)))
+
+						Consistently(registeredRoutes).ShouldNot(Receive())
+					})
+
+					It("does not greet the routers", func() {
+						Consistently(greetings, 2*time.Second).ShouldNot(Receive())
+					})
+				})
+
+				Context("when the audit log is enabled", func() {
+					var auditLogDir string
+
+					BeforeEach(func() {
+						var err error
+						auditLogDir, err = ioutil.TempDir("", "audit-log")
+						Expect(err).NotTo(HaveOccurred())
+
+						cfgs = append(cfgs, func(cfg *config.RouteEmitterConfig) {
+							cfg.EnableAuditLog = true
+							cfg.AuditLog.Path = path.Join(auditLogDir, "audit.jsonl")
+						})
+					})
+
+					AfterEach(func() {
+						os.RemoveAll(auditLogDir)
+					})
+
+					It("serves the route changes of the instance on the healthcheck address", func() {
+						client := http.Client{
+							Timeout: time.Second,
+						}
+						Eventually(func() ([]string, error) {
+							resp, err := client.Get("http://" + healthCheckAddress + "/audit?process_guid=" + processGuid)
+							if err != nil {
+								return nil, err
+							}
+							defer resp.Body.Close()
+
+							sources := []string{}
+							decoder := json.NewDecoder(resp.Body)
+							for decoder.More() {
+								var entry struct {
+									Source string `json:"source"`
+								}
+								err = decoder.Decode(&entry)
+								if err != nil {
+									return nil, err
+								}
+								sources = append(sources, entry.Source)
+							}
+							return sources, nil
+						}).Should(ContainElement("event"))
+
+						Expect(path.Join(auditLogDir, "audit.jsonl")).To(BeAnExistingFile())
+					})
+				})
+
+				Context("when the route history is enabled", func() {
+					BeforeEach(func() {
+						cfgs = append(cfgs, func(cfg *config.RouteEmitterConfig) {
+							cfg.EnableRouteHistory = true
+						})
+					})
+
+					It("serves the route changes of a hostname on the healthcheck address", func() {
+						client := http.Client{
+							Timeout: time.Second,
+						}
+						Eventually(func() ([]string, error) {
+							resp, err := client.Get("http://" + healthCheckAddress + "/route_history?hostname=" + hostnames[0])
+							if err != nil {
+								return nil, err
+							}
+							defer resp.Body.Close()
+
+							var history struct {
+								Records []struct {
+									ProcessGUID string `json:"process_guid"`
+									Change      string `json:"change"`
+								} `json:"records"`
+							}
+							err = json.NewDecoder(resp.Body).Decode(&history)
+							if err != nil {
+								return nil, err
+							}
+
+							changes := []string{}
+							for _, record := range history.Records {
+								Expect(record.ProcessGUID).To(Equal(processGuid))
+								changes = append(changes, record.Change)
+							}
+							return changes, nil
+						}).Should(ContainElement("added"))
+					})
+				})
+
+				Context("when prometheus metrics are enabled", func() {
+					BeforeEach(func() {
+						cfgs = append(cfgs, func(cfg *config.RouteEmitterConfig) {
+							cfg.EnablePrometheusMetrics = true
+						})
+					})
+
+					It("serves the metrics on the healthcheck address", func() {
+						client := http.Client{
+							Timeout: time.Second,
+						}
+						Eventually(func() (string, error) {
+							resp, err := client.Get("http://" + healthCheckAddress + "/metrics")
+							if err != nil {
+								return "", err
+							}
+							defer resp.Body.Close()
+
+							body, err := ioutil.ReadAll(resp.Body)
+							return string(body), err
+						}).Should(ContainSubstring(`route_emitter_nats_messages_published_total{outcome="success",subject="router.register"}`))
+					})
+				})
+
 				Context("when running in local mode", func() {
 					BeforeEach(func() {
 						cellID = "cell-id"
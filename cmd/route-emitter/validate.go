@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"code.cloudfoundry.org/route-emitter/cmd/route-emitter/config"
+)
+
+const validateCommand = "validate"
+
+// runValidate implements `route-emitter validate -config <path>`. It prints
+// every problem with the config, one per line, and returns the exit code.
+func runValidate(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet(validateCommand, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", "", "Path to JSON configuration file")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	if *configPath == "" {
+		fmt.Fprintln(stderr, "-config must be provided")
+		return 2
+	}
+
+	err = config.ValidateFile(*configPath)
+	if errs, ok := err.(config.ValidationErrors); ok {
+		for _, validationErr := range errs {
+			fmt.Fprintln(stderr, validationErr.Error())
+		}
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "%s is valid\n", *configPath)
+	return 0
+}
+
+func isValidateCommand() bool {
+	return len(os.Args) > 1 && os.Args[1] == validateCommand
+}
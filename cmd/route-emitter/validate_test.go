@@ -0,0 +1,57 @@
+package main_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("Validate", func() {
+	var configPath string
+
+	writeConfig := func(data string) {
+		Expect(ioutil.WriteFile(configPath, []byte(data), 0644)).To(Succeed())
+	}
+
+	validate := func() *gexec.Session {
+		session, err := gexec.Start(exec.Command(emitterPath, "validate", "-config", configPath), GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit())
+		return session
+	}
+
+	BeforeEach(func() {
+		configFile, err := ioutil.TempFile("", "route-emitter-validate")
+		Expect(err).NotTo(HaveOccurred())
+		configPath = configFile.Name()
+		Expect(configFile.Close()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(configPath)
+	})
+
+	It("exits successfully for a valid config", func() {
+		writeConfig(`{"cell_id": "cell-id"}`)
+
+		session := validate()
+		Expect(session.ExitCode()).To(Equal(0))
+		Expect(session.Out).To(gbytes.Say("is valid"))
+	})
+
+	It("reports every problem and exits with a failure", func() {
+		writeConfig(`{"tcp_route_ttl": "24h", "enable_tcp_emitter": true, "nats_adresses": "127.0.0.1:4222"}`)
+
+		session := validate()
+		Expect(session.ExitCode()).To(Equal(1))
+		Expect(session.Err).To(gbytes.Say("nats_adresses: unknown field"))
+		Expect(session.Err).To(gbytes.Say("tcp_route_ttl: must not be more than"))
+		Expect(session.Err).To(gbytes.Say("routing_api.url: must be set when enable_tcp_emitter is true"))
+		Expect(session.Err).To(gbytes.Say("cell_id: must be set unless consul_enabled or locket_enabled is true"))
+	})
+})
@@ -5,8 +5,8 @@ import (
 	"time"
 
 	"code.cloudfoundry.org/clock"
-	loggingclient "code.cloudfoundry.org/diego-logging-client"
 	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/metrics"
 )
 
 const (
@@ -14,11 +14,11 @@ const (
 )
 
 type ConsulDownModeNotifier struct {
-	logger       lager.Logger
-	value        int
-	clock        clock.Clock
-	interval     time.Duration
-	metronClient loggingclient.IngressClient
+	logger      lager.Logger
+	value       int
+	clock       clock.Clock
+	interval    time.Duration
+	metricsSink metrics.Sink
 }
 
 func NewConsulDownModeNotifier(
@@ -26,10 +26,10 @@ func NewConsulDownModeNotifier(
 	value int,
 	clock clock.Clock,
 	interval time.Duration,
-	metronClient loggingclient.IngressClient,
+	metricsSink metrics.Sink,
 ) *ConsulDownModeNotifier {
 	return &ConsulDownModeNotifier{
-		logger: logger, value: value, clock: clock, interval: interval, metronClient: metronClient,
+		logger: logger, value: value, clock: clock, interval: interval, metricsSink: metricsSink,
 	}
 }
 
@@ -47,7 +47,7 @@ func (p *ConsulDownModeNotifier) Run(signals <-chan os.Signal, ready chan<- stru
 			logger.Info("received-signal")
 			return nil
 		case <-retryTimer.C():
-			err := p.metronClient.SendMetric(consulDownMetric, p.value)
+			err := p.metricsSink.SendMetric(consulDownMetric, p.value, nil)
 			if err != nil {
 				p.logger.Error("cannot-send-consul-down-metric", err)
 			}
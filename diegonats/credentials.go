@@ -0,0 +1,202 @@
+package diegonats
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	"github.com/nats-io/nkeys"
+)
+
+var (
+	ErrMissingUserJWT  = errors.New("nats credentials file contains no user jwt")
+	ErrMissingNKeySeed = errors.New("nats credentials file contains no nkey seed")
+)
+
+// Credentials authenticate NATS connections with an NKey, along with a user
+// JWT when read from a .creds file. The file is polled and a rotated file is
+// used from the next connection on, a file that cannot be parsed is ignored
+// and the previous credentials are kept.
+type Credentials struct {
+	logger       lager.Logger
+	clock        clock.Clock
+	path         string
+	withJWT      bool
+	pollInterval time.Duration
+	keyChanges   chan struct{}
+
+	lock      sync.RWMutex
+	contents  []byte
+	userJWT   string
+	keyPair   nkeys.KeyPair
+	publicKey string
+}
+
+// NewUserCredentials reads the user JWT and NKey seed of the .creds file at
+// path.
+func NewUserCredentials(logger lager.Logger, clock clock.Clock, path string, pollInterval time.Duration) (*Credentials, error) {
+	return newCredentials(logger, clock, path, true, pollInterval)
+}
+
+// NewNKeySeedCredentials reads the NKey seed file at path, the seed may be
+// bare or decorated.
+func NewNKeySeedCredentials(logger lager.Logger, clock clock.Clock, path string, pollInterval time.Duration) (*Credentials, error) {
+	return newCredentials(logger, clock, path, false, pollInterval)
+}
+
+func newCredentials(logger lager.Logger, clock clock.Clock, path string, withJWT bool, pollInterval time.Duration) (*Credentials, error) {
+	c := &Credentials{
+		logger:       logger.Session("nats-credentials", lager.Data{"path": path}),
+		clock:        clock,
+		path:         path,
+		withJWT:      withJWT,
+		pollInterval: pollInterval,
+		keyChanges:   make(chan struct{}, 1),
+	}
+
+	_, err := c.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Credentials) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	ticker := c.clock.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	close(ready)
+
+	for {
+		select {
+		case <-ticker.C():
+			changed, err := c.Reload()
+			if err != nil {
+				c.logger.Error("failed-to-reload", err)
+				continue
+			}
+			if changed {
+				c.logger.Info("reloaded", lager.Data{"public-key": c.PublicKey()})
+			}
+		case <-signals:
+			return nil
+		}
+	}
+}
+
+// Reload reads the file again and returns true if its contents changed.
+func (c *Credentials) Reload() (bool, error) {
+	contents, err := ioutil.ReadFile(c.path)
+	if err != nil {
+		return false, err
+	}
+
+	c.lock.RLock()
+	unchanged := bytes.Equal(contents, c.contents)
+	c.lock.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	blocks := decoratedBlocks(contents)
+	userJWT, seed := "", ""
+	if c.withJWT {
+		if len(blocks) < 1 {
+			return false, ErrMissingUserJWT
+		}
+		userJWT = blocks[0]
+		if len(blocks) > 1 {
+			seed = blocks[1]
+		}
+	} else if len(blocks) > 0 {
+		seed = blocks[0]
+	} else {
+		seed = strings.TrimSpace(string(contents))
+	}
+	if seed == "" {
+		return false, ErrMissingNKeySeed
+	}
+
+	keyPair, err := nkeys.FromSeed([]byte(seed))
+	if err != nil {
+		return false, err
+	}
+	publicKey, err := keyPair.PublicKey()
+	if err != nil {
+		return false, err
+	}
+
+	c.lock.Lock()
+	keyChanged := c.publicKey != "" && publicKey != c.publicKey
+	c.contents = contents
+	c.userJWT = userJWT
+	c.keyPair = keyPair
+	c.publicKey = publicKey
+	c.lock.Unlock()
+
+	if keyChanged {
+		select {
+		case c.keyChanges <- struct{}{}:
+		default:
+		}
+	}
+
+	return true, nil
+}
+
+// HasUserJWT returns true for the credentials of a .creds file.
+func (c *Credentials) HasUserJWT() bool {
+	return c.withJWT
+}
+
+func (c *Credentials) UserJWT() (string, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.userJWT, nil
+}
+
+func (c *Credentials) PublicKey() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.publicKey
+}
+
+// Sign signs the nonce sent by the server with the current NKey.
+func (c *Credentials) Sign(nonce []byte) ([]byte, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.keyPair.Sign(nonce)
+}
+
+// KeyChanges receives when a reload changed the public NKey. Connections
+// authenticated with an NKey alone keep presenting the key they were
+// established with and have to be replaced to use the new one.
+func (c *Credentials) KeyChanges() <-chan struct{} {
+	return c.keyChanges
+}
+
+// decoratedBlocks returns the contents of the -----BEGIN ...----- and
+// ------END ...------ delimited blocks in the order they appear.
+func decoratedBlocks(contents []byte) []string {
+	blocks := []string{}
+	inBlock := false
+	for _, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "---") && strings.Contains(line, "BEGIN"):
+			inBlock = true
+		case strings.HasPrefix(line, "---") && strings.Contains(line, "END"):
+			inBlock = false
+		case inBlock && line != "":
+			blocks = append(blocks, line)
+			inBlock = false
+		}
+	}
+	return blocks
+}
@@ -0,0 +1,332 @@
+package diegonats_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "code.cloudfoundry.org/route-emitter/diegonats"
+	"github.com/nats-io/nkeys"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("Credentials", func() {
+	const pollInterval = 10 * time.Second
+
+	var (
+		logger    *lagertest.TestLogger
+		fakeClock *fakeclock.FakeClock
+		dir       string
+		credsFile string
+		seedFile  string
+	)
+
+	createUser := func() (nkeys.KeyPair, string) {
+		keyPair, err := nkeys.CreateUser()
+		Expect(err).NotTo(HaveOccurred())
+		publicKey, err := keyPair.PublicKey()
+		Expect(err).NotTo(HaveOccurred())
+		return keyPair, publicKey
+	}
+
+	seedOf := func(keyPair nkeys.KeyPair) string {
+		seed, err := keyPair.Seed()
+		Expect(err).NotTo(HaveOccurred())
+		return string(seed)
+	}
+
+	writeCreds := func(userJWT string, keyPair nkeys.KeyPair) {
+		creds := fmt.Sprintf(`-----BEGIN NATS USER JWT-----
+%s
+------END NATS USER JWT------
+
+************************* IMPORTANT *************************
+NKEY Seed printed below can be used to sign and prove identity.
+
+-----BEGIN USER NKEY SEED-----
+%s
+------END USER NKEY SEED------
+
+*************************************************************
+`, userJWT, seedOf(keyPair))
+		Expect(ioutil.WriteFile(credsFile, []byte(creds), 0600)).To(Succeed())
+	}
+
+	writeSeed := func(keyPair nkeys.KeyPair) {
+		Expect(ioutil.WriteFile(seedFile, []byte(seedOf(keyPair)+"\n"), 0600)).To(Succeed())
+	}
+
+	verifySignature := func(publicKey, encodedSignature string) {
+		signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+		Expect(err).NotTo(HaveOccurred())
+		verifier, err := nkeys.FromPublicKey(publicKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(verifier.Verify([]byte(standInNonce), signature)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+
+		var err error
+		dir, err = ioutil.TempDir("", "nats-credentials")
+		Expect(err).NotTo(HaveOccurred())
+		credsFile = filepath.Join(dir, "user.creds")
+		seedFile = filepath.Join(dir, "user.nk")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	Describe("NewUserCredentials", func() {
+		It("reads the user jwt and nkey seed", func() {
+			keyPair, publicKey := createUser()
+			writeCreds("user.jwt.token", keyPair)
+
+			credentials, err := NewUserCredentials(logger, fakeClock, credsFile, pollInterval)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(credentials.HasUserJWT()).To(BeTrue())
+			Expect(credentials.UserJWT()).To(Equal("user.jwt.token"))
+			Expect(credentials.PublicKey()).To(Equal(publicKey))
+
+			signature, err := credentials.Sign([]byte(standInNonce))
+			Expect(err).NotTo(HaveOccurred())
+			verifySignature(publicKey, base64.RawURLEncoding.EncodeToString(signature))
+		})
+
+		It("fails when the file does not exist", func() {
+			_, err := NewUserCredentials(logger, fakeClock, credsFile, pollInterval)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("fails without a user jwt", func() {
+			Expect(ioutil.WriteFile(credsFile, []byte("nothing decorated"), 0600)).To(Succeed())
+			_, err := NewUserCredentials(logger, fakeClock, credsFile, pollInterval)
+			Expect(err).To(Equal(ErrMissingUserJWT))
+		})
+
+		It("fails without an nkey seed", func() {
+			Expect(ioutil.WriteFile(credsFile, []byte("-----BEGIN NATS USER JWT-----\nuser.jwt.token\n------END NATS USER JWT------\n"), 0600)).To(Succeed())
+			_, err := NewUserCredentials(logger, fakeClock, credsFile, pollInterval)
+			Expect(err).To(Equal(ErrMissingNKeySeed))
+		})
+	})
+
+	Describe("NewNKeySeedCredentials", func() {
+		It("reads a bare seed", func() {
+			keyPair, publicKey := createUser()
+			writeSeed(keyPair)
+
+			credentials, err := NewNKeySeedCredentials(logger, fakeClock, seedFile, pollInterval)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(credentials.HasUserJWT()).To(BeFalse())
+			Expect(credentials.PublicKey()).To(Equal(publicKey))
+		})
+
+		It("reads a decorated seed", func() {
+			keyPair, publicKey := createUser()
+			seed := "-----BEGIN USER NKEY SEED-----\n" + seedOf(keyPair) + "\n------END USER NKEY SEED------\n"
+			Expect(ioutil.WriteFile(seedFile, []byte(seed), 0600)).To(Succeed())
+
+			credentials, err := NewNKeySeedCredentials(logger, fakeClock, seedFile, pollInterval)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(credentials.PublicKey()).To(Equal(publicKey))
+		})
+
+		It("fails with an invalid seed", func() {
+			Expect(ioutil.WriteFile(seedFile, []byte("not-a-seed"), 0600)).To(Succeed())
+			_, err := NewNKeySeedCredentials(logger, fakeClock, seedFile, pollInterval)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("watching the file", func() {
+		var (
+			credentials *Credentials
+			process     ifrit.Process
+		)
+
+		BeforeEach(func() {
+			keyPair, _ := createUser()
+			writeCreds("user.jwt.token", keyPair)
+
+			var err error
+			credentials, err = NewUserCredentials(logger, fakeClock, credsFile, pollInterval)
+			Expect(err).NotTo(HaveOccurred())
+			process = ifrit.Invoke(credentials)
+		})
+
+		AfterEach(func() {
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive())
+		})
+
+		It("picks up a rotated file on the next poll", func() {
+			keyPair, publicKey := createUser()
+			writeCreds("rotated.jwt.token", keyPair)
+
+			Consistently(credentials.PublicKey).ShouldNot(Equal(publicKey))
+
+			fakeClock.WaitForWatcherAndIncrement(pollInterval)
+			Eventually(credentials.PublicKey).Should(Equal(publicKey))
+			Expect(credentials.UserJWT()).To(Equal("rotated.jwt.token"))
+			Eventually(logger).Should(gbytes.Say("reloaded"))
+			Expect(credentials.KeyChanges()).To(Receive())
+		})
+
+		It("does not report a key change when only the user jwt changed", func() {
+			contents, err := ioutil.ReadFile(credsFile)
+			Expect(err).NotTo(HaveOccurred())
+			rotated := []byte("-----BEGIN NATS USER JWT-----\nrotated.jwt.token\n" + string(contents[len("-----BEGIN NATS USER JWT-----\nuser.jwt.token\n"):]))
+			Expect(ioutil.WriteFile(credsFile, rotated, 0600)).To(Succeed())
+
+			fakeClock.WaitForWatcherAndIncrement(pollInterval)
+			Eventually(credentials.UserJWT).Should(Equal("rotated.jwt.token"))
+			Expect(credentials.KeyChanges()).NotTo(Receive())
+		})
+
+		It("keeps the previous credentials when the file is invalid", func() {
+			publicKey := credentials.PublicKey()
+			Expect(ioutil.WriteFile(credsFile, []byte("garbage"), 0600)).To(Succeed())
+
+			fakeClock.WaitForWatcherAndIncrement(pollInterval)
+			Eventually(logger).Should(gbytes.Say("failed-to-reload"))
+			Expect(credentials.PublicKey()).To(Equal(publicKey))
+			Expect(credentials.UserJWT()).To(Equal("user.jwt.token"))
+		})
+	})
+
+	Describe("authenticating a client", func() {
+		var (
+			server     *natsServerStandIn
+			natsClient NATSClient
+		)
+
+		BeforeEach(func() {
+			server = startNATSServerStandIn(nil)
+			natsClient = NewClient()
+		})
+
+		AfterEach(func() {
+			natsClient.Close()
+			server.Stop()
+		})
+
+		Context("with user credentials", func() {
+			var (
+				credentials *Credentials
+				publicKey   string
+			)
+
+			BeforeEach(func() {
+				var keyPair nkeys.KeyPair
+				keyPair, publicKey = createUser()
+				writeCreds("user.jwt.token", keyPair)
+
+				var err error
+				credentials, err = NewUserCredentials(logger, fakeClock, credsFile, pollInterval)
+				Expect(err).NotTo(HaveOccurred())
+				natsClient.SetCredentials(credentials)
+			})
+
+			It("sends the user jwt and signs the nonce", func() {
+				_, err := natsClient.Connect([]string{"nats://" + server.Address()})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(natsClient.Ping()).To(BeTrue())
+
+				Expect(server.Connects()).To(HaveLen(1))
+				connect := server.Connects()[0]
+				Expect(connect).To(HaveKeyWithValue("jwt", "user.jwt.token"))
+				Expect(connect).NotTo(HaveKey("user"))
+				verifySignature(publicKey, connect["sig"].(string))
+			})
+
+			It("uses rotated credentials when the connection is reestablished", func() {
+				_, err := natsClient.Connect([]string{"nats://" + server.Address()})
+				Expect(err).NotTo(HaveOccurred())
+
+				keyPair, rotatedPublicKey := createUser()
+				writeCreds("rotated.jwt.token", keyPair)
+				Expect(credentials.Reload()).To(BeTrue())
+
+				server.Stop()
+				Eventually(natsClient.Ping).Should(BeFalse())
+				server.Start()
+				Eventually(natsClient.Ping, 5).Should(BeTrue())
+
+				Expect(server.Connects()).To(HaveLen(2))
+				connect := server.Connects()[1]
+				Expect(connect).To(HaveKeyWithValue("jwt", "rotated.jwt.token"))
+				verifySignature(rotatedPublicKey, connect["sig"].(string))
+			})
+		})
+
+		Context("with an nkey seed", func() {
+			var (
+				credentials *Credentials
+				publicKey   string
+			)
+
+			BeforeEach(func() {
+				var keyPair nkeys.KeyPair
+				keyPair, publicKey = createUser()
+				writeSeed(keyPair)
+
+				var err error
+				credentials, err = NewNKeySeedCredentials(logger, fakeClock, seedFile, pollInterval)
+				Expect(err).NotTo(HaveOccurred())
+				natsClient.SetCredentials(credentials)
+			})
+
+			It("sends the public nkey and signs the nonce", func() {
+				_, err := natsClient.Connect([]string{"nats://" + server.Address()})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(natsClient.Ping()).To(BeTrue())
+
+				Expect(server.Connects()).To(HaveLen(1))
+				connect := server.Connects()[0]
+				Expect(connect).To(HaveKeyWithValue("nkey", publicKey))
+				Expect(connect).NotTo(HaveKey("jwt"))
+				verifySignature(publicKey, connect["sig"].(string))
+			})
+
+			Context("through the client runner", func() {
+				var natsClientProcess ifrit.Process
+
+				BeforeEach(func() {
+					natsClientRunner := NewClientRunner(server.Address(), "", "", logger, natsClient).
+						WithKeyChanges(credentials.KeyChanges())
+					natsClientProcess = ifrit.Invoke(natsClientRunner)
+				})
+
+				AfterEach(func() {
+					natsClientProcess.Signal(os.Interrupt)
+					Eventually(natsClientProcess.Wait(), 5).Should(Receive())
+				})
+
+				It("reconnects with a rotated nkey", func() {
+					Expect(server.Connects()).To(HaveLen(1))
+
+					keyPair, rotatedPublicKey := createUser()
+					writeSeed(keyPair)
+					Expect(credentials.Reload()).To(BeTrue())
+
+					Eventually(logger).Should(gbytes.Say("reconnecting-with-new-nkey-succeeded"))
+					Expect(server.Connects()).To(HaveLen(2))
+					connect := server.Connects()[1]
+					Expect(connect).To(HaveKeyWithValue("nkey", rotatedPublicKey))
+					verifySignature(rotatedPublicKey, connect["sig"].(string))
+				})
+			})
+		})
+	})
+})
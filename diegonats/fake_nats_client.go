@@ -1,6 +1,7 @@
 package diegonats
 
 import (
+	"crypto/tls"
 	"sync"
 	"time"
 
@@ -23,6 +24,8 @@ type FakeNATSClient struct {
 	onPing       func() bool
 	pingResponse bool
 	pingInterval time.Duration
+	tlsConfig    *tls.Config
+	credentials  *Credentials
 
 	sync.RWMutex
 }
@@ -45,6 +48,8 @@ func (f *FakeNATSClient) Reset() {
 	f.connectError = nil
 	f.unsubscribeError = nil
 	f.pingInterval = -1
+	f.tlsConfig = nil
+	f.credentials = nil
 
 	f.whenSubscribing = map[string]func(nats.MsgHandler) error{}
 	f.whenPublishing = map[string]func(*nats.Msg) error{}
@@ -66,6 +71,20 @@ func (f *FakeNATSClient) SetPingInterval(interval time.Duration) {
 	f.pingInterval = interval
 }
 
+func (f *FakeNATSClient) SetTLSConfig(tlsConfig *tls.Config) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.tlsConfig = tlsConfig
+}
+
+func (f *FakeNATSClient) SetCredentials(credentials *Credentials) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.credentials = credentials
+}
+
 func (f *FakeNATSClient) Close() {
 	f.Lock()
 	defer f.Unlock()
@@ -1,6 +1,9 @@
 package diegonats
 
 import (
+	"crypto/tls"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/nats-io/nats"
@@ -9,6 +12,8 @@ import (
 type NATSClient interface {
 	Connect(urls []string) (chan struct{}, error)
 	SetPingInterval(interval time.Duration)
+	SetTLSConfig(tlsConfig *tls.Config)
+	SetCredentials(credentials *Credentials)
 	Close()
 	Ping() bool
 	Unsubscribe(sub *nats.Subscription) error
@@ -22,13 +27,28 @@ type NATSClient interface {
 }
 
 type natsClient struct {
-	*nats.Conn
+	lock         sync.RWMutex
+	conn         *nats.Conn
 	pingInterval time.Duration
+	tlsConfig    *tls.Config
+	credentials  *Credentials
+
+	// subscriptions are keyed by the subscription handed out to the caller so
+	// that they can be recreated on a new connection
+	subscriptions map[*nats.Subscription]*subscription
+}
+
+type subscription struct {
+	subject string
+	queue   string
+	handler nats.MsgHandler
+	current *nats.Subscription
 }
 
 func NewClient() NATSClient {
 	return &natsClient{
-		pingInterval: nats.DefaultPingInterval,
+		pingInterval:  nats.DefaultPingInterval,
+		subscriptions: map[*nats.Subscription]*subscription{},
 	}
 }
 
@@ -36,12 +56,39 @@ func (nc *natsClient) SetPingInterval(interval time.Duration) {
 	nc.pingInterval = interval
 }
 
+// SetTLSConfig makes every connection, reconnections included, use TLS with
+// tlsConfig. Without a TLS config only tls:// urls are connected to with TLS.
+func (nc *natsClient) SetTLSConfig(tlsConfig *tls.Config) {
+	nc.tlsConfig = tlsConfig
+}
+
+// SetCredentials authenticates every connection with credentials instead of
+// the user and password of the urls. The user JWT and signature are taken from
+// credentials on every connection attempt, the NKey of an NKey only
+// authentication when Connect is called.
+func (nc *natsClient) SetCredentials(credentials *Credentials) {
+	nc.credentials = credentials
+}
+
+// Connect establishes a connection to urls. When the client is already
+// connected the existing subscriptions are moved to the new connection and the
+// old one is closed, a failed attempt leaves the existing connection in place.
 func (nc *natsClient) Connect(urls []string) (chan struct{}, error) {
 	options := nats.DefaultOptions
 	options.Servers = urls
 	options.ReconnectWait = 500 * time.Millisecond
 	options.MaxReconnect = -1
 	options.PingInterval = nc.pingInterval
+	options.Secure = nc.tlsConfig != nil || hasTLSScheme(urls)
+	options.TLSConfig = nc.tlsConfig
+	if nc.credentials != nil {
+		if nc.credentials.HasUserJWT() {
+			options.UserJWT = nc.credentials.UserJWT
+		} else {
+			options.Nkey = nc.credentials.PublicKey()
+		}
+		options.SignatureCB = nc.credentials.Sign
+	}
 
 	closedChan := make(chan struct{})
 	options.ClosedCB = func(*nats.Conn) {
@@ -53,21 +100,129 @@ func (nc *natsClient) Connect(urls []string) (chan struct{}, error) {
 		return nil, err
 	}
 
-	nc.Conn = natsConnection
+	nc.lock.Lock()
+	defer nc.lock.Unlock()
+
+	for key, sub := range nc.subscriptions {
+		// auto-unsubscribed subscriptions are done, e.g. replies to a greeting
+		if !sub.current.IsValid() {
+			delete(nc.subscriptions, key)
+			continue
+		}
+
+		current, err := nc.subscribe(natsConnection, sub.subject, sub.queue, sub.handler)
+		if err != nil {
+			natsConnection.Close()
+			return nil, err
+		}
+		sub.current = current
+	}
+
+	previous := nc.conn
+	nc.conn = natsConnection
+	if previous != nil {
+		previous.Close()
+	}
+
 	return closedChan, nil
 }
 
 func (nc *natsClient) Close() {
-	if nc.Conn != nil {
-		nc.Conn.Close()
+	conn := nc.connection()
+	if conn != nil {
+		conn.Close()
 	}
 }
 
-func (c *natsClient) Ping() bool {
-	err := c.FlushTimeout(500 * time.Millisecond)
+func (nc *natsClient) Ping() bool {
+	conn := nc.connection()
+	if conn == nil {
+		return false
+	}
+	err := conn.FlushTimeout(500 * time.Millisecond)
 	return err == nil
 }
 
 func (nc *natsClient) Unsubscribe(sub *nats.Subscription) error {
+	nc.lock.Lock()
+	tracked, ok := nc.subscriptions[sub]
+	delete(nc.subscriptions, sub)
+	nc.lock.Unlock()
+
+	if ok {
+		return tracked.current.Unsubscribe()
+	}
 	return sub.Unsubscribe()
 }
+
+func (nc *natsClient) Publish(subject string, data []byte) error {
+	conn := nc.connection()
+	if conn == nil {
+		return nats.ErrInvalidConnection
+	}
+	return conn.Publish(subject, data)
+}
+
+func (nc *natsClient) PublishRequest(subj, reply string, data []byte) error {
+	conn := nc.connection()
+	if conn == nil {
+		return nats.ErrInvalidConnection
+	}
+	return conn.PublishRequest(subj, reply, data)
+}
+
+func (nc *natsClient) Request(subj string, data []byte, timeout time.Duration) (*nats.Msg, error) {
+	conn := nc.connection()
+	if conn == nil {
+		return nil, nats.ErrInvalidConnection
+	}
+	return conn.Request(subj, data, timeout)
+}
+
+func (nc *natsClient) Subscribe(subject string, handler nats.MsgHandler) (*nats.Subscription, error) {
+	return nc.QueueSubscribe(subject, "", handler)
+}
+
+func (nc *natsClient) QueueSubscribe(subject, queue string, handler nats.MsgHandler) (*nats.Subscription, error) {
+	nc.lock.Lock()
+	defer nc.lock.Unlock()
+
+	if nc.conn == nil {
+		return nil, nats.ErrInvalidConnection
+	}
+
+	sub, err := nc.subscribe(nc.conn, subject, queue, handler)
+	if err != nil {
+		return nil, err
+	}
+
+	nc.subscriptions[sub] = &subscription{
+		subject: subject,
+		queue:   queue,
+		handler: handler,
+		current: sub,
+	}
+	return sub, nil
+}
+
+func (nc *natsClient) subscribe(conn *nats.Conn, subject, queue string, handler nats.MsgHandler) (*nats.Subscription, error) {
+	if queue == "" {
+		return conn.Subscribe(subject, handler)
+	}
+	return conn.QueueSubscribe(subject, queue, handler)
+}
+
+func hasTLSScheme(urls []string) bool {
+	for _, url := range urls {
+		if strings.HasPrefix(url, "tls://") {
+			return true
+		}
+	}
+	return false
+}
+
+func (nc *natsClient) connection() *nats.Conn {
+	nc.lock.RLock()
+	defer nc.lock.RUnlock()
+	return nc.conn
+}
@@ -10,35 +10,47 @@ import (
 )
 
 type NATSClientRunner struct {
+	addresses  string
+	username   string
+	password   string
+	logger     lager.Logger
+	client     NATSClient
+	reconnects chan natsCredentials
+	keyChanges <-chan struct{}
+}
+
+type natsCredentials struct {
 	addresses string
 	username  string
 	password  string
-	logger    lager.Logger
-	client    NATSClient
 }
 
 func NewClientRunner(addresses, username, password string, logger lager.Logger, client NATSClient) NATSClientRunner {
 	return NATSClientRunner{
-		addresses: addresses,
-		username:  username,
-		password:  password,
-		logger:    logger.Session("nats-runner"),
-		client:    client,
+		addresses:  addresses,
+		username:   username,
+		password:   password,
+		logger:     logger.Session("nats-runner"),
+		client:     client,
+		reconnects: make(chan natsCredentials, 1),
 	}
 }
 
+// WithKeyChanges returns a runner that connects again, to the addresses it is
+// connected to, whenever keyChanges receives. Use it with the KeyChanges of
+// the credentials of the client.
+func (runner NATSClientRunner) WithKeyChanges(keyChanges <-chan struct{}) NATSClientRunner {
+	runner.keyChanges = keyChanges
+	return runner
+}
+
 func (runner NATSClientRunner) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
-	natsMembers := []string{}
-	for _, addr := range strings.Split(runner.addresses, ",") {
-		uri := url.URL{
-			Scheme: "nats",
-			User:   url.UserPassword(runner.username, runner.password),
-			Host:   addr,
-		}
-		natsMembers = append(natsMembers, uri.String())
+	current := natsCredentials{
+		addresses: runner.addresses,
+		username:  runner.username,
+		password:  runner.password,
 	}
-
-	unexpectedConnClosed, err := runner.client.Connect(natsMembers)
+	unexpectedConnClosed, err := runner.client.Connect(natsURLs(current.addresses, current.username, current.password))
 	if err != nil {
 		runner.logger.Error("connecting-to-nats-failed", err)
 		return err
@@ -47,13 +59,70 @@ func (runner NATSClientRunner) Run(signals <-chan os.Signal, ready chan<- struct
 	runner.logger.Info("connecting-to-nats-succeeeded")
 	close(ready)
 
+	for {
+		select {
+		case <-signals:
+			runner.client.Close()
+			runner.logger.Info("shutting-down")
+			return nil
+		case creds := <-runner.reconnects:
+			// the old connection is closed by the client once the new one is
+			// up, its closed channel must no longer be watched
+			closed, err := runner.client.Connect(natsURLs(creds.addresses, creds.username, creds.password))
+			if err != nil {
+				runner.logger.Error("reconnecting-to-nats-failed", err)
+				continue
+			}
+			unexpectedConnClosed = closed
+			current = creds
+			runner.logger.Info("reconnecting-to-nats-succeeded")
+		case <-runner.keyChanges:
+			closed, err := runner.client.Connect(natsURLs(current.addresses, current.username, current.password))
+			if err != nil {
+				runner.logger.Error("reconnecting-with-new-nkey-failed", err)
+				continue
+			}
+			unexpectedConnClosed = closed
+			runner.logger.Info("reconnecting-with-new-nkey-succeeded")
+		case <-unexpectedConnClosed:
+			runner.logger.Error("unexpected-nats-close", nil)
+			return errors.New("nats closed unexpectedly")
+		}
+	}
+}
+
+// Reconnect asks a running runner to connect with the given addresses and
+// credentials. The current connection is kept if the new one cannot be
+// established.
+func (runner NATSClientRunner) Reconnect(addresses, username, password string) {
 	select {
-	case <-signals:
-		runner.client.Close()
-		runner.logger.Info("shutting-down")
-		return nil
-	case <-unexpectedConnClosed:
-		runner.logger.Error("unexpected-nats-close", nil)
-		return errors.New("nats closed unexpectedly")
+	case <-runner.reconnects:
+	default:
+	}
+	runner.reconnects <- natsCredentials{
+		addresses: addresses,
+		username:  username,
+		password:  password,
+	}
+}
+
+// natsURLs adds the credentials, unless both are empty, to every address,
+// addresses without a nats:// or tls:// scheme get the nats:// one.
+func natsURLs(addresses, username, password string) []string {
+	natsMembers := []string{}
+	for _, addr := range strings.Split(addresses, ",") {
+		scheme := "nats"
+		if i := strings.Index(addr, "://"); i >= 0 {
+			scheme, addr = addr[:i], addr[i+len("://"):]
+		}
+		uri := url.URL{
+			Scheme: scheme,
+			Host:   addr,
+		}
+		if username != "" || password != "" {
+			uri.User = url.UserPassword(username, password)
+		}
+		natsMembers = append(natsMembers, uri.String())
 	}
+	return natsMembers
 }
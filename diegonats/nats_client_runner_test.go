@@ -9,6 +9,7 @@ import (
 	. "code.cloudfoundry.org/route-emitter/diegonats"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 	"github.com/tedsuo/ifrit"
 )
 
@@ -16,11 +17,14 @@ var _ = Describe("Starting the NatsClientRunner process", func() {
 	var natsClient NATSClient
 	var natsClientRunner ifrit.Runner
 	var natsClientProcess ifrit.Process
+	var natsAddress string
+	var logger *lagertest.TestLogger
 
 	BeforeEach(func() {
-		natsAddress := fmt.Sprintf("127.0.0.1:%d", natsPort)
+		natsAddress = fmt.Sprintf("127.0.0.1:%d", natsPort)
 		natsClient = NewClient()
-		natsClientRunner = NewClientRunner(natsAddress, "nats", "nats", lagertest.NewTestLogger("test"), natsClient)
+		logger = lagertest.NewTestLogger("test")
+		natsClientRunner = NewClientRunner(natsAddress, "nats", "nats", logger, natsClient)
 	})
 
 	AfterEach(func() {
@@ -65,6 +69,24 @@ var _ = Describe("Starting the NatsClientRunner process", func() {
 			startNATS()
 			Expect(natsClient.Ping()).To(BeTrue())
 		})
+
+		Context("when asked to reconnect", func() {
+			It("connects with the new credentials", func() {
+				natsClientRunner.(NATSClientRunner).Reconnect(natsAddress, "new-user", "new-password")
+				Eventually(logger).Should(gbytes.Say("reconnecting-to-nats-succeeded"))
+
+				Expect(natsClient.Ping()).To(BeTrue())
+				Consistently(natsClientProcess.Wait()).ShouldNot(Receive())
+			})
+
+			It("keeps the existing connection when the new one fails", func() {
+				natsClientRunner.(NATSClientRunner).Reconnect("127.0.0.1:1", "nats", "nats")
+				Eventually(logger).Should(gbytes.Say("reconnecting-to-nats-failed"))
+
+				Expect(natsClient.Ping()).To(BeTrue())
+				Consistently(natsClientProcess.Wait()).ShouldNot(Receive())
+			})
+		})
 	})
 
 	Describe("when NATS is not up", func() {
@@ -0,0 +1,197 @@
+package diegonats_test
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	. "code.cloudfoundry.org/route-emitter/diegonats"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("NATS over TLS", func() {
+	var (
+		certsDir  string
+		certs     testCertificates
+		mutualTLS bool
+		server    *natsServerStandIn
+	)
+
+	BeforeEach(func() {
+		var err error
+		certsDir, err = ioutil.TempDir("", "nats-certs")
+		Expect(err).NotTo(HaveOccurred())
+		certs = generateTestCertificates(certsDir)
+		mutualTLS = false
+	})
+
+	JustBeforeEach(func() {
+		server = startNATSServerStandIn(certs.serverTLSConfig(mutualTLS))
+	})
+
+	AfterEach(func() {
+		server.Stop()
+		os.RemoveAll(certsDir)
+	})
+
+	Describe("NewTLSConfig", func() {
+		It("trusts the CAs of the bundle and overrides the server name", func() {
+			tlsConfig, err := NewTLSConfig(certs.caCertFile, "", "", testServerName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tlsConfig.RootCAs.Subjects()).To(Equal(certs.caPool.Subjects()))
+			Expect(tlsConfig.ServerName).To(Equal(testServerName))
+			Expect(tlsConfig.Certificates).To(BeEmpty())
+		})
+
+		It("loads the client certificate", func() {
+			tlsConfig, err := NewTLSConfig(certs.caCertFile, certs.clientCertFile, certs.clientKeyFile, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tlsConfig.Certificates).To(HaveLen(1))
+		})
+
+		It("fails when the CA bundle has no certificates", func() {
+			emptyBundle := filepath.Join(certsDir, "empty.crt")
+			Expect(ioutil.WriteFile(emptyBundle, []byte("not a certificate"), 0600)).To(Succeed())
+
+			_, err := NewTLSConfig(emptyBundle, "", "", "")
+			Expect(err).To(Equal(ErrInvalidCACert))
+		})
+
+		It("fails when the client key is missing", func() {
+			_, err := NewTLSConfig(certs.caCertFile, certs.clientCertFile, "", "")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("connecting", func() {
+		var (
+			natsClient NATSClient
+			tlsConfig  *tls.Config
+		)
+
+		BeforeEach(func() {
+			var err error
+			tlsConfig, err = NewTLSConfig(certs.caCertFile, "", "", testServerName)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		JustBeforeEach(func() {
+			natsClient = NewClient()
+			natsClient.SetTLSConfig(tlsConfig)
+		})
+
+		AfterEach(func() {
+			natsClient.Close()
+		})
+
+		It("connects to tls:// urls", func() {
+			_, err := natsClient.Connect([]string{"tls://nats:nats@" + server.Address()})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(natsClient.Ping()).To(BeTrue())
+			Expect(natsClient.Publish("some.subject", []byte("hello"))).To(Succeed())
+
+			Expect(server.Connects()).To(HaveLen(1))
+			Expect(server.Connects()[0]).To(HaveKeyWithValue("ssl_required", true))
+		})
+
+		It("connects to nats:// urls with TLS", func() {
+			_, err := natsClient.Connect([]string{"nats://nats:nats@" + server.Address()})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(natsClient.Ping()).To(BeTrue())
+		})
+
+		It("keeps using TLS when the server comes back", func() {
+			_, err := natsClient.Connect([]string{"tls://nats:nats@" + server.Address()})
+			Expect(err).NotTo(HaveOccurred())
+
+			server.Stop()
+			Eventually(natsClient.Ping).Should(BeFalse())
+
+			server.Start()
+			Eventually(natsClient.Ping, 5).Should(BeTrue())
+			Expect(server.Connects()).To(HaveLen(2))
+		})
+
+		Context("without a TLS config", func() {
+			BeforeEach(func() {
+				tlsConfig = nil
+			})
+
+			It("refuses to connect to nats:// urls in cleartext", func() {
+				_, err := natsClient.Connect([]string{"nats://nats:nats@" + server.Address()})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("without the server name override", func() {
+			BeforeEach(func() {
+				tlsConfig.ServerName = ""
+			})
+
+			It("fails to verify the server certificate", func() {
+				_, err := natsClient.Connect([]string{"tls://nats:nats@" + server.Address()})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when the server requires a client certificate", func() {
+			BeforeEach(func() {
+				mutualTLS = true
+			})
+
+			It("fails without one", func() {
+				_, err := natsClient.Connect([]string{"tls://nats:nats@" + server.Address()})
+				Expect(err).To(HaveOccurred())
+			})
+
+			Context("and the client has one", func() {
+				BeforeEach(func() {
+					var err error
+					tlsConfig, err = NewTLSConfig(certs.caCertFile, certs.clientCertFile, certs.clientKeyFile, testServerName)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("connects", func() {
+					_, err := natsClient.Connect([]string{"tls://nats:nats@" + server.Address()})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(natsClient.Ping()).To(BeTrue())
+				})
+			})
+		})
+
+		Context("through the client runner", func() {
+			var (
+				logger            *lagertest.TestLogger
+				natsClientRunner  NATSClientRunner
+				natsClientProcess ifrit.Process
+			)
+
+			JustBeforeEach(func() {
+				logger = lagertest.NewTestLogger("test")
+				natsClientRunner = NewClientRunner("tls://"+server.Address(), "nats", "nats", logger, natsClient)
+				natsClientProcess = ifrit.Invoke(natsClientRunner)
+			})
+
+			AfterEach(func() {
+				natsClientProcess.Signal(os.Interrupt)
+				Eventually(natsClientProcess.Wait(), 5).Should(Receive())
+			})
+
+			It("keeps using TLS when asked to reconnect", func() {
+				Expect(natsClient.Ping()).To(BeTrue())
+
+				natsClientRunner.Reconnect("tls://"+server.Address(), "new-user", "new-password")
+				Eventually(logger).Should(gbytes.Say("reconnecting-to-nats-succeeded"))
+
+				Expect(natsClient.Ping()).To(BeTrue())
+				Expect(server.Connects()).To(HaveLen(2))
+				Expect(server.Connects()[1]).To(HaveKeyWithValue("user", "new-user"))
+			})
+		})
+	})
+})
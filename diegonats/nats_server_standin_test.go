@@ -0,0 +1,242 @@
+package diegonats_test
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// natsServerStandIn speaks just enough of the NATS protocol, over TLS when
+// given a TLS config, for a client to connect, publish and ping. Subscriptions
+// are accepted but never receive anything. Clients are sent standInNonce to
+// sign.
+type natsServerStandIn struct {
+	address   string
+	tlsConfig *tls.Config
+
+	lock     sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	connects []map[string]interface{}
+}
+
+const standInNonce = "stand-in-nonce"
+
+func startNATSServerStandIn(tlsConfig *tls.Config) *natsServerStandIn {
+	server := &natsServerStandIn{address: "127.0.0.1:0", tlsConfig: tlsConfig}
+	server.Start()
+	return server
+}
+
+// Start listens on the address of the stand-in, which is kept across
+// restarts.
+func (s *natsServerStandIn) Start() {
+	listener, err := net.Listen("tcp", s.address)
+	Expect(err).NotTo(HaveOccurred())
+
+	s.lock.Lock()
+	s.address = listener.Addr().String()
+	s.listener = listener
+	s.conns = map[net.Conn]struct{}{}
+	s.lock.Unlock()
+
+	go s.serve(listener)
+}
+
+// Stop closes the listener and every client connection.
+func (s *natsServerStandIn) Stop() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.listener.Close()
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func (s *natsServerStandIn) Address() string {
+	return s.address
+}
+
+// Connects returns the CONNECT options sent by the clients.
+func (s *natsServerStandIn) Connects() []map[string]interface{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]map[string]interface{}{}, s.connects...)
+}
+
+func (s *natsServerStandIn) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.lock.Lock()
+		s.conns[conn] = struct{}{}
+		s.lock.Unlock()
+
+		go s.handle(conn)
+	}
+}
+
+func (s *natsServerStandIn) handle(conn net.Conn) {
+	defer conn.Close()
+
+	host, portString, _ := net.SplitHostPort(s.address)
+	port, _ := strconv.Atoi(portString)
+	info, _ := json.Marshal(map[string]interface{}{
+		"server_id":     "nats-server-stand-in",
+		"version":       "1.0.0",
+		"host":          host,
+		"port":          port,
+		"auth_required": false,
+		"tls_required":  s.tlsConfig != nil,
+		"tls_verify":    s.tlsConfig != nil && s.tlsConfig.ClientAuth == tls.RequireAndVerifyClientCert,
+		"max_payload":   1024 * 1024,
+		"nonce":         standInNonce,
+	})
+	if _, err := conn.Write([]byte("INFO " + string(info) + "\r\n")); err != nil {
+		return
+	}
+
+	if s.tlsConfig != nil {
+		tlsConn := tls.Server(conn, s.tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return
+		}
+		conn = tlsConn
+	}
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(line, "CONNECT "):
+			var options map[string]interface{}
+			json.Unmarshal([]byte(strings.TrimPrefix(line, "CONNECT ")), &options)
+			s.lock.Lock()
+			s.connects = append(s.connects, options)
+			s.lock.Unlock()
+		case line == "PING":
+			if _, err := conn.Write([]byte("PONG\r\n")); err != nil {
+				return
+			}
+		case strings.HasPrefix(line, "PUB "):
+			fields := strings.Fields(line)
+			size, _ := strconv.Atoi(fields[len(fields)-1])
+			// the payload is followed by a CRLF
+			if _, err := io.CopyN(ioutil.Discard, reader, int64(size+2)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// testCertificates are the files of a self-signed CA, of a server certificate
+// for nats.service.internal and of a client certificate, both signed by it.
+type testCertificates struct {
+	caPool         *x509.CertPool
+	caCertFile     string
+	serverCert     tls.Certificate
+	clientCertFile string
+	clientKeyFile  string
+}
+
+const testServerName = "nats.service.internal"
+
+func generateTestCertificates(dir string) testCertificates {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "nats-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	Expect(err).NotTo(HaveOccurred())
+	caCert, err := x509.ParseCertificate(caDER)
+	Expect(err).NotTo(HaveOccurred())
+
+	issue := func(serial int64, commonName string, usage x509.ExtKeyUsage, dnsNames ...string) ([]byte, []byte) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: commonName},
+			DNSNames:     dnsNames,
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		Expect(err).NotTo(HaveOccurred())
+
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		Expect(err).NotTo(HaveOccurred())
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	}
+
+	serverCertPEM, serverKeyPEM := issue(2, testServerName, x509.ExtKeyUsageServerAuth, testServerName)
+	serverCert, err := tls.X509KeyPair(serverCertPEM, serverKeyPEM)
+	Expect(err).NotTo(HaveOccurred())
+
+	clientCertPEM, clientKeyPEM := issue(3, "route-emitter", x509.ExtKeyUsageClientAuth)
+
+	certs := testCertificates{
+		caPool:         x509.NewCertPool(),
+		caCertFile:     filepath.Join(dir, "ca.crt"),
+		serverCert:     serverCert,
+		clientCertFile: filepath.Join(dir, "client.crt"),
+		clientKeyFile:  filepath.Join(dir, "client.key"),
+	}
+	certs.caPool.AddCert(caCert)
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+	Expect(ioutil.WriteFile(certs.caCertFile, caPEM, 0600)).To(Succeed())
+	Expect(ioutil.WriteFile(certs.clientCertFile, clientCertPEM, 0600)).To(Succeed())
+	Expect(ioutil.WriteFile(certs.clientKeyFile, clientKeyPEM, 0600)).To(Succeed())
+
+	return certs
+}
+
+// serverTLSConfig presents the server certificate and, with mutualTLS,
+// requires a client certificate signed by the CA.
+func (c testCertificates) serverTLSConfig(mutualTLS bool) *tls.Config {
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{c.serverCert}}
+	if mutualTLS {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		tlsConfig.ClientCAs = c.caPool
+	}
+	return tlsConfig
+}
@@ -0,0 +1,42 @@
+package diegonats
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+)
+
+var ErrInvalidCACert = errors.New("nats ca cert file contains no certificates")
+
+// NewTLSConfig returns the TLS config for NATS servers signed by the CAs in
+// caCertFile. The client certificate is only presented, for mutual TLS, when
+// certFile and keyFile are set. serverName overrides the host name the server
+// certificates are verified against.
+func NewTLSConfig(caCertFile, certFile, keyFile, serverName string) (*tls.Config, error) {
+	caCerts, err := ioutil.ReadFile(caCertFile)
+	if err != nil {
+		return nil, err
+	}
+
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caCerts) {
+		return nil, ErrInvalidCACert
+	}
+
+	tlsConfig := &tls.Config{
+		RootCAs:    caPool,
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1 @@
+package dnsserver // import "code.cloudfoundry.org/route-emitter/dnsserver"
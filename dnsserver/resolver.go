@@ -0,0 +1,178 @@
+package dnsserver
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"github.com/miekg/dns"
+)
+
+type instance struct {
+	hostname string
+	index    int32
+	ip       net.IP
+	ports    []uint32
+}
+
+// Resolver answers questions about the hostnames of internal routes from an
+// index of the routing table, rebuilt by Refresh. Every hostname resolves to
+// all of its instances and <index>.<hostname> to a single instance, the same
+// names the internal registration messages carry.
+type Resolver struct {
+	table   routingtable.RoutingTable
+	domains []string
+	ttl     uint32
+
+	lock  sync.RWMutex
+	names map[string][]instance
+}
+
+func NewResolver(table routingtable.RoutingTable, domains []string, ttl time.Duration) *Resolver {
+	fqdns := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		fqdns = append(fqdns, dns.Fqdn(strings.ToLower(domain)))
+	}
+
+	return &Resolver{
+		table:   table,
+		domains: fqdns,
+		ttl:     uint32(ttl.Seconds()),
+		names:   map[string][]instance{},
+	}
+}
+
+// Authoritative returns true if name is in one of the domains served by the
+// resolver.
+func (r *Resolver) Authoritative(name string) bool {
+	name = dns.Fqdn(strings.ToLower(name))
+	for _, domain := range r.domains {
+		if dns.IsSubDomain(domain, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the answer and additional records for question. It returns
+// false if the name does not exist, an existing name without records of the
+// requested type has an empty answer.
+func (r *Resolver) Resolve(question dns.Question) ([]dns.RR, []dns.RR, bool) {
+	name := dns.Fqdn(strings.ToLower(question.Name))
+
+	r.lock.RLock()
+	instances, ok := r.names[name]
+	r.lock.RUnlock()
+	if !ok {
+		return nil, nil, false
+	}
+
+	var answer, extra []dns.RR
+	switch question.Qtype {
+	case dns.TypeA, dns.TypeAAAA:
+		for _, instance := range instances {
+			rr := r.addressRecord(question.Name, instance.ip)
+			if rr.Header().Rrtype == question.Qtype {
+				answer = append(answer, rr)
+			}
+		}
+	case dns.TypeSRV:
+		// targets are the per-index names so that clients can tell instances
+		// apart, their addresses come along as additional records
+		for _, instance := range instances {
+			target := fmt.Sprintf("%d.%s", instance.index, instance.hostname)
+			for _, port := range instance.ports {
+				answer = append(answer, &dns.SRV{
+					Hdr:      r.header(question.Name, dns.TypeSRV),
+					Priority: 0,
+					Weight:   1,
+					Port:     uint16(port),
+					Target:   target,
+				})
+			}
+			if len(instance.ports) > 0 {
+				extra = append(extra, r.addressRecord(target, instance.ip))
+			}
+		}
+	}
+
+	return answer, extra, true
+}
+
+// Refresh rebuilds the index from the internal entries of the routing table.
+// Container ports are taken from the http entries of the same instances, they
+// hold an entry for every port of a running instance even without routes.
+func (r *Resolver) Refresh() {
+	ports := map[routingtable.EndpointKey][]uint32{}
+	for key, entry := range r.table.HTTPEntries() {
+		for endpointKey := range entry.Endpoints {
+			ports[endpointKey] = append(ports[endpointKey], key.ContainerPort)
+		}
+	}
+	for _, endpointPorts := range ports {
+		sort.Slice(endpointPorts, func(i, j int) bool { return endpointPorts[i] < endpointPorts[j] })
+	}
+
+	names := map[string][]instance{}
+	for _, entry := range r.table.InternalEntries() {
+		for _, route := range entry.Routes {
+			internalRoute, ok := route.(routingtable.InternalRoute)
+			if !ok {
+				continue
+			}
+			hostname := dns.Fqdn(strings.ToLower(internalRoute.Hostname))
+
+			for endpointKey, endpoint := range entry.Endpoints {
+				ip := net.ParseIP(endpoint.ContainerIP)
+				if ip == nil || endpoint.InstanceGUID == "" {
+					continue
+				}
+				instance := instance{hostname: hostname, index: endpoint.Index, ip: ip, ports: ports[endpointKey]}
+				names[hostname] = appendInstance(names[hostname], instance)
+
+				indexName := fmt.Sprintf("%d.%s", endpoint.Index, hostname)
+				names[indexName] = appendInstance(names[indexName], instance)
+			}
+		}
+	}
+
+	for _, instances := range names {
+		sort.Slice(instances, func(i, j int) bool {
+			if instances[i].index != instances[j].index {
+				return instances[i].index < instances[j].index
+			}
+			return instances[i].ip.String() < instances[j].ip.String()
+		})
+	}
+
+	r.lock.Lock()
+	r.names = names
+	r.lock.Unlock()
+}
+
+func (r *Resolver) header(name string, rrtype uint16) dns.RR_Header {
+	return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: r.ttl}
+}
+
+func (r *Resolver) addressRecord(name string, ip net.IP) dns.RR {
+	if ipv4 := ip.To4(); ipv4 != nil {
+		return &dns.A{Hdr: r.header(name, dns.TypeA), A: ipv4}
+	}
+	return &dns.AAAA{Hdr: r.header(name, dns.TypeAAAA), AAAA: ip}
+}
+
+// appendInstance skips instances whose address is already known, an
+// evacuating instance and its replacement may share an index but not an
+// address.
+func appendInstance(instances []instance, newInstance instance) []instance {
+	for _, instance := range instances {
+		if instance.ip.Equal(newInstance.ip) {
+			return instances
+		}
+	}
+	return append(instances, newInstance)
+}
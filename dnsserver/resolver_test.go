@@ -0,0 +1,125 @@
+package dnsserver_test
+
+import (
+	"net"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/dnsserver"
+	metricsfakes "code.cloudfoundry.org/route-emitter/metrics/fakes"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/routing-info/internalroutes"
+	"github.com/miekg/dns"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Resolver", func() {
+	var (
+		table    routingtable.RoutingTable
+		resolver *dnsserver.Resolver
+		tag      models.ModificationTag
+	)
+
+	desire := func(processGuid string, instances int32, hostname string) {
+		routes := internalroutes.InternalRoutes{{Hostname: hostname}}.RoutingInfo()
+		schedulingInfo := models.NewDesiredLRPSchedulingInfo(
+			models.NewDesiredLRPKey(processGuid, "domain", "log-guid"),
+			"", instances, models.NewDesiredLRPResource(0, 0, 0, ""), routes, tag, nil, nil,
+		)
+		table.SetRoutes(nil, &schedulingInfo)
+	}
+
+	start := func(processGuid, instanceGuid string, index int32, containerIP string, ports ...*models.PortMapping) {
+		table.AddEndpoint(&routingtable.ActualLRPRoutingInfo{
+			ActualLRP: &models.ActualLRP{
+				ActualLRPKey:         models.NewActualLRPKey(processGuid, index, "domain"),
+				ActualLRPInstanceKey: models.NewActualLRPInstanceKey(instanceGuid, "cell-id"),
+				ActualLRPNetInfo:     models.NewActualLRPNetInfo("1.1.1.1", containerIP, ports...),
+				State:                models.ActualLRPStateRunning,
+				ModificationTag:      tag,
+			},
+		})
+	}
+
+	question := func(name string, qtype uint16) dns.Question {
+		return dns.Question{Name: name, Qtype: qtype, Qclass: dns.ClassINET}
+	}
+
+	BeforeEach(func() {
+		tag = models.ModificationTag{Epoch: "abc", Index: 1}
+		table = routingtable.NewRoutingTable(lagertest.NewTestLogger("test"), false, &metricsfakes.FakeSink{})
+		resolver = dnsserver.NewResolver(table, []string{"apps.internal"}, 5*time.Second)
+
+		desire("process-guid", 2, "app.apps.internal")
+		start("process-guid", "instance-guid-0", 0, "10.0.0.1", models.NewPortMapping(61000, 8080), models.NewPortMapping(61001, 9090))
+		start("process-guid", "instance-guid-1", 1, "fd00::2", models.NewPortMapping(61002, 8080))
+		resolver.Refresh()
+	})
+
+	It("is authoritative for names in its domains", func() {
+		Expect(resolver.Authoritative("app.apps.internal.")).To(BeTrue())
+		Expect(resolver.Authoritative("APPS.INTERNAL")).To(BeTrue())
+		Expect(resolver.Authoritative("app.example.com.")).To(BeFalse())
+	})
+
+	It("returns the addresses of every instance of a hostname", func() {
+		answer, extra, found := resolver.Resolve(question("app.apps.internal.", dns.TypeA))
+		Expect(found).To(BeTrue())
+		Expect(extra).To(BeEmpty())
+		Expect(answer).To(HaveLen(1))
+		Expect(answer[0].(*dns.A).A.Equal(net.ParseIP("10.0.0.1"))).To(BeTrue())
+		Expect(answer[0].Header().Ttl).To(BeEquivalentTo(5))
+
+		answer, _, found = resolver.Resolve(question("app.apps.internal.", dns.TypeAAAA))
+		Expect(found).To(BeTrue())
+		Expect(answer).To(HaveLen(1))
+		Expect(answer[0].(*dns.AAAA).AAAA.Equal(net.ParseIP("fd00::2"))).To(BeTrue())
+	})
+
+	It("returns the address of a single instance for its index", func() {
+		answer, _, found := resolver.Resolve(question("0.app.apps.internal.", dns.TypeA))
+		Expect(found).To(BeTrue())
+		Expect(answer).To(HaveLen(1))
+		Expect(answer[0].(*dns.A).A.Equal(net.ParseIP("10.0.0.1"))).To(BeTrue())
+
+		answer, _, found = resolver.Resolve(question("1.app.apps.internal.", dns.TypeA))
+		Expect(found).To(BeTrue())
+		Expect(answer).To(BeEmpty())
+	})
+
+	It("returns srv records with the container ports of every instance", func() {
+		answer, extra, found := resolver.Resolve(question("app.apps.internal.", dns.TypeSRV))
+		Expect(found).To(BeTrue())
+		Expect(answer).To(HaveLen(3))
+		Expect(answer[0].(*dns.SRV).Target).To(Equal("0.app.apps.internal."))
+		Expect(answer[0].(*dns.SRV).Port).To(BeEquivalentTo(8080))
+		Expect(answer[1].(*dns.SRV).Target).To(Equal("0.app.apps.internal."))
+		Expect(answer[1].(*dns.SRV).Port).To(BeEquivalentTo(9090))
+		Expect(answer[2].(*dns.SRV).Target).To(Equal("1.app.apps.internal."))
+		Expect(answer[2].(*dns.SRV).Port).To(BeEquivalentTo(8080))
+
+		Expect(extra).To(HaveLen(2))
+		Expect(extra[0].Header().Name).To(Equal("0.app.apps.internal."))
+		Expect(extra[1].Header().Name).To(Equal("1.app.apps.internal."))
+	})
+
+	It("does not find unknown names", func() {
+		_, _, found := resolver.Resolve(question("other.apps.internal.", dns.TypeA))
+		Expect(found).To(BeFalse())
+	})
+
+	It("only changes after a refresh", func() {
+		desire("other-process-guid", 1, "other.apps.internal")
+		start("other-process-guid", "other-instance-guid", 0, "10.0.0.3", models.NewPortMapping(61003, 8080))
+
+		_, _, found := resolver.Resolve(question("other.apps.internal.", dns.TypeA))
+		Expect(found).To(BeFalse())
+
+		resolver.Refresh()
+		_, _, found = resolver.Resolve(question("other.apps.internal.", dns.TypeA))
+		Expect(found).To(BeTrue())
+	})
+})
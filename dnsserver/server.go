@@ -0,0 +1,128 @@
+package dnsserver
+
+import (
+	"net"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	"github.com/miekg/dns"
+)
+
+type Server struct {
+	logger          lager.Logger
+	clock           clock.Clock
+	listenAddress   string
+	resolver        *Resolver
+	refreshInterval time.Duration
+}
+
+// NewServer returns a runner answering DNS queries over UDP and TCP on
+// listenAddress with the records of resolver, refreshed every
+// refreshInterval. Queries outside the domains of the resolver are refused.
+func NewServer(logger lager.Logger, clock clock.Clock, listenAddress string, resolver *Resolver, refreshInterval time.Duration) *Server {
+	return &Server{
+		logger:          logger.Session("dns-server"),
+		clock:           clock,
+		listenAddress:   listenAddress,
+		resolver:        resolver,
+		refreshInterval: refreshInterval,
+	}
+}
+
+func (s *Server) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	s.logger.Info("starting", lager.Data{"listen-address": s.listenAddress})
+
+	packetConn, err := net.ListenPacket("udp", s.listenAddress)
+	if err != nil {
+		s.logger.Error("failed-to-listen", err, lager.Data{"net": "udp"})
+		return err
+	}
+
+	listener, err := net.Listen("tcp", s.listenAddress)
+	if err != nil {
+		packetConn.Close()
+		s.logger.Error("failed-to-listen", err, lager.Data{"net": "tcp"})
+		return err
+	}
+
+	udpServer := &dns.Server{PacketConn: packetConn, Handler: s}
+	tcpServer := &dns.Server{Listener: listener, Handler: s}
+
+	errCh := make(chan error, 2)
+	go func() {
+		errCh <- udpServer.ActivateAndServe()
+	}()
+	go func() {
+		errCh <- tcpServer.ActivateAndServe()
+	}()
+
+	s.resolver.Refresh()
+	ticker := s.clock.NewTicker(s.refreshInterval)
+	defer ticker.Stop()
+
+	close(ready)
+	s.logger.Info("started")
+
+	for {
+		select {
+		case <-ticker.C():
+			s.resolver.Refresh()
+		case <-signals:
+			s.logger.Info("stopping")
+			udpServer.Shutdown()
+			tcpServer.Shutdown()
+			return nil
+		case err := <-errCh:
+			s.logger.Error("failed-to-serve", err)
+			udpServer.Shutdown()
+			tcpServer.Shutdown()
+			return err
+		}
+	}
+}
+
+func (s *Server) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	response := new(dns.Msg)
+	response.SetReply(req)
+
+	if len(req.Question) != 1 {
+		response.SetRcode(req, dns.RcodeFormatError)
+		s.writeMsg(w, req, response)
+		return
+	}
+
+	question := req.Question[0]
+	if question.Qclass != dns.ClassINET || !s.resolver.Authoritative(question.Name) {
+		response.SetRcode(req, dns.RcodeRefused)
+		s.writeMsg(w, req, response)
+		return
+	}
+
+	response.Authoritative = true
+	answer, extra, found := s.resolver.Resolve(question)
+	if !found {
+		response.SetRcode(req, dns.RcodeNameError)
+	}
+	response.Answer = answer
+	response.Extra = extra
+
+	s.writeMsg(w, req, response)
+}
+
+func (s *Server) writeMsg(w dns.ResponseWriter, req, response *dns.Msg) {
+	// oversized udp responses are truncated so that clients retry over tcp
+	if _, ok := w.RemoteAddr().(*net.UDPAddr); ok {
+		size := dns.MinMsgSize
+		if opt := req.IsEdns0(); opt != nil {
+			size = int(opt.UDPSize())
+		}
+		response.Truncate(size)
+	}
+
+	err := w.WriteMsg(response)
+	if err != nil {
+		s.logger.Error("failed-to-write-response", err)
+	}
+}
@@ -0,0 +1,111 @@
+package dnsserver_test
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/dnsserver"
+	metricsfakes "code.cloudfoundry.org/route-emitter/metrics/fakes"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/routing-info/internalroutes"
+	"github.com/miekg/dns"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		table         routingtable.RoutingTable
+		clock         *fakeclock.FakeClock
+		process       ifrit.Process
+		listenAddress string
+	)
+
+	addApp := func(processGuid, hostname, containerIP string) {
+		tag := models.ModificationTag{Epoch: "abc", Index: 1}
+		routes := internalroutes.InternalRoutes{{Hostname: hostname}}.RoutingInfo()
+		schedulingInfo := models.NewDesiredLRPSchedulingInfo(
+			models.NewDesiredLRPKey(processGuid, "domain", "log-guid"),
+			"", 1, models.NewDesiredLRPResource(0, 0, 0, ""), routes, tag, nil, nil,
+		)
+		table.SetRoutes(nil, &schedulingInfo)
+		table.AddEndpoint(&routingtable.ActualLRPRoutingInfo{
+			ActualLRP: &models.ActualLRP{
+				ActualLRPKey:         models.NewActualLRPKey(processGuid, 0, "domain"),
+				ActualLRPInstanceKey: models.NewActualLRPInstanceKey(processGuid+"-instance", "cell-id"),
+				ActualLRPNetInfo:     models.NewActualLRPNetInfo("1.1.1.1", containerIP, models.NewPortMapping(61000, 8080)),
+				State:                models.ActualLRPStateRunning,
+				ModificationTag:      tag,
+			},
+		})
+	}
+
+	query := func(network, name string, qtype uint16) *dns.Msg {
+		client := &dns.Client{Net: network}
+		req := new(dns.Msg)
+		req.SetQuestion(name, qtype)
+		response, _, err := client.Exchange(req, listenAddress)
+		Expect(err).NotTo(HaveOccurred())
+		return response
+	}
+
+	BeforeEach(func() {
+		logger := lagertest.NewTestLogger("test")
+		clock = fakeclock.NewFakeClock(time.Now())
+		table = routingtable.NewRoutingTable(logger, false, &metricsfakes.FakeSink{})
+		addApp("process-guid", "app.apps.internal", "10.0.0.1")
+
+		resolver := dnsserver.NewResolver(table, []string{"apps.internal"}, 0)
+		listenAddress = fmt.Sprintf("127.0.0.1:%d", 18500+GinkgoParallelNode())
+		process = ifrit.Invoke(dnsserver.NewServer(logger, clock, listenAddress, resolver, time.Second))
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	It("answers authoritatively over udp and tcp", func() {
+		for _, network := range []string{"udp", "tcp"} {
+			response := query(network, "app.apps.internal.", dns.TypeA)
+			Expect(response.Rcode).To(Equal(dns.RcodeSuccess))
+			Expect(response.Authoritative).To(BeTrue())
+			Expect(response.Answer).To(HaveLen(1))
+			Expect(response.Answer[0].(*dns.A).A.String()).To(Equal("10.0.0.1"))
+		}
+	})
+
+	It("answers srv queries", func() {
+		response := query("udp", "app.apps.internal.", dns.TypeSRV)
+		Expect(response.Answer).To(HaveLen(1))
+		Expect(response.Answer[0].(*dns.SRV).Port).To(BeEquivalentTo(8080))
+		Expect(response.Extra).To(HaveLen(1))
+	})
+
+	It("returns nxdomain for unknown names in its domains", func() {
+		response := query("udp", "unknown.apps.internal.", dns.TypeA)
+		Expect(response.Rcode).To(Equal(dns.RcodeNameError))
+		Expect(response.Authoritative).To(BeTrue())
+	})
+
+	It("refuses names outside its domains", func() {
+		response := query("udp", "example.com.", dns.TypeA)
+		Expect(response.Rcode).To(Equal(dns.RcodeRefused))
+	})
+
+	It("picks up routing table changes on every refresh", func() {
+		addApp("other-process-guid", "other.apps.internal", "10.0.0.2")
+		Expect(query("udp", "other.apps.internal.", dns.TypeA).Rcode).To(Equal(dns.RcodeNameError))
+
+		clock.WaitForWatcherAndIncrement(time.Second)
+		Eventually(func() int {
+			return query("udp", "other.apps.internal.", dns.TypeA).Rcode
+		}).Should(Equal(dns.RcodeSuccess))
+	})
+})
@@ -0,0 +1,148 @@
+package emitter
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"github.com/hashicorp/consul/api"
+)
+
+const (
+	ConsulInstanceIndexTag = "instance-index"
+	ConsulAppGUIDTag       = "app-guid"
+)
+
+type consulEmitter struct {
+	logger       lager.Logger
+	consulClient consuladapter.Client
+	ttl          time.Duration
+
+	lock       sync.Mutex
+	registered map[string]struct{}
+}
+
+// NewConsulEmitter returns an emitter registering internal routes as services
+// of the local Consul agent, the other messages are ignored. Every service
+// has a TTL check that is passed each time its registration is emitted again,
+// which happens on every internal broadcast.
+func NewConsulEmitter(logger lager.Logger, consulClient consuladapter.Client, ttl time.Duration) NATSEmitter {
+	return &consulEmitter{
+		logger:       logger.Session("consul-emitter"),
+		consulClient: consulClient,
+		ttl:          ttl,
+		registered:   map[string]struct{}{},
+	}
+}
+
+func (c *consulEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var finalErr error
+	for _, message := range messagesToEmit.InternalUnregistrationMessages {
+		err := c.deregister(message)
+		if err != nil {
+			finalErr = err
+		}
+	}
+
+	for _, message := range messagesToEmit.InternalRegistrationMessages {
+		err := c.register(message)
+		if err != nil {
+			finalErr = err
+		}
+	}
+
+	return finalErr
+}
+
+func (c *consulEmitter) register(message routingtable.RegistryMessage) error {
+	if len(message.URIs) == 0 {
+		return nil
+	}
+
+	agent := c.consulClient.Agent()
+	serviceID := consulServiceID(message)
+	checkID := "service:" + serviceID
+
+	if _, ok := c.registered[serviceID]; ok {
+		err := agent.PassTTL(checkID, "")
+		if err == nil {
+			return nil
+		}
+		// the agent may have lost the service, register it again
+		c.logger.Error("failed-to-pass-ttl", err, lager.Data{"service-id": serviceID})
+	}
+
+	registration := &api.AgentServiceRegistration{
+		ID:      serviceID,
+		Name:    consulServiceName(message.URIs[0]),
+		Tags:    consulServiceTags(message),
+		Address: message.Host,
+		Check: &api.AgentServiceCheck{
+			TTL:                            c.ttl.String(),
+			DeregisterCriticalServiceAfter: (3 * c.ttl).String(),
+		},
+	}
+
+	err := agent.ServiceRegister(registration)
+	if err != nil {
+		c.logger.Error("failed-to-register-service", err, lager.Data{"service-id": serviceID})
+		delete(c.registered, serviceID)
+		return err
+	}
+
+	// ttl checks start out critical
+	err = agent.PassTTL(checkID, "")
+	if err != nil {
+		c.logger.Error("failed-to-pass-ttl", err, lager.Data{"service-id": serviceID})
+		return err
+	}
+
+	c.registered[serviceID] = struct{}{}
+	return nil
+}
+
+func (c *consulEmitter) deregister(message routingtable.RegistryMessage) error {
+	if len(message.URIs) == 0 {
+		return nil
+	}
+
+	serviceID := consulServiceID(message)
+	delete(c.registered, serviceID)
+
+	err := c.consulClient.Agent().ServiceDeregister(serviceID)
+	if err != nil {
+		c.logger.Error("failed-to-deregister-service", err, lager.Data{"service-id": serviceID})
+		return err
+	}
+	return nil
+}
+
+// consulServiceID identifies an instance of an internal route, the first uri
+// of an internal registry message is the route's hostname.
+func consulServiceID(message routingtable.RegistryMessage) string {
+	return fmt.Sprintf("route-emitter:%s:%s", message.URIs[0], message.Host)
+}
+
+// consulServiceName turns hostname into a single dns label, Consul serves
+// services as <name>.service.<domain>.
+func consulServiceName(hostname string) string {
+	return strings.Replace(hostname, ".", "-", -1)
+}
+
+func consulServiceTags(message routingtable.RegistryMessage) []string {
+	tags := []string{}
+	if message.PrivateInstanceIndex != "" {
+		tags = append(tags, ConsulInstanceIndexTag+"="+message.PrivateInstanceIndex)
+	}
+	if message.App != "" {
+		tags = append(tags, ConsulAppGUIDTag+"="+message.App)
+	}
+	return tags
+}
@@ -0,0 +1,147 @@
+package emitter_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/consuladapter/fakes"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	emitterfakes "code.cloudfoundry.org/route-emitter/emitter/fakes"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"github.com/hashicorp/consul/api"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ConsulEmitter", func() {
+	var (
+		consulClient  *fakes.FakeClient
+		agent         *fakes.FakeAgent
+		consulEmitter emitter.NATSEmitter
+	)
+
+	registration := routingtable.RegistryMessage{
+		URIs:                 []string{"app.apps.internal", "1.app.apps.internal"},
+		Host:                 "10.0.0.1",
+		App:                  "some-app-guid",
+		PrivateInstanceIndex: "1",
+	}
+
+	BeforeEach(func() {
+		agent = &fakes.FakeAgent{}
+		consulClient = &fakes.FakeClient{}
+		consulClient.AgentReturns(agent)
+		consulEmitter = emitter.NewConsulEmitter(lagertest.NewTestLogger("test"), consulClient, time.Minute)
+	})
+
+	It("registers internal routes as services with a ttl check", func() {
+		err := consulEmitter.Emit(routingtable.MessagesToEmit{
+			InternalRegistrationMessages: []routingtable.RegistryMessage{registration},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(agent.ServiceRegisterCallCount()).To(Equal(1))
+		Expect(agent.ServiceRegisterArgsForCall(0)).To(Equal(&api.AgentServiceRegistration{
+			ID:      "route-emitter:app.apps.internal:10.0.0.1",
+			Name:    "app-apps-internal",
+			Tags:    []string{"instance-index=1", "app-guid=some-app-guid"},
+			Address: "10.0.0.1",
+			Check: &api.AgentServiceCheck{
+				TTL:                            "1m0s",
+				DeregisterCriticalServiceAfter: "3m0s",
+			},
+		}))
+
+		Expect(agent.PassTTLCallCount()).To(Equal(1))
+		checkID, _ := agent.PassTTLArgsForCall(0)
+		Expect(checkID).To(Equal("service:route-emitter:app.apps.internal:10.0.0.1"))
+	})
+
+	It("ignores external routes", func() {
+		err := consulEmitter.Emit(routingtable.MessagesToEmit{
+			RegistrationMessages:   []routingtable.RegistryMessage{registration},
+			UnregistrationMessages: []routingtable.RegistryMessage{registration},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(agent.ServiceRegisterCallCount()).To(Equal(0))
+		Expect(agent.ServiceDeregisterCallCount()).To(Equal(0))
+	})
+
+	Context("when the service is already registered", func() {
+		BeforeEach(func() {
+			err := consulEmitter.Emit(routingtable.MessagesToEmit{
+				InternalRegistrationMessages: []routingtable.RegistryMessage{registration},
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("only refreshes the ttl check", func() {
+			err := consulEmitter.Emit(routingtable.MessagesToEmit{
+				InternalRegistrationMessages: []routingtable.RegistryMessage{registration},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(agent.ServiceRegisterCallCount()).To(Equal(1))
+			Expect(agent.PassTTLCallCount()).To(Equal(2))
+		})
+
+		Context("and the ttl check cannot be passed", func() {
+			BeforeEach(func() {
+				agent.PassTTLReturnsOnCall(1, errors.New("unknown check"))
+			})
+
+			It("registers the service again", func() {
+				err := consulEmitter.Emit(routingtable.MessagesToEmit{
+					InternalRegistrationMessages: []routingtable.RegistryMessage{registration},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(agent.ServiceRegisterCallCount()).To(Equal(2))
+				Expect(agent.PassTTLCallCount()).To(Equal(3))
+			})
+		})
+
+		It("deregisters the service on unregistration", func() {
+			err := consulEmitter.Emit(routingtable.MessagesToEmit{
+				InternalUnregistrationMessages: []routingtable.RegistryMessage{registration},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(agent.ServiceDeregisterCallCount()).To(Equal(1))
+			Expect(agent.ServiceDeregisterArgsForCall(0)).To(Equal("route-emitter:app.apps.internal:10.0.0.1"))
+		})
+	})
+
+	Context("when registering fails", func() {
+		BeforeEach(func() {
+			agent.ServiceRegisterReturns(errors.New("boom"))
+		})
+
+		It("returns the error and registers again on the next emit", func() {
+			messagesToEmit := routingtable.MessagesToEmit{
+				InternalRegistrationMessages: []routingtable.RegistryMessage{registration},
+			}
+			Expect(consulEmitter.Emit(messagesToEmit)).To(MatchError("boom"))
+			Expect(consulEmitter.Emit(messagesToEmit)).To(MatchError("boom"))
+			Expect(agent.ServiceRegisterCallCount()).To(Equal(2))
+			Expect(agent.PassTTLCallCount()).To(Equal(0))
+		})
+	})
+})
+
+var _ = Describe("MultiNATSEmitter", func() {
+	It("emits to every emitter and returns the last error", func() {
+		first := &emitterfakes.FakeNATSEmitter{}
+		first.EmitReturns(errors.New("boom"))
+		second := &emitterfakes.FakeNATSEmitter{}
+		multiEmitter := emitter.NewMultiNATSEmitter(first, second)
+
+		messagesToEmit := routingtable.MessagesToEmit{
+			RegistrationMessages: []routingtable.RegistryMessage{{URIs: []string{"foo.com"}}},
+		}
+		Expect(multiEmitter.Emit(messagesToEmit)).To(MatchError("boom"))
+		Expect(first.EmitCallCount()).To(Equal(1))
+		Expect(first.EmitArgsForCall(0)).To(Equal(messagesToEmit))
+		Expect(second.EmitCallCount()).To(Equal(1))
+		Expect(second.EmitArgsForCall(0)).To(Equal(messagesToEmit))
+	})
+})
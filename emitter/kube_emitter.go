@@ -0,0 +1,366 @@
+package emitter
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	KubeManagedByLabel          = "app.kubernetes.io/managed-by"
+	KubeManager                 = "route-emitter"
+	KubeEndpointSliceManager    = "route-emitter.cloudfoundry.org"
+	KubeProcessGUIDAnnotation   = "route-emitter.cloudfoundry.org/process-guid"
+	KubeContainerPortAnnotation = "route-emitter.cloudfoundry.org/container-port"
+	KubeHostnamesAnnotation     = "route-emitter.cloudfoundry.org/hostnames"
+	KubePortName                = "http"
+)
+
+//go:generate counterfeiter -o fakes/fake_kube_object_writer.go . KubeObjectWriter
+
+// KubeObjectWriter stores the Service and EndpointSlices mirroring a routing
+// key. Apply replaces every EndpointSlice of the service, Delete removes the
+// service and its EndpointSlices and List returns the names of the services
+// written, by this or a previous route emitter.
+type KubeObjectWriter interface {
+	Apply(service *corev1.Service, endpointSlices []*discoveryv1.EndpointSlice) error
+	Delete(serviceName string) error
+	List() ([]string, error)
+}
+
+// KubeRoutingTable is the part of the routing table the Kubernetes emitter
+// reads the current state of a routing key from.
+type KubeRoutingTable interface {
+	HTTPEntriesForHostnames(hostnames []string) map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+}
+
+type kubeEmitter struct {
+	logger                lager.Logger
+	table                 KubeRoutingTable
+	writer                KubeObjectWriter
+	namespace             string
+	directInstanceAddress bool
+
+	lock           sync.Mutex
+	collected      bool // the objects of a previous run were cleaned up
+	applied        map[routingtable.RoutingKey]string
+	hostnamesByKey map[routingtable.RoutingKey][]string
+	keysByHostname map[string]map[routingtable.RoutingKey]struct{}
+}
+
+// NewKubeEmitter returns an emitter mirroring every http routing key into a
+// Service and EndpointSlices in namespace. The messages only tell which
+// hostnames changed, the routing keys serving them are read back from table
+// and only those whose objects changed are written.
+func NewKubeEmitter(logger lager.Logger, table KubeRoutingTable, writer KubeObjectWriter, namespace string, directInstanceAddress bool) BroadcastEmitter {
+	return &kubeEmitter{
+		logger:                logger.Session("kube-emitter"),
+		table:                 table,
+		writer:                writer,
+		namespace:             namespace,
+		directInstanceAddress: directInstanceAddress,
+		applied:               map[routingtable.RoutingKey]string{},
+		hostnamesByKey:        map[routingtable.RoutingKey][]string{},
+		keysByHostname:        map[string]map[routingtable.RoutingKey]struct{}{},
+	}
+}
+
+// KubeObjectName returns the name of the Service mirroring key. Process guids
+// are too long for a DNS label, so the name is derived from a hash.
+func KubeObjectName(key routingtable.RoutingKey) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s:%d", key.ProcessGUID, key.ContainerPort)
+	return fmt.Sprintf("diego-%016x", h.Sum64())
+}
+
+func (e *kubeEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	changed := map[string]struct{}{}
+	for _, messages := range [][]routingtable.RegistryMessage{messagesToEmit.RegistrationMessages, messagesToEmit.UnregistrationMessages} {
+		for _, message := range messages {
+			for _, uri := range message.URIs {
+				changed[uri] = struct{}{}
+			}
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	// keys that used to serve a changed hostname may still serve others, look
+	// them all up so that they are updated rather than deleted
+	keys := map[routingtable.RoutingKey]struct{}{}
+	hostnames := map[string]struct{}{}
+	for hostname := range changed {
+		hostnames[hostname] = struct{}{}
+		for key := range e.keysByHostname[hostname] {
+			keys[key] = struct{}{}
+			for _, previous := range e.hostnamesByKey[key] {
+				hostnames[previous] = struct{}{}
+			}
+		}
+	}
+
+	entries := e.table.HTTPEntriesForHostnames(setToSlice(hostnames))
+	for key := range entries {
+		keys[key] = struct{}{}
+	}
+
+	var finalErr error
+	for key := range keys {
+		err := e.sync(key, entries[key])
+		if err != nil {
+			finalErr = err
+		}
+	}
+	return finalErr
+}
+
+// EmitBroadcast emits messagesToEmit, every route of the table. The first
+// time, the objects of the routing keys no longer in the table are deleted:
+// the routes removed while no route emitter was running, or whose removal
+// failed, are never emitted again.
+func (e *kubeEmitter) EmitBroadcast(messagesToEmit routingtable.MessagesToEmit) error {
+	err := e.Emit(messagesToEmit)
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.collected {
+		return err
+	}
+
+	hostnames := map[string]struct{}{}
+	for _, message := range messagesToEmit.RegistrationMessages {
+		for _, uri := range message.URIs {
+			hostnames[uri] = struct{}{}
+		}
+	}
+	current := map[string]struct{}{}
+	for key, entry := range e.table.HTTPEntriesForHostnames(setToSlice(hostnames)) {
+		for _, route := range entry.Routes {
+			if _, ok := route.(routingtable.Route); ok {
+				current[KubeObjectName(key)] = struct{}{}
+				break
+			}
+		}
+	}
+
+	collectErr := e.deleteOrphans(current)
+	if collectErr != nil {
+		return collectErr
+	}
+	e.collected = true
+	return err
+}
+
+func (e *kubeEmitter) deleteOrphans(current map[string]struct{}) error {
+	logger := e.logger.Session("delete-orphans")
+
+	names, err := e.writer.List()
+	if err != nil {
+		logger.Error("failed-to-list-objects", err)
+		return err
+	}
+
+	keysByName := map[string]routingtable.RoutingKey{}
+	for key := range e.applied {
+		keysByName[KubeObjectName(key)] = key
+	}
+
+	var finalErr error
+	for _, name := range names {
+		if _, ok := current[name]; ok {
+			continue
+		}
+		err := e.writer.Delete(name)
+		if err != nil {
+			logger.Error("failed-to-delete-objects", err, lager.Data{"name": name})
+			finalErr = err
+			continue
+		}
+		if key, ok := keysByName[name]; ok {
+			e.forget(key)
+		}
+		logger.Info("deleted-objects", lager.Data{"name": name})
+	}
+	return finalErr
+}
+
+func (e *kubeEmitter) sync(key routingtable.RoutingKey, entry routingtable.RoutableEndpoints) error {
+	name := KubeObjectName(key)
+	logger := e.logger.Session("sync", lager.Data{"process-guid": key.ProcessGUID, "container-port": key.ContainerPort, "name": name})
+
+	hostnames := []string{}
+	for _, route := range entry.Routes {
+		if httpRoute, ok := route.(routingtable.Route); ok {
+			hostnames = append(hostnames, httpRoute.Hostname)
+		}
+	}
+	sort.Strings(hostnames)
+
+	if len(hostnames) == 0 {
+		if _, ok := e.applied[key]; !ok {
+			return nil
+		}
+		err := e.writer.Delete(name)
+		if err != nil {
+			logger.Error("failed-to-delete-objects", err)
+			return err
+		}
+		e.forget(key)
+		logger.Info("deleted-objects")
+		return nil
+	}
+
+	service, endpointSlices := e.objects(key, name, hostnames, entry)
+	fingerprint, err := json.Marshal(struct {
+		Service        *corev1.Service
+		EndpointSlices []*discoveryv1.EndpointSlice
+	}{service, endpointSlices})
+	if err != nil {
+		return err
+	}
+	if e.applied[key] == string(fingerprint) {
+		return nil
+	}
+
+	err = e.writer.Apply(service, endpointSlices)
+	if err != nil {
+		logger.Error("failed-to-apply-objects", err)
+		return err
+	}
+
+	e.forget(key)
+	e.applied[key] = string(fingerprint)
+	e.hostnamesByKey[key] = hostnames
+	for _, hostname := range hostnames {
+		if e.keysByHostname[hostname] == nil {
+			e.keysByHostname[hostname] = map[routingtable.RoutingKey]struct{}{}
+		}
+		e.keysByHostname[hostname][key] = struct{}{}
+	}
+	logger.Debug("applied-objects", lager.Data{"endpoint-slices": len(endpointSlices)})
+	return nil
+}
+
+func (e *kubeEmitter) forget(key routingtable.RoutingKey) {
+	for _, hostname := range e.hostnamesByKey[key] {
+		delete(e.keysByHostname[hostname], key)
+		if len(e.keysByHostname[hostname]) == 0 {
+			delete(e.keysByHostname, hostname)
+		}
+	}
+	delete(e.hostnamesByKey, key)
+	delete(e.applied, key)
+}
+
+type kubeSliceKey struct {
+	addressType discoveryv1.AddressType
+	port        uint32
+}
+
+// objects builds the Service and EndpointSlices of key. All endpoints of an
+// EndpointSlice share its ports and address type, so there is one slice per
+// address type and port: host ports differ from instance to instance.
+func (e *kubeEmitter) objects(key routingtable.RoutingKey, name string, hostnames []string, entry routingtable.RoutableEndpoints) (*corev1.Service, []*discoveryv1.EndpointSlice) {
+	service := &corev1.Service{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: e.objectMeta(name, key, hostnames),
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{
+				Name:     KubePortName,
+				Protocol: corev1.ProtocolTCP,
+				Port:     int32(key.ContainerPort),
+			}},
+		},
+	}
+
+	endpoints := map[kubeSliceKey][]discoveryv1.Endpoint{}
+	for _, endpoint := range entry.Endpoints {
+		host, port := endpoint.Host, endpoint.Port
+		if e.directInstanceAddress {
+			host, port = endpoint.ContainerIP, endpoint.ContainerPort
+		}
+
+		ip := net.ParseIP(host)
+		if ip == nil {
+			continue
+		}
+		sliceKey := kubeSliceKey{addressType: discoveryv1.AddressTypeIPv4, port: port}
+		if ip.To4() == nil {
+			sliceKey.addressType = discoveryv1.AddressTypeIPv6
+		}
+
+		// evacuating instances keep serving until their replacement is routed
+		ready, serving, terminating := !endpoint.Evacuating, true, endpoint.Evacuating
+		endpoints[sliceKey] = append(endpoints[sliceKey], discoveryv1.Endpoint{
+			Addresses: []string{ip.String()},
+			Conditions: discoveryv1.EndpointConditions{
+				Ready:       &ready,
+				Serving:     &serving,
+				Terminating: &terminating,
+			},
+		})
+	}
+
+	endpointSlices := make([]*discoveryv1.EndpointSlice, 0, len(endpoints))
+	for sliceKey, sliceEndpoints := range endpoints {
+		sort.Slice(sliceEndpoints, func(i, j int) bool { return sliceEndpoints[i].Addresses[0] < sliceEndpoints[j].Addresses[0] })
+
+		sliceName := fmt.Sprintf("%s-%d", name, sliceKey.port)
+		if sliceKey.addressType == discoveryv1.AddressTypeIPv6 {
+			sliceName += "-v6"
+		}
+		objectMeta := e.objectMeta(sliceName, key, hostnames)
+		objectMeta.Labels[discoveryv1.LabelServiceName] = name
+		objectMeta.Labels[discoveryv1.LabelManagedBy] = KubeEndpointSliceManager
+
+		portName, protocol, port := KubePortName, corev1.ProtocolTCP, int32(sliceKey.port)
+		endpointSlices = append(endpointSlices, &discoveryv1.EndpointSlice{
+			TypeMeta:    metav1.TypeMeta{APIVersion: "discovery.k8s.io/v1", Kind: "EndpointSlice"},
+			ObjectMeta:  objectMeta,
+			AddressType: sliceKey.addressType,
+			Endpoints:   sliceEndpoints,
+			Ports:       []discoveryv1.EndpointPort{{Name: &portName, Protocol: &protocol, Port: &port}},
+		})
+	}
+	sort.Slice(endpointSlices, func(i, j int) bool { return endpointSlices[i].Name < endpointSlices[j].Name })
+
+	return service, endpointSlices
+}
+
+func (e *kubeEmitter) objectMeta(name string, key routingtable.RoutingKey, hostnames []string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: e.namespace,
+		Labels:    map[string]string{KubeManagedByLabel: KubeManager},
+		Annotations: map[string]string{
+			KubeProcessGUIDAnnotation:   key.ProcessGUID,
+			KubeContainerPortAnnotation: strconv.FormatUint(uint64(key.ContainerPort), 10),
+			KubeHostnamesAnnotation:     strings.Join(hostnames, ","),
+		},
+	}
+}
+
+func setToSlice(set map[string]struct{}) []string {
+	slice := make([]string, 0, len(set))
+	for value := range set {
+		slice = append(slice, value)
+	}
+	sort.Strings(slice)
+	return slice
+}
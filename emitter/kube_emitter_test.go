@@ -0,0 +1,299 @@
+package emitter_test
+
+import (
+	"context"
+	"errors"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/cfroutes"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	emitterfakes "code.cloudfoundry.org/route-emitter/emitter/fakes"
+	metricsfakes "code.cloudfoundry.org/route-emitter/metrics/fakes"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("KubeEmitter", func() {
+	var (
+		table       routingtable.RoutingTable
+		client      *fake.Clientset
+		kubeEmitter emitter.BroadcastEmitter
+		tag         models.ModificationTag
+		key         routingtable.RoutingKey
+		name        string
+	)
+
+	desire := func(hostnames ...string) routingtable.MessagesToEmit {
+		routes := cfroutes.CFRoutes{{Hostnames: hostnames, Port: 8080}}.RoutingInfo()
+		schedulingInfo := models.NewDesiredLRPSchedulingInfo(
+			models.NewDesiredLRPKey("process-guid", "domain", "log-guid"),
+			"", 2, models.NewDesiredLRPResource(0, 0, 0, ""), routes, tag, nil, nil,
+		)
+		tag.Increment()
+		_, messages := table.SetRoutes(nil, &schedulingInfo)
+		return messages
+	}
+
+	start := func(instanceGuid string, index int32, host string, hostPort uint32) routingtable.MessagesToEmit {
+		_, messages := table.AddEndpoint(&routingtable.ActualLRPRoutingInfo{
+			ActualLRP: &models.ActualLRP{
+				ActualLRPKey:         models.NewActualLRPKey("process-guid", index, "domain"),
+				ActualLRPInstanceKey: models.NewActualLRPInstanceKey(instanceGuid, "cell-id"),
+				ActualLRPNetInfo:     models.NewActualLRPNetInfo(host, "10.255.0.1", models.NewPortMapping(hostPort, 8080)),
+				State:                models.ActualLRPStateRunning,
+				ModificationTag:      tag,
+			},
+		})
+		return messages
+	}
+
+	getService := func() (*corev1.Service, error) {
+		return client.CoreV1().Services("cf").Get(context.Background(), name, metav1.GetOptions{})
+	}
+
+	listEndpointSlices := func() []discoveryv1.EndpointSlice {
+		list, err := client.DiscoveryV1().EndpointSlices("cf").List(context.Background(), metav1.ListOptions{
+			LabelSelector: discoveryv1.LabelServiceName + "=" + name,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return list.Items
+	}
+
+	BeforeEach(func() {
+		logger := lagertest.NewTestLogger("test")
+		tag = models.ModificationTag{Epoch: "abc", Index: 1}
+		table = routingtable.NewRoutingTable(logger, false, &metricsfakes.FakeSink{})
+		client = fake.NewSimpleClientset()
+		kubeEmitter = emitter.NewKubeEmitter(logger, table, emitter.NewKubeAPIWriter(client, "cf"), "cf", false)
+
+		key = routingtable.RoutingKey{ProcessGUID: "process-guid", ContainerPort: 8080}
+		name = emitter.KubeObjectName(key)
+	})
+
+	It("derives a dns label from the routing key", func() {
+		Expect(name).To(MatchRegexp(`^diego-[0-9a-f]{16}$`))
+		Expect(emitter.KubeObjectName(routingtable.RoutingKey{ProcessGUID: "process-guid", ContainerPort: 9090})).NotTo(Equal(name))
+	})
+
+	Context("when a routing key gets routes and endpoints", func() {
+		BeforeEach(func() {
+			Expect(kubeEmitter.Emit(desire("foo.example.com", "bar.example.com"))).To(Succeed())
+			Expect(kubeEmitter.Emit(start("instance-guid-0", 0, "1.1.1.1", 61000))).To(Succeed())
+			Expect(kubeEmitter.Emit(start("instance-guid-1", 1, "2.2.2.2", 61001))).To(Succeed())
+		})
+
+		It("creates a service annotated with the hostnames", func() {
+			service, err := getService()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(service.Labels).To(HaveKeyWithValue(emitter.KubeManagedByLabel, emitter.KubeManager))
+			Expect(service.Annotations).To(Equal(map[string]string{
+				emitter.KubeProcessGUIDAnnotation:   "process-guid",
+				emitter.KubeContainerPortAnnotation: "8080",
+				emitter.KubeHostnamesAnnotation:     "bar.example.com,foo.example.com",
+			}))
+			Expect(service.Spec.Selector).To(BeEmpty())
+			Expect(service.Spec.Ports).To(HaveLen(1))
+			Expect(service.Spec.Ports[0].Port).To(BeEquivalentTo(8080))
+		})
+
+		It("creates an endpoint slice per host port", func() {
+			endpointSlices := listEndpointSlices()
+			Expect(endpointSlices).To(HaveLen(2))
+
+			addresses := map[int32][]string{}
+			for _, endpointSlice := range endpointSlices {
+				Expect(endpointSlice.AddressType).To(Equal(discoveryv1.AddressTypeIPv4))
+				Expect(endpointSlice.Labels).To(HaveKeyWithValue(discoveryv1.LabelManagedBy, emitter.KubeEndpointSliceManager))
+				Expect(endpointSlice.Ports).To(HaveLen(1))
+				Expect(*endpointSlice.Ports[0].Name).To(Equal(emitter.KubePortName))
+				for _, endpoint := range endpointSlice.Endpoints {
+					Expect(*endpoint.Conditions.Ready).To(BeTrue())
+					addresses[*endpointSlice.Ports[0].Port] = append(addresses[*endpointSlice.Ports[0].Port], endpoint.Addresses...)
+				}
+			}
+			Expect(addresses).To(Equal(map[int32][]string{
+				61000: {"1.1.1.1"},
+				61001: {"2.2.2.2"},
+			}))
+		})
+
+		It("does not write unchanged objects again", func() {
+			writes := len(client.Actions())
+
+			_, messages := table.GetExternalRoutingEvents()
+			Expect(kubeEmitter.Emit(messages)).To(Succeed())
+			Expect(client.Actions()).To(HaveLen(writes))
+		})
+
+		Context("when an endpoint goes away", func() {
+			BeforeEach(func() {
+				_, messages := table.RemoveEndpoint(&routingtable.ActualLRPRoutingInfo{
+					ActualLRP: &models.ActualLRP{
+						ActualLRPKey:         models.NewActualLRPKey("process-guid", 1, "domain"),
+						ActualLRPInstanceKey: models.NewActualLRPInstanceKey("instance-guid-1", "cell-id"),
+						ActualLRPNetInfo:     models.NewActualLRPNetInfo("2.2.2.2", "10.255.0.1", models.NewPortMapping(61001, 8080)),
+						State:                models.ActualLRPStateRunning,
+						ModificationTag:      tag,
+					},
+				})
+				Expect(kubeEmitter.Emit(messages)).To(Succeed())
+			})
+
+			It("deletes its endpoint slice", func() {
+				endpointSlices := listEndpointSlices()
+				Expect(endpointSlices).To(HaveLen(1))
+				Expect(endpointSlices[0].Endpoints[0].Addresses).To(Equal([]string{"1.1.1.1"}))
+			})
+		})
+
+		Context("when a hostname is removed", func() {
+			BeforeEach(func() {
+				Expect(kubeEmitter.Emit(desire("foo.example.com"))).To(Succeed())
+			})
+
+			It("updates the annotations", func() {
+				service, err := getService()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(service.Annotations).To(HaveKeyWithValue(emitter.KubeHostnamesAnnotation, "foo.example.com"))
+			})
+		})
+
+		Context("when every route is removed", func() {
+			BeforeEach(func() {
+				Expect(kubeEmitter.Emit(desire())).To(Succeed())
+			})
+
+			It("deletes the service and its endpoint slices", func() {
+				_, err := getService()
+				Expect(err).To(HaveOccurred())
+				Expect(listEndpointSlices()).To(BeEmpty())
+			})
+		})
+	})
+
+	Context("when registering direct instance addresses", func() {
+		BeforeEach(func() {
+			kubeEmitter = emitter.NewKubeEmitter(lagertest.NewTestLogger("test"), table, emitter.NewKubeAPIWriter(client, "cf"), "cf", true)
+			Expect(kubeEmitter.Emit(desire("foo.example.com"))).To(Succeed())
+			Expect(kubeEmitter.Emit(start("instance-guid-0", 0, "1.1.1.1", 61000))).To(Succeed())
+		})
+
+		It("uses the container address", func() {
+			endpointSlices := listEndpointSlices()
+			Expect(endpointSlices).To(HaveLen(1))
+			Expect(endpointSlices[0].Endpoints[0].Addresses).To(Equal([]string{"10.255.0.1"}))
+			Expect(*endpointSlices[0].Ports[0].Port).To(BeEquivalentTo(8080))
+		})
+	})
+
+	Context("when writing fails", func() {
+		var writer *emitterfakes.FakeKubeObjectWriter
+
+		BeforeEach(func() {
+			writer = &emitterfakes.FakeKubeObjectWriter{}
+			writer.ApplyReturns(errors.New("boom"))
+			kubeEmitter = emitter.NewKubeEmitter(lagertest.NewTestLogger("test"), table, writer, "cf", false)
+		})
+
+		It("returns the error and writes again on the next emit", func() {
+			messages := desire("foo.example.com")
+			Expect(kubeEmitter.Emit(messages)).To(MatchError("boom"))
+			Expect(kubeEmitter.Emit(messages)).To(MatchError("boom"))
+			Expect(writer.ApplyCallCount()).To(Equal(2))
+		})
+	})
+
+	Describe("EmitBroadcast", func() {
+		var orphan string
+
+		createService := func(serviceName string, labels map[string]string) {
+			_, err := client.CoreV1().Services("cf").Create(context.Background(), &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: serviceName, Namespace: "cf", Labels: labels},
+			}, metav1.CreateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		broadcast := func() error {
+			_, messages := table.GetExternalRoutingEvents()
+			return kubeEmitter.EmitBroadcast(messages)
+		}
+
+		BeforeEach(func() {
+			orphan = emitter.KubeObjectName(routingtable.RoutingKey{ProcessGUID: "gone-process-guid", ContainerPort: 8080})
+			createService(orphan, map[string]string{emitter.KubeManagedByLabel: emitter.KubeManager})
+			_, err := client.DiscoveryV1().EndpointSlices("cf").Create(context.Background(), &discoveryv1.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      orphan + "-61000",
+					Namespace: "cf",
+					Labels:    map[string]string{discoveryv1.LabelServiceName: orphan},
+				},
+				AddressType: discoveryv1.AddressTypeIPv4,
+			}, metav1.CreateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			createService("someone-elses", nil)
+
+			desire("foo.example.com")
+			start("instance-guid-0", 0, "1.1.1.1", 61000)
+		})
+
+		It("deletes the objects of the routing keys no longer in the table", func() {
+			Expect(broadcast()).To(Succeed())
+
+			_, err := client.CoreV1().Services("cf").Get(context.Background(), orphan, metav1.GetOptions{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			list, err := client.DiscoveryV1().EndpointSlices("cf").List(context.Background(), metav1.ListOptions{
+				LabelSelector: discoveryv1.LabelServiceName + "=" + orphan,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(list.Items).To(BeEmpty())
+
+			_, err = getService()
+			Expect(err).NotTo(HaveOccurred())
+			_, err = client.CoreV1().Services("cf").Get(context.Background(), "someone-elses", metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("only deletes them after the first broadcast", func() {
+			Expect(broadcast()).To(Succeed())
+
+			createService(orphan, map[string]string{emitter.KubeManagedByLabel: emitter.KubeManager})
+			Expect(broadcast()).To(Succeed())
+
+			_, err := client.CoreV1().Services("cf").Get(context.Background(), orphan, metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when listing fails", func() {
+			var writer *emitterfakes.FakeKubeObjectWriter
+
+			BeforeEach(func() {
+				writer = &emitterfakes.FakeKubeObjectWriter{}
+				writer.ListReturnsOnCall(0, nil, errors.New("boom"))
+				writer.ListReturnsOnCall(1, []string{orphan}, nil)
+				kubeEmitter = emitter.NewKubeEmitter(lagertest.NewTestLogger("test"), table, writer, "cf", false)
+			})
+
+			It("tries again on the next broadcast", func() {
+				Expect(broadcast()).To(MatchError("boom"))
+				Expect(broadcast()).To(Succeed())
+				Expect(writer.DeleteCallCount()).To(Equal(1))
+				Expect(writer.DeleteArgsForCall(0)).To(Equal(orphan))
+			})
+		})
+	})
+
+	It("ignores internal routes", func() {
+		Expect(kubeEmitter.Emit(routingtable.MessagesToEmit{
+			InternalRegistrationMessages: []routingtable.RegistryMessage{{URIs: []string{"app.apps.internal"}}},
+		})).To(Succeed())
+		Expect(client.Actions()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,206 @@
+package emitter
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type kubeAPIWriter struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+// NewKubeAPIWriter returns a writer creating, updating and deleting the
+// objects in namespace through the Kubernetes API.
+func NewKubeAPIWriter(client kubernetes.Interface, namespace string) KubeObjectWriter {
+	return &kubeAPIWriter{
+		client:    client,
+		namespace: namespace,
+	}
+}
+
+func (w *kubeAPIWriter) Apply(service *corev1.Service, endpointSlices []*discoveryv1.EndpointSlice) error {
+	ctx := context.Background()
+
+	services := w.client.CoreV1().Services(w.namespace)
+	existing, err := services.Get(ctx, service.Name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		_, err = services.Create(ctx, service, metav1.CreateOptions{})
+	case err == nil:
+		// the cluster ip is allocated on creation and cannot be changed
+		service.ResourceVersion = existing.ResourceVersion
+		service.Spec.ClusterIP = existing.Spec.ClusterIP
+		service.Spec.ClusterIPs = existing.Spec.ClusterIPs
+		_, err = services.Update(ctx, service, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	slices := w.client.DiscoveryV1().EndpointSlices(w.namespace)
+	existingSlices, err := w.listEndpointSlices(ctx, service.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, endpointSlice := range endpointSlices {
+		existing, ok := existingSlices[endpointSlice.Name]
+		if ok {
+			endpointSlice.ResourceVersion = existing.ResourceVersion
+			_, err = slices.Update(ctx, endpointSlice, metav1.UpdateOptions{})
+		} else {
+			_, err = slices.Create(ctx, endpointSlice, metav1.CreateOptions{})
+		}
+		if err != nil {
+			return err
+		}
+		delete(existingSlices, endpointSlice.Name)
+	}
+
+	for name := range existingSlices {
+		err = slices.Delete(ctx, name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *kubeAPIWriter) Delete(serviceName string) error {
+	ctx := context.Background()
+
+	existingSlices, err := w.listEndpointSlices(ctx, serviceName)
+	if err != nil {
+		return err
+	}
+	for name := range existingSlices {
+		err = w.client.DiscoveryV1().EndpointSlices(w.namespace).Delete(ctx, name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	err = w.client.CoreV1().Services(w.namespace).Delete(ctx, serviceName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (w *kubeAPIWriter) List() ([]string, error) {
+	list, err := w.client.CoreV1().Services(w.namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: KubeManagedByLabel + "=" + KubeManager,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(list.Items))
+	for _, service := range list.Items {
+		names = append(names, service.Name)
+	}
+	return names, nil
+}
+
+func (w *kubeAPIWriter) listEndpointSlices(ctx context.Context, serviceName string) (map[string]discoveryv1.EndpointSlice, error) {
+	list, err := w.client.DiscoveryV1().EndpointSlices(w.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: discoveryv1.LabelServiceName + "=" + serviceName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	endpointSlices := make(map[string]discoveryv1.EndpointSlice, len(list.Items))
+	for _, endpointSlice := range list.Items {
+		endpointSlices[endpointSlice.Name] = endpointSlice
+	}
+	return endpointSlices, nil
+}
+
+// KubeManifest is the content of the files written by the file writer, a
+// list that kubectl apply accepts.
+type KubeManifest struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Items      []interface{} `json:"items"`
+}
+
+type kubeFileWriter struct {
+	dir string
+}
+
+// NewKubeFileWriter returns a writer keeping the objects of every service in
+// <dir>/<service name>.json instead of a cluster, for testing offline.
+func NewKubeFileWriter(dir string) KubeObjectWriter {
+	return &kubeFileWriter{dir: dir}
+}
+
+func (w *kubeFileWriter) Apply(service *corev1.Service, endpointSlices []*discoveryv1.EndpointSlice) error {
+	manifest := KubeManifest{
+		APIVersion: "v1",
+		Kind:       "List",
+		Items:      []interface{}{service},
+	}
+	for _, endpointSlice := range endpointSlices {
+		manifest.Items = append(manifest.Items, endpointSlice)
+	}
+
+	path := w.path(service.Name)
+	tmpFile, err := ioutil.TempFile(w.dir, "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	encoder := json.NewEncoder(tmpFile)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(manifest)
+	if err != nil {
+		tmpFile.Close()
+		return err
+	}
+
+	err = tmpFile.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), path)
+}
+
+func (w *kubeFileWriter) Delete(serviceName string) error {
+	err := os.Remove(w.path(serviceName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (w *kubeFileWriter) List() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(w.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(paths))
+	for _, path := range paths {
+		names = append(names, strings.TrimSuffix(filepath.Base(path), ".json"))
+	}
+	return names, nil
+}
+
+func (w *kubeFileWriter) path(serviceName string) string {
+	return filepath.Join(w.dir, serviceName+".json")
+}
@@ -0,0 +1,74 @@
+package emitter_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/route-emitter/emitter"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("KubeFileWriter", func() {
+	var (
+		dir    string
+		writer emitter.KubeObjectWriter
+	)
+
+	service := &corev1.Service{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{Name: "diego-service", Namespace: "cf"},
+	}
+	endpointSlice := &discoveryv1.EndpointSlice{
+		TypeMeta:    metav1.TypeMeta{APIVersion: "discovery.k8s.io/v1", Kind: "EndpointSlice"},
+		ObjectMeta:  metav1.ObjectMeta{Name: "diego-service-61000", Namespace: "cf"},
+		AddressType: discoveryv1.AddressTypeIPv4,
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "kube-file-writer")
+		Expect(err).NotTo(HaveOccurred())
+		writer = emitter.NewKubeFileWriter(dir)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("writes the objects of a service as a list", func() {
+		Expect(writer.Apply(service, []*discoveryv1.EndpointSlice{endpointSlice})).To(Succeed())
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, "diego-service.json"))
+		Expect(err).NotTo(HaveOccurred())
+
+		var manifest struct {
+			Kind  string
+			Items []metav1.PartialObjectMetadata
+		}
+		Expect(json.Unmarshal(data, &manifest)).To(Succeed())
+		Expect(manifest.Kind).To(Equal("List"))
+		Expect(manifest.Items).To(HaveLen(2))
+		Expect(manifest.Items[0].Kind).To(Equal("Service"))
+		Expect(manifest.Items[1].Kind).To(Equal("EndpointSlice"))
+		Expect(manifest.Items[1].Name).To(Equal("diego-service-61000"))
+
+		files, err := ioutil.ReadDir(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+	})
+
+	It("removes the file on delete", func() {
+		Expect(writer.Apply(service, nil)).To(Succeed())
+		Expect(writer.Delete("diego-service")).To(Succeed())
+		Expect(filepath.Join(dir, "diego-service.json")).NotTo(BeAnExistingFile())
+
+		Expect(writer.Delete("diego-service")).To(Succeed())
+	})
+})
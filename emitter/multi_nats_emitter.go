@@ -0,0 +1,43 @@
+package emitter
+
+import "code.cloudfoundry.org/route-emitter/routingtable"
+
+type multiNATSEmitter struct {
+	emitters []NATSEmitter
+}
+
+// NewMultiNATSEmitter returns an emitter handing every message to all of
+// emitters. They are all called even if one fails, the last error is
+// returned.
+func NewMultiNATSEmitter(emitters ...NATSEmitter) BroadcastEmitter {
+	return &multiNATSEmitter{emitters: emitters}
+}
+
+func (m *multiNATSEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
+	var finalErr error
+	for _, emitter := range m.emitters {
+		err := emitter.Emit(messagesToEmit)
+		if err != nil {
+			finalErr = err
+		}
+	}
+	return finalErr
+}
+
+// EmitBroadcast hands messagesToEmit to EmitBroadcast of the broadcast
+// emitters and to Emit of the others.
+func (m *multiNATSEmitter) EmitBroadcast(messagesToEmit routingtable.MessagesToEmit) error {
+	var finalErr error
+	for _, emitter := range m.emitters {
+		var err error
+		if broadcaster, ok := emitter.(BroadcastEmitter); ok {
+			err = broadcaster.EmitBroadcast(messagesToEmit)
+		} else {
+			err = emitter.Emit(messagesToEmit)
+		}
+		if err != nil {
+			finalErr = err
+		}
+	}
+	return finalErr
+}
@@ -2,19 +2,30 @@ package emitter
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 	"sync"
 
-	loggingclient "code.cloudfoundry.org/diego-logging-client"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/route-emitter/diegonats"
+	"code.cloudfoundry.org/route-emitter/metrics"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/workpool"
 )
 
+const (
+	RouterRegisterSubject             = "router.register"
+	RouterRegisterBatchSubject        = "router.register.batch"
+	RouterUnregisterSubject           = "router.unregister"
+	ServiceDiscoveryRegisterSubject   = "service-discovery.register"
+	ServiceDiscoveryUnregisterSubject = "service-discovery.unregister"
+)
+
 const (
 	messagesEmittedCounter                  = "MessagesEmitted"
 	httpRouteNATSMessagesEmittedCounter     = "HTTPRouteNATSMessagesEmitted"
 	internalRouteNATSMessagesEmittedCounter = "InternalRouteNATSMessagesEmitted"
+	natsMessagesPublishedCounter            = "NATSMessagesPublished"
 )
 
 //go:generate counterfeiter -o fakes/fake_nats_emitter.go . NATSEmitter
@@ -22,35 +33,82 @@ type NATSEmitter interface {
 	Emit(messagesToEmit routingtable.MessagesToEmit) error
 }
 
+// WorkPoolSetter is implemented by NATS emitters that publish through a work
+// pool, it allows the pool to be resized while the emitter is running.
+type WorkPoolSetter interface {
+	SetWorkPool(workPool *workpool.WorkPool)
+}
+
+// BroadcastEmitter is implemented by NATS emitters that keep state outside of
+// the routing table. EmitBroadcast is given every route of a synced table.
+type BroadcastEmitter interface {
+	NATSEmitter
+	EmitBroadcast(messagesToEmit routingtable.MessagesToEmit) error
+}
+
 type natsEmitter struct {
-	natsClient         diegonats.NATSClient
-	workPool           *workpool.WorkPool
+	natsClient diegonats.NATSClient
+
+	// workPoolLock is held for reading for the duration of an Emit so that a
+	// replaced pool is only stopped once no more work is submitted to it
+	workPoolLock sync.RWMutex
+	workPool     *workpool.WorkPool
+
 	logger             lager.Logger
-	metronClient       loggingclient.IngressClient
+	metricsSink        metrics.Sink
+	retryQueue         *RetryQueue
+	batcher            *RegistrationBatcher
 	emitInternalRoutes bool
 }
 
-func NewNATSEmitter(natsClient diegonats.NATSClient, workPool *workpool.WorkPool, logger lager.Logger, metronClient loggingclient.IngressClient, emitInternalRoutes bool) NATSEmitter {
+// NewNATSEmitter returns an emitter publishing through workPool. Messages that
+// fail to publish are retried per URI through retryQueue, which may be nil.
+// Router registrations are published in batches while batcher, which may also
+// be nil, reports that every router supports them.
+func NewNATSEmitter(natsClient diegonats.NATSClient, workPool *workpool.WorkPool, logger lager.Logger, metricsSink metrics.Sink, retryQueue *RetryQueue, batcher *RegistrationBatcher, emitInternalRoutes bool) NATSEmitter {
 	return &natsEmitter{
 		natsClient:         natsClient,
 		workPool:           workPool,
 		logger:             logger.Session("nats-emitter"),
-		metronClient:       metronClient,
+		metricsSink:        metricsSink,
+		retryQueue:         retryQueue,
+		batcher:            batcher,
 		emitInternalRoutes: emitInternalRoutes,
 	}
 }
 
+func (n *natsEmitter) SetWorkPool(workPool *workpool.WorkPool) {
+	n.workPoolLock.Lock()
+	previous := n.workPool
+	n.workPool = workPool
+	n.workPoolLock.Unlock()
+
+	previous.Stop()
+}
+
 func (n *natsEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
+	n.workPoolLock.RLock()
+	defer n.workPoolLock.RUnlock()
+
 	errors := make(chan error, 1)
+	outcomes := newPublishOutcomes()
 	var wg sync.WaitGroup
-	wg.Add(len(messagesToEmit.RegistrationMessages))
-	for _, message := range messagesToEmit.RegistrationMessages {
-		n.emit("router.register", message, &wg, errors)
+	if n.batcher.Enabled() {
+		batches := n.batcher.batches(messagesToEmit.RegistrationMessages)
+		wg.Add(len(batches))
+		for _, batch := range batches {
+			n.emitBatch(batch, &wg, errors, outcomes)
+		}
+	} else {
+		wg.Add(len(messagesToEmit.RegistrationMessages))
+		for _, message := range messagesToEmit.RegistrationMessages {
+			n.emit(RouterRegisterSubject, message, &wg, errors, outcomes)
+		}
 	}
 
 	wg.Add(len(messagesToEmit.UnregistrationMessages))
 	for _, message := range messagesToEmit.UnregistrationMessages {
-		n.emit("router.unregister", message, &wg, errors)
+		n.emit(RouterUnregisterSubject, message, &wg, errors, outcomes)
 	}
 
 	var numberOfInternalMessages uint64
@@ -59,12 +117,12 @@ func (n *natsEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
 	if n.emitInternalRoutes {
 		wg.Add(len(messagesToEmit.InternalRegistrationMessages))
 		for _, message := range messagesToEmit.InternalRegistrationMessages {
-			n.emit("service-discovery.register", message, &wg, errors)
+			n.emit(ServiceDiscoveryRegisterSubject, message, &wg, errors, outcomes)
 		}
 
 		wg.Add(len(messagesToEmit.InternalUnregistrationMessages))
 		for _, message := range messagesToEmit.InternalUnregistrationMessages {
-			n.emit("service-discovery.unregister", message, &wg, errors)
+			n.emit(ServiceDiscoveryUnregisterSubject, message, &wg, errors, outcomes)
 		}
 
 		numberOfInternalMessages = uint64(len(messagesToEmit.InternalRegistrationMessages) + len(messagesToEmit.InternalUnregistrationMessages))
@@ -75,34 +133,49 @@ func (n *natsEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
 
 	select {
 	case finalError := <-errors:
+		n.sendPublishOutcomes(outcomes)
 		return finalError
 	default:
 	}
 
-	err := n.metronClient.IncrementCounterWithDelta(messagesEmittedCounter, numberOfMessages)
+	err := n.metricsSink.IncrementCounterWithDelta(messagesEmittedCounter, numberOfMessages, nil)
 	if err != nil {
 		n.logger.Error("cannot-emit-number-of-messages", err)
 	}
 
-	err = n.metronClient.IncrementCounterWithDelta(httpRouteNATSMessagesEmittedCounter, numberOfHTTPMessages)
+	err = n.metricsSink.IncrementCounterWithDelta(httpRouteNATSMessagesEmittedCounter, numberOfHTTPMessages, metrics.Labels{metrics.TableLabel: metrics.HTTPTable})
 	if err != nil {
 		n.logger.Error("cannot-emit-number-of-http-messages", err)
 	}
 
 	if n.emitInternalRoutes {
-		err := n.metronClient.IncrementCounterWithDelta(internalRouteNATSMessagesEmittedCounter, numberOfInternalMessages)
+		err := n.metricsSink.IncrementCounterWithDelta(internalRouteNATSMessagesEmittedCounter, numberOfInternalMessages, metrics.Labels{metrics.TableLabel: metrics.InternalTable})
 		if err != nil {
 			n.logger.Error("cannot-emit-number-of-internal-messages", err)
 		}
 	}
 
+	n.sendPublishOutcomes(outcomes)
 	return nil
 }
 
-func (n *natsEmitter) emit(subject string, message routingtable.RegistryMessage, wg *sync.WaitGroup, errors chan error) {
+func (n *natsEmitter) sendPublishOutcomes(outcomes *publishOutcomes) {
+	for key, count := range outcomes.counts() {
+		err := n.metricsSink.IncrementCounterWithDelta(natsMessagesPublishedCounter, count, metrics.Labels{
+			metrics.SubjectLabel: key.subject,
+			metrics.OutcomeLabel: key.outcome,
+		})
+		if err != nil {
+			n.logger.Error("cannot-emit-number-of-published-messages", err, lager.Data{"subject": key.subject, "outcome": key.outcome})
+		}
+	}
+}
+
+func (n *natsEmitter) emit(subject string, message routingtable.RegistryMessage, wg *sync.WaitGroup, errors chan error, outcomes *publishOutcomes) {
 	n.workPool.Submit(func() {
 		var err error
 		defer func() {
+			outcomes.record(subject, err)
 			if err != nil {
 				select {
 				case errors <- err:
@@ -131,6 +204,133 @@ func (n *natsEmitter) emit(subject string, message routingtable.RegistryMessage,
 				"message": message,
 				"subject": subject,
 			})
+			n.queueRetries(subject, message)
+			return
 		}
+
+		n.cancelRetries(subject, message)
 	})
 }
+
+// emitBatch publishes messages as a single batched registration. Failed
+// batches are retried as individual registrations.
+func (n *natsEmitter) emitBatch(messages []routingtable.RegistryMessage, wg *sync.WaitGroup, errors chan error, outcomes *publishOutcomes) {
+	n.workPool.Submit(func() {
+		var err error
+		defer func() {
+			outcomes.record(RouterRegisterBatchSubject, err)
+			if err != nil {
+				select {
+				case errors <- err:
+				default:
+				}
+			}
+			wg.Done()
+		}()
+
+		n.logger.Debug("emit-batch", lager.Data{
+			"subject":  RouterRegisterBatchSubject,
+			"messages": len(messages),
+		})
+
+		payload, err := json.Marshal(routingtable.RegistryMessageBatch{Messages: messages})
+		if err != nil {
+			n.logger.Error("failed-to-marshal-batch", err, lager.Data{
+				"messages": len(messages),
+				"subject":  RouterRegisterBatchSubject,
+			})
+			return
+		}
+
+		err = n.natsClient.Publish(RouterRegisterBatchSubject, payload)
+		if err != nil {
+			n.logger.Error("failed-to-publish-batch", err, lager.Data{
+				"messages": len(messages),
+				"subject":  RouterRegisterBatchSubject,
+			})
+			for _, message := range messages {
+				n.queueRetries(RouterRegisterSubject, message)
+			}
+			return
+		}
+
+		for _, message := range messages {
+			n.cancelRetries(RouterRegisterSubject, message)
+		}
+	})
+}
+
+// queueRetries splits message per URI so that a later message for one of
+// its URIs only supersedes the retry for that URI.
+func (n *natsEmitter) queueRetries(subject string, message routingtable.RegistryMessage) {
+	if n.retryQueue == nil {
+		return
+	}
+
+	for _, uri := range message.URIs {
+		uriMessage := message
+		uriMessage.URIs = []string{uri}
+
+		payload, err := json.Marshal(uriMessage)
+		if err != nil {
+			continue
+		}
+
+		n.retryQueue.Add(natsRetryKey(subject, uri, message), func() error {
+			return n.natsClient.Publish(subject, payload)
+		})
+	}
+}
+
+func (n *natsEmitter) cancelRetries(subject string, message routingtable.RegistryMessage) {
+	if n.retryQueue == nil {
+		return
+	}
+
+	for _, uri := range message.URIs {
+		n.retryQueue.Remove(natsRetryKey(subject, uri, message))
+	}
+}
+
+// natsRetryKey is the same for the register and unregister subjects so that
+// they supersede each other.
+func natsRetryKey(subject, uri string, message routingtable.RegistryMessage) string {
+	prefix := strings.SplitN(subject, ".", 2)[0]
+	return fmt.Sprintf("%s|%s|%s:%d:%d", prefix, uri, message.Host, message.Port, message.TlsPort)
+}
+
+type publishOutcomeKey struct {
+	subject string
+	outcome string
+}
+
+type publishOutcomes struct {
+	lock   sync.Mutex
+	values map[publishOutcomeKey]uint64
+}
+
+func newPublishOutcomes() *publishOutcomes {
+	return &publishOutcomes{values: map[publishOutcomeKey]uint64{}}
+}
+
+func (o *publishOutcomes) record(subject string, err error) {
+	key := publishOutcomeKey{subject: subject, outcome: metrics.SuccessOutcome}
+	if err != nil {
+		key.outcome = metrics.FailureOutcome
+	}
+
+	o.lock.Lock()
+	o.values[key]++
+	o.lock.Unlock()
+}
+
+func (o *publishOutcomes) counts() map[publishOutcomeKey]uint64 {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	counts := make(map[publishOutcomeKey]uint64, len(o.values))
+	for key, count := range o.values {
+		counts[key] = count
+	}
+	return counts
+}
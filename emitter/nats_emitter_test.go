@@ -2,11 +2,14 @@ package emitter_test
 
 import (
 	"errors"
+	"time"
 
-	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/clock/fakeclock"
 	"code.cloudfoundry.org/lager/lagertest"
 	"code.cloudfoundry.org/route-emitter/diegonats"
 	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/metrics"
+	metricsfakes "code.cloudfoundry.org/route-emitter/metrics/fakes"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/workpool"
 	"github.com/nats-io/nats"
@@ -19,7 +22,7 @@ import (
 var _ = Describe("NatsEmitter", func() {
 	var natsEmitter emitter.NATSEmitter
 	var natsClient *diegonats.FakeNATSClient
-	var fakeMetronClient *mfakes.FakeIngressClient
+	var fakeMetricsSink *metricsfakes.FakeSink
 	var logger *lagertest.TestLogger
 
 	messagesToEmit := routingtable.MessagesToEmit{
@@ -46,8 +49,8 @@ var _ = Describe("NatsEmitter", func() {
 		logger = lagertest.NewTestLogger("test")
 		workPool, err := workpool.NewWorkPool(1)
 		Expect(err).NotTo(HaveOccurred())
-		fakeMetronClient = &mfakes.FakeIngressClient{}
-		natsEmitter = emitter.NewNATSEmitter(natsClient, workPool, logger, fakeMetronClient, true)
+		fakeMetricsSink = &metricsfakes.FakeSink{}
+		natsEmitter = emitter.NewNATSEmitter(natsClient, workPool, logger, fakeMetricsSink, nil, nil, true)
 	})
 
 	Describe("Emitting", func() {
@@ -145,18 +148,28 @@ var _ = Describe("NatsEmitter", func() {
         }
       `)))
 
-			Eventually(fakeMetronClient.IncrementCounterWithDeltaCallCount).Should(Equal(3))
-			name, delta := fakeMetronClient.IncrementCounterWithDeltaArgsForCall(0)
+			Eventually(fakeMetricsSink.IncrementCounterWithDeltaCallCount).Should(Equal(7))
+			name, delta, labels := fakeMetricsSink.IncrementCounterWithDeltaArgsForCall(0)
 			Expect(name).To(Equal("MessagesEmitted"))
 			Expect(delta).To(BeEquivalentTo(8))
+			Expect(labels).To(BeEmpty())
 
-			name, delta = fakeMetronClient.IncrementCounterWithDeltaArgsForCall(1)
+			name, delta, labels = fakeMetricsSink.IncrementCounterWithDeltaArgsForCall(1)
 			Expect(name).To(Equal("HTTPRouteNATSMessagesEmitted"))
 			Expect(delta).To(BeEquivalentTo(4))
+			Expect(labels).To(Equal(metrics.Labels{metrics.TableLabel: metrics.HTTPTable}))
 
-			name, delta = fakeMetronClient.IncrementCounterWithDeltaArgsForCall(2)
+			name, delta, labels = fakeMetricsSink.IncrementCounterWithDeltaArgsForCall(2)
 			Expect(name).To(Equal("InternalRouteNATSMessagesEmitted"))
 			Expect(delta).To(BeEquivalentTo(4))
+			Expect(labels).To(Equal(metrics.Labels{metrics.TableLabel: metrics.InternalTable}))
+
+			Expect(publishedCounts(fakeMetricsSink)).To(Equal(map[string]uint64{
+				"router.register/success":              2,
+				"router.unregister/success":            2,
+				"service-discovery.register/success":   2,
+				"service-discovery.unregister/success": 2,
+			}))
 		})
 
 		Context("when the nats emitter is configured to not emit internal routes", func() {
@@ -164,7 +177,7 @@ var _ = Describe("NatsEmitter", func() {
 				logger := lagertest.NewTestLogger("test")
 				workPool, err := workpool.NewWorkPool(1)
 				Expect(err).NotTo(HaveOccurred())
-				natsEmitter = emitter.NewNATSEmitter(natsClient, workPool, logger, fakeMetronClient, false)
+				natsEmitter = emitter.NewNATSEmitter(natsClient, workPool, logger, fakeMetricsSink, nil, nil, false)
 			})
 
 			It("only emits http routes", func() {
@@ -219,14 +232,35 @@ var _ = Describe("NatsEmitter", func() {
         }
       `)))
 
-				Eventually(fakeMetronClient.IncrementCounterWithDeltaCallCount).Should(Equal(2))
-				name, delta := fakeMetronClient.IncrementCounterWithDeltaArgsForCall(0)
+				Eventually(fakeMetricsSink.IncrementCounterWithDeltaCallCount).Should(Equal(4))
+				name, delta, _ := fakeMetricsSink.IncrementCounterWithDeltaArgsForCall(0)
 				Expect(name).To(Equal("MessagesEmitted"))
 				Expect(delta).To(BeEquivalentTo(4))
 
-				name, delta = fakeMetronClient.IncrementCounterWithDeltaArgsForCall(1)
+				name, delta, _ = fakeMetricsSink.IncrementCounterWithDeltaArgsForCall(1)
 				Expect(name).To(Equal("HTTPRouteNATSMessagesEmitted"))
 				Expect(delta).To(BeEquivalentTo(4))
+
+				Expect(publishedCounts(fakeMetricsSink)).To(Equal(map[string]uint64{
+					"router.register/success":   2,
+					"router.unregister/success": 2,
+				}))
+			})
+		})
+
+		Context("when the work pool is replaced", func() {
+			BeforeEach(func() {
+				workPool, err := workpool.NewWorkPool(5)
+				Expect(err).NotTo(HaveOccurred())
+				natsEmitter.(emitter.WorkPoolSetter).SetWorkPool(workPool)
+			})
+
+			It("emits through the new work pool", func() {
+				err := natsEmitter.Emit(messagesToEmit)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(natsClient.PublishedMessages("router.register")).To(HaveLen(2))
+				Expect(natsClient.PublishedMessages("router.unregister")).To(HaveLen(2))
 			})
 		})
 
@@ -240,11 +274,115 @@ var _ = Describe("NatsEmitter", func() {
 			It("should error", func() {
 				Expect(natsEmitter.Emit(messagesToEmit)).To(MatchError(errors.New("bam")))
 			})
+
+			It("counts the failed publishes", func() {
+				Expect(natsEmitter.Emit(messagesToEmit)).NotTo(Succeed())
+				Expect(publishedCounts(fakeMetricsSink)).To(HaveKeyWithValue("router.register/failure", BeEquivalentTo(2)))
+			})
+
+			Context("with a retry queue", func() {
+				var retryQueue *emitter.RetryQueue
+
+				BeforeEach(func() {
+					workPool, err := workpool.NewWorkPool(1)
+					Expect(err).NotTo(HaveOccurred())
+					retryQueue = emitter.NewRetryQueue(logger, fakeclock.NewFakeClock(time.Now()), fakeMetricsSink, "nats", 10, 3, time.Second, time.Minute)
+					natsEmitter = emitter.NewNATSEmitter(natsClient, workPool, logger, fakeMetricsSink, retryQueue, nil, true)
+				})
+
+				It("queues a retry for every uri of the failed messages", func() {
+					Expect(natsEmitter.Emit(messagesToEmit)).NotTo(Succeed())
+					Expect(retryQueue.Len()).To(Equal(3))
+				})
+
+				It("cancels the retries once a newer message for the uri and endpoint is published", func() {
+					Expect(natsEmitter.Emit(messagesToEmit)).NotTo(Succeed())
+
+					natsClient.WhenPublishing("router.register", func(*nats.Msg) error {
+						return nil
+					})
+					err := natsEmitter.Emit(routingtable.MessagesToEmit{
+						UnregistrationMessages: []routingtable.RegistryMessage{
+							{URIs: []string{"foo.com", "bar.com"}, Host: "1.1.1.1", Port: 11},
+						},
+					})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(retryQueue.Len()).To(Equal(1))
+				})
+			})
 		})
 
-		Context("when the metron client errors", func() {
+		Context("with a registration batcher", func() {
+			var (
+				batcher *emitter.RegistrationBatcher
+				clock   *fakeclock.FakeClock
+			)
+
 			BeforeEach(func() {
-				fakeMetronClient.IncrementCounterWithDeltaReturns(errors.New("boo"))
+				workPool, err := workpool.NewWorkPool(1)
+				Expect(err).NotTo(HaveOccurred())
+				clock = fakeclock.NewFakeClock(time.Now())
+				batcher = emitter.NewRegistrationBatcher(logger, clock, 1)
+				natsEmitter = emitter.NewNATSEmitter(natsClient, workPool, logger, fakeMetricsSink, nil, batcher, true)
+			})
+
+			Context("when every router supports batched registrations", func() {
+				BeforeEach(func() {
+					batcher.Observe(routingtable.ExternalServiceGreetingMessage{ID: "router-1", PruneThresholdInSeconds: 120, SupportsBatchedRegistration: true})
+				})
+
+				It("publishes the registrations in batches", func() {
+					err := natsEmitter.Emit(messagesToEmit)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(natsClient.PublishedMessages("router.register")).To(BeEmpty())
+					Expect(natsClient.PublishedMessages("router.register.batch")).To(HaveLen(2))
+					Expect(natsClient.PublishedMessages("router.register.batch")[0].Data).To(MatchJSON(`{
+						"messages": [{"uris": ["foo.com", "bar.com"], "host": "1.1.1.1", "port": 11}]
+					}`))
+					Expect(natsClient.PublishedMessages("router.register.batch")[1].Data).To(MatchJSON(`{
+						"messages": [{"uris": ["baz.com"], "host": "2.2.2.2", "port": 22}]
+					}`))
+				})
+
+				It("still publishes the other messages individually", func() {
+					err := natsEmitter.Emit(messagesToEmit)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(natsClient.PublishedMessages("router.unregister")).To(HaveLen(2))
+					Expect(natsClient.PublishedMessages("service-discovery.register")).To(HaveLen(2))
+				})
+
+				It("counts every registry message as emitted", func() {
+					err := natsEmitter.Emit(messagesToEmit)
+					Expect(err).NotTo(HaveOccurred())
+
+					name, delta, _ := fakeMetricsSink.IncrementCounterWithDeltaArgsForCall(0)
+					Expect(name).To(Equal("MessagesEmitted"))
+					Expect(delta).To(BeEquivalentTo(8))
+					Expect(publishedCounts(fakeMetricsSink)).To(HaveKeyWithValue("router.register.batch/success", BeEquivalentTo(2)))
+				})
+			})
+
+			Context("when a router does not support batched registrations", func() {
+				BeforeEach(func() {
+					batcher.Observe(routingtable.ExternalServiceGreetingMessage{ID: "router-1", PruneThresholdInSeconds: 120, SupportsBatchedRegistration: true})
+					batcher.Observe(routingtable.ExternalServiceGreetingMessage{ID: "router-2", PruneThresholdInSeconds: 120})
+				})
+
+				It("falls back to individual registrations", func() {
+					err := natsEmitter.Emit(messagesToEmit)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(natsClient.PublishedMessages("router.register.batch")).To(BeEmpty())
+					Expect(natsClient.PublishedMessages("router.register")).To(HaveLen(2))
+				})
+			})
+		})
+
+		Context("when the metrics sink errors", func() {
+			BeforeEach(func() {
+				fakeMetricsSink.IncrementCounterWithDeltaReturns(errors.New("boo"))
 			})
 
 			It("should log the error message", func() {
@@ -257,3 +395,14 @@ var _ = Describe("NatsEmitter", func() {
 		})
 	})
 })
+
+func publishedCounts(sink *metricsfakes.FakeSink) map[string]uint64 {
+	counts := map[string]uint64{}
+	for i := 0; i < sink.IncrementCounterWithDeltaCallCount(); i++ {
+		name, delta, labels := sink.IncrementCounterWithDeltaArgsForCall(i)
+		if name == "NATSMessagesPublished" {
+			counts[labels[metrics.SubjectLabel]+"/"+labels[metrics.OutcomeLabel]] += delta
+		}
+	}
+	return counts
+}
@@ -0,0 +1,163 @@
+package emitter
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/routing-api/models"
+)
+
+const (
+	// the routing api is not reached over nats, these subjects only exist to
+	// file its upserts and deletes next to the nats messages
+	RoutingAPIUpsertSubject = "routing-api.upsert"
+	RoutingAPIDeleteSubject = "routing-api.delete"
+)
+
+type RecordedMessage struct {
+	Subject         string                        `json:"subject"`
+	RecordedAt      time.Time                     `json:"recorded_at"`
+	RegistryMessage *routingtable.RegistryMessage `json:"registry_message,omitempty"`
+	TcpRouteMapping *models.TcpRouteMapping       `json:"tcp_route_mapping,omitempty"`
+}
+
+type Recording struct {
+	Counts   map[string]uint64 `json:"counts"`
+	Messages []RecordedMessage `json:"messages"`
+}
+
+// RecordingEmitter stands in for the nats and routing api emitters when
+// running in dry-run mode. It counts every message per subject and keeps the
+// most recent ones in a fixed size buffer instead of publishing them.
+type RecordingEmitter struct {
+	logger             lager.Logger
+	clock              clock.Clock
+	emitInternalRoutes bool
+
+	lock     sync.Mutex
+	counts   map[string]uint64
+	messages []RecordedMessage
+	next     int
+	full     bool
+}
+
+func NewRecordingEmitter(logger lager.Logger, clock clock.Clock, bufferSize int, emitInternalRoutes bool) *RecordingEmitter {
+	return &RecordingEmitter{
+		logger:             logger.Session("recording-emitter"),
+		clock:              clock,
+		emitInternalRoutes: emitInternalRoutes,
+		counts:             map[string]uint64{},
+		messages:           make([]RecordedMessage, bufferSize),
+	}
+}
+
+func (r *RecordingEmitter) NATSEmitter() NATSEmitter {
+	return recordingNATSEmitter{r}
+}
+
+func (r *RecordingEmitter) RoutingAPIEmitter() RoutingAPIEmitter {
+	return recordingRoutingAPIEmitter{r}
+}
+
+// Recording returns a copy of the counts and of the buffered messages,
+// oldest first.
+func (r *RecordingEmitter) Recording() Recording {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	counts := make(map[string]uint64, len(r.counts))
+	for subject, count := range r.counts {
+		counts[subject] = count
+	}
+
+	messages := []RecordedMessage{}
+	if r.full {
+		messages = append(messages, r.messages[r.next:]...)
+	}
+	messages = append(messages, r.messages[:r.next]...)
+
+	return Recording{Counts: counts, Messages: messages}
+}
+
+func (r *RecordingEmitter) recordRegistryMessages(subject string, registryMessages []routingtable.RegistryMessage) {
+	for _, message := range registryMessages {
+		message := message
+		r.logger.Debug("would-emit", lager.Data{"subject": subject, "message": message})
+		r.record(RecordedMessage{Subject: subject, RegistryMessage: &message})
+	}
+}
+
+func (r *RecordingEmitter) recordTcpRouteMappings(subject string, mappings []models.TcpRouteMapping) {
+	for _, mapping := range mappings {
+		mapping := mapping
+		r.logger.Debug("would-emit", lager.Data{"subject": subject, "mapping": mapping})
+		r.record(RecordedMessage{Subject: subject, TcpRouteMapping: &mapping})
+	}
+}
+
+func (r *RecordingEmitter) record(message RecordedMessage) {
+	message.RecordedAt = r.clock.Now()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.counts[message.Subject]++
+
+	if len(r.messages) == 0 {
+		return
+	}
+
+	r.messages[r.next] = message
+	r.next++
+	if r.next == len(r.messages) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+type recordingNATSEmitter struct {
+	*RecordingEmitter
+}
+
+func (r recordingNATSEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
+	data := lager.Data{
+		"num-registration-messages":   len(messagesToEmit.RegistrationMessages),
+		"num-unregistration-messages": len(messagesToEmit.UnregistrationMessages),
+	}
+
+	r.recordRegistryMessages(RouterRegisterSubject, messagesToEmit.RegistrationMessages)
+	r.recordRegistryMessages(RouterUnregisterSubject, messagesToEmit.UnregistrationMessages)
+
+	if r.emitInternalRoutes {
+		data["num-internal-registration-messages"] = len(messagesToEmit.InternalRegistrationMessages)
+		data["num-internal-unregistration-messages"] = len(messagesToEmit.InternalUnregistrationMessages)
+
+		r.recordRegistryMessages(ServiceDiscoveryRegisterSubject, messagesToEmit.InternalRegistrationMessages)
+		r.recordRegistryMessages(ServiceDiscoveryUnregisterSubject, messagesToEmit.InternalUnregistrationMessages)
+	}
+
+	r.logger.Info("recorded-nats-messages", data)
+	return nil
+}
+
+type recordingRoutingAPIEmitter struct {
+	*RecordingEmitter
+}
+
+func (r recordingRoutingAPIEmitter) Emit(routingEvents routingtable.TCPRouteMappings) error {
+	if len(routingEvents.Registrations) == 0 && len(routingEvents.Unregistrations) == 0 {
+		return nil
+	}
+
+	r.recordTcpRouteMappings(RoutingAPIUpsertSubject, routingEvents.Registrations)
+	r.recordTcpRouteMappings(RoutingAPIDeleteSubject, routingEvents.Unregistrations)
+
+	r.logger.Info("recorded-routing-api-messages", lager.Data{
+		"num-registrations":   len(routingEvents.Registrations),
+		"num-unregistrations": len(routingEvents.Unregistrations),
+	})
+	return nil
+}
@@ -0,0 +1,101 @@
+package emitter_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	apimodels "code.cloudfoundry.org/routing-api/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecordingEmitter", func() {
+	var (
+		clock              *fakeclock.FakeClock
+		recorder           *emitter.RecordingEmitter
+		bufferSize         int
+		emitInternalRoutes bool
+		messagesToEmit     routingtable.MessagesToEmit
+		routingEvents      routingtable.TCPRouteMappings
+	)
+
+	BeforeEach(func() {
+		clock = fakeclock.NewFakeClock(time.Now())
+		bufferSize = 10
+		emitInternalRoutes = true
+
+		messagesToEmit = routingtable.MessagesToEmit{
+			RegistrationMessages: []routingtable.RegistryMessage{
+				{Host: "1.1.1.1", Port: 61001, URIs: []string{"foo.example.com"}},
+				{Host: "1.1.1.1", Port: 61001, URIs: []string{"bar.example.com"}},
+			},
+			UnregistrationMessages: []routingtable.RegistryMessage{
+				{Host: "2.2.2.2", Port: 61002, URIs: []string{"baz.example.com"}},
+			},
+			InternalRegistrationMessages: []routingtable.RegistryMessage{
+				{Host: "10.0.0.1", URIs: []string{"foo.apps.internal"}},
+			},
+		}
+		routingEvents = routingtable.TCPRouteMappings{
+			Registrations: []apimodels.TcpRouteMapping{
+				apimodels.NewTcpRouteMapping("router-group-guid", 5222, "1.1.1.1", 61003, 0),
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		recorder = emitter.NewRecordingEmitter(lagertest.NewTestLogger("test"), clock, bufferSize, emitInternalRoutes)
+		Expect(recorder.NATSEmitter().Emit(messagesToEmit)).To(Succeed())
+		Expect(recorder.RoutingAPIEmitter().Emit(routingEvents)).To(Succeed())
+	})
+
+	It("counts the messages per subject", func() {
+		Expect(recorder.Recording().Counts).To(Equal(map[string]uint64{
+			emitter.RouterRegisterSubject:           2,
+			emitter.RouterUnregisterSubject:         1,
+			emitter.ServiceDiscoveryRegisterSubject: 1,
+			emitter.RoutingAPIUpsertSubject:         1,
+		}))
+	})
+
+	It("records the messages in the order they were emitted", func() {
+		messages := recorder.Recording().Messages
+		Expect(messages).To(HaveLen(5))
+
+		Expect(messages[0].Subject).To(Equal(emitter.RouterRegisterSubject))
+		Expect(*messages[0].RegistryMessage).To(Equal(messagesToEmit.RegistrationMessages[0]))
+		Expect(messages[0].RecordedAt).To(Equal(clock.Now()))
+		Expect(messages[2].Subject).To(Equal(emitter.RouterUnregisterSubject))
+		Expect(messages[3].Subject).To(Equal(emitter.ServiceDiscoveryRegisterSubject))
+		Expect(messages[4].Subject).To(Equal(emitter.RoutingAPIUpsertSubject))
+		Expect(*messages[4].TcpRouteMapping).To(Equal(routingEvents.Registrations[0]))
+	})
+
+	Context("when the buffer is full", func() {
+		BeforeEach(func() {
+			bufferSize = 3
+		})
+
+		It("keeps only the most recent messages but still counts all of them", func() {
+			recording := recorder.Recording()
+			Expect(recording.Messages).To(HaveLen(3))
+			Expect(recording.Messages[0].Subject).To(Equal(emitter.RouterUnregisterSubject))
+			Expect(recording.Messages[2].Subject).To(Equal(emitter.RoutingAPIUpsertSubject))
+			Expect(recording.Counts[emitter.RouterRegisterSubject]).To(BeEquivalentTo(2))
+		})
+	})
+
+	Context("when internal routes are not emitted", func() {
+		BeforeEach(func() {
+			emitInternalRoutes = false
+		})
+
+		It("does not record them", func() {
+			Expect(recorder.Recording().Counts).NotTo(HaveKey(emitter.ServiceDiscoveryRegisterSubject))
+		})
+	})
+})
@@ -0,0 +1,108 @@
+package emitter
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+// defaultRouterExpiry is used for routers that do not send a prune threshold
+// in their greeting.
+const defaultRouterExpiry = 2 * time.Minute
+
+// RegistrationBatcher tracks which routers accept batched registrations.
+// Registrations are only batched once every router heard from within its
+// prune threshold advertised support for them, since a single publish
+// reaches all routers.
+type RegistrationBatcher struct {
+	logger    lager.Logger
+	clock     clock.Clock
+	batchSize int
+
+	lock    sync.Mutex
+	routers map[string]routerGreeting
+}
+
+type routerGreeting struct {
+	supportsBatching bool
+	expiresAt        time.Time
+}
+
+func NewRegistrationBatcher(logger lager.Logger, clock clock.Clock, batchSize int) *RegistrationBatcher {
+	return &RegistrationBatcher{
+		logger:    logger.Session("registration-batcher"),
+		clock:     clock,
+		batchSize: batchSize,
+		routers:   map[string]routerGreeting{},
+	}
+}
+
+// Observe records a greeting sent by a router, either in its router.start
+// message or in reply to router.greet.
+func (b *RegistrationBatcher) Observe(greeting routingtable.ExternalServiceGreetingMessage) {
+	expiry := time.Duration(greeting.PruneThresholdInSeconds) * time.Second
+	if expiry <= 0 {
+		expiry = defaultRouterExpiry
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	previous, known := b.routers[greeting.ID]
+	if !known || previous.supportsBatching != greeting.SupportsBatchedRegistration {
+		b.logger.Info("observed-router", lager.Data{
+			"router-id":         greeting.ID,
+			"supports-batching": greeting.SupportsBatchedRegistration,
+		})
+	}
+
+	b.routers[greeting.ID] = routerGreeting{
+		supportsBatching: greeting.SupportsBatchedRegistration,
+		expiresAt:        b.clock.Now().Add(expiry),
+	}
+}
+
+// Enabled returns true if registrations should be batched. It is false until
+// a router has been heard from, and for a nil batcher.
+func (b *RegistrationBatcher) Enabled() bool {
+	if b == nil {
+		return false
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := b.clock.Now()
+	for id, router := range b.routers {
+		if now.After(router.expiresAt) {
+			b.logger.Info("expired-router", lager.Data{"router-id": id})
+			delete(b.routers, id)
+		}
+	}
+
+	if len(b.routers) == 0 {
+		return false
+	}
+
+	for _, router := range b.routers {
+		if !router.supportsBatching {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *RegistrationBatcher) batches(messages []routingtable.RegistryMessage) [][]routingtable.RegistryMessage {
+	batches := [][]routingtable.RegistryMessage{}
+	for b.batchSize > 0 && len(messages) > b.batchSize {
+		batches = append(batches, messages[:b.batchSize])
+		messages = messages[b.batchSize:]
+	}
+	if len(messages) > 0 {
+		batches = append(batches, messages)
+	}
+	return batches
+}
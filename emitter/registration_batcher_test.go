@@ -0,0 +1,76 @@
+package emitter_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RegistrationBatcher", func() {
+	var (
+		clock   *fakeclock.FakeClock
+		batcher *emitter.RegistrationBatcher
+	)
+
+	greeting := func(id string, supportsBatching bool) routingtable.ExternalServiceGreetingMessage {
+		return routingtable.ExternalServiceGreetingMessage{
+			ID:                          id,
+			MinimumRegisterInterval:     20,
+			PruneThresholdInSeconds:     120,
+			SupportsBatchedRegistration: supportsBatching,
+		}
+	}
+
+	BeforeEach(func() {
+		clock = fakeclock.NewFakeClock(time.Now())
+		batcher = emitter.NewRegistrationBatcher(lagertest.NewTestLogger("test"), clock, 100)
+	})
+
+	It("is disabled until a router has been heard from", func() {
+		Expect(batcher.Enabled()).To(BeFalse())
+	})
+
+	It("is disabled when nil", func() {
+		var nilBatcher *emitter.RegistrationBatcher
+		Expect(nilBatcher.Enabled()).To(BeFalse())
+	})
+
+	It("is enabled when every router supports batched registrations", func() {
+		batcher.Observe(greeting("router-1", true))
+		batcher.Observe(greeting("router-2", true))
+		Expect(batcher.Enabled()).To(BeTrue())
+	})
+
+	It("is disabled when any router does not support batched registrations", func() {
+		batcher.Observe(greeting("router-1", true))
+		batcher.Observe(greeting("router-2", false))
+		Expect(batcher.Enabled()).To(BeFalse())
+	})
+
+	It("uses the latest greeting of a router", func() {
+		batcher.Observe(greeting("router-1", false))
+		batcher.Observe(greeting("router-1", true))
+		Expect(batcher.Enabled()).To(BeTrue())
+	})
+
+	Context("when a router is not heard from within its prune threshold", func() {
+		BeforeEach(func() {
+			batcher.Observe(greeting("router-1", false))
+			clock.Increment(time.Minute)
+			batcher.Observe(greeting("router-2", true))
+		})
+
+		It("forgets about it", func() {
+			Expect(batcher.Enabled()).To(BeFalse())
+
+			clock.Increment(90 * time.Second)
+			Expect(batcher.Enabled()).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,212 @@
+package emitter
+
+import (
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/metrics"
+)
+
+const (
+	retryQueueDepthMetric    = "RetryQueueDepth"
+	retryQueueDroppedCounter = "RetryQueueDropped"
+
+	// reasons for dropping a retry
+	RetryQueueFullReason        = "queue-full"
+	RetryAttemptsExceededReason = "attempts-exceeded"
+)
+
+type retryItem struct {
+	publish  func() error
+	attempts int
+	due      time.Time
+}
+
+// RetryQueue retries failed publishes with exponential backoff and jitter
+// until they succeed, are superseded or run out of attempts. Entries are keyed
+// by what they publish, e.g. a URI and endpoint, so that only the latest
+// message for a key is ever retried.
+type RetryQueue struct {
+	logger      lager.Logger
+	clock       clock.Clock
+	metricsSink metrics.Sink
+	name        string
+	maxSize     int
+	maxAttempts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+
+	lock  sync.Mutex
+	items map[string]*retryItem
+}
+
+func NewRetryQueue(
+	logger lager.Logger,
+	clock clock.Clock,
+	metricsSink metrics.Sink,
+	name string,
+	maxSize int,
+	maxAttempts int,
+	minBackoff time.Duration,
+	maxBackoff time.Duration,
+) *RetryQueue {
+	return &RetryQueue{
+		logger:      logger.Session("retry-queue", lager.Data{"emitter": name}),
+		clock:       clock,
+		metricsSink: metricsSink,
+		name:        name,
+		maxSize:     maxSize,
+		maxAttempts: maxAttempts,
+		minBackoff:  minBackoff,
+		maxBackoff:  maxBackoff,
+		items:       map[string]*retryItem{},
+	}
+}
+
+// Add queues publish to be retried under key, replacing any pending retry for
+// the same key. A nil queue drops everything.
+func (q *RetryQueue) Add(key string, publish func() error) {
+	if q == nil {
+		return
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if _, ok := q.items[key]; !ok && len(q.items) >= q.maxSize {
+		q.drop(key, RetryQueueFullReason)
+		return
+	}
+
+	q.items[key] = &retryItem{
+		publish:  publish,
+		attempts: 1,
+		due:      q.clock.Now().Add(q.backoff(1)),
+	}
+}
+
+// Remove cancels the pending retry for key, it is called once a newer message
+// for the same key has been published.
+func (q *RetryQueue) Remove(key string) {
+	if q == nil {
+		return
+	}
+
+	q.lock.Lock()
+	delete(q.items, key)
+	q.lock.Unlock()
+}
+
+func (q *RetryQueue) Len() int {
+	if q == nil {
+		return 0
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.items)
+}
+
+func (q *RetryQueue) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	close(ready)
+	q.logger.Info("started")
+
+	ticker := q.clock.NewTicker(q.minBackoff)
+
+	for {
+		select {
+		case <-ticker.C():
+			q.retryDue()
+			q.sendDepth()
+		case <-signals:
+			q.logger.Info("stopping", lager.Data{"pending": q.Len()})
+			ticker.Stop()
+			return nil
+		}
+	}
+}
+
+type dueRetry struct {
+	key  string
+	item *retryItem
+}
+
+func (q *RetryQueue) retryDue() {
+	now := q.clock.Now()
+
+	q.lock.Lock()
+	due := []dueRetry{}
+	for key, item := range q.items {
+		if !item.due.After(now) {
+			due = append(due, dueRetry{key: key, item: item})
+		}
+	}
+	q.lock.Unlock()
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].item.due.Before(due[j].item.due)
+	})
+
+	for _, retry := range due {
+		err := retry.item.publish()
+
+		q.lock.Lock()
+		// the retry was superseded or cancelled while publishing
+		if q.items[retry.key] != retry.item {
+			q.lock.Unlock()
+			continue
+		}
+
+		switch {
+		case err == nil:
+			delete(q.items, retry.key)
+		case retry.item.attempts >= q.maxAttempts:
+			delete(q.items, retry.key)
+			q.drop(retry.key, RetryAttemptsExceededReason)
+		default:
+			q.logger.Info("retry-failed", lager.Data{"key": retry.key, "attempts": retry.item.attempts, "error": err.Error()})
+			retry.item.attempts++
+			retry.item.due = q.clock.Now().Add(q.backoff(retry.item.attempts))
+		}
+		q.lock.Unlock()
+	}
+}
+
+// backoff doubles minBackoff for every attempt up to maxBackoff and picks a
+// random duration between half of that and all of it.
+func (q *RetryQueue) backoff(attempt int) time.Duration {
+	backoff := q.minBackoff
+	for i := 1; i < attempt && backoff < q.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > q.maxBackoff {
+		backoff = q.maxBackoff
+	}
+
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(backoff-half)+1))
+}
+
+func (q *RetryQueue) drop(key, reason string) {
+	q.logger.Info("dropped-retry", lager.Data{"key": key, "reason": reason})
+
+	err := q.metricsSink.IncrementCounter(retryQueueDroppedCounter, metrics.Labels{
+		metrics.EmitterLabel: q.name,
+		metrics.ReasonLabel:  reason,
+	})
+	if err != nil {
+		q.logger.Error("cannot-emit-number-of-dropped-retries", err)
+	}
+}
+
+func (q *RetryQueue) sendDepth() {
+	err := q.metricsSink.SendMetric(retryQueueDepthMetric, q.Len(), metrics.Labels{metrics.EmitterLabel: q.name})
+	if err != nil {
+		q.logger.Error("cannot-emit-retry-queue-depth", err)
+	}
+}
@@ -0,0 +1,173 @@
+package emitter_test
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/metrics"
+	metricsfakes "code.cloudfoundry.org/route-emitter/metrics/fakes"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RetryQueue", func() {
+	const (
+		minBackoff = time.Second
+		maxBackoff = 8 * time.Second
+	)
+
+	var (
+		clock           *fakeclock.FakeClock
+		fakeMetricsSink *metricsfakes.FakeSink
+		queue           *emitter.RetryQueue
+		process         ifrit.Process
+		maxSize         int
+		maxAttempts     int
+	)
+
+	type publisher struct {
+		lock  sync.Mutex
+		calls int
+		err   error
+	}
+
+	publish := func(p *publisher) func() error {
+		return func() error {
+			p.lock.Lock()
+			defer p.lock.Unlock()
+			p.calls++
+			return p.err
+		}
+	}
+
+	calls := func(p *publisher) func() int {
+		return func() int {
+			p.lock.Lock()
+			defer p.lock.Unlock()
+			return p.calls
+		}
+	}
+
+	// advance moves the clock by d and waits for the queue to process the tick,
+	// the depth is reported at the end of every tick
+	advance := func(d time.Duration) {
+		Eventually(clock.WatcherCount).Should(Equal(1))
+		ticks := fakeMetricsSink.SendMetricCallCount()
+		clock.Increment(d)
+		Eventually(fakeMetricsSink.SendMetricCallCount).Should(Equal(ticks + 1))
+	}
+
+	BeforeEach(func() {
+		clock = fakeclock.NewFakeClock(time.Now())
+		fakeMetricsSink = &metricsfakes.FakeSink{}
+		maxSize = 10
+		maxAttempts = 3
+	})
+
+	JustBeforeEach(func() {
+		queue = emitter.NewRetryQueue(lagertest.NewTestLogger("test"), clock, fakeMetricsSink, "nats", maxSize, maxAttempts, minBackoff, maxBackoff)
+		process = ifrit.Invoke(queue)
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive())
+	})
+
+	It("retries until the publish succeeds", func() {
+		p := &publisher{err: errors.New("boom")}
+		queue.Add("key", publish(p))
+
+		advance(minBackoff)
+		Expect(calls(p)()).To(Equal(1))
+		Expect(queue.Len()).To(Equal(1))
+
+		p.lock.Lock()
+		p.err = nil
+		p.lock.Unlock()
+
+		advance(2 * minBackoff)
+		Expect(calls(p)()).To(Equal(2))
+		Expect(queue.Len()).To(Equal(0))
+	})
+
+	It("reports the queue depth", func() {
+		queue.Add("key", publish(&publisher{err: errors.New("boom")}))
+		advance(minBackoff)
+
+		name, value, labels := fakeMetricsSink.SendMetricArgsForCall(0)
+		Expect(name).To(Equal("RetryQueueDepth"))
+		Expect(value).To(Equal(1))
+		Expect(labels).To(Equal(metrics.Labels{metrics.EmitterLabel: "nats"}))
+	})
+
+	Context("when a newer message for the same key is added", func() {
+		It("only retries the newer one", func() {
+			older := &publisher{}
+			newer := &publisher{}
+			queue.Add("key", publish(older))
+			queue.Add("key", publish(newer))
+			Expect(queue.Len()).To(Equal(1))
+
+			advance(minBackoff)
+			Expect(calls(newer)()).To(Equal(1))
+			Expect(calls(older)()).To(Equal(0))
+		})
+	})
+
+	Context("when the retry is removed", func() {
+		It("does not retry", func() {
+			p := &publisher{}
+			queue.Add("key", publish(p))
+			queue.Remove("key")
+
+			advance(minBackoff)
+			Expect(calls(p)()).To(Equal(0))
+		})
+	})
+
+	Context("when the queue is full", func() {
+		BeforeEach(func() {
+			maxSize = 1
+		})
+
+		It("drops new keys but still replaces existing ones", func() {
+			queue.Add("key", publish(&publisher{}))
+			queue.Add("other-key", publish(&publisher{}))
+			queue.Add("key", publish(&publisher{}))
+			Expect(queue.Len()).To(Equal(1))
+
+			Expect(fakeMetricsSink.IncrementCounterCallCount()).To(Equal(1))
+			name, labels := fakeMetricsSink.IncrementCounterArgsForCall(0)
+			Expect(name).To(Equal("RetryQueueDropped"))
+			Expect(labels).To(Equal(metrics.Labels{
+				metrics.EmitterLabel: "nats",
+				metrics.ReasonLabel:  emitter.RetryQueueFullReason,
+			}))
+		})
+	})
+
+	Context("when a retry keeps failing", func() {
+		It("drops it after the last attempt", func() {
+			p := &publisher{err: errors.New("boom")}
+			queue.Add("key", publish(p))
+
+			for attempt := 1; attempt <= maxAttempts; attempt++ {
+				advance(maxBackoff)
+				Expect(calls(p)()).To(Equal(attempt))
+			}
+
+			Expect(queue.Len()).To(Equal(0))
+			Expect(fakeMetricsSink.IncrementCounterCallCount()).To(Equal(1))
+			_, labels := fakeMetricsSink.IncrementCounterArgsForCall(0)
+			Expect(labels[metrics.ReasonLabel]).To(Equal(emitter.RetryAttemptsExceededReason))
+		})
+	})
+})
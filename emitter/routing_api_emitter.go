@@ -1,6 +1,9 @@
 package emitter
 
 import (
+	"fmt"
+	"sync"
+
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/routing-api"
@@ -13,22 +16,47 @@ type RoutingAPIEmitter interface {
 	Emit(routingEvents routingtable.TCPRouteMappings) error
 }
 
+// RouteTTLSetter is implemented by routing API emitters, it changes the TTL
+// of the route mappings emitted from now on.
+type RouteTTLSetter interface {
+	SetRouteTTL(routeTTL int)
+}
+
 type routingAPIEmitter struct {
 	logger           lager.Logger
 	routingAPIClient routing_api.Client
-	ttl              int
 	uaaClient        uaaclient.Client
+	retryQueue       *RetryQueue
+
+	ttlLock sync.Mutex
+	ttl     int
 }
 
-func NewRoutingAPIEmitter(logger lager.Logger, routingAPIClient routing_api.Client, uaaClient uaaclient.Client, routeTTL int) RoutingAPIEmitter {
+// NewRoutingAPIEmitter returns an emitter for TCP route mappings. Mappings of a
+// batch that fails are retried one by one through retryQueue, which may be
+// nil.
+func NewRoutingAPIEmitter(logger lager.Logger, routingAPIClient routing_api.Client, uaaClient uaaclient.Client, routeTTL int, retryQueue *RetryQueue) RoutingAPIEmitter {
 	return &routingAPIEmitter{
 		logger:           logger,
 		routingAPIClient: routingAPIClient,
 		ttl:              routeTTL,
 		uaaClient:        uaaClient,
+		retryQueue:       retryQueue,
 	}
 }
 
+func (t *routingAPIEmitter) SetRouteTTL(routeTTL int) {
+	t.ttlLock.Lock()
+	defer t.ttlLock.Unlock()
+	t.ttl = routeTTL
+}
+
+func (t *routingAPIEmitter) routeTTL() int {
+	t.ttlLock.Lock()
+	defer t.ttlLock.Unlock()
+	return t.ttl
+}
+
 func (t *routingAPIEmitter) Emit(tcpEvents routingtable.TCPRouteMappings) error {
 	defer t.logger.Debug("complete-emit")
 
@@ -38,12 +66,50 @@ func (t *routingAPIEmitter) Emit(tcpEvents routingtable.TCPRouteMappings) error
 
 	err := t.emit(tcpEvents.Registrations, tcpEvents.Unregistrations)
 	if err != nil {
+		t.queueRetries(tcpEvents)
 		return err
 	}
 
+	t.cancelRetries(tcpEvents)
 	return nil
 }
 
+func (t *routingAPIEmitter) queueRetries(tcpEvents routingtable.TCPRouteMappings) {
+	if t.retryQueue == nil {
+		return
+	}
+
+	for _, mapping := range tcpEvents.Registrations {
+		registration := mapping
+		t.retryQueue.Add(routingAPIRetryKey(mapping), func() error {
+			return t.emit([]models.TcpRouteMapping{registration}, nil)
+		})
+	}
+	for _, mapping := range tcpEvents.Unregistrations {
+		unregistration := mapping
+		t.retryQueue.Add(routingAPIRetryKey(mapping), func() error {
+			return t.emit(nil, []models.TcpRouteMapping{unregistration})
+		})
+	}
+}
+
+func (t *routingAPIEmitter) cancelRetries(tcpEvents routingtable.TCPRouteMappings) {
+	if t.retryQueue == nil {
+		return
+	}
+
+	for _, mapping := range tcpEvents.Registrations {
+		t.retryQueue.Remove(routingAPIRetryKey(mapping))
+	}
+	for _, mapping := range tcpEvents.Unregistrations {
+		t.retryQueue.Remove(routingAPIRetryKey(mapping))
+	}
+}
+
+func routingAPIRetryKey(mapping models.TcpRouteMapping) string {
+	return fmt.Sprintf("%s|%d|%s:%d", mapping.RouterGroupGuid, mapping.ExternalPort, mapping.HostIP, mapping.HostPort)
+}
+
 func (t *routingAPIEmitter) emit(registrationMappingRequests, unregistrationMappingRequests []models.TcpRouteMapping) error {
 	var forceUpdate bool
 
@@ -69,11 +135,12 @@ func (t *routingAPIEmitter) emit(registrationMappingRequests, unregistrationMapp
 }
 
 func (t *routingAPIEmitter) emitRoutingAPI(regMsgs, unregMsgs []models.TcpRouteMapping) error {
+	ttl := t.routeTTL()
 	for i := range regMsgs {
-		regMsgs[i].TTL = &t.ttl
+		regMsgs[i].TTL = &ttl
 	}
 	for i := range unregMsgs {
-		unregMsgs[i].TTL = &t.ttl
+		unregMsgs[i].TTL = &ttl
 	}
 
 	if len(regMsgs) > 0 {
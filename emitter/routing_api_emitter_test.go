@@ -2,10 +2,13 @@ package emitter_test
 
 import (
 	"errors"
+	"time"
 
+	"code.cloudfoundry.org/clock/fakeclock"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagertest"
 	"code.cloudfoundry.org/route-emitter/emitter"
+	metricsfakes "code.cloudfoundry.org/route-emitter/metrics/fakes"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/routing-api/fake_routing_api"
 	apimodels "code.cloudfoundry.org/routing-api/models"
@@ -34,7 +37,7 @@ var _ = Describe("RoutingAPIEmitter", func() {
 		ttl = 60
 		logger = lagertest.NewTestLogger("test")
 		uaaClient = &fakeuaa.FakeClient{}
-		routingAPIEmitter = emitter.NewRoutingAPIEmitter(logger, routingApiClient, uaaClient, ttl)
+		routingAPIEmitter = emitter.NewRoutingAPIEmitter(logger, routingApiClient, uaaClient, ttl, nil)
 
 		routingEvents = routingtable.TCPRouteMappings{
 			Registrations: []apimodels.TcpRouteMapping{apimodels.NewTcpRouteMapping("123", 61000, "some-ip-1", 62003, 0)},
@@ -93,6 +96,20 @@ var _ = Describe("RoutingAPIEmitter", func() {
 					mappingRequests := routingApiClient.UpsertTcpRouteMappingsArgsForCall(0)
 					Expect(mappingRequests).To(ConsistOf(expectedRoutingEvents.Registrations))
 				})
+
+				Context("when the ttl is changed", func() {
+					BeforeEach(func() {
+						routingAPIEmitter.(emitter.RouteTTLSetter).SetRouteTTL(120)
+					})
+
+					It("emits the new ttl", func() {
+						err := routingAPIEmitter.Emit(routingEvents)
+						Expect(err).ShouldNot(HaveOccurred())
+						mappingRequests := routingApiClient.UpsertTcpRouteMappingsArgsForCall(0)
+						Expect(mappingRequests).To(HaveLen(1))
+						Expect(*mappingRequests[0].TTL).To(Equal(120))
+					})
+				})
 			})
 
 			Context("and there are unregistration events", func() {
@@ -134,6 +151,30 @@ var _ = Describe("RoutingAPIEmitter", func() {
 				Expect(uaaClient.FetchTokenArgsForCall(1)).To(BeTrue())
 			})
 
+			Context("with a retry queue", func() {
+				var retryQueue *emitter.RetryQueue
+
+				BeforeEach(func() {
+					retryQueue = emitter.NewRetryQueue(logger, fakeclock.NewFakeClock(time.Now()), &metricsfakes.FakeSink{}, "routing-api", 10, 3, time.Second, time.Minute)
+					routingAPIEmitter = emitter.NewRoutingAPIEmitter(logger, routingApiClient, uaaClient, ttl, retryQueue)
+				})
+
+				It("queues a retry for every mapping", func() {
+					Expect(routingAPIEmitter.Emit(routingEvents)).NotTo(Succeed())
+					Expect(retryQueue.Len()).To(Equal(1))
+				})
+
+				It("cancels the retry once the mapping is unregistered", func() {
+					Expect(routingAPIEmitter.Emit(routingEvents)).NotTo(Succeed())
+
+					err := routingAPIEmitter.Emit(routingtable.TCPRouteMappings{
+						Unregistrations: routingEvents.Registrations,
+					})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(retryQueue.Len()).To(Equal(0))
+				})
+			})
+
 			Context("when refreshing the cached token authorizes the emitter", func() {
 				BeforeEach(func() {
 					var count uint
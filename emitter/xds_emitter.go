@@ -0,0 +1,160 @@
+package emitter
+
+import (
+	"strconv"
+	"sync"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	"github.com/envoyproxy/go-control-plane/pkg/cache"
+)
+
+// XDSNodeGroup is the snapshot cache key every Envoy is served from. All
+// proxies connected to the emitter receive the same routing state.
+const XDSNodeGroup = "route-emitter"
+
+//go:generate counterfeiter -o fakes/fake_xds_emitter.go . XDSEmitter
+type XDSEmitter interface {
+	Emit(messagesToEmit routingtable.MessagesToEmit, routeMappings routingtable.TCPRouteMappings) error
+}
+
+// XDSNodeHash maps every node onto XDSNodeGroup.
+type XDSNodeHash struct{}
+
+func (XDSNodeHash) ID(*core.Node) string {
+	return XDSNodeGroup
+}
+
+type xdsEndpoint struct {
+	host string
+	port uint32
+}
+
+type xdsEndpoints map[xdsEndpoint]struct{}
+
+type xdsTCPRouteKey struct {
+	routerGroupGUID string
+	externalPort    uint32
+}
+
+type xdsEmitter struct {
+	logger           lager.Logger
+	snapshotCache    cache.SnapshotCache
+	httpListenerPort uint32
+
+	lock       sync.Mutex
+	version    uint64
+	httpRoutes map[string]xdsEndpoints
+	tcpRoutes  map[xdsTCPRouteKey]xdsEndpoints
+}
+
+// NewXDSEmitter returns an emitter that accumulates the http and tcp route
+// deltas it is given and publishes the result as a new snapshot version in
+// snapshotCache. Internal routes are not exposed over xDS.
+func NewXDSEmitter(logger lager.Logger, snapshotCache cache.SnapshotCache, httpListenerPort uint32) XDSEmitter {
+	return &xdsEmitter{
+		logger:           logger.Session("xds-emitter"),
+		snapshotCache:    snapshotCache,
+		httpListenerPort: httpListenerPort,
+		httpRoutes:       map[string]xdsEndpoints{},
+		tcpRoutes:        map[xdsTCPRouteKey]xdsEndpoints{},
+	}
+}
+
+func (e *xdsEmitter) Emit(messagesToEmit routingtable.MessagesToEmit, routeMappings routingtable.TCPRouteMappings) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	changed := false
+
+	for _, message := range messagesToEmit.RegistrationMessages {
+		endpoint := xdsEndpoint{host: message.Host, port: message.Port}
+		for _, uri := range message.URIs {
+			endpoints, ok := e.httpRoutes[uri]
+			if !ok {
+				endpoints = xdsEndpoints{}
+				e.httpRoutes[uri] = endpoints
+			}
+			changed = endpoints.add(endpoint) || changed
+		}
+	}
+
+	for _, message := range messagesToEmit.UnregistrationMessages {
+		endpoint := xdsEndpoint{host: message.Host, port: message.Port}
+		for _, uri := range message.URIs {
+			endpoints, ok := e.httpRoutes[uri]
+			if !ok {
+				continue
+			}
+			changed = endpoints.remove(endpoint) || changed
+			if len(endpoints) == 0 {
+				delete(e.httpRoutes, uri)
+			}
+		}
+	}
+
+	for _, mapping := range routeMappings.Registrations {
+		key := xdsTCPRouteKey{routerGroupGUID: mapping.RouterGroupGuid, externalPort: uint32(mapping.ExternalPort)}
+		endpoints, ok := e.tcpRoutes[key]
+		if !ok {
+			endpoints = xdsEndpoints{}
+			e.tcpRoutes[key] = endpoints
+		}
+		changed = endpoints.add(xdsEndpoint{host: mapping.HostIP, port: uint32(mapping.HostPort)}) || changed
+	}
+
+	for _, mapping := range routeMappings.Unregistrations {
+		key := xdsTCPRouteKey{routerGroupGUID: mapping.RouterGroupGuid, externalPort: uint32(mapping.ExternalPort)}
+		endpoints, ok := e.tcpRoutes[key]
+		if !ok {
+			continue
+		}
+		changed = endpoints.remove(xdsEndpoint{host: mapping.HostIP, port: uint32(mapping.HostPort)}) || changed
+		if len(endpoints) == 0 {
+			delete(e.tcpRoutes, key)
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	e.version++
+	version := strconv.FormatUint(e.version, 10)
+
+	snapshot, err := e.snapshot(version)
+	if err != nil {
+		e.logger.Error("failed-to-build-snapshot", err, lager.Data{"version": version})
+		return err
+	}
+
+	err = e.snapshotCache.SetSnapshot(XDSNodeGroup, snapshot)
+	if err != nil {
+		e.logger.Error("failed-to-set-snapshot", err, lager.Data{"version": version})
+		return err
+	}
+
+	e.logger.Debug("set-snapshot", lager.Data{
+		"version":     version,
+		"http-routes": len(e.httpRoutes),
+		"tcp-routes":  len(e.tcpRoutes),
+	})
+	return nil
+}
+
+func (endpoints xdsEndpoints) add(endpoint xdsEndpoint) bool {
+	if _, ok := endpoints[endpoint]; ok {
+		return false
+	}
+	endpoints[endpoint] = struct{}{}
+	return true
+}
+
+func (endpoints xdsEndpoints) remove(endpoint xdsEndpoint) bool {
+	if _, ok := endpoints[endpoint]; !ok {
+		return false
+	}
+	delete(endpoints, endpoint)
+	return true
+}
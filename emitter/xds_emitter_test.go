@@ -0,0 +1,127 @@
+package emitter_test
+
+import (
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	apimodels "code.cloudfoundry.org/routing-api/models"
+	"github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	"github.com/envoyproxy/go-control-plane/pkg/cache"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("XDSEmitter", func() {
+	var (
+		snapshotCache cache.SnapshotCache
+		xdsEmitter    emitter.XDSEmitter
+		fooMessage    routingtable.RegistryMessage
+		barMessage    routingtable.RegistryMessage
+		tcpMapping    apimodels.TcpRouteMapping
+	)
+
+	currentSnapshot := func() cache.Snapshot {
+		snapshot, err := snapshotCache.GetSnapshot(emitter.XDSNodeGroup)
+		Expect(err).NotTo(HaveOccurred())
+		return snapshot
+	}
+
+	BeforeEach(func() {
+		snapshotCache = cache.NewSnapshotCache(true, emitter.XDSNodeHash{}, nil)
+		xdsEmitter = emitter.NewXDSEmitter(lagertest.NewTestLogger("test"), snapshotCache, 8080)
+
+		fooMessage = routingtable.RegistryMessage{Host: "1.1.1.1", Port: 61001, URIs: []string{"foo.example.com"}}
+		barMessage = routingtable.RegistryMessage{Host: "2.2.2.2", Port: 61002, URIs: []string{"foo.example.com/bar"}}
+		tcpMapping = apimodels.NewTcpRouteMapping("router-group-guid", 5222, "3.3.3.3", 61003, 0)
+
+		err := xdsEmitter.Emit(routingtable.MessagesToEmit{
+			RegistrationMessages: []routingtable.RegistryMessage{fooMessage, barMessage},
+		}, routingtable.TCPRouteMappings{
+			Registrations: []apimodels.TcpRouteMapping{tcpMapping},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("publishes a cluster and load assignment per http uri and tcp route", func() {
+		snapshot := currentSnapshot()
+		Expect(snapshot.Clusters.Version).To(Equal("1"))
+		Expect(snapshot.Clusters.Items).To(HaveLen(3))
+		Expect(snapshot.Clusters.Items).To(HaveKey(emitter.XDSHTTPClusterName("foo.example.com")))
+		Expect(snapshot.Clusters.Items).To(HaveKey(emitter.XDSHTTPClusterName("foo.example.com/bar")))
+		Expect(snapshot.Clusters.Items).To(HaveKey(emitter.XDSTCPClusterName("router-group-guid", 5222)))
+
+		assignment := snapshot.Endpoints.Items[emitter.XDSHTTPClusterName("foo.example.com")].(*v2.ClusterLoadAssignment)
+		Expect(assignment.Endpoints).To(HaveLen(1))
+		Expect(assignment.Endpoints[0].LbEndpoints).To(HaveLen(1))
+		address := assignment.Endpoints[0].LbEndpoints[0].Endpoint.Address.GetSocketAddress()
+		Expect(address.Address).To(Equal("1.1.1.1"))
+		Expect(address.GetPortValue()).To(BeEquivalentTo(61001))
+	})
+
+	It("routes each hostname through a virtual host, longest path first", func() {
+		snapshot := currentSnapshot()
+		Expect(snapshot.Routes.Items).To(HaveKey(emitter.XDSHTTPRouteConfigName))
+
+		routeConfig := snapshot.Routes.Items[emitter.XDSHTTPRouteConfigName].(*v2.RouteConfiguration)
+		Expect(routeConfig.VirtualHosts).To(HaveLen(1))
+		virtualHost := routeConfig.VirtualHosts[0]
+		Expect(virtualHost.Domains).To(ConsistOf("foo.example.com"))
+		Expect(virtualHost.Routes).To(HaveLen(2))
+		Expect(virtualHost.Routes[0].Match.GetPrefix()).To(Equal("/bar"))
+		Expect(virtualHost.Routes[0].GetRoute().GetCluster()).To(Equal(emitter.XDSHTTPClusterName("foo.example.com/bar")))
+		Expect(virtualHost.Routes[1].Match.GetPrefix()).To(Equal("/"))
+		Expect(virtualHost.Routes[1].GetRoute().GetCluster()).To(Equal(emitter.XDSHTTPClusterName("foo.example.com")))
+	})
+
+	It("publishes the http listener and a listener per tcp route", func() {
+		snapshot := currentSnapshot()
+		Expect(snapshot.Listeners.Items).To(HaveLen(2))
+		Expect(snapshot.Listeners.Items).To(HaveKey(emitter.XDSHTTPListenerName))
+		Expect(snapshot.Listeners.Items).To(HaveKey(emitter.XDSTCPClusterName("router-group-guid", 5222)))
+
+		httpListener := snapshot.Listeners.Items[emitter.XDSHTTPListenerName].(*v2.Listener)
+		Expect(httpListener.Address.GetSocketAddress().GetPortValue()).To(BeEquivalentTo(8080))
+	})
+
+	Context("when the same registrations are emitted again", func() {
+		It("does not bump the version", func() {
+			err := xdsEmitter.Emit(routingtable.MessagesToEmit{
+				RegistrationMessages: []routingtable.RegistryMessage{fooMessage},
+			}, routingtable.TCPRouteMappings{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(currentSnapshot().Clusters.Version).To(Equal("1"))
+		})
+	})
+
+	Context("when routes are unregistered", func() {
+		BeforeEach(func() {
+			err := xdsEmitter.Emit(routingtable.MessagesToEmit{
+				UnregistrationMessages: []routingtable.RegistryMessage{barMessage},
+			}, routingtable.TCPRouteMappings{
+				Unregistrations: []apimodels.TcpRouteMapping{tcpMapping},
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("removes the resources in a new version", func() {
+			snapshot := currentSnapshot()
+			Expect(snapshot.Clusters.Version).To(Equal("2"))
+			Expect(snapshot.Clusters.Items).To(HaveLen(1))
+			Expect(snapshot.Clusters.Items).To(HaveKey(emitter.XDSHTTPClusterName("foo.example.com")))
+			Expect(snapshot.Listeners.Items).To(HaveLen(1))
+		})
+	})
+
+	Context("when only internal routes are emitted", func() {
+		It("ignores them", func() {
+			err := xdsEmitter.Emit(routingtable.MessagesToEmit{
+				InternalRegistrationMessages: []routingtable.RegistryMessage{
+					{Host: "10.0.0.1", URIs: []string{"foo.apps.internal"}},
+				},
+			}, routingtable.TCPRouteMappings{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(currentSnapshot().Clusters.Version).To(Equal("1"))
+		})
+	})
+})
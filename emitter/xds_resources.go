@@ -0,0 +1,266 @@
+package emitter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	"github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	"github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
+	"github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
+	"github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	tcpproxy "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/tcp_proxy/v2"
+	"github.com/envoyproxy/go-control-plane/pkg/cache"
+	"github.com/envoyproxy/go-control-plane/pkg/util"
+)
+
+const (
+	XDSHTTPListenerName    = "http"
+	XDSHTTPRouteConfigName = "http"
+
+	xdsConnectTimeout = 5 * time.Second
+)
+
+func XDSHTTPClusterName(uri string) string {
+	return "http:" + uri
+}
+
+func XDSTCPClusterName(routerGroupGUID string, externalPort uint32) string {
+	return fmt.Sprintf("tcp:%s:%d", routerGroupGUID, externalPort)
+}
+
+// snapshot renders the current routes. Every resource list is sorted so that
+// two emitters holding the same routes produce identical snapshots.
+func (e *xdsEmitter) snapshot(version string) (cache.Snapshot, error) {
+	var (
+		endpoints []cache.Resource
+		clusters  []cache.Resource
+		routes    []cache.Resource
+		listeners []cache.Resource
+	)
+
+	uris := make([]string, 0, len(e.httpRoutes))
+	for uri := range e.httpRoutes {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+
+	for _, uri := range uris {
+		name := XDSHTTPClusterName(uri)
+		clusters = append(clusters, xdsCluster(name))
+		endpoints = append(endpoints, xdsLoadAssignment(name, e.httpRoutes[uri]))
+	}
+
+	if len(uris) > 0 {
+		httpListener, err := xdsHTTPListener(e.httpListenerPort)
+		if err != nil {
+			return cache.Snapshot{}, err
+		}
+		listeners = append(listeners, httpListener)
+		routes = append(routes, xdsRouteConfiguration(uris))
+	}
+
+	tcpKeys := make([]xdsTCPRouteKey, 0, len(e.tcpRoutes))
+	for key := range e.tcpRoutes {
+		tcpKeys = append(tcpKeys, key)
+	}
+	sort.Slice(tcpKeys, func(i, j int) bool {
+		if tcpKeys[i].routerGroupGUID != tcpKeys[j].routerGroupGUID {
+			return tcpKeys[i].routerGroupGUID < tcpKeys[j].routerGroupGUID
+		}
+		return tcpKeys[i].externalPort < tcpKeys[j].externalPort
+	})
+
+	for _, key := range tcpKeys {
+		name := XDSTCPClusterName(key.routerGroupGUID, key.externalPort)
+		clusters = append(clusters, xdsCluster(name))
+		endpoints = append(endpoints, xdsLoadAssignment(name, e.tcpRoutes[key]))
+
+		tcpListener, err := xdsTCPListener(name, key.externalPort)
+		if err != nil {
+			return cache.Snapshot{}, err
+		}
+		listeners = append(listeners, tcpListener)
+	}
+
+	return cache.NewSnapshot(version, endpoints, clusters, routes, listeners), nil
+}
+
+func xdsAdsConfigSource() core.ConfigSource {
+	return core.ConfigSource{
+		ConfigSourceSpecifier: &core.ConfigSource_Ads{
+			Ads: &core.AggregatedConfigSource{},
+		},
+	}
+}
+
+func xdsSocketAddress(host string, port uint32) core.Address {
+	return core.Address{
+		Address: &core.Address_SocketAddress{
+			SocketAddress: &core.SocketAddress{
+				Protocol: core.TCP,
+				Address:  host,
+				PortSpecifier: &core.SocketAddress_PortValue{
+					PortValue: port,
+				},
+			},
+		},
+	}
+}
+
+func xdsCluster(name string) *v2.Cluster {
+	edsConfig := xdsAdsConfigSource()
+	return &v2.Cluster{
+		Name:           name,
+		ConnectTimeout: xdsConnectTimeout,
+		Type:           v2.Cluster_EDS,
+		EdsClusterConfig: &v2.Cluster_EdsClusterConfig{
+			EdsConfig: &edsConfig,
+		},
+	}
+}
+
+func xdsLoadAssignment(name string, endpoints xdsEndpoints) *v2.ClusterLoadAssignment {
+	sorted := make([]xdsEndpoint, 0, len(endpoints))
+	for e := range endpoints {
+		sorted = append(sorted, e)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].host != sorted[j].host {
+			return sorted[i].host < sorted[j].host
+		}
+		return sorted[i].port < sorted[j].port
+	})
+
+	lbEndpoints := make([]endpoint.LbEndpoint, 0, len(sorted))
+	for _, e := range sorted {
+		address := xdsSocketAddress(e.host, e.port)
+		lbEndpoints = append(lbEndpoints, endpoint.LbEndpoint{
+			Endpoint: &endpoint.Endpoint{
+				Address: &address,
+			},
+		})
+	}
+
+	return &v2.ClusterLoadAssignment{
+		ClusterName: name,
+		Endpoints: []endpoint.LocalityLbEndpoints{
+			{LbEndpoints: lbEndpoints},
+		},
+	}
+}
+
+// xdsRouteConfiguration groups the uris by hostname into virtual hosts. A uri
+// with a context path becomes a prefix match, ordered before the shorter
+// prefixes of the same host.
+func xdsRouteConfiguration(uris []string) *v2.RouteConfiguration {
+	paths := map[string][]string{}
+	hostnames := []string{}
+	for _, uri := range uris {
+		hostname, path := uri, "/"
+		if i := strings.Index(uri, "/"); i >= 0 {
+			hostname, path = uri[:i], uri[i:]
+		}
+		if _, ok := paths[hostname]; !ok {
+			hostnames = append(hostnames, hostname)
+		}
+		paths[hostname] = append(paths[hostname], path)
+	}
+	sort.Strings(hostnames)
+
+	virtualHosts := make([]route.VirtualHost, 0, len(hostnames))
+	for _, hostname := range hostnames {
+		hostPaths := paths[hostname]
+		sort.Slice(hostPaths, func(i, j int) bool {
+			return len(hostPaths[i]) > len(hostPaths[j])
+		})
+
+		routes := make([]route.Route, 0, len(hostPaths))
+		for _, path := range hostPaths {
+			uri := hostname
+			if path != "/" {
+				uri = hostname + path
+			}
+			routes = append(routes, route.Route{
+				Match: route.RouteMatch{
+					PathSpecifier: &route.RouteMatch_Prefix{Prefix: path},
+				},
+				Action: &route.Route_Route{
+					Route: &route.RouteAction{
+						ClusterSpecifier: &route.RouteAction_Cluster{
+							Cluster: XDSHTTPClusterName(uri),
+						},
+					},
+				},
+			})
+		}
+
+		virtualHosts = append(virtualHosts, route.VirtualHost{
+			Name:    hostname,
+			Domains: []string{hostname},
+			Routes:  routes,
+		})
+	}
+
+	return &v2.RouteConfiguration{
+		Name:         XDSHTTPRouteConfigName,
+		VirtualHosts: virtualHosts,
+	}
+}
+
+func xdsHTTPListener(port uint32) (*v2.Listener, error) {
+	manager := &hcm.HttpConnectionManager{
+		StatPrefix: XDSHTTPListenerName,
+		RouteSpecifier: &hcm.HttpConnectionManager_Rds{
+			Rds: &hcm.Rds{
+				ConfigSource:    xdsAdsConfigSource(),
+				RouteConfigName: XDSHTTPRouteConfigName,
+			},
+		},
+		HttpFilters: []*hcm.HttpFilter{{
+			Name: util.Router,
+		}},
+	}
+
+	config, err := util.MessageToStruct(manager)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v2.Listener{
+		Name:    XDSHTTPListenerName,
+		Address: xdsSocketAddress("0.0.0.0", port),
+		FilterChains: []listener.FilterChain{{
+			Filters: []listener.Filter{{
+				Name:   util.HTTPConnectionManager,
+				Config: config,
+			}},
+		}},
+	}, nil
+}
+
+func xdsTCPListener(clusterName string, port uint32) (*v2.Listener, error) {
+	proxy := &tcpproxy.TcpProxy{
+		StatPrefix: clusterName,
+		Cluster:    clusterName,
+	}
+
+	config, err := util.MessageToStruct(proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v2.Listener{
+		Name:    clusterName,
+		Address: xdsSocketAddress("0.0.0.0", port),
+		FilterChains: []listener.FilterChain{{
+			Filters: []listener.Filter{{
+				Name:   util.TCPProxy,
+				Config: config,
+			}},
+		}},
+	}, nil
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"time"
+
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	loggregator "code.cloudfoundry.org/go-loggregator"
+)
+
+type loggregatorSink struct {
+	client loggingclient.IngressClient
+}
+
+// NewLoggregatorSink forwards metrics to metron. Gauges and durations carry
+// their labels as envelope tags. Counters cannot be tagged, they are sent
+// under their name alone, summed up over their labels.
+func NewLoggregatorSink(client loggingclient.IngressClient) Sink {
+	return &loggregatorSink{client: client}
+}
+
+func (s *loggregatorSink) IncrementCounter(name string, _ Labels) error {
+	return s.client.IncrementCounter(name)
+}
+
+func (s *loggregatorSink) IncrementCounterWithDelta(name string, delta uint64, _ Labels) error {
+	return s.client.IncrementCounterWithDelta(name, delta)
+}
+
+func (s *loggregatorSink) SendMetric(name string, value int, labels Labels) error {
+	if len(labels) == 0 {
+		return s.client.SendMetric(name, value)
+	}
+	return s.client.SendMetric(name, value, loggregator.WithEnvelopeTags(labels))
+}
+
+func (s *loggregatorSink) SendDuration(name string, duration time.Duration, labels Labels) error {
+	if len(labels) == 0 {
+		return s.client.SendDuration(name, duration)
+	}
+	return s.client.SendDuration(name, duration, loggregator.WithEnvelopeTags(labels))
+}
+
+type sinkIngressClient struct {
+	sink Sink
+}
+
+// NewSinkIngressClient adapts sink for the libraries reporting their metrics
+// to metron themselves, such as the consul lock. Only gauges, durations and
+// counters are forwarded, their envelope options are dropped, everything else
+// is discarded.
+func NewSinkIngressClient(sink Sink) loggingclient.IngressClient {
+	return &sinkIngressClient{sink: sink}
+}
+
+func (c *sinkIngressClient) SendDuration(name string, value time.Duration, _ ...loggregator.EmitGaugeOption) error {
+	return c.sink.SendDuration(name, value, nil)
+}
+
+func (c *sinkIngressClient) SendMebiBytes(name string, value int, _ ...loggregator.EmitGaugeOption) error {
+	return c.sink.SendMetric(name, value, nil)
+}
+
+func (c *sinkIngressClient) SendMetric(name string, value int, _ ...loggregator.EmitGaugeOption) error {
+	return c.sink.SendMetric(name, value, nil)
+}
+
+func (c *sinkIngressClient) SendBytesPerSecond(name string, value float64) error {
+	return c.sink.SendMetric(name, int(value), nil)
+}
+
+func (c *sinkIngressClient) SendRequestsPerSecond(name string, value float64) error {
+	return c.sink.SendMetric(name, int(value), nil)
+}
+
+func (c *sinkIngressClient) IncrementCounter(name string) error {
+	return c.sink.IncrementCounter(name, nil)
+}
+
+func (c *sinkIngressClient) IncrementCounterWithDelta(name string, value uint64) error {
+	return c.sink.IncrementCounterWithDelta(name, value, nil)
+}
+
+func (c *sinkIngressClient) SendAppLog(string, string, map[string]string) error {
+	return nil
+}
+
+func (c *sinkIngressClient) SendAppErrorLog(string, string, map[string]string) error {
+	return nil
+}
+
+func (c *sinkIngressClient) SendAppMetrics(loggingclient.ContainerMetric) error {
+	return nil
+}
+
+func (c *sinkIngressClient) SendSpikeMetrics(loggingclient.SpikeMetric) error {
+	return nil
+}
+
+func (c *sinkIngressClient) SendComponentMetric(name string, value float64, _ string) error {
+	return c.sink.SendMetric(name, int(value), nil)
+}
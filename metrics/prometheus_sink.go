@@ -0,0 +1,157 @@
+package metrics
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const prometheusNamespace = "route_emitter"
+
+// sync durations range from milliseconds for an empty deployment to minutes
+// for a large one
+var durationBuckets = prometheus.ExponentialBuckets(0.01, 2, 15)
+
+type prometheusSink struct {
+	registerer prometheus.Registerer
+
+	lock       sync.Mutex
+	counters   map[string]*prometheus.CounterVec
+	gauges     map[string]*prometheus.GaugeVec
+	histograms map[string]*prometheus.HistogramVec
+}
+
+// NewPrometheusSink registers a collector in registerer the first time a
+// metric is sent. Counters become <name>_total, durations become
+// <name>_duration_seconds histograms and everything else a gauge, all in snake
+// case under the route_emitter namespace. A metric must always be sent with the
+// same label names.
+func NewPrometheusSink(registerer prometheus.Registerer) Sink {
+	return &prometheusSink{
+		registerer: registerer,
+		counters:   map[string]*prometheus.CounterVec{},
+		gauges:     map[string]*prometheus.GaugeVec{},
+		histograms: map[string]*prometheus.HistogramVec{},
+	}
+}
+
+func (s *prometheusSink) IncrementCounter(name string, labels Labels) error {
+	return s.IncrementCounterWithDelta(name, 1, labels)
+}
+
+func (s *prometheusSink) IncrementCounterWithDelta(name string, delta uint64, labels Labels) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	counter, ok := s.counters[name]
+	if !ok {
+		counter = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Name:      prometheusName(name) + "_total",
+			Help:      name,
+		}, labelNames(labels))
+		if err := s.registerer.Register(counter); err != nil {
+			return err
+		}
+		s.counters[name] = counter
+	}
+
+	c, err := counter.GetMetricWith(prometheus.Labels(labels))
+	if err != nil {
+		return err
+	}
+	c.Add(float64(delta))
+	return nil
+}
+
+func (s *prometheusSink) SendMetric(name string, value int, labels Labels) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	gauge, ok := s.gauges[name]
+	if !ok {
+		gauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Name:      prometheusName(name),
+			Help:      name,
+		}, labelNames(labels))
+		if err := s.registerer.Register(gauge); err != nil {
+			return err
+		}
+		s.gauges[name] = gauge
+	}
+
+	g, err := gauge.GetMetricWith(prometheus.Labels(labels))
+	if err != nil {
+		return err
+	}
+	g.Set(float64(value))
+	return nil
+}
+
+func (s *prometheusSink) SendDuration(name string, duration time.Duration, labels Labels) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	histogram, ok := s.histograms[name]
+	if !ok {
+		histogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: prometheusNamespace,
+			Name:      strings.TrimSuffix(prometheusName(name), "_duration") + "_duration_seconds",
+			Help:      name,
+			Buckets:   durationBuckets,
+		}, labelNames(labels))
+		if err := s.registerer.Register(histogram); err != nil {
+			return err
+		}
+		s.histograms[name] = histogram
+	}
+
+	h, err := histogram.GetMetricWith(prometheus.Labels(labels))
+	if err != nil {
+		return err
+	}
+	h.Observe(duration.Seconds())
+	return nil
+}
+
+func labelNames(labels Labels) []string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// prometheusName converts a metron metric name such as
+// HTTPRouteNATSMessagesEmitted to http_route_nats_messages_emitted. A leading
+// RouteEmitter is dropped as it would repeat the namespace, and characters
+// prometheus does not allow, such as the dots of LockHeld.route_emitter_lock,
+// are replaced with underscores.
+func prometheusName(name string) string {
+	name = strings.TrimPrefix(name, "RouteEmitter")
+	runes := []rune(name)
+
+	var b bytes.Buffer
+	for i, r := range runes {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			b.WriteRune('_')
+			continue
+		}
+		if i > 0 && unicode.IsUpper(r) {
+			previous := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
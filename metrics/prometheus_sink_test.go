@@ -0,0 +1,93 @@
+package metrics_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/route-emitter/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PrometheusSink", func() {
+	var (
+		registry *prometheus.Registry
+		sink     metrics.Sink
+	)
+
+	gather := func(name string) *dto.MetricFamily {
+		families, err := registry.Gather()
+		Expect(err).NotTo(HaveOccurred())
+		for _, family := range families {
+			if family.GetName() == name {
+				return family
+			}
+		}
+		Fail("metric " + name + " was not gathered")
+		return nil
+	}
+
+	labelValue := func(metric *dto.Metric, name string) string {
+		for _, label := range metric.GetLabel() {
+			if label.GetName() == name {
+				return label.GetValue()
+			}
+		}
+		return ""
+	}
+
+	BeforeEach(func() {
+		registry = prometheus.NewRegistry()
+		sink = metrics.NewPrometheusSink(registry)
+	})
+
+	It("exposes counters per label value", func() {
+		Expect(sink.IncrementCounterWithDelta("HTTPRouteNATSMessagesEmitted", 3, metrics.Labels{metrics.SubjectLabel: "router.register"})).To(Succeed())
+		Expect(sink.IncrementCounterWithDelta("HTTPRouteNATSMessagesEmitted", 2, metrics.Labels{metrics.SubjectLabel: "router.register"})).To(Succeed())
+		Expect(sink.IncrementCounter("HTTPRouteNATSMessagesEmitted", metrics.Labels{metrics.SubjectLabel: "router.unregister"})).To(Succeed())
+
+		family := gather("route_emitter_http_route_nats_messages_emitted_total")
+		Expect(family.GetType()).To(Equal(dto.MetricType_COUNTER))
+		Expect(family.GetMetric()).To(HaveLen(2))
+
+		values := map[string]float64{}
+		for _, metric := range family.GetMetric() {
+			values[labelValue(metric, metrics.SubjectLabel)] = metric.GetCounter().GetValue()
+		}
+		Expect(values).To(Equal(map[string]float64{"router.register": 5, "router.unregister": 1}))
+	})
+
+	It("exposes gauges", func() {
+		Expect(sink.SendMetric("RoutesTotal", 7, nil)).To(Succeed())
+		Expect(sink.SendMetric("RoutesTotal", 4, nil)).To(Succeed())
+
+		family := gather("route_emitter_routes_total")
+		Expect(family.GetType()).To(Equal(dto.MetricType_GAUGE))
+		Expect(family.GetMetric()[0].GetGauge().GetValue()).To(BeEquivalentTo(4))
+	})
+
+	It("exposes durations as histograms in seconds", func() {
+		Expect(sink.SendDuration("RouteEmitterSyncDuration", 1500*time.Millisecond, nil)).To(Succeed())
+
+		family := gather("route_emitter_sync_duration_seconds")
+		Expect(family.GetType()).To(Equal(dto.MetricType_HISTOGRAM))
+		Expect(family.GetMetric()[0].GetHistogram().GetSampleCount()).To(BeEquivalentTo(1))
+		Expect(family.GetMetric()[0].GetHistogram().GetSampleSum()).To(BeNumerically("~", 1.5))
+	})
+
+	It("replaces the characters prometheus does not allow in names", func() {
+		Expect(sink.SendMetric("LockHeld.route_emitter_lock", 1, nil)).To(Succeed())
+
+		family := gather("route_emitter_lock_held_route_emitter_lock")
+		Expect(family.GetMetric()[0].GetGauge().GetValue()).To(BeEquivalentTo(1))
+	})
+
+	Context("when a metric is sent with different label names", func() {
+		It("returns an error", func() {
+			Expect(sink.IncrementCounter("AddressCollisions", metrics.Labels{metrics.TableLabel: "http"})).To(Succeed())
+			Expect(sink.IncrementCounter("AddressCollisions", metrics.Labels{metrics.OutcomeLabel: "success"})).NotTo(Succeed())
+		})
+	})
+})
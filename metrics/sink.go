@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"time"
+)
+
+const (
+	TableLabel   = "table"
+	SubjectLabel = "subject"
+	OutcomeLabel = "outcome"
+	EmitterLabel = "emitter"
+	ReasonLabel  = "reason"
+	WorkerLabel  = "worker"
+
+	HTTPTable     = "http"
+	TCPTable      = "tcp"
+	InternalTable = "internal"
+
+	SuccessOutcome = "success"
+	FailureOutcome = "failure"
+)
+
+// Labels qualify a metric. A metric must remain meaningful when all of its
+// label values are summed up.
+type Labels map[string]string
+
+//go:generate counterfeiter -o fakes/fake_sink.go . Sink
+type Sink interface {
+	IncrementCounter(name string, labels Labels) error
+	IncrementCounterWithDelta(name string, delta uint64, labels Labels) error
+	SendMetric(name string, value int, labels Labels) error
+	SendDuration(name string, duration time.Duration, labels Labels) error
+}
+
+type multiSink []Sink
+
+// NewMultiSink sends every metric to all of the sinks. The first error is
+// returned once all sinks have been called.
+func NewMultiSink(sinks ...Sink) Sink {
+	return multiSink(sinks)
+}
+
+func (m multiSink) IncrementCounter(name string, labels Labels) error {
+	return m.each(func(sink Sink) error {
+		return sink.IncrementCounter(name, labels)
+	})
+}
+
+func (m multiSink) IncrementCounterWithDelta(name string, delta uint64, labels Labels) error {
+	return m.each(func(sink Sink) error {
+		return sink.IncrementCounterWithDelta(name, delta, labels)
+	})
+}
+
+func (m multiSink) SendMetric(name string, value int, labels Labels) error {
+	return m.each(func(sink Sink) error {
+		return sink.SendMetric(name, value, labels)
+	})
+}
+
+func (m multiSink) SendDuration(name string, duration time.Duration, labels Labels) error {
+	return m.each(func(sink Sink) error {
+		return sink.SendDuration(name, duration, labels)
+	})
+}
+
+func (m multiSink) each(send func(Sink) error) error {
+	var firstErr error
+	for _, sink := range m {
+		err := send(sink)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,141 @@
+package metrics_test
+
+import (
+	"errors"
+	"time"
+
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/route-emitter/metrics"
+	"code.cloudfoundry.org/route-emitter/metrics/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sink", func() {
+	Describe("LoggregatorSink", func() {
+		var (
+			fakeMetronClient *mfakes.FakeIngressClient
+			sink             metrics.Sink
+		)
+
+		BeforeEach(func() {
+			fakeMetronClient = &mfakes.FakeIngressClient{}
+			sink = metrics.NewLoggregatorSink(fakeMetronClient)
+		})
+
+		It("forwards every metric to metron under its own name", func() {
+			Expect(sink.IncrementCounter("EventStreamResubscriptions", nil)).To(Succeed())
+			Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("EventStreamResubscriptions"))
+
+			Expect(sink.SendMetric("RoutesTotal", 3, nil)).To(Succeed())
+			name, value, opts := fakeMetronClient.SendMetricArgsForCall(0)
+			Expect(name).To(Equal("RoutesTotal"))
+			Expect(value).To(Equal(3))
+			Expect(opts).To(BeEmpty())
+
+			Expect(sink.SendDuration("RouteEmitterSyncDuration", time.Second, nil)).To(Succeed())
+			name, duration, _ := fakeMetronClient.SendDurationArgsForCall(0)
+			Expect(name).To(Equal("RouteEmitterSyncDuration"))
+			Expect(duration).To(Equal(time.Second))
+		})
+
+		It("tags gauges and durations with their labels", func() {
+			labels := metrics.Labels{metrics.EmitterLabel: "nats"}
+
+			Expect(sink.SendMetric("RetryQueueDepth", 3, labels)).To(Succeed())
+			_, _, opts := fakeMetronClient.SendMetricArgsForCall(0)
+			Expect(opts).To(HaveLen(1))
+			envelope := &loggregator_v2.Envelope{Tags: map[string]string{}}
+			opts[0](envelope)
+			Expect(envelope.Tags).To(Equal(map[string]string{metrics.EmitterLabel: "nats"}))
+
+			Expect(sink.SendDuration("EventQueueLatency", time.Second, labels)).To(Succeed())
+			_, _, opts = fakeMetronClient.SendDurationArgsForCall(0)
+			Expect(opts).To(HaveLen(1))
+			envelope = &loggregator_v2.Envelope{Tags: map[string]string{}}
+			opts[0](envelope)
+			Expect(envelope.Tags).To(Equal(map[string]string{metrics.EmitterLabel: "nats"}))
+		})
+
+		It("keeps the original name of counters and drops their labels", func() {
+			Expect(sink.IncrementCounterWithDelta("NATSMessagesPublished", 2, metrics.Labels{
+				metrics.SubjectLabel: "router.register",
+				metrics.OutcomeLabel: metrics.SuccessOutcome,
+			})).To(Succeed())
+			name, delta := fakeMetronClient.IncrementCounterWithDeltaArgsForCall(0)
+			Expect(name).To(Equal("NATSMessagesPublished"))
+			Expect(delta).To(BeEquivalentTo(2))
+
+			Expect(sink.IncrementCounter("AddressCollisions", metrics.Labels{metrics.TableLabel: metrics.HTTPTable})).To(Succeed())
+			Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("AddressCollisions"))
+		})
+	})
+
+	Describe("SinkIngressClient", func() {
+		var (
+			fakeSink *fakes.FakeSink
+			client   loggingclient.IngressClient
+		)
+
+		BeforeEach(func() {
+			fakeSink = &fakes.FakeSink{}
+			client = metrics.NewSinkIngressClient(fakeSink)
+		})
+
+		It("forwards the metrics to the sink", func() {
+			Expect(client.SendMetric("LockHeld.route_emitter_lock", 1)).To(Succeed())
+			name, value, labels := fakeSink.SendMetricArgsForCall(0)
+			Expect(name).To(Equal("LockHeld.route_emitter_lock"))
+			Expect(value).To(Equal(1))
+			Expect(labels).To(BeNil())
+
+			Expect(client.SendDuration("LockHeldDuration.route_emitter_lock", time.Second)).To(Succeed())
+			name, duration, _ := fakeSink.SendDurationArgsForCall(0)
+			Expect(name).To(Equal("LockHeldDuration.route_emitter_lock"))
+			Expect(duration).To(Equal(time.Second))
+
+			Expect(client.IncrementCounterWithDelta("LocksExpired", 2)).To(Succeed())
+			name, delta, _ := fakeSink.IncrementCounterWithDeltaArgsForCall(0)
+			Expect(name).To(Equal("LocksExpired"))
+			Expect(delta).To(BeEquivalentTo(2))
+		})
+
+		It("returns the errors of the sink", func() {
+			fakeSink.SendMetricReturns(errors.New("boom"))
+			Expect(client.SendMetric("ConsulDownMode", 1)).To(MatchError("boom"))
+		})
+	})
+
+	Describe("MultiSink", func() {
+		var (
+			first, second *fakes.FakeSink
+			sink          metrics.Sink
+		)
+
+		BeforeEach(func() {
+			first = &fakes.FakeSink{}
+			second = &fakes.FakeSink{}
+			sink = metrics.NewMultiSink(first, second)
+		})
+
+		It("sends to every sink", func() {
+			Expect(sink.SendMetric("RoutesTotal", 3, nil)).To(Succeed())
+			Expect(first.SendMetricCallCount()).To(Equal(1))
+			Expect(second.SendMetricCallCount()).To(Equal(1))
+		})
+
+		Context("when a sink fails", func() {
+			BeforeEach(func() {
+				first.IncrementCounterWithDeltaReturns(errors.New("boom"))
+			})
+
+			It("still sends to the other sinks and returns the error", func() {
+				Expect(sink.IncrementCounterWithDelta("RoutesSynced", 1, nil)).To(MatchError("boom"))
+				Expect(second.IncrementCounterWithDeltaCallCount()).To(Equal(1))
+			})
+		})
+	})
+})
@@ -0,0 +1 @@
+package reloader // import "code.cloudfoundry.org/route-emitter/reloader"
@@ -0,0 +1,104 @@
+package reloader
+
+import (
+	"errors"
+	"os"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/cmd/route-emitter/config"
+)
+
+var ErrRestartRequired = errors.New("changed settings are only applied on restart")
+
+// ApplyFunc applies the reloadable settings of next to the running route
+// emitter, current holds the settings it is running with.
+type ApplyFunc func(logger lager.Logger, current, next config.RouteEmitterConfig) error
+
+type Reloader struct {
+	logger     lager.Logger
+	configPath string
+	current    config.RouteEmitterConfig
+	reloads    <-chan os.Signal
+	apply      ApplyFunc
+}
+
+func NewReloader(
+	logger lager.Logger,
+	configPath string,
+	current config.RouteEmitterConfig,
+	reloads <-chan os.Signal,
+	apply ApplyFunc,
+) *Reloader {
+	return &Reloader{
+		logger:     logger.Session("reloader"),
+		configPath: configPath,
+		current:    current,
+		reloads:    reloads,
+		apply:      apply,
+	}
+}
+
+// Run re-reads the config file every time a signal arrives on reloads.
+// Settings that cannot be changed while running are logged and ignored, the
+// rest are applied by apply.
+func (r *Reloader) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	close(ready)
+	r.logger.Info("started")
+
+	for {
+		select {
+		case <-r.reloads:
+			r.reload()
+		case <-signals:
+			r.logger.Info("stopping")
+			return nil
+		}
+	}
+}
+
+func (r *Reloader) reload() {
+	logger := r.logger.Session("reload", lager.Data{"config-path": r.configPath})
+	logger.Info("starting")
+	defer logger.Info("finished")
+
+	next, err := config.NewRouteEmitterConfig(r.configPath)
+	if err != nil {
+		logger.Error("failed-to-parse-config", err)
+		return
+	}
+
+	err = next.Validate()
+	if err != nil {
+		logger.Error("invalid-config", err)
+		return
+	}
+
+	reloadable := []string{}
+	restartRequired := []string{}
+	for _, field := range config.ChangedFields(r.current, next) {
+		if config.IsReloadable(field) {
+			reloadable = append(reloadable, field)
+		} else {
+			restartRequired = append(restartRequired, field)
+		}
+	}
+
+	if len(restartRequired) > 0 {
+		logger.Error("rejected-settings", ErrRestartRequired, lager.Data{"fields": restartRequired})
+	}
+
+	if len(reloadable) == 0 {
+		logger.Info("nothing-to-apply")
+		return
+	}
+
+	updated := r.current.WithReloadable(next)
+	err = r.apply(logger, r.current, updated)
+	if err != nil {
+		logger.Error("failed-to-apply-settings", err, lager.Data{"fields": reloadable})
+		return
+	}
+
+	r.current = updated
+	logger.Info("applied-settings", lager.Data{"fields": reloadable})
+}
@@ -0,0 +1,169 @@
+package reloader_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"syscall"
+	"time"
+
+	"code.cloudfoundry.org/durationjson"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/cmd/route-emitter/config"
+	"code.cloudfoundry.org/route-emitter/reloader"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Reloader", func() {
+	type applied struct {
+		current config.RouteEmitterConfig
+		next    config.RouteEmitterConfig
+	}
+
+	var (
+		logger     *lagertest.TestLogger
+		configPath string
+		current    config.RouteEmitterConfig
+		reloads    chan os.Signal
+		applyCalls chan applied
+		applyErr   error
+		process    ifrit.Process
+	)
+
+	writeConfig := func(data string) {
+		Expect(ioutil.WriteFile(configPath, []byte(data), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+
+		configFile, err := ioutil.TempFile("", "route-emitter-config")
+		Expect(err).NotTo(HaveOccurred())
+		configPath = configFile.Name()
+		Expect(configFile.Close()).To(Succeed())
+
+		current = config.DefaultRouteEmitterConfig()
+		current.CellID = "cell-id"
+		reloads = make(chan os.Signal, 1)
+		applyCalls = make(chan applied, 1)
+		applyErr = nil
+	})
+
+	JustBeforeEach(func() {
+		apply := func(logger lager.Logger, current, next config.RouteEmitterConfig) error {
+			applyCalls <- applied{current: current, next: next}
+			return applyErr
+		}
+		process = ifrit.Invoke(reloader.NewReloader(logger, configPath, current, reloads, apply))
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+		os.Remove(configPath)
+	})
+
+	Context("when reloadable settings change", func() {
+		BeforeEach(func() {
+			writeConfig(`{"cell_id": "cell-id", "sync_interval": "10s", "log_level": "debug"}`)
+		})
+
+		It("applies them", func() {
+			reloads <- syscall.SIGHUP
+
+			var call applied
+			Eventually(applyCalls).Should(Receive(&call))
+			Expect(call.current).To(Equal(current))
+			Expect(call.next.SyncInterval).To(Equal(durationjson.Duration(10 * time.Second)))
+			Expect(call.next.LogLevel).To(Equal("debug"))
+			Eventually(logger).Should(gbytes.Say("applied-settings"))
+		})
+
+		It("applies later reloads on top of them", func() {
+			reloads <- syscall.SIGHUP
+			Eventually(applyCalls).Should(Receive())
+
+			writeConfig(`{"cell_id": "cell-id", "sync_interval": "20s", "log_level": "debug"}`)
+			reloads <- syscall.SIGHUP
+
+			var call applied
+			Eventually(applyCalls).Should(Receive(&call))
+			Expect(call.current.SyncInterval).To(Equal(durationjson.Duration(10 * time.Second)))
+			Expect(call.next.SyncInterval).To(Equal(durationjson.Duration(20 * time.Second)))
+		})
+
+		Context("when applying fails", func() {
+			BeforeEach(func() {
+				applyErr = errors.New("boom")
+			})
+
+			It("logs the error and retries on the next reload", func() {
+				reloads <- syscall.SIGHUP
+				Eventually(applyCalls).Should(Receive())
+				Eventually(logger).Should(gbytes.Say("failed-to-apply-settings.*boom"))
+
+				reloads <- syscall.SIGHUP
+				var call applied
+				Eventually(applyCalls).Should(Receive(&call))
+				Expect(call.current).To(Equal(current))
+			})
+		})
+	})
+
+	Context("when settings that need a restart change", func() {
+		BeforeEach(func() {
+			writeConfig(`{"cell_id": "other-cell-id", "sync_interval": "10s"}`)
+		})
+
+		It("rejects them and applies the rest", func() {
+			reloads <- syscall.SIGHUP
+
+			var call applied
+			Eventually(applyCalls).Should(Receive(&call))
+			Expect(call.next.CellID).To(Equal("cell-id"))
+			Expect(call.next.SyncInterval).To(Equal(durationjson.Duration(10 * time.Second)))
+			Eventually(logger).Should(gbytes.Say(`rejected-settings.*"fields":\["cell_id"\]`))
+		})
+	})
+
+	Context("when nothing reloadable changed", func() {
+		BeforeEach(func() {
+			writeConfig(`{"cell_id": "cell-id"}`)
+		})
+
+		It("does not apply anything", func() {
+			reloads <- syscall.SIGHUP
+			Eventually(logger).Should(gbytes.Say("nothing-to-apply"))
+			Consistently(applyCalls).ShouldNot(Receive())
+		})
+	})
+
+	Context("when the new config is invalid", func() {
+		BeforeEach(func() {
+			writeConfig(`{"cell_id": "cell-id", "route_emitting_workers": -1}`)
+		})
+
+		It("keeps the current settings", func() {
+			reloads <- syscall.SIGHUP
+			Eventually(logger).Should(gbytes.Say("invalid-config.*route_emitting_workers"))
+			Consistently(applyCalls).ShouldNot(Receive())
+		})
+	})
+
+	Context("when the config file cannot be parsed", func() {
+		BeforeEach(func() {
+			writeConfig(`{`)
+		})
+
+		It("keeps the current settings", func() {
+			reloads <- syscall.SIGHUP
+			Eventually(logger).Should(gbytes.Say("failed-to-parse-config"))
+			Consistently(applyCalls).ShouldNot(Receive())
+		})
+	})
+})
@@ -2,11 +2,13 @@ package routehandlers
 
 import (
 	"errors"
+	"sync"
 
 	"code.cloudfoundry.org/bbs/models"
-	loggingclient "code.cloudfoundry.org/diego-logging-client"
 	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/auditlog"
 	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/metrics"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/route-emitter/watcher"
 )
@@ -24,19 +26,53 @@ type Handler struct {
 	routingTable      routingtable.RoutingTable
 	natsEmitter       emitter.NATSEmitter
 	routingAPIEmitter emitter.RoutingAPIEmitter
+	xdsEmitter        emitter.XDSEmitter
 	localMode         bool
-	metronClient      loggingclient.IngressClient
+	ignoreRoutability bool
+	shardFilter       watcher.ShardFilter
+	metricsSink       metrics.Sink
+	auditLog          auditlog.Recorder
+	synced            bool // a full sync completed, broadcasts hold every route
+
+	filterLock  sync.RWMutex
+	routeFilter RouteFilter
+	excluded    map[string]*models.DesiredLRPSchedulingInfo // desired lrps filtered out, by process guid
 }
 
 var _ watcher.RouteHandler = new(Handler)
 
-func NewHandler(routingTable routingtable.RoutingTable, natsEmitter emitter.NATSEmitter, routingAPIEmitter emitter.RoutingAPIEmitter, localMode bool, metronClient loggingclient.IngressClient) *Handler {
+// NewHandler returns a handler keeping routingTable up to date. When
+// shardFilter is not nil the table only holds the process guids of owned
+// shards. Unless ignoreRoutability is set, running instances are only routed
+// while their actual lrp reports them routable. Every route change is recorded
+// to auditLog unless it is nil.
+func NewHandler(routingTable routingtable.RoutingTable, natsEmitter emitter.NATSEmitter, routingAPIEmitter emitter.RoutingAPIEmitter, xdsEmitter emitter.XDSEmitter, localMode, ignoreRoutability bool, shardFilter watcher.ShardFilter, metricsSink metrics.Sink, auditLog auditlog.Recorder) *Handler {
 	return &Handler{
 		routingTable:      routingTable,
 		natsEmitter:       natsEmitter,
 		routingAPIEmitter: routingAPIEmitter,
+		xdsEmitter:        xdsEmitter,
 		localMode:         localMode,
-		metronClient:      metronClient,
+		ignoreRoutability: ignoreRoutability,
+		shardFilter:       shardFilter,
+		metricsSink:       metricsSink,
+		auditLog:          auditLog,
+		excluded:          map[string]*models.DesiredLRPSchedulingInfo{},
+	}
+}
+
+// SetRouteFilter restricts the routes emitted from now on. The routes already
+// emitted that routeFilter excludes are unregistered by the next sync, the
+// desired lrps filtered out so far stay filtered out unless routeFilter allows
+// them.
+func (handler *Handler) SetRouteFilter(routeFilter RouteFilter) {
+	handler.filterLock.Lock()
+	defer handler.filterLock.Unlock()
+	handler.routeFilter = routeFilter
+	for processGuid, lrp := range handler.excluded {
+		if routeFilter.AllowsDesired(lrp) {
+			delete(handler.excluded, processGuid)
+		}
 	}
 }
 
@@ -44,23 +80,53 @@ func (handler *Handler) HandleEvent(logger lager.Logger, event models.Event) {
 	switch event := event.(type) {
 	case *models.DesiredLRPCreatedEvent:
 		desiredInfo := event.DesiredLrp.DesiredLRPSchedulingInfo()
+		if !handler.allowsDesired(&desiredInfo) {
+			logger.Debug("skipping-filtered-desired-lrp", routingtable.DesiredLRPData(&desiredInfo))
+			return
+		}
 		handler.handleDesiredCreate(logger, &desiredInfo)
 	case *models.DesiredLRPChangedEvent:
 		before := event.Before.DesiredLRPSchedulingInfo()
 		after := event.After.DesiredLRPSchedulingInfo()
+		beforeAllowed := handler.filterAllowsDesired(&before)
+		if !handler.allowsDesired(&after) {
+			if !beforeAllowed {
+				logger.Debug("skipping-filtered-desired-lrp", routingtable.DesiredLRPData(&after))
+				return
+			}
+			// the routes of a process that just got filtered out are unregistered
+			handler.handleDesiredDelete(logger, &before)
+			return
+		}
 		handler.handleDesiredUpdate(logger, &before, &after)
 	case *models.DesiredLRPRemovedEvent:
 		desiredInfo := event.DesiredLrp.DesiredLRPSchedulingInfo()
+		if !handler.allowsDesired(&desiredInfo) {
+			logger.Debug("skipping-filtered-desired-lrp", routingtable.DesiredLRPData(&desiredInfo))
+			return
+		}
 		handler.handleDesiredDelete(logger, &desiredInfo)
 	case *models.ActualLRPCreatedEvent:
 		routingInfo := routingtable.NewActualLRPRoutingInfo(event.ActualLrpGroup)
+		if !handler.allowsActual(routingInfo) {
+			logger.Debug("skipping-filtered-actual-lrp", routingtable.ActualLRPData(routingInfo))
+			return
+		}
 		handler.handleActualCreate(logger, routingInfo)
 	case *models.ActualLRPChangedEvent:
 		before := routingtable.NewActualLRPRoutingInfo(event.Before)
 		after := routingtable.NewActualLRPRoutingInfo(event.After)
+		if !handler.allowsActual(after) {
+			logger.Debug("skipping-filtered-actual-lrp", routingtable.ActualLRPData(after))
+			return
+		}
 		handler.handleActualUpdate(logger, before, after)
 	case *models.ActualLRPRemovedEvent:
 		routingInfo := routingtable.NewActualLRPRoutingInfo(event.ActualLrpGroup)
+		if !handler.allowsActual(routingInfo) {
+			logger.Debug("skipping-filtered-actual-lrp", routingtable.ActualLRPData(routingInfo))
+			return
+		}
 		handler.handleActualDelete(logger, routingInfo)
 	default:
 		logger.Error("did-not-handle-unrecognizable-event", errors.New("unrecognizable-event"), lager.Data{"event-type": event.EventType()})
@@ -72,7 +138,12 @@ func (handler *Handler) EmitExternal(logger lager.Logger) {
 
 	logger.Info("emitting-nats-messages", lager.Data{"messages": messagesToEmit})
 	if handler.natsEmitter != nil {
-		err := handler.natsEmitter.Emit(messagesToEmit)
+		var err error
+		if broadcaster, ok := handler.natsEmitter.(emitter.BroadcastEmitter); ok && handler.synced {
+			err = broadcaster.EmitBroadcast(messagesToEmit)
+		} else {
+			err = handler.natsEmitter.Emit(messagesToEmit)
+		}
 		if err != nil {
 			logger.Error("failed-to-emit-nats-routes", err)
 		}
@@ -86,11 +157,18 @@ func (handler *Handler) EmitExternal(logger lager.Logger) {
 		}
 	}
 
-	err := handler.metronClient.IncrementCounterWithDelta(routesSyncedCounter, messagesToEmit.RouteRegistrationCount())
+	if handler.xdsEmitter != nil {
+		err := handler.xdsEmitter.Emit(messagesToEmit, routingEvents)
+		if err != nil {
+			logger.Error("failed-to-emit-xds-routes", err)
+		}
+	}
+
+	err := handler.metricsSink.IncrementCounterWithDelta(routesSyncedCounter, messagesToEmit.RouteRegistrationCount(), metrics.Labels{metrics.TableLabel: metrics.HTTPTable})
 	if err != nil {
 		logger.Error("failed-send-routes-synced-count-metric", err)
 	}
-	err = handler.metronClient.SendMetric(routesTotalMetric, handler.routingTable.HTTPAssociationsCount())
+	err = handler.metricsSink.SendMetric(routesTotalMetric, handler.routingTable.HTTPAssociationsCount(), metrics.Labels{metrics.TableLabel: metrics.HTTPTable})
 	if err != nil {
 		logger.Error("failed-to-send-total-route-count-metric", err)
 	}
@@ -108,6 +186,18 @@ func (handler *Handler) EmitInternal(logger lager.Logger) {
 	}
 }
 
+// EmitDrained unregisters the endpoints whose drain window elapsed.
+func (handler *Handler) EmitDrained(logger lager.Logger) {
+	messagesToEmit := handler.routingTable.UnregisterDrainedEndpoints()
+	if len(messagesToEmit.UnregistrationMessages) == 0 {
+		return
+	}
+
+	logger.Info("unregistering-drained-endpoints", lager.Data{"num-unregistration-messages": len(messagesToEmit.UnregistrationMessages)})
+	handler.emitMessages(logger, messagesToEmit, routingtable.TCPRouteMappings{})
+	handler.audit(auditlog.Entry{Source: auditlog.SourceDrain}, messagesToEmit, routingtable.TCPRouteMappings{})
+}
+
 func (handler *Handler) Sync(
 	logger lager.Logger,
 	desired []*models.DesiredLRPSchedulingInfo,
@@ -119,22 +209,41 @@ func (handler *Handler) Sync(
 	logger.Debug("starting")
 	defer logger.Debug("completed")
 
-	newTable := routingtable.NewRoutingTable(logger, false, handler.metronClient)
+	if handler.shardFilter != nil {
+		handler.releaseUnownedShards(logger)
+	}
+
+	newTable := routingtable.NewRoutingTable(logger, false, handler.metricsSink)
+
+	// the filtered out desired lrps are found again from scratch
+	handler.filterLock.Lock()
+	handler.excluded = map[string]*models.DesiredLRPSchedulingInfo{}
+	handler.filterLock.Unlock()
 
 	for _, lrp := range desired {
-		newTable.SetRoutes(nil, lrp)
+		if handler.owns(lrp.ProcessGuid) && handler.allowsDesired(lrp) {
+			newTable.SetRoutes(nil, lrp)
+		}
 	}
 
 	for _, lrp := range actuals {
-		newTable.AddEndpoint(lrp)
+		if handler.owns(lrp.ActualLRP.ProcessGuid) && handler.allowsActual(lrp) {
+			newTable.AddEndpoint(lrp)
+		}
 	}
 
 	natsEmitter := handler.natsEmitter
 	routingAPIEmitter := handler.routingAPIEmitter
+	xdsEmitter := handler.xdsEmitter
+	auditLog := handler.auditLog
 	table := handler.routingTable
 
+	// the cached events are part of the sync, their changes are audited with
+	// it
 	handler.natsEmitter = nil
 	handler.routingAPIEmitter = nil
+	handler.xdsEmitter = nil
+	handler.auditLog = nil
 	handler.routingTable = newTable
 
 	for _, event := range cachedEvents {
@@ -144,6 +253,8 @@ func (handler *Handler) Sync(
 	handler.routingTable = table
 	handler.natsEmitter = natsEmitter
 	handler.routingAPIEmitter = routingAPIEmitter
+	handler.xdsEmitter = xdsEmitter
+	handler.auditLog = auditLog
 
 	routeMappings, messages := handler.routingTable.Swap(newTable, domains)
 	logger.Debug("start-emitting-messages", lager.Data{
@@ -153,6 +264,8 @@ func (handler *Handler) Sync(
 		"num-internal-unregistration-messages": len(messages.InternalUnregistrationMessages),
 	})
 	handler.emitMessages(logger, messages, routeMappings)
+	handler.audit(auditlog.Entry{Source: auditlog.SourceSync}, messages, routeMappings)
+	handler.synced = true
 	logger.Debug("done-emitting-messages", lager.Data{
 		"num-registration-messages":            len(messages.RegistrationMessages),
 		"num-unregistration-messages":          len(messages.UnregistrationMessages),
@@ -161,26 +274,133 @@ func (handler *Handler) Sync(
 	})
 
 	if handler.localMode {
-		err := handler.metronClient.SendMetric(httpRouteCount, handler.routingTable.HTTPAssociationsCount())
+		err := handler.metricsSink.SendMetric(httpRouteCount, handler.routingTable.HTTPAssociationsCount(), metrics.Labels{metrics.TableLabel: metrics.HTTPTable})
 		if err != nil {
 			logger.Error("failed-to-send-http-routes-count-metric", err)
 		}
-		err = handler.metronClient.SendMetric(tcpRouteCount, handler.routingTable.TCPAssociationsCount())
+		err = handler.metricsSink.SendMetric(tcpRouteCount, handler.routingTable.TCPAssociationsCount(), metrics.Labels{metrics.TableLabel: metrics.TCPTable})
 		if err != nil {
 			logger.Error("failed-to-send-tcp-route-count-metric", err)
 		}
 	}
 }
 
+// SyncProcesses rebuilds the entries of processGuids from desired and actuals
+// and swaps them into the routing table, the entries of every other process
+// guid are left as they are.
+func (handler *Handler) SyncProcesses(
+	logger lager.Logger,
+	processGuids []string,
+	desired []*models.DesiredLRPSchedulingInfo,
+	actuals []*routingtable.ActualLRPRoutingInfo,
+	domains models.DomainSet,
+) {
+	logger = logger.Session("sync-processes", lager.Data{"num-process-guids": len(processGuids)})
+	logger.Debug("starting")
+	defer logger.Debug("completed")
+
+	if handler.shardFilter != nil {
+		handler.releaseUnownedShards(logger)
+	}
+
+	newTable := routingtable.NewRoutingTable(logger, false, handler.metricsSink)
+
+	handler.filterLock.Lock()
+	for _, guid := range processGuids {
+		delete(handler.excluded, guid)
+	}
+	handler.filterLock.Unlock()
+
+	for _, lrp := range desired {
+		if handler.owns(lrp.ProcessGuid) && handler.allowsDesired(lrp) {
+			newTable.SetRoutes(nil, lrp)
+		}
+	}
+
+	for _, lrp := range actuals {
+		if handler.owns(lrp.ActualLRP.ProcessGuid) && handler.allowsActual(lrp) {
+			newTable.AddEndpoint(lrp)
+		}
+	}
+
+	routeMappings, messages := handler.routingTable.SwapProcesses(newTable, processGuids, domains)
+	logger.Debug("start-emitting-messages", lager.Data{
+		"num-registration-messages":            len(messages.RegistrationMessages),
+		"num-unregistration-messages":          len(messages.UnregistrationMessages),
+		"num-internal-registration-messages":   len(messages.InternalRegistrationMessages),
+		"num-internal-unregistration-messages": len(messages.InternalUnregistrationMessages),
+	})
+	handler.emitMessages(logger, messages, routeMappings)
+	handler.audit(auditlog.Entry{Source: auditlog.SourceSync}, messages, routeMappings)
+}
+
+func (handler *Handler) owns(processGuid string) bool {
+	return handler.shardFilter == nil || handler.shardFilter.Owns(processGuid)
+}
+
+// releaseUnownedShards drops the entries of shards that moved to another
+// emitter without unregistering their routes, the new owner keeps them
+// registered.
+func (handler *Handler) releaseUnownedShards(logger lager.Logger) {
+	// the new owners of the shards keep their routes registered, the
+	// unregistrations are not emitted
+	_, messages := handler.routingTable.RemoveEntries(func(key routingtable.RoutingKey) bool {
+		return !handler.shardFilter.Owns(key.ProcessGUID)
+	})
+
+	if len(messages.UnregistrationMessages) == 0 {
+		return
+	}
+	logger.Info("released-unowned-shards", lager.Data{"num-unregistrations": len(messages.UnregistrationMessages)})
+}
+
 func (handler *Handler) RefreshDesired(logger lager.Logger, desiredInfo []*models.DesiredLRPSchedulingInfo) {
 	for _, desiredLRP := range desiredInfo {
+		if !handler.allowsDesired(desiredLRP) {
+			continue
+		}
 		routeMappings, messagesToEmit := handler.routingTable.SetRoutes(nil, desiredLRP)
 		handler.emitMessages(logger, messagesToEmit, routeMappings)
+		handler.audit(auditlog.Entry{Source: auditlog.SourceRefresh, ProcessGUID: desiredLRP.ProcessGuid}, messagesToEmit, routeMappings)
 	}
 }
 
+// ShouldRefreshDesired returns false for the actual lrps of filtered out
+// processes, their desired lrps are not routed anyway.
 func (handler *Handler) ShouldRefreshDesired(actual *routingtable.ActualLRPRoutingInfo) bool {
-	return !handler.routingTable.HasExternalRoutes(actual)
+	return handler.allowsActual(actual) && !handler.routingTable.HasExternalRoutes(actual)
+}
+
+// allowsDesired returns true if the route filter allows lrp and remembers the
+// process guid of lrp otherwise.
+func (handler *Handler) allowsDesired(lrp *models.DesiredLRPSchedulingInfo) bool {
+	handler.filterLock.Lock()
+	defer handler.filterLock.Unlock()
+
+	if !handler.routeFilter.AllowsDesired(lrp) {
+		handler.excluded[lrp.ProcessGuid] = lrp
+		return false
+	}
+	delete(handler.excluded, lrp.ProcessGuid)
+	return true
+}
+
+// filterAllowsDesired returns true if the route filter allows lrp without
+// remembering anything.
+func (handler *Handler) filterAllowsDesired(lrp *models.DesiredLRPSchedulingInfo) bool {
+	handler.filterLock.RLock()
+	defer handler.filterLock.RUnlock()
+	return handler.routeFilter.AllowsDesired(lrp)
+}
+
+func (handler *Handler) allowsActual(actual *routingtable.ActualLRPRoutingInfo) bool {
+	handler.filterLock.RLock()
+	defer handler.filterLock.RUnlock()
+
+	if _, excluded := handler.excluded[actual.ActualLRP.ProcessGuid]; excluded {
+		return false
+	}
+	return handler.routeFilter.AllowsActual(actual.ActualLRP)
 }
 
 func (handler *Handler) handleDesiredCreate(logger lager.Logger, desiredLRP *models.DesiredLRPSchedulingInfo) {
@@ -189,6 +409,7 @@ func (handler *Handler) handleDesiredCreate(logger lager.Logger, desiredLRP *mod
 	defer logger.Info("complete")
 	routeMappings, messagesToEmit := handler.routingTable.SetRoutes(nil, desiredLRP)
 	handler.emitMessages(logger, messagesToEmit, routeMappings)
+	handler.audit(eventEntry(models.EventTypeDesiredLRPCreated, desiredLRP.ProcessGuid, ""), messagesToEmit, routeMappings)
 }
 
 func (handler *Handler) handleDesiredUpdate(logger lager.Logger, before, after *models.DesiredLRPSchedulingInfo) {
@@ -201,6 +422,7 @@ func (handler *Handler) handleDesiredUpdate(logger lager.Logger, before, after *
 
 	routeMappings, messagesToEmit := handler.routingTable.SetRoutes(before, after)
 	handler.emitMessages(logger, messagesToEmit, routeMappings)
+	handler.audit(eventEntry(models.EventTypeDesiredLRPChanged, after.ProcessGuid, ""), messagesToEmit, routeMappings)
 }
 
 func (handler *Handler) handleDesiredDelete(logger lager.Logger, schedulingInfo *models.DesiredLRPSchedulingInfo) {
@@ -209,16 +431,18 @@ func (handler *Handler) handleDesiredDelete(logger lager.Logger, schedulingInfo
 	defer logger.Info("complete")
 	routeMappings, messagesToEmit := handler.routingTable.RemoveRoutes(schedulingInfo)
 	handler.emitMessages(logger, messagesToEmit, routeMappings)
+	handler.audit(eventEntry(models.EventTypeDesiredLRPRemoved, schedulingInfo.ProcessGuid, ""), messagesToEmit, routeMappings)
 }
 
 func (handler *Handler) handleActualCreate(logger lager.Logger, actualLRPInfo *routingtable.ActualLRPRoutingInfo) {
 	logger = logger.Session("handling-actual-create", routingtable.ActualLRPData(actualLRPInfo))
 	logger.Info("starting")
 	defer logger.Info("complete")
-	if actualLRPInfo.ActualLRP.State == models.ActualLRPStateRunning {
+	if actualLRPInfo.Routable(handler.ignoreRoutability) {
 		logger.Info("handler-adding-endpoint", lager.Data{"net_info": actualLRPInfo.ActualLRP.ActualLRPNetInfo})
 		routeMappings, messagesToEmit := handler.routingTable.AddEndpoint(actualLRPInfo)
 		handler.emitMessages(logger, messagesToEmit, routeMappings)
+		handler.audit(actualEventEntry(models.EventTypeActualLRPCreated, actualLRPInfo), messagesToEmit, routeMappings)
 	}
 }
 
@@ -233,26 +457,34 @@ func (handler *Handler) handleActualUpdate(logger lager.Logger, before, after *r
 	var (
 		messagesToEmit routingtable.MessagesToEmit
 		routeMappings  routingtable.TCPRouteMappings
+		changed        = after
 	)
 	switch {
-	case after.ActualLRP.State == models.ActualLRPStateRunning:
+	case after.Routable(handler.ignoreRoutability):
 		logger.Info("handler-adding-endpoint", lager.Data{"net_info": after.ActualLRP.ActualLRPNetInfo})
 		routeMappings, messagesToEmit = handler.routingTable.AddEndpoint(after)
-	case after.ActualLRP.State != models.ActualLRPStateRunning && before.ActualLRP.State == models.ActualLRPStateRunning:
+	// a running instance that crashes or stops being routable is unregistered
+	// right away, it cannot serve the requests a drain would send it
+	case before.ActualLRP.State == models.ActualLRPStateRunning:
 		logger.Info("handler-removing-endpoint", lager.Data{"net_info": before.ActualLRP.ActualLRPNetInfo})
 		routeMappings, messagesToEmit = handler.routingTable.RemoveEndpoint(before)
+		changed = before
 	}
 	handler.emitMessages(logger, messagesToEmit, routeMappings)
+	handler.audit(actualEventEntry(models.EventTypeActualLRPChanged, changed), messagesToEmit, routeMappings)
 }
 
 func (handler *Handler) handleActualDelete(logger lager.Logger, actualLRPInfo *routingtable.ActualLRPRoutingInfo) {
 	logger = logger.Session("handling-actual-delete", routingtable.ActualLRPData(actualLRPInfo))
 	logger.Info("starting")
 	defer logger.Info("complete")
+	// a running instance is only removed once it evacuated or stopped
+	// gracefully, its routes are drained
 	if actualLRPInfo.ActualLRP.State == models.ActualLRPStateRunning {
-		logger.Info("handler-removing-endpoint", lager.Data{"net_info": actualLRPInfo.ActualLRP.ActualLRPNetInfo})
-		routeMappings, messagesToEmit := handler.routingTable.RemoveEndpoint(actualLRPInfo)
+		logger.Info("handler-draining-endpoint", lager.Data{"net_info": actualLRPInfo.ActualLRP.ActualLRPNetInfo})
+		routeMappings, messagesToEmit := handler.routingTable.DrainEndpoint(actualLRPInfo)
 		handler.emitMessages(logger, messagesToEmit, routeMappings)
+		handler.audit(actualEventEntry(models.EventTypeActualLRPRemoved, actualLRPInfo), messagesToEmit, routeMappings)
 	}
 }
 
@@ -274,11 +506,11 @@ func (handler *Handler) emitMessages(logger lager.Logger, messagesToEmit routing
 		if err != nil {
 			logger.Error("failed-to-emit-http-routes", err)
 		}
-		err = handler.metronClient.IncrementCounterWithDelta(routesRegisteredCounter, messagesToEmit.RouteRegistrationCount())
+		err = handler.metricsSink.IncrementCounterWithDelta(routesRegisteredCounter, messagesToEmit.RouteRegistrationCount(), metrics.Labels{metrics.TableLabel: metrics.HTTPTable})
 		if err != nil {
 			logger.Error("failed-to-emit-registration-message-count", err)
 		}
-		err = handler.metronClient.IncrementCounterWithDelta(routesUnregisteredCounter, messagesToEmit.RouteUnregistrationCount())
+		err = handler.metricsSink.IncrementCounterWithDelta(routesUnregisteredCounter, messagesToEmit.RouteUnregistrationCount(), metrics.Labels{metrics.TableLabel: metrics.HTTPTable})
 		if err != nil {
 			logger.Error("failed-to-emit-unregistration-message-count", err)
 		}
@@ -292,4 +524,38 @@ func (handler *Handler) emitMessages(logger lager.Logger, messagesToEmit routing
 			logger.Error("failed-to-emit-http-routes", err)
 		}
 	}
+
+	if handler.xdsEmitter != nil {
+		err := handler.xdsEmitter.Emit(messagesToEmit, routeMappings)
+		if err != nil {
+			logger.Error("failed-to-emit-xds-routes", err)
+		}
+	}
+}
+
+// audit records the changes that were just emitted, nothing is recorded when
+// nothing changed.
+func (handler *Handler) audit(entry auditlog.Entry, messagesToEmit routingtable.MessagesToEmit, routeMappings routingtable.TCPRouteMappings) {
+	if handler.auditLog == nil {
+		return
+	}
+
+	entry = entry.WithChanges(messagesToEmit, routeMappings)
+	if entry.Empty() {
+		return
+	}
+	handler.auditLog.Record(entry)
+}
+
+func eventEntry(eventType, processGuid, instanceGuid string) auditlog.Entry {
+	return auditlog.Entry{
+		Source:       auditlog.SourceEvent,
+		EventType:    eventType,
+		ProcessGUID:  processGuid,
+		InstanceGUID: instanceGuid,
+	}
+}
+
+func actualEventEntry(eventType string, actualLRPInfo *routingtable.ActualLRPRoutingInfo) auditlog.Entry {
+	return eventEntry(eventType, actualLRPInfo.ActualLRP.ProcessGuid, actualLRPInfo.ActualLRP.InstanceGuid)
 }
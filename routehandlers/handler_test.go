@@ -3,17 +3,23 @@ package routehandlers_test
 import (
 	"encoding/json"
 	"fmt"
+	"sync"
 
 	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
 	loggregator "code.cloudfoundry.org/go-loggregator"
 
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/auditlog"
+	auditlogfakes "code.cloudfoundry.org/route-emitter/auditlog/fakes"
+	"code.cloudfoundry.org/route-emitter/cfroutes"
+	"code.cloudfoundry.org/route-emitter/emitter"
 	"code.cloudfoundry.org/route-emitter/emitter/fakes"
+	"code.cloudfoundry.org/route-emitter/metrics"
 	"code.cloudfoundry.org/route-emitter/routehandlers"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/route-emitter/routingtable/fakeroutingtable"
-	"code.cloudfoundry.org/routing-info/cfroutes"
+	watcherfakes "code.cloudfoundry.org/route-emitter/watcher/fakes"
 	"github.com/gogo/protobuf/proto"
 
 	. "github.com/onsi/ginkgo"
@@ -110,7 +116,7 @@ var _ = Describe("Handler", func() {
 			return nil
 		}
 
-		routeHandler = routehandlers.NewHandler(fakeTable, natsEmitter, nil, false, fakeMetronClient)
+		routeHandler = routehandlers.NewHandler(fakeTable, natsEmitter, nil, nil, false, false, nil, metrics.NewLoggregatorSink(fakeMetronClient), nil)
 	})
 
 	Context("when an unrecognized event is received", func() {
@@ -668,6 +674,102 @@ var _ = Describe("Handler", func() {
 				})
 			})
 
+			Context("when a running LRP crashes", func() {
+				var (
+					beforeActualLRP, afterActualLRP *models.ActualLRPGroup
+				)
+
+				BeforeEach(func() {
+					beforeActualLRP = &models.ActualLRPGroup{
+						Instance: &models.ActualLRP{
+							ActualLRPKey:         models.NewActualLRPKey(expectedProcessGuid, expectedIndex, "domain"),
+							ActualLRPInstanceKey: models.NewActualLRPInstanceKey(expectedInstanceGUID, "cell-id"),
+							ActualLRPNetInfo: models.NewActualLRPNetInfo(
+								expectedHost,
+								expectedInstanceAddress,
+								models.NewPortMapping(expectedExternalPort, expectedContainerPort),
+							),
+							State: models.ActualLRPStateRunning,
+						},
+					}
+					afterActualLRP = &models.ActualLRPGroup{
+						Instance: &models.ActualLRP{
+							ActualLRPKey: models.NewActualLRPKey(expectedProcessGuid, expectedIndex, "domain"),
+							State:        models.ActualLRPStateCrashed,
+						},
+					}
+					fakeTable.RemoveEndpointReturns(emptyTCPRouteMappings, dummyMessagesToEmit)
+				})
+
+				JustBeforeEach(func() {
+					routeHandler.HandleEvent(logger, models.NewActualLRPChangedEvent(beforeActualLRP, afterActualLRP))
+				})
+
+				It("removes the endpoint from the table without draining it", func() {
+					Expect(fakeTable.DrainEndpointCallCount()).To(BeZero())
+					Expect(fakeTable.RemoveEndpointCallCount()).To(Equal(1))
+					Expect(fakeTable.RemoveEndpointArgsForCall(0)).To(Equal(routingtable.NewActualLRPRoutingInfo(beforeActualLRP)))
+				})
+
+				It("should emit whatever the table tells it to emit", func() {
+					Expect(natsEmitter.EmitCallCount()).To(Equal(1))
+					Expect(natsEmitter.EmitArgsForCall(0)).To(Equal(dummyMessagesToEmit))
+				})
+			})
+
+			Context("when a running LRP stops being routable", func() {
+				var (
+					beforeActualLRP, afterActualLRP *models.ActualLRPGroup
+				)
+
+				BeforeEach(func() {
+					newRunningLRP := func(routable bool) *models.ActualLRP {
+						lrp := &models.ActualLRP{
+							ActualLRPKey:         models.NewActualLRPKey(expectedProcessGuid, expectedIndex, "domain"),
+							ActualLRPInstanceKey: models.NewActualLRPInstanceKey(expectedInstanceGUID, "cell-id"),
+							ActualLRPNetInfo: models.NewActualLRPNetInfo(
+								expectedHost,
+								expectedInstanceAddress,
+								models.NewPortMapping(expectedExternalPort, expectedContainerPort),
+							),
+							State: models.ActualLRPStateRunning,
+						}
+						lrp.SetRoutable(routable)
+						return lrp
+					}
+					beforeActualLRP = &models.ActualLRPGroup{Instance: newRunningLRP(true)}
+					afterActualLRP = &models.ActualLRPGroup{Instance: newRunningLRP(false)}
+					fakeTable.RemoveEndpointReturns(emptyTCPRouteMappings, dummyMessagesToEmit)
+				})
+
+				JustBeforeEach(func() {
+					routeHandler.HandleEvent(logger, models.NewActualLRPChangedEvent(beforeActualLRP, afterActualLRP))
+				})
+
+				It("removes the endpoint from the table without draining it", func() {
+					Expect(fakeTable.AddEndpointCallCount()).To(BeZero())
+					Expect(fakeTable.DrainEndpointCallCount()).To(BeZero())
+					Expect(fakeTable.RemoveEndpointCallCount()).To(Equal(1))
+					Expect(fakeTable.RemoveEndpointArgsForCall(0)).To(Equal(routingtable.NewActualLRPRoutingInfo(beforeActualLRP)))
+				})
+
+				It("should emit whatever the table tells it to emit", func() {
+					Expect(natsEmitter.EmitCallCount()).To(Equal(1))
+					Expect(natsEmitter.EmitArgsForCall(0)).To(Equal(dummyMessagesToEmit))
+				})
+
+				Context("when routability is ignored", func() {
+					BeforeEach(func() {
+						routeHandler = routehandlers.NewHandler(fakeTable, natsEmitter, nil, nil, false, true, nil, metrics.NewLoggregatorSink(fakeMetronClient), nil)
+					})
+
+					It("keeps the endpoint in the table", func() {
+						Expect(fakeTable.RemoveEndpointCallCount()).To(BeZero())
+						Expect(fakeTable.AddEndpointCallCount()).To(Equal(1))
+					})
+				})
+			})
+
 			Context("when the endpoint neither starts nor ends in the RUNNING state", func() {
 				JustBeforeEach(func() {
 					beforeActualLRP := &models.ActualLRPGroup{
@@ -724,7 +826,7 @@ var _ = Describe("Handler", func() {
 				)
 
 				BeforeEach(func() {
-					fakeTable.RemoveEndpointReturns(emptyTCPRouteMappings, dummyMessagesToEmit)
+					fakeTable.DrainEndpointReturns(emptyTCPRouteMappings, dummyMessagesToEmit)
 
 					actualLRP = &models.ActualLRPGroup{
 						Instance: &models.ActualLRP{
@@ -759,15 +861,16 @@ var _ = Describe("Handler", func() {
 					))
 				})
 
-				It("should remove the endpoint from the table", func() {
-					Expect(fakeTable.RemoveEndpointCallCount()).To(Equal(1))
+				It("should drain the endpoint from the table", func() {
+					Expect(fakeTable.RemoveEndpointCallCount()).To(BeZero())
+					Expect(fakeTable.DrainEndpointCallCount()).To(Equal(1))
 
 					lrp, evacuating := actualLRP.Resolve()
 					lrpRoutingInfo := &routingtable.ActualLRPRoutingInfo{
 						ActualLRP:  lrp,
 						Evacuating: evacuating,
 					}
-					routingInfo := fakeTable.RemoveEndpointArgsForCall(0)
+					routingInfo := fakeTable.DrainEndpointArgsForCall(0)
 					Expect(routingInfo).To(Equal(lrpRoutingInfo))
 				})
 
@@ -813,6 +916,7 @@ var _ = Describe("Handler", func() {
 
 				It("doesn't remove the endpoint from the table", func() {
 					Expect(fakeTable.RemoveEndpointCallCount()).To(Equal(0))
+					Expect(fakeTable.DrainEndpointCallCount()).To(Equal(0))
 				})
 
 				It("doesn't emit", func() {
@@ -992,7 +1096,7 @@ var _ = Describe("Handler", func() {
 
 			Context("when emitting metrics in localMode", func() {
 				BeforeEach(func() {
-					routeHandler = routehandlers.NewHandler(fakeTable, natsEmitter, nil, true, fakeMetronClient)
+					routeHandler = routehandlers.NewHandler(fakeTable, natsEmitter, nil, nil, true, false, nil, metrics.NewLoggregatorSink(fakeMetronClient), nil)
 					fakeTable.HTTPAssociationsCountReturns(5)
 				})
 
@@ -1005,6 +1109,62 @@ var _ = Describe("Handler", func() {
 				})
 			})
 
+			Context("when sharded", func() {
+				BeforeEach(func() {
+					shardFilter := &watcherfakes.FakeShardFilter{}
+					shardFilter.OwnsStub = func(processGuid string) bool {
+						return processGuid != "pg-2"
+					}
+					routeHandler = routehandlers.NewHandler(fakeTable, natsEmitter, nil, nil, false, false, shardFilter, metrics.NewLoggregatorSink(fakeMetronClient), nil)
+
+					fakeTable.RemoveEntriesReturns(routingtable.TCPRouteMappings{}, routingtable.MessagesToEmit{
+						UnregistrationMessages: []routingtable.RegistryMessage{{URIs: []string{"bar.example.com"}}},
+					})
+				})
+
+				It("only adds the lrps of owned shards to the new table", func() {
+					routeHandler.Sync(logger, desiredInfo, actualInfo, domains, nil)
+					Expect(fakeTable.SwapCallCount()).Should(Equal(1))
+					tempRoutingTable, _ := fakeTable.SwapArgsForCall(0)
+					Expect(tempRoutingTable.HTTPAssociationsCount()).To(Equal(2))
+				})
+
+				It("releases the entries of shards it no longer owns without emitting them", func() {
+					routeHandler.Sync(logger, desiredInfo, actualInfo, domains, nil)
+					Expect(fakeTable.RemoveEntriesCallCount()).To(Equal(1))
+					remove := fakeTable.RemoveEntriesArgsForCall(0)
+					Expect(remove(routingtable.RoutingKey{ProcessGUID: "pg-1", ContainerPort: 8080})).To(BeFalse())
+					Expect(remove(routingtable.RoutingKey{ProcessGUID: "pg-2", ContainerPort: 8080})).To(BeTrue())
+
+					Expect(natsEmitter.EmitCallCount()).Should(Equal(1))
+					Expect(natsEmitter.EmitArgsForCall(0).UnregistrationMessages).To(BeEmpty())
+					Expect(fakeTable.SnapshotCallCount()).To(Equal(0))
+					Expect(fakeTable.RestoreCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when a route filter is set", func() {
+				BeforeEach(func() {
+					desiredInfo[1].PlacementTags = []string{"isolation-segment-1"}
+					routeHandler.SetRouteFilter(routehandlers.RouteFilter{
+						ExcludeIsolationSegments: []string{"isolation-segment-1"},
+					})
+				})
+
+				It("only adds the allowed lrps to the new table", func() {
+					routeHandler.Sync(logger, desiredInfo, actualInfo, domains, nil)
+					Expect(fakeTable.SwapCallCount()).Should(Equal(1))
+					tempRoutingTable, _ := fakeTable.SwapArgsForCall(0)
+					Expect(tempRoutingTable.HTTPAssociationsCount()).To(Equal(2))
+				})
+
+				It("stops refreshing the desired lrps of the filtered out processes", func() {
+					routeHandler.Sync(logger, desiredInfo, actualInfo, domains, nil)
+					Expect(routeHandler.ShouldRefreshDesired(actualInfo[0])).To(BeTrue())
+					Expect(routeHandler.ShouldRefreshDesired(actualInfo[1])).To(BeFalse())
+				})
+			})
+
 			Context("when NATS events are cached", func() {
 				BeforeEach(func() {
 					routes := cfroutes.CFRoutes{
@@ -1061,6 +1221,83 @@ var _ = Describe("Handler", func() {
 		})
 	})
 
+	Describe("SyncProcesses", func() {
+		var (
+			desiredInfo []*models.DesiredLRPSchedulingInfo
+			actualInfo  []*routingtable.ActualLRPRoutingInfo
+			domains     models.DomainSet
+			messages    routingtable.MessagesToEmit
+		)
+
+		BeforeEach(func() {
+			desiredInfo = nil
+			actualInfo = nil
+			for _, processGuid := range []string{"pg-1", "pg-2"} {
+				desiredInfo = append(desiredInfo, &models.DesiredLRPSchedulingInfo{
+					DesiredLRPKey: models.NewDesiredLRPKey(processGuid, "tests", "lg"),
+					Routes: cfroutes.CFRoutes{
+						cfroutes.CFRoute{
+							Hostnames: []string{processGuid + ".example.com"},
+							Port:      8080,
+						},
+					}.RoutingInfo(),
+					Instances: 1,
+				})
+				actualInfo = append(actualInfo, routingtable.NewActualLRPRoutingInfo(&models.ActualLRPGroup{
+					Instance: &models.ActualLRP{
+						ActualLRPKey:         models.NewActualLRPKey(processGuid, 0, "domain"),
+						ActualLRPInstanceKey: models.NewActualLRPInstanceKey("ig-"+processGuid, "cell-id"),
+						ActualLRPNetInfo:     models.NewActualLRPNetInfo("1.1.1.1", "container-ip", models.NewPortMapping(11, 8080)),
+						State:                models.ActualLRPStateRunning,
+					},
+				}))
+			}
+			domains = models.NewDomainSet([]string{"domain"})
+
+			messages = routingtable.MessagesToEmit{
+				RegistrationMessages: []routingtable.RegistryMessage{
+					{URIs: []string{"pg-1.example.com"}, Host: "1.1.1.1", Port: 11, App: "lg"},
+				},
+			}
+			fakeTable.SwapProcessesReturns(emptyTCPRouteMappings, messages)
+		})
+
+		It("swaps the entries of the process guids and emits the changes", func() {
+			routeHandler.SyncProcesses(logger, []string{"pg-1", "pg-2"}, desiredInfo, actualInfo, domains)
+
+			Expect(fakeTable.SwapProcessesCallCount()).To(Equal(1))
+			tempRoutingTable, processGuids, swapDomains := fakeTable.SwapProcessesArgsForCall(0)
+			Expect(tempRoutingTable.HTTPAssociationsCount()).To(Equal(2))
+			Expect(processGuids).To(Equal([]string{"pg-1", "pg-2"}))
+			Expect(swapDomains).To(Equal(domains))
+			Expect(fakeTable.SwapCallCount()).To(Equal(0))
+
+			Expect(natsEmitter.EmitCallCount()).To(Equal(1))
+			Expect(natsEmitter.EmitArgsForCall(0)).To(Equal(messages))
+		})
+
+		Context("when sharded", func() {
+			BeforeEach(func() {
+				shardFilter := &watcherfakes.FakeShardFilter{}
+				shardFilter.OwnsStub = func(processGuid string) bool {
+					return processGuid != "pg-2"
+				}
+				routeHandler = routehandlers.NewHandler(fakeTable, natsEmitter, nil, nil, false, false, shardFilter, metrics.NewLoggregatorSink(fakeMetronClient), nil)
+			})
+
+			It("releases the shards it no longer owns and only adds the lrps of owned shards", func() {
+				routeHandler.SyncProcesses(logger, []string{"pg-1", "pg-2"}, desiredInfo, actualInfo, domains)
+
+				Expect(fakeTable.RemoveEntriesCallCount()).To(Equal(1))
+				remove := fakeTable.RemoveEntriesArgsForCall(0)
+				Expect(remove(routingtable.RoutingKey{ProcessGUID: "pg-2", ContainerPort: 8080})).To(BeTrue())
+
+				tempRoutingTable, _, _ := fakeTable.SwapProcessesArgsForCall(0)
+				Expect(tempRoutingTable.HTTPAssociationsCount()).To(Equal(1))
+			})
+		})
+	})
+
 	Describe("EmitExternal", func() {
 		var registrationMsgs routingtable.MessagesToEmit
 		BeforeEach(func() {
@@ -1126,6 +1363,27 @@ var _ = Describe("Handler", func() {
 				delta: 3,
 			})))
 		})
+
+		Context("when an emitter keeps state outside of the table", func() {
+			var writer *fakes.FakeKubeObjectWriter
+
+			BeforeEach(func() {
+				writer = &fakes.FakeKubeObjectWriter{}
+				kubeEmitter := emitter.NewKubeEmitter(logger, fakeTable, writer, "cf", false)
+				multiEmitter := emitter.NewMultiNATSEmitter(natsEmitter, kubeEmitter)
+				routeHandler = routehandlers.NewHandler(fakeTable, multiEmitter, nil, nil, false, false, nil, metrics.NewLoggregatorSink(fakeMetronClient), nil)
+			})
+
+			It("broadcasts to it once the table was synced", func() {
+				routeHandler.EmitExternal(logger)
+				Expect(writer.ListCallCount()).To(BeZero())
+
+				routeHandler.Sync(logger, nil, nil, models.NewDomainSet([]string{"domain"}), nil)
+				routeHandler.EmitExternal(logger)
+				Expect(writer.ListCallCount()).To(Equal(1))
+				Expect(natsEmitter.EmitArgsForCall(natsEmitter.EmitCallCount() - 1)).To(Equal(registrationMsgs))
+			})
+		})
 	})
 
 	Describe("EmitInternal", func() {
@@ -1184,6 +1442,40 @@ var _ = Describe("Handler", func() {
 		})
 	})
 
+	Describe("EmitDrained", func() {
+		var unregistrationMsgs routingtable.MessagesToEmit
+		BeforeEach(func() {
+			unregistrationMsgs = routingtable.MessagesToEmit{
+				UnregistrationMessages: []routingtable.RegistryMessage{
+					{
+						URIs:                 []string{"foo.example.com"},
+						Host:                 "1.1.1.1",
+						Port:                 11,
+						Tags:                 map[string]string{"component": "route-emitter"},
+						App:                  logGuid,
+						PrivateInstanceIndex: "0",
+					},
+				},
+			}
+		})
+
+		It("emits the unregistrations of the drained endpoints", func() {
+			fakeTable.UnregisterDrainedEndpointsReturns(unregistrationMsgs)
+			routeHandler.EmitDrained(logger)
+			Expect(fakeTable.UnregisterDrainedEndpointsCallCount()).To(Equal(1))
+			Expect(natsEmitter.EmitCallCount()).To(Equal(1))
+			Expect(natsEmitter.EmitArgsForCall(0)).To(Equal(unregistrationMsgs))
+		})
+
+		Context("when no endpoint is done draining", func() {
+			It("does not emit", func() {
+				routeHandler.EmitDrained(logger)
+				Expect(fakeTable.UnregisterDrainedEndpointsCallCount()).To(Equal(1))
+				Expect(natsEmitter.EmitCallCount()).To(Equal(0))
+			})
+		})
+	})
+
 	Describe("RefreshDesired", func() {
 		BeforeEach(func() {
 			fakeTable.SetRoutesReturns(emptyTCPRouteMappings, routingtable.MessagesToEmit{})
@@ -1211,6 +1503,219 @@ var _ = Describe("Handler", func() {
 		})
 	})
 
+	Describe("route filter", func() {
+		var (
+			desiredLRP *models.DesiredLRP
+			actualLRP  *models.ActualLRPGroup
+		)
+
+		BeforeEach(func() {
+			desiredLRP = &models.DesiredLRP{
+				ProcessGuid:   "pg-1",
+				Domain:        "domain",
+				LogGuid:       logGuid,
+				Routes:        cfroutes.CFRoutes{expectedCFRoute}.RoutingInfo(),
+				PlacementTags: []string{"isolation-segment-1"},
+			}
+			actualLRP = &models.ActualLRPGroup{
+				Instance: &models.ActualLRP{
+					ActualLRPKey:         models.NewActualLRPKey("pg-1", 0, "domain"),
+					ActualLRPInstanceKey: models.NewActualLRPInstanceKey(expectedInstanceGUID, "cell-id"),
+					ActualLRPNetInfo:     models.NewActualLRPNetInfo(expectedHost, expectedInstanceAddress, models.NewPortMapping(expectedExternalPort, expectedContainerPort)),
+					State:                models.ActualLRPStateRunning,
+				},
+			}
+		})
+
+		Context("when the isolation segment of a process is excluded", func() {
+			BeforeEach(func() {
+				routeHandler.SetRouteFilter(routehandlers.RouteFilter{ExcludeIsolationSegments: []string{"isolation-segment-1"}})
+			})
+
+			It("ignores the events of its desired lrp", func() {
+				routeHandler.HandleEvent(logger, models.NewDesiredLRPCreatedEvent(desiredLRP))
+				routeHandler.HandleEvent(logger, models.NewDesiredLRPChangedEvent(desiredLRP, desiredLRP))
+				routeHandler.HandleEvent(logger, models.NewDesiredLRPRemovedEvent(desiredLRP))
+				Expect(fakeTable.SetRoutesCallCount()).To(BeZero())
+				Expect(fakeTable.RemoveRoutesCallCount()).To(BeZero())
+			})
+
+			It("ignores the events of its actual lrps once its desired lrp was seen", func() {
+				desiredInfo := desiredLRP.DesiredLRPSchedulingInfo()
+				routeHandler.RefreshDesired(logger, []*models.DesiredLRPSchedulingInfo{&desiredInfo})
+				Expect(fakeTable.SetRoutesCallCount()).To(BeZero())
+
+				routeHandler.HandleEvent(logger, models.NewActualLRPCreatedEvent(actualLRP))
+				Expect(fakeTable.AddEndpointCallCount()).To(BeZero())
+				Expect(routeHandler.ShouldRefreshDesired(routingtable.NewActualLRPRoutingInfo(actualLRP))).To(BeFalse())
+			})
+
+			Context("and then allowed again", func() {
+				It("handles the events of its actual lrps again", func() {
+					routeHandler.HandleEvent(logger, models.NewDesiredLRPCreatedEvent(desiredLRP))
+					routeHandler.SetRouteFilter(routehandlers.RouteFilter{})
+
+					routeHandler.HandleEvent(logger, models.NewActualLRPCreatedEvent(actualLRP))
+					Expect(fakeTable.AddEndpointCallCount()).To(Equal(1))
+				})
+			})
+		})
+
+		Context("when the isolation segment of a process changes to an excluded one", func() {
+			var movedLRP *models.DesiredLRP
+
+			BeforeEach(func() {
+				routeHandler.SetRouteFilter(routehandlers.RouteFilter{ExcludeIsolationSegments: []string{"isolation-segment-2"}})
+				fakeTable.RemoveRoutesReturns(emptyTCPRouteMappings, dummyMessagesToEmit)
+
+				movedLRP = &models.DesiredLRP{}
+				*movedLRP = *desiredLRP
+				movedLRP.PlacementTags = []string{"isolation-segment-2"}
+			})
+
+			It("removes and unregisters its routes", func() {
+				routeHandler.HandleEvent(logger, models.NewDesiredLRPChangedEvent(desiredLRP, movedLRP))
+
+				Expect(fakeTable.SetRoutesCallCount()).To(BeZero())
+				Expect(fakeTable.RemoveRoutesCallCount()).To(Equal(1))
+				Expect(*fakeTable.RemoveRoutesArgsForCall(0)).To(Equal(desiredLRP.DesiredLRPSchedulingInfo()))
+
+				Expect(natsEmitter.EmitCallCount()).To(Equal(1))
+				Expect(natsEmitter.EmitArgsForCall(0)).To(Equal(dummyMessagesToEmit))
+			})
+
+			It("ignores the events of its actual lrps afterwards", func() {
+				routeHandler.HandleEvent(logger, models.NewDesiredLRPChangedEvent(desiredLRP, movedLRP))
+				routeHandler.HandleEvent(logger, models.NewActualLRPCreatedEvent(actualLRP))
+				Expect(fakeTable.AddEndpointCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when the route filter changes", func() {
+			It("keeps ignoring the actual lrps of processes the new filter still excludes", func() {
+				routeHandler.SetRouteFilter(routehandlers.RouteFilter{ExcludeIsolationSegments: []string{"isolation-segment-1"}})
+				routeHandler.HandleEvent(logger, models.NewDesiredLRPCreatedEvent(desiredLRP))
+
+				routeHandler.SetRouteFilter(routehandlers.RouteFilter{
+					ExcludeIsolationSegments: []string{"isolation-segment-1"},
+					ExcludeDomains:           []string{"other-domain"},
+				})
+
+				routeHandler.HandleEvent(logger, models.NewActualLRPCreatedEvent(actualLRP))
+				Expect(fakeTable.AddEndpointCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when only other isolation segments are included", func() {
+			BeforeEach(func() {
+				routeHandler.SetRouteFilter(routehandlers.RouteFilter{IncludeIsolationSegments: []string{routehandlers.SharedIsolationSegment}})
+			})
+
+			It("ignores the events of the process", func() {
+				routeHandler.HandleEvent(logger, models.NewDesiredLRPCreatedEvent(desiredLRP))
+				Expect(fakeTable.SetRoutesCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when the domain of a process is excluded", func() {
+			BeforeEach(func() {
+				routeHandler.SetRouteFilter(routehandlers.RouteFilter{ExcludeDomains: []string{"domain"}})
+			})
+
+			It("ignores the events of its actual lrps", func() {
+				routeHandler.HandleEvent(logger, models.NewActualLRPCreatedEvent(actualLRP))
+				routeHandler.HandleEvent(logger, models.NewActualLRPChangedEvent(actualLRP, actualLRP))
+				routeHandler.HandleEvent(logger, models.NewActualLRPRemovedEvent(actualLRP))
+				Expect(fakeTable.AddEndpointCallCount()).To(BeZero())
+				Expect(fakeTable.RemoveEndpointCallCount()).To(BeZero())
+				Expect(fakeTable.DrainEndpointCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when the process guid prefix of a process is not included", func() {
+			BeforeEach(func() {
+				routeHandler.SetRouteFilter(routehandlers.RouteFilter{IncludeProcessGuidPrefixes: []string{"other-"}})
+			})
+
+			It("ignores the events of the process", func() {
+				routeHandler.HandleEvent(logger, models.NewDesiredLRPCreatedEvent(desiredLRP))
+				routeHandler.HandleEvent(logger, models.NewActualLRPCreatedEvent(actualLRP))
+				Expect(fakeTable.SetRoutesCallCount()).To(BeZero())
+				Expect(fakeTable.AddEndpointCallCount()).To(BeZero())
+			})
+		})
+	})
+
+	Describe("audit log", func() {
+		var (
+			auditLog  *auditlogfakes.FakeRecorder
+			actualLRP *models.ActualLRPGroup
+		)
+
+		BeforeEach(func() {
+			auditLog = &auditlogfakes.FakeRecorder{}
+			routeHandler = routehandlers.NewHandler(fakeTable, natsEmitter, nil, nil, false, false, nil, metrics.NewLoggregatorSink(fakeMetronClient), auditLog)
+
+			actualLRP = &models.ActualLRPGroup{
+				Instance: &models.ActualLRP{
+					ActualLRPKey:         models.NewActualLRPKey(expectedProcessGuid, expectedIndex, "domain"),
+					ActualLRPInstanceKey: models.NewActualLRPInstanceKey(expectedInstanceGUID, "cell-id"),
+					ActualLRPNetInfo:     models.NewActualLRPNetInfo(expectedHost, expectedInstanceAddress, models.NewPortMapping(expectedExternalPort, expectedContainerPort)),
+					State:                models.ActualLRPStateRunning,
+				},
+			}
+		})
+
+		It("records the changes an event resulted in", func() {
+			fakeTable.DrainEndpointReturns(emptyTCPRouteMappings, dummyMessagesToEmit)
+			routeHandler.HandleEvent(logger, models.NewActualLRPRemovedEvent(actualLRP))
+
+			Expect(auditLog.RecordCallCount()).To(Equal(1))
+			Expect(auditLog.RecordArgsForCall(0)).To(Equal(auditlog.Entry{
+				Source:        auditlog.SourceEvent,
+				EventType:     models.EventTypeActualLRPRemoved,
+				ProcessGUID:   expectedProcessGuid,
+				InstanceGUID:  expectedInstanceGUID,
+				Registrations: dummyMessagesToEmit.RegistrationMessages,
+			}))
+		})
+
+		It("records nothing when an event changes nothing", func() {
+			fakeTable.DrainEndpointReturns(emptyTCPRouteMappings, routingtable.MessagesToEmit{})
+			routeHandler.HandleEvent(logger, models.NewActualLRPRemovedEvent(actualLRP))
+			Expect(auditLog.RecordCallCount()).To(Equal(0))
+		})
+
+		It("records the changes of a sync as a single sync entry", func() {
+			fakeTable.SwapReturns(emptyTCPRouteMappings, dummyMessagesToEmit)
+			cachedEvents := map[string]models.Event{
+				expectedInstanceGUID: models.NewActualLRPCreatedEvent(actualLRP),
+			}
+
+			routeHandler.Sync(logger, nil, nil, models.NewDomainSet([]string{"domain"}), cachedEvents)
+
+			Expect(auditLog.RecordCallCount()).To(Equal(1))
+			Expect(auditLog.RecordArgsForCall(0)).To(Equal(auditlog.Entry{
+				Source:        auditlog.SourceSync,
+				Registrations: dummyMessagesToEmit.RegistrationMessages,
+			}))
+		})
+
+		It("records the unregistrations of drained endpoints", func() {
+			drained := routingtable.MessagesToEmit{
+				UnregistrationMessages: dummyMessagesToEmit.RegistrationMessages,
+			}
+			fakeTable.UnregisterDrainedEndpointsReturns(drained)
+			routeHandler.EmitDrained(logger)
+
+			Expect(auditLog.RecordCallCount()).To(Equal(1))
+			Expect(auditLog.RecordArgsForCall(0)).To(Equal(auditlog.Entry{
+				Source:          auditlog.SourceDrain,
+				Unregistrations: drained.UnregistrationMessages,
+			}))
+		})
+	})
+
 	Describe("ShouldRefreshDesired", func() {
 		var (
 			actualInfo *routingtable.ActualLRPRoutingInfo
@@ -1262,5 +1767,49 @@ var _ = Describe("Handler", func() {
 				Expect(routeHandler.ShouldRefreshDesired(actualInfo)).To(BeFalse())
 			})
 		})
+
+		Context("when the events of other processes are handled at the same time", func() {
+			BeforeEach(func() {
+				metricsSink := metrics.NewLoggregatorSink(&mfakes.FakeIngressClient{})
+				table := routingtable.NewRoutingTable(logger, false, metricsSink)
+				routeHandler = routehandlers.NewHandler(table, natsEmitter, nil, nil, false, false, nil, metricsSink, nil)
+			})
+
+			It("does not race with them", func() {
+				var wg sync.WaitGroup
+				for i := 0; i < 4; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer GinkgoRecover()
+						defer wg.Done()
+
+						processGuid := fmt.Sprintf("other-pg-%d", i)
+						routes := cfroutes.CFRoutes{
+							cfroutes.CFRoute{Hostnames: []string{processGuid + ".example.com"}, Port: 8080},
+						}.RoutingInfo()
+						for j := 0; j < 20; j++ {
+							routeHandler.HandleEvent(logger, models.NewDesiredLRPCreatedEvent(&models.DesiredLRP{
+								ProcessGuid: processGuid,
+								Routes:      &routes,
+								Instances:   1,
+							}))
+							routeHandler.HandleEvent(logger, models.NewActualLRPCreatedEvent(&models.ActualLRPGroup{
+								Instance: &models.ActualLRP{
+									ActualLRPKey:         models.NewActualLRPKey(processGuid, 0, "domain"),
+									ActualLRPInstanceKey: models.NewActualLRPInstanceKey(fmt.Sprintf("ig-%d-%d", i, j), "cell-id"),
+									ActualLRPNetInfo:     models.NewActualLRPNetInfo("1.1.1.1", "container-ip", models.NewPortMapping(uint32(1000+j), 8080)),
+									State:                models.ActualLRPStateRunning,
+								},
+							}))
+						}
+					}(i)
+				}
+
+				for j := 0; j < 100; j++ {
+					Expect(routeHandler.ShouldRefreshDesired(actualInfo)).To(BeTrue())
+				}
+				wg.Wait()
+			})
+		})
 	})
 })
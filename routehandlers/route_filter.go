@@ -0,0 +1,88 @@
+package routehandlers
+
+import (
+	"strings"
+
+	"code.cloudfoundry.org/bbs/models"
+)
+
+// SharedIsolationSegment is the isolation segment of the desired lrps without
+// placement tags.
+const SharedIsolationSegment = "shared"
+
+// RouteFilter selects the processes whose routes are emitted by isolation
+// segment, domain and process guid prefix. An empty include list includes
+// everything, excludes take precedence over includes.
+type RouteFilter struct {
+	IncludeIsolationSegments   []string
+	ExcludeIsolationSegments   []string
+	IncludeDomains             []string
+	ExcludeDomains             []string
+	IncludeProcessGuidPrefixes []string
+	ExcludeProcessGuidPrefixes []string
+}
+
+// AllowsDesired returns true if the routes of lrp are emitted. The placement
+// tags of lrp are its isolation segment.
+func (f RouteFilter) AllowsDesired(lrp *models.DesiredLRPSchedulingInfo) bool {
+	if !f.allows(lrp.Domain, lrp.ProcessGuid) {
+		return false
+	}
+
+	segments := lrp.PlacementTags
+	if len(segments) == 0 {
+		segments = []string{SharedIsolationSegment}
+	}
+	for _, segment := range segments {
+		if contains(f.ExcludeIsolationSegments, segment) {
+			return false
+		}
+	}
+	if len(f.IncludeIsolationSegments) == 0 {
+		return true
+	}
+	for _, segment := range segments {
+		if contains(f.IncludeIsolationSegments, segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsActual returns true unless the domain or process guid of lrp is
+// filtered out. Actual lrps do not know their isolation segment, the ones of
+// an excluded segment are left out along with their desired lrp.
+func (f RouteFilter) AllowsActual(lrp *models.ActualLRP) bool {
+	return f.allows(lrp.Domain, lrp.ProcessGuid)
+}
+
+func (f RouteFilter) allows(domain, processGuid string) bool {
+	if contains(f.ExcludeDomains, domain) || hasAnyPrefix(processGuid, f.ExcludeProcessGuidPrefixes) {
+		return false
+	}
+	if len(f.IncludeDomains) > 0 && !contains(f.IncludeDomains, domain) {
+		return false
+	}
+	if len(f.IncludeProcessGuidPrefixes) > 0 && !hasAnyPrefix(processGuid, f.IncludeProcessGuidPrefixes) {
+		return false
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(value string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
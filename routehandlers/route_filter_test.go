@@ -0,0 +1,93 @@
+package routehandlers_test
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/route-emitter/routehandlers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RouteFilter", func() {
+	desired := func(processGuid, domain string, placementTags ...string) *models.DesiredLRPSchedulingInfo {
+		return &models.DesiredLRPSchedulingInfo{
+			DesiredLRPKey: models.NewDesiredLRPKey(processGuid, domain, "log-guid"),
+			PlacementTags: placementTags,
+		}
+	}
+
+	actual := func(processGuid, domain string) *models.ActualLRP {
+		return &models.ActualLRP{ActualLRPKey: models.NewActualLRPKey(processGuid, 0, domain)}
+	}
+
+	It("allows everything when empty", func() {
+		filter := routehandlers.RouteFilter{}
+		Expect(filter.AllowsDesired(desired("pg-1", "cf-apps"))).To(BeTrue())
+		Expect(filter.AllowsDesired(desired("pg-1", "cf-apps", "isolation-segment-1"))).To(BeTrue())
+		Expect(filter.AllowsActual(actual("pg-1", "cf-apps"))).To(BeTrue())
+	})
+
+	Describe("isolation segments", func() {
+		It("only allows the included ones", func() {
+			filter := routehandlers.RouteFilter{IncludeIsolationSegments: []string{"isolation-segment-1"}}
+			Expect(filter.AllowsDesired(desired("pg-1", "cf-apps", "isolation-segment-1"))).To(BeTrue())
+			Expect(filter.AllowsDesired(desired("pg-1", "cf-apps", "isolation-segment-2"))).To(BeFalse())
+			Expect(filter.AllowsDesired(desired("pg-1", "cf-apps"))).To(BeFalse())
+		})
+
+		It("puts desired lrps without placement tags in the shared segment", func() {
+			filter := routehandlers.RouteFilter{ExcludeIsolationSegments: []string{routehandlers.SharedIsolationSegment}}
+			Expect(filter.AllowsDesired(desired("pg-1", "cf-apps"))).To(BeFalse())
+			Expect(filter.AllowsDesired(desired("pg-1", "cf-apps", "isolation-segment-1"))).To(BeTrue())
+		})
+
+		It("prefers excludes over includes", func() {
+			filter := routehandlers.RouteFilter{
+				IncludeIsolationSegments: []string{"isolation-segment-1"},
+				ExcludeIsolationSegments: []string{"isolation-segment-2"},
+			}
+			Expect(filter.AllowsDesired(desired("pg-1", "cf-apps", "isolation-segment-1", "isolation-segment-2"))).To(BeFalse())
+		})
+
+		It("leaves actual lrps to their desired lrp", func() {
+			filter := routehandlers.RouteFilter{IncludeIsolationSegments: []string{"isolation-segment-1"}}
+			Expect(filter.AllowsActual(actual("pg-1", "cf-apps"))).To(BeTrue())
+		})
+	})
+
+	Describe("domains", func() {
+		It("only allows the included ones", func() {
+			filter := routehandlers.RouteFilter{IncludeDomains: []string{"cf-apps"}}
+			Expect(filter.AllowsDesired(desired("pg-1", "cf-apps"))).To(BeTrue())
+			Expect(filter.AllowsDesired(desired("pg-1", "cf-tasks"))).To(BeFalse())
+			Expect(filter.AllowsActual(actual("pg-1", "cf-apps"))).To(BeTrue())
+			Expect(filter.AllowsActual(actual("pg-1", "cf-tasks"))).To(BeFalse())
+		})
+
+		It("does not allow the excluded ones", func() {
+			filter := routehandlers.RouteFilter{ExcludeDomains: []string{"cf-tasks"}}
+			Expect(filter.AllowsDesired(desired("pg-1", "cf-apps"))).To(BeTrue())
+			Expect(filter.AllowsDesired(desired("pg-1", "cf-tasks"))).To(BeFalse())
+			Expect(filter.AllowsActual(actual("pg-1", "cf-tasks"))).To(BeFalse())
+		})
+	})
+
+	Describe("process guid prefixes", func() {
+		It("only allows the included ones", func() {
+			filter := routehandlers.RouteFilter{IncludeProcessGuidPrefixes: []string{"team-a-", "team-b-"}}
+			Expect(filter.AllowsDesired(desired("team-a-pg-1", "cf-apps"))).To(BeTrue())
+			Expect(filter.AllowsActual(actual("team-b-pg-1", "cf-apps"))).To(BeTrue())
+			Expect(filter.AllowsDesired(desired("team-c-pg-1", "cf-apps"))).To(BeFalse())
+			Expect(filter.AllowsActual(actual("team-c-pg-1", "cf-apps"))).To(BeFalse())
+		})
+
+		It("prefers excludes over includes", func() {
+			filter := routehandlers.RouteFilter{
+				IncludeProcessGuidPrefixes: []string{"team-a-"},
+				ExcludeProcessGuidPrefixes: []string{"team-a-internal-"},
+			}
+			Expect(filter.AllowsDesired(desired("team-a-pg-1", "cf-apps"))).To(BeTrue())
+			Expect(filter.AllowsDesired(desired("team-a-internal-pg-1", "cf-apps"))).To(BeFalse())
+		})
+	})
+})
@@ -7,6 +7,7 @@ import (
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagertest"
 	emitterfakes "code.cloudfoundry.org/route-emitter/emitter/fakes"
+	"code.cloudfoundry.org/route-emitter/metrics"
 	"code.cloudfoundry.org/route-emitter/routehandlers"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/route-emitter/routingtable/fakeroutingtable"
@@ -32,7 +33,7 @@ var _ = Describe("RoutingAPIHandler", func() {
 		fakeRoutingTable = new(fakeroutingtable.FakeRoutingTable)
 		fakeRoutingAPIEmitter = new(emitterfakes.FakeRoutingAPIEmitter)
 		fakeMetronClient = &mfakes.FakeIngressClient{}
-		routeHandler = routehandlers.NewHandler(fakeRoutingTable, nil, fakeRoutingAPIEmitter, false, fakeMetronClient)
+		routeHandler = routehandlers.NewHandler(fakeRoutingTable, nil, fakeRoutingAPIEmitter, nil, false, false, nil, metrics.NewLoggregatorSink(fakeMetronClient), nil)
 	})
 
 	Describe("DesiredLRP Event", func() {
@@ -398,15 +399,15 @@ var _ = Describe("RoutingAPIHandler", func() {
 					}
 				})
 
-				It("invokes RemoveEndpoint on RoutingTable", func() {
-					Expect(fakeRoutingTable.RemoveEndpointCallCount()).Should(Equal(1))
-					lrp := fakeRoutingTable.RemoveEndpointArgsForCall(0)
+				It("invokes DrainEndpoint on RoutingTable", func() {
+					Expect(fakeRoutingTable.DrainEndpointCallCount()).Should(Equal(1))
+					lrp := fakeRoutingTable.DrainEndpointArgsForCall(0)
 					Expect(lrp).Should(Equal(routingtable.NewActualLRPRoutingInfo(actualLRP)))
 				})
 
 				Context("when there are routing events", func() {
 					BeforeEach(func() {
-						fakeRoutingTable.RemoveEndpointReturns(routingEvents, emptyNatsMessages)
+						fakeRoutingTable.DrainEndpointReturns(routingEvents, emptyNatsMessages)
 					})
 
 					It("invokes Emit on Emitter", func() {
@@ -433,8 +434,8 @@ var _ = Describe("RoutingAPIHandler", func() {
 					}
 				})
 
-				It("does not invoke RemoveEndpoint on RoutingTable", func() {
-					Expect(fakeRoutingTable.RemoveEndpointCallCount()).Should(Equal(0))
+				It("does not invoke DrainEndpoint on RoutingTable", func() {
+					Expect(fakeRoutingTable.DrainEndpointCallCount()).Should(Equal(0))
 				})
 
 				It("does not invoke Emit on Emitter", func() {
@@ -590,7 +591,7 @@ var _ = Describe("RoutingAPIHandler", func() {
 						}
 						return nil
 					}
-					routeHandler = routehandlers.NewHandler(fakeRoutingTable, nil, fakeRoutingAPIEmitter, true, fakeMetronClient)
+					routeHandler = routehandlers.NewHandler(fakeRoutingTable, nil, fakeRoutingAPIEmitter, nil, true, false, nil, metrics.NewLoggregatorSink(fakeMetronClient), nil)
 					fakeRoutingTable.TCPAssociationsCountReturns(1)
 				})
 
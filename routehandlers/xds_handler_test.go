@@ -0,0 +1,100 @@
+package routehandlers_test
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/cfroutes"
+	emitterfakes "code.cloudfoundry.org/route-emitter/emitter/fakes"
+	metricsfakes "code.cloudfoundry.org/route-emitter/metrics/fakes"
+	"code.cloudfoundry.org/route-emitter/routehandlers"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/fakeroutingtable"
+	tcpmodels "code.cloudfoundry.org/routing-api/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("XDSHandler", func() {
+	var (
+		logger             lager.Logger
+		fakeRoutingTable   *fakeroutingtable.FakeRoutingTable
+		fakeXDSEmitter     *emitterfakes.FakeXDSEmitter
+		routeHandler       *routehandlers.Handler
+		messagesToEmit     routingtable.MessagesToEmit
+		routingEvents      routingtable.TCPRouteMappings
+		desiredLRPCreation *models.DesiredLRPCreatedEvent
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		fakeRoutingTable = new(fakeroutingtable.FakeRoutingTable)
+		fakeXDSEmitter = new(emitterfakes.FakeXDSEmitter)
+		routeHandler = routehandlers.NewHandler(fakeRoutingTable, nil, nil, fakeXDSEmitter, false, false, nil, &metricsfakes.FakeSink{}, nil)
+
+		messagesToEmit = routingtable.MessagesToEmit{
+			RegistrationMessages: []routingtable.RegistryMessage{
+				{Host: "1.1.1.1", Port: 61001, URIs: []string{"foo.example.com"}},
+			},
+		}
+		routingEvents = routingtable.TCPRouteMappings{
+			Registrations: []tcpmodels.TcpRouteMapping{
+				tcpmodels.NewTcpRouteMapping("router-group-guid", 5222, "1.1.1.1", 61002, 0),
+			},
+		}
+
+		routes := cfroutes.CFRoutes{{Hostnames: []string{"foo.example.com"}, Port: 8080}}.RoutingInfo()
+		desiredLRPCreation = models.NewDesiredLRPCreatedEvent(&models.DesiredLRP{
+			ProcessGuid: "process-guid",
+			Ports:       []uint32{8080},
+			Routes:      &routes,
+			Instances:   1,
+		})
+	})
+
+	Context("when an event produces routing changes", func() {
+		BeforeEach(func() {
+			fakeRoutingTable.SetRoutesReturns(routingEvents, messagesToEmit)
+		})
+
+		It("emits both the http messages and the tcp route mappings", func() {
+			routeHandler.HandleEvent(logger, desiredLRPCreation)
+			Expect(fakeXDSEmitter.EmitCallCount()).To(Equal(1))
+			messages, mappings := fakeXDSEmitter.EmitArgsForCall(0)
+			Expect(messages).To(Equal(messagesToEmit))
+			Expect(mappings).To(Equal(routingEvents))
+		})
+	})
+
+	Describe("Sync", func() {
+		BeforeEach(func() {
+			fakeRoutingTable.SwapReturns(routingEvents, messagesToEmit)
+		})
+
+		It("emits only the result of the swap", func() {
+			cachedEvents := map[string]models.Event{
+				desiredLRPCreation.Key(): desiredLRPCreation,
+			}
+			routeHandler.Sync(logger, nil, nil, nil, cachedEvents)
+
+			Expect(fakeXDSEmitter.EmitCallCount()).To(Equal(1))
+			messages, mappings := fakeXDSEmitter.EmitArgsForCall(0)
+			Expect(messages).To(Equal(messagesToEmit))
+			Expect(mappings).To(Equal(routingEvents))
+		})
+	})
+
+	Describe("EmitExternal", func() {
+		BeforeEach(func() {
+			fakeRoutingTable.GetExternalRoutingEventsReturns(routingEvents, messagesToEmit)
+		})
+
+		It("emits all external registrations", func() {
+			routeHandler.EmitExternal(logger)
+			Expect(fakeXDSEmitter.EmitCallCount()).To(Equal(1))
+			messages, mappings := fakeXDSEmitter.EmitArgsForCall(0)
+			Expect(messages).To(Equal(messagesToEmit))
+			Expect(mappings).To(Equal(routingEvents))
+		})
+	})
+})
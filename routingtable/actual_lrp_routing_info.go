@@ -14,3 +14,17 @@ func NewActualLRPRoutingInfo(actualLRPGroup *models.ActualLRPGroup) *ActualLRPRo
 		Evacuating: evacuating,
 	}
 }
+
+// Routable returns true if the instance should receive traffic. A running
+// instance is routable once its cell reports it routable, e.g. after its
+// readiness check passed, unless ignoreRoutability is set. Cells that do not
+// report routability leave every running instance routable.
+func (info *ActualLRPRoutingInfo) Routable(ignoreRoutability bool) bool {
+	if info.ActualLRP.State != models.ActualLRPStateRunning {
+		return false
+	}
+	if ignoreRoutability || !info.ActualLRP.RoutableExists() {
+		return true
+	}
+	return info.ActualLRP.GetRoutable()
+}
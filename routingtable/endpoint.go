@@ -110,6 +110,16 @@ type Route struct {
 	RouteServiceUrl  string
 	IsolationSegment string
 	LogGUID          string
+	Weight           uint32
+	Options          RouteOptions
+}
+
+// RouteOptions are the router options of an http route. The sticky session
+// cookie names are kept comma separated so that routes stay comparable.
+type RouteOptions struct {
+	LoadBalancingAlgorithm   string
+	StickySessionCookieNames string
+	RequestTimeoutMs         uint32
 }
 
 func (r Route) MessageFor(endpoint Endpoint, directInstanceAddress, emitEndpointUpdatedAt bool) (*RegistryMessage, *tcpmodels.TcpRouteMapping, *RegistryMessage) {
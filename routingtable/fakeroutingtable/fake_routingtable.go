@@ -62,6 +62,19 @@ type FakeRoutingTable struct {
 		result1 routingtable.TCPRouteMappings
 		result2 routingtable.MessagesToEmit
 	}
+	DrainEndpointStub        func(actualLRP *routingtable.ActualLRPRoutingInfo) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit)
+	drainEndpointMutex       sync.RWMutex
+	drainEndpointArgsForCall []struct {
+		actualLRP *routingtable.ActualLRPRoutingInfo
+	}
+	drainEndpointReturns struct {
+		result1 routingtable.TCPRouteMappings
+		result2 routingtable.MessagesToEmit
+	}
+	drainEndpointReturnsOnCall map[int]struct {
+		result1 routingtable.TCPRouteMappings
+		result2 routingtable.MessagesToEmit
+	}
 	SwapStub        func(t routingtable.RoutingTable, domains models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit)
 	swapMutex       sync.RWMutex
 	swapArgsForCall []struct {
@@ -76,6 +89,21 @@ type FakeRoutingTable struct {
 		result1 routingtable.TCPRouteMappings
 		result2 routingtable.MessagesToEmit
 	}
+	SwapProcessesStub        func(t routingtable.RoutingTable, processGuids []string, domains models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit)
+	swapProcessesMutex       sync.RWMutex
+	swapProcessesArgsForCall []struct {
+		t            routingtable.RoutingTable
+		processGuids []string
+		domains      models.DomainSet
+	}
+	swapProcessesReturns struct {
+		result1 routingtable.TCPRouteMappings
+		result2 routingtable.MessagesToEmit
+	}
+	swapProcessesReturnsOnCall map[int]struct {
+		result1 routingtable.TCPRouteMappings
+		result2 routingtable.MessagesToEmit
+	}
 	GetInternalRoutingEventsStub        func() (routingtable.TCPRouteMappings, routingtable.MessagesToEmit)
 	getInternalRoutingEventsMutex       sync.RWMutex
 	getInternalRoutingEventsArgsForCall []struct{}
@@ -98,6 +126,28 @@ type FakeRoutingTable struct {
 		result1 routingtable.TCPRouteMappings
 		result2 routingtable.MessagesToEmit
 	}
+	UnregisterDrainedEndpointsStub        func() routingtable.MessagesToEmit
+	unregisterDrainedEndpointsMutex       sync.RWMutex
+	unregisterDrainedEndpointsArgsForCall []struct{}
+	unregisterDrainedEndpointsReturns     struct {
+		result1 routingtable.MessagesToEmit
+	}
+	unregisterDrainedEndpointsReturnsOnCall map[int]struct {
+		result1 routingtable.MessagesToEmit
+	}
+	RemoveEntriesStub        func(remove func(routingtable.RoutingKey) bool) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit)
+	removeEntriesMutex       sync.RWMutex
+	removeEntriesArgsForCall []struct {
+		remove func(routingtable.RoutingKey) bool
+	}
+	removeEntriesReturns struct {
+		result1 routingtable.TCPRouteMappings
+		result2 routingtable.MessagesToEmit
+	}
+	removeEntriesReturnsOnCall map[int]struct {
+		result1 routingtable.TCPRouteMappings
+		result2 routingtable.MessagesToEmit
+	}
 	HasExternalRoutesStub        func(actual *routingtable.ActualLRPRoutingInfo) bool
 	hasExternalRoutesMutex       sync.RWMutex
 	hasExternalRoutesArgsForCall []struct {
@@ -145,6 +195,73 @@ type FakeRoutingTable struct {
 	tableSizeReturnsOnCall map[int]struct {
 		result1 int
 	}
+	HTTPEntriesStub        func() map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+	hTTPEntriesMutex       sync.RWMutex
+	hTTPEntriesArgsForCall []struct{}
+	hTTPEntriesReturns     struct {
+		result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+	}
+	hTTPEntriesReturnsOnCall map[int]struct {
+		result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+	}
+	HTTPEntriesForHostnamesStub        func(hostnames []string) map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+	hTTPEntriesForHostnamesMutex       sync.RWMutex
+	hTTPEntriesForHostnamesArgsForCall []struct {
+		hostnames []string
+	}
+	hTTPEntriesForHostnamesReturns struct {
+		result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+	}
+	hTTPEntriesForHostnamesReturnsOnCall map[int]struct {
+		result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+	}
+	TCPEntriesStub        func() map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+	tCPEntriesMutex       sync.RWMutex
+	tCPEntriesArgsForCall []struct{}
+	tCPEntriesReturns     struct {
+		result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+	}
+	tCPEntriesReturnsOnCall map[int]struct {
+		result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+	}
+	InternalEntriesStub        func() map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+	internalEntriesMutex       sync.RWMutex
+	internalEntriesArgsForCall []struct{}
+	internalEntriesReturns     struct {
+		result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+	}
+	internalEntriesReturnsOnCall map[int]struct {
+		result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+	}
+	AddressEntriesStub        func() map[routingtable.Address]routingtable.EndpointKey
+	addressEntriesMutex       sync.RWMutex
+	addressEntriesArgsForCall []struct{}
+	addressEntriesReturns     struct {
+		result1 map[routingtable.Address]routingtable.EndpointKey
+	}
+	addressEntriesReturnsOnCall map[int]struct {
+		result1 map[routingtable.Address]routingtable.EndpointKey
+	}
+	SnapshotStub        func() routingtable.Snapshot
+	snapshotMutex       sync.RWMutex
+	snapshotArgsForCall []struct{}
+	snapshotReturns     struct {
+		result1 routingtable.Snapshot
+	}
+	snapshotReturnsOnCall map[int]struct {
+		result1 routingtable.Snapshot
+	}
+	RestoreStub        func(snapshot routingtable.Snapshot) error
+	restoreMutex       sync.RWMutex
+	restoreArgsForCall []struct {
+		snapshot routingtable.Snapshot
+	}
+	restoreReturns struct {
+		result1 error
+	}
+	restoreReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -354,6 +471,57 @@ func (fake *FakeRoutingTable) RemoveEndpointReturnsOnCall(i int, result1 routing
 	}{result1, result2}
 }
 
+func (fake *FakeRoutingTable) DrainEndpoint(actualLRP *routingtable.ActualLRPRoutingInfo) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit) {
+	fake.drainEndpointMutex.Lock()
+	ret, specificReturn := fake.drainEndpointReturnsOnCall[len(fake.drainEndpointArgsForCall)]
+	fake.drainEndpointArgsForCall = append(fake.drainEndpointArgsForCall, struct {
+		actualLRP *routingtable.ActualLRPRoutingInfo
+	}{actualLRP})
+	fake.recordInvocation("DrainEndpoint", []interface{}{actualLRP})
+	fake.drainEndpointMutex.Unlock()
+	if fake.DrainEndpointStub != nil {
+		return fake.DrainEndpointStub(actualLRP)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.drainEndpointReturns.result1, fake.drainEndpointReturns.result2
+}
+
+func (fake *FakeRoutingTable) DrainEndpointCallCount() int {
+	fake.drainEndpointMutex.RLock()
+	defer fake.drainEndpointMutex.RUnlock()
+	return len(fake.drainEndpointArgsForCall)
+}
+
+func (fake *FakeRoutingTable) DrainEndpointArgsForCall(i int) *routingtable.ActualLRPRoutingInfo {
+	fake.drainEndpointMutex.RLock()
+	defer fake.drainEndpointMutex.RUnlock()
+	return fake.drainEndpointArgsForCall[i].actualLRP
+}
+
+func (fake *FakeRoutingTable) DrainEndpointReturns(result1 routingtable.TCPRouteMappings, result2 routingtable.MessagesToEmit) {
+	fake.DrainEndpointStub = nil
+	fake.drainEndpointReturns = struct {
+		result1 routingtable.TCPRouteMappings
+		result2 routingtable.MessagesToEmit
+	}{result1, result2}
+}
+
+func (fake *FakeRoutingTable) DrainEndpointReturnsOnCall(i int, result1 routingtable.TCPRouteMappings, result2 routingtable.MessagesToEmit) {
+	fake.DrainEndpointStub = nil
+	if fake.drainEndpointReturnsOnCall == nil {
+		fake.drainEndpointReturnsOnCall = make(map[int]struct {
+			result1 routingtable.TCPRouteMappings
+			result2 routingtable.MessagesToEmit
+		})
+	}
+	fake.drainEndpointReturnsOnCall[i] = struct {
+		result1 routingtable.TCPRouteMappings
+		result2 routingtable.MessagesToEmit
+	}{result1, result2}
+}
+
 func (fake *FakeRoutingTable) Swap(t routingtable.RoutingTable, domains models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit) {
 	fake.swapMutex.Lock()
 	ret, specificReturn := fake.swapReturnsOnCall[len(fake.swapArgsForCall)]
@@ -406,6 +574,64 @@ func (fake *FakeRoutingTable) SwapReturnsOnCall(i int, result1 routingtable.TCPR
 	}{result1, result2}
 }
 
+func (fake *FakeRoutingTable) SwapProcesses(t routingtable.RoutingTable, processGuids []string, domains models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit) {
+	var processGuidsCopy []string
+	if processGuids != nil {
+		processGuidsCopy = make([]string, len(processGuids))
+		copy(processGuidsCopy, processGuids)
+	}
+	fake.swapProcessesMutex.Lock()
+	ret, specificReturn := fake.swapProcessesReturnsOnCall[len(fake.swapProcessesArgsForCall)]
+	fake.swapProcessesArgsForCall = append(fake.swapProcessesArgsForCall, struct {
+		t            routingtable.RoutingTable
+		processGuids []string
+		domains      models.DomainSet
+	}{t, processGuidsCopy, domains})
+	fake.recordInvocation("SwapProcesses", []interface{}{t, processGuidsCopy, domains})
+	fake.swapProcessesMutex.Unlock()
+	if fake.SwapProcessesStub != nil {
+		return fake.SwapProcessesStub(t, processGuids, domains)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.swapProcessesReturns.result1, fake.swapProcessesReturns.result2
+}
+
+func (fake *FakeRoutingTable) SwapProcessesCallCount() int {
+	fake.swapProcessesMutex.RLock()
+	defer fake.swapProcessesMutex.RUnlock()
+	return len(fake.swapProcessesArgsForCall)
+}
+
+func (fake *FakeRoutingTable) SwapProcessesArgsForCall(i int) (routingtable.RoutingTable, []string, models.DomainSet) {
+	fake.swapProcessesMutex.RLock()
+	defer fake.swapProcessesMutex.RUnlock()
+	return fake.swapProcessesArgsForCall[i].t, fake.swapProcessesArgsForCall[i].processGuids, fake.swapProcessesArgsForCall[i].domains
+}
+
+func (fake *FakeRoutingTable) SwapProcessesReturns(result1 routingtable.TCPRouteMappings, result2 routingtable.MessagesToEmit) {
+	fake.SwapProcessesStub = nil
+	fake.swapProcessesReturns = struct {
+		result1 routingtable.TCPRouteMappings
+		result2 routingtable.MessagesToEmit
+	}{result1, result2}
+}
+
+func (fake *FakeRoutingTable) SwapProcessesReturnsOnCall(i int, result1 routingtable.TCPRouteMappings, result2 routingtable.MessagesToEmit) {
+	fake.SwapProcessesStub = nil
+	if fake.swapProcessesReturnsOnCall == nil {
+		fake.swapProcessesReturnsOnCall = make(map[int]struct {
+			result1 routingtable.TCPRouteMappings
+			result2 routingtable.MessagesToEmit
+		})
+	}
+	fake.swapProcessesReturnsOnCall[i] = struct {
+		result1 routingtable.TCPRouteMappings
+		result2 routingtable.MessagesToEmit
+	}{result1, result2}
+}
+
 func (fake *FakeRoutingTable) GetInternalRoutingEvents() (routingtable.TCPRouteMappings, routingtable.MessagesToEmit) {
 	fake.getInternalRoutingEventsMutex.Lock()
 	ret, specificReturn := fake.getInternalRoutingEventsReturnsOnCall[len(fake.getInternalRoutingEventsArgsForCall)]
@@ -492,6 +718,97 @@ func (fake *FakeRoutingTable) GetExternalRoutingEventsReturnsOnCall(i int, resul
 	}{result1, result2}
 }
 
+func (fake *FakeRoutingTable) UnregisterDrainedEndpoints() routingtable.MessagesToEmit {
+	fake.unregisterDrainedEndpointsMutex.Lock()
+	ret, specificReturn := fake.unregisterDrainedEndpointsReturnsOnCall[len(fake.unregisterDrainedEndpointsArgsForCall)]
+	fake.unregisterDrainedEndpointsArgsForCall = append(fake.unregisterDrainedEndpointsArgsForCall, struct{}{})
+	fake.recordInvocation("UnregisterDrainedEndpoints", []interface{}{})
+	fake.unregisterDrainedEndpointsMutex.Unlock()
+	if fake.UnregisterDrainedEndpointsStub != nil {
+		return fake.UnregisterDrainedEndpointsStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.unregisterDrainedEndpointsReturns.result1
+}
+
+func (fake *FakeRoutingTable) UnregisterDrainedEndpointsCallCount() int {
+	fake.unregisterDrainedEndpointsMutex.RLock()
+	defer fake.unregisterDrainedEndpointsMutex.RUnlock()
+	return len(fake.unregisterDrainedEndpointsArgsForCall)
+}
+
+func (fake *FakeRoutingTable) UnregisterDrainedEndpointsReturns(result1 routingtable.MessagesToEmit) {
+	fake.UnregisterDrainedEndpointsStub = nil
+	fake.unregisterDrainedEndpointsReturns = struct {
+		result1 routingtable.MessagesToEmit
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) UnregisterDrainedEndpointsReturnsOnCall(i int, result1 routingtable.MessagesToEmit) {
+	fake.UnregisterDrainedEndpointsStub = nil
+	if fake.unregisterDrainedEndpointsReturnsOnCall == nil {
+		fake.unregisterDrainedEndpointsReturnsOnCall = make(map[int]struct {
+			result1 routingtable.MessagesToEmit
+		})
+	}
+	fake.unregisterDrainedEndpointsReturnsOnCall[i] = struct {
+		result1 routingtable.MessagesToEmit
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) RemoveEntries(remove func(routingtable.RoutingKey) bool) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit) {
+	fake.removeEntriesMutex.Lock()
+	ret, specificReturn := fake.removeEntriesReturnsOnCall[len(fake.removeEntriesArgsForCall)]
+	fake.removeEntriesArgsForCall = append(fake.removeEntriesArgsForCall, struct {
+		remove func(routingtable.RoutingKey) bool
+	}{remove})
+	fake.recordInvocation("RemoveEntries", []interface{}{remove})
+	fake.removeEntriesMutex.Unlock()
+	if fake.RemoveEntriesStub != nil {
+		return fake.RemoveEntriesStub(remove)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.removeEntriesReturns.result1, fake.removeEntriesReturns.result2
+}
+
+func (fake *FakeRoutingTable) RemoveEntriesCallCount() int {
+	fake.removeEntriesMutex.RLock()
+	defer fake.removeEntriesMutex.RUnlock()
+	return len(fake.removeEntriesArgsForCall)
+}
+
+func (fake *FakeRoutingTable) RemoveEntriesArgsForCall(i int) func(routingtable.RoutingKey) bool {
+	fake.removeEntriesMutex.RLock()
+	defer fake.removeEntriesMutex.RUnlock()
+	return fake.removeEntriesArgsForCall[i].remove
+}
+
+func (fake *FakeRoutingTable) RemoveEntriesReturns(result1 routingtable.TCPRouteMappings, result2 routingtable.MessagesToEmit) {
+	fake.RemoveEntriesStub = nil
+	fake.removeEntriesReturns = struct {
+		result1 routingtable.TCPRouteMappings
+		result2 routingtable.MessagesToEmit
+	}{result1, result2}
+}
+
+func (fake *FakeRoutingTable) RemoveEntriesReturnsOnCall(i int, result1 routingtable.TCPRouteMappings, result2 routingtable.MessagesToEmit) {
+	fake.RemoveEntriesStub = nil
+	if fake.removeEntriesReturnsOnCall == nil {
+		fake.removeEntriesReturnsOnCall = make(map[int]struct {
+			result1 routingtable.TCPRouteMappings
+			result2 routingtable.MessagesToEmit
+		})
+	}
+	fake.removeEntriesReturnsOnCall[i] = struct {
+		result1 routingtable.TCPRouteMappings
+		result2 routingtable.MessagesToEmit
+	}{result1, result2}
+}
+
 func (fake *FakeRoutingTable) HasExternalRoutes(actual *routingtable.ActualLRPRoutingInfo) bool {
 	fake.hasExternalRoutesMutex.Lock()
 	ret, specificReturn := fake.hasExternalRoutesReturnsOnCall[len(fake.hasExternalRoutesArgsForCall)]
@@ -700,6 +1017,307 @@ func (fake *FakeRoutingTable) TableSizeReturnsOnCall(i int, result1 int) {
 	}{result1}
 }
 
+func (fake *FakeRoutingTable) HTTPEntries() map[routingtable.RoutingKey]routingtable.RoutableEndpoints {
+	fake.hTTPEntriesMutex.Lock()
+	ret, specificReturn := fake.hTTPEntriesReturnsOnCall[len(fake.hTTPEntriesArgsForCall)]
+	fake.hTTPEntriesArgsForCall = append(fake.hTTPEntriesArgsForCall, struct{}{})
+	fake.recordInvocation("HTTPEntries", []interface{}{})
+	fake.hTTPEntriesMutex.Unlock()
+	if fake.HTTPEntriesStub != nil {
+		return fake.HTTPEntriesStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.hTTPEntriesReturns.result1
+}
+
+func (fake *FakeRoutingTable) HTTPEntriesCallCount() int {
+	fake.hTTPEntriesMutex.RLock()
+	defer fake.hTTPEntriesMutex.RUnlock()
+	return len(fake.hTTPEntriesArgsForCall)
+}
+
+func (fake *FakeRoutingTable) HTTPEntriesReturns(result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints) {
+	fake.HTTPEntriesStub = nil
+	fake.hTTPEntriesReturns = struct {
+		result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) HTTPEntriesReturnsOnCall(i int, result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints) {
+	fake.HTTPEntriesStub = nil
+	if fake.hTTPEntriesReturnsOnCall == nil {
+		fake.hTTPEntriesReturnsOnCall = make(map[int]struct {
+			result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+		})
+	}
+	fake.hTTPEntriesReturnsOnCall[i] = struct {
+		result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) HTTPEntriesForHostnames(hostnames []string) map[routingtable.RoutingKey]routingtable.RoutableEndpoints {
+	var hostnamesCopy []string
+	if hostnames != nil {
+		hostnamesCopy = make([]string, len(hostnames))
+		copy(hostnamesCopy, hostnames)
+	}
+	fake.hTTPEntriesForHostnamesMutex.Lock()
+	ret, specificReturn := fake.hTTPEntriesForHostnamesReturnsOnCall[len(fake.hTTPEntriesForHostnamesArgsForCall)]
+	fake.hTTPEntriesForHostnamesArgsForCall = append(fake.hTTPEntriesForHostnamesArgsForCall, struct {
+		hostnames []string
+	}{hostnamesCopy})
+	fake.recordInvocation("HTTPEntriesForHostnames", []interface{}{hostnamesCopy})
+	fake.hTTPEntriesForHostnamesMutex.Unlock()
+	if fake.HTTPEntriesForHostnamesStub != nil {
+		return fake.HTTPEntriesForHostnamesStub(hostnames)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.hTTPEntriesForHostnamesReturns.result1
+}
+
+func (fake *FakeRoutingTable) HTTPEntriesForHostnamesCallCount() int {
+	fake.hTTPEntriesForHostnamesMutex.RLock()
+	defer fake.hTTPEntriesForHostnamesMutex.RUnlock()
+	return len(fake.hTTPEntriesForHostnamesArgsForCall)
+}
+
+func (fake *FakeRoutingTable) HTTPEntriesForHostnamesArgsForCall(i int) []string {
+	fake.hTTPEntriesForHostnamesMutex.RLock()
+	defer fake.hTTPEntriesForHostnamesMutex.RUnlock()
+	return fake.hTTPEntriesForHostnamesArgsForCall[i].hostnames
+}
+
+func (fake *FakeRoutingTable) HTTPEntriesForHostnamesReturns(result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints) {
+	fake.HTTPEntriesForHostnamesStub = nil
+	fake.hTTPEntriesForHostnamesReturns = struct {
+		result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) HTTPEntriesForHostnamesReturnsOnCall(i int, result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints) {
+	fake.HTTPEntriesForHostnamesStub = nil
+	if fake.hTTPEntriesForHostnamesReturnsOnCall == nil {
+		fake.hTTPEntriesForHostnamesReturnsOnCall = make(map[int]struct {
+			result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+		})
+	}
+	fake.hTTPEntriesForHostnamesReturnsOnCall[i] = struct {
+		result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) TCPEntries() map[routingtable.RoutingKey]routingtable.RoutableEndpoints {
+	fake.tCPEntriesMutex.Lock()
+	ret, specificReturn := fake.tCPEntriesReturnsOnCall[len(fake.tCPEntriesArgsForCall)]
+	fake.tCPEntriesArgsForCall = append(fake.tCPEntriesArgsForCall, struct{}{})
+	fake.recordInvocation("TCPEntries", []interface{}{})
+	fake.tCPEntriesMutex.Unlock()
+	if fake.TCPEntriesStub != nil {
+		return fake.TCPEntriesStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.tCPEntriesReturns.result1
+}
+
+func (fake *FakeRoutingTable) TCPEntriesCallCount() int {
+	fake.tCPEntriesMutex.RLock()
+	defer fake.tCPEntriesMutex.RUnlock()
+	return len(fake.tCPEntriesArgsForCall)
+}
+
+func (fake *FakeRoutingTable) TCPEntriesReturns(result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints) {
+	fake.TCPEntriesStub = nil
+	fake.tCPEntriesReturns = struct {
+		result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) TCPEntriesReturnsOnCall(i int, result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints) {
+	fake.TCPEntriesStub = nil
+	if fake.tCPEntriesReturnsOnCall == nil {
+		fake.tCPEntriesReturnsOnCall = make(map[int]struct {
+			result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+		})
+	}
+	fake.tCPEntriesReturnsOnCall[i] = struct {
+		result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) InternalEntries() map[routingtable.RoutingKey]routingtable.RoutableEndpoints {
+	fake.internalEntriesMutex.Lock()
+	ret, specificReturn := fake.internalEntriesReturnsOnCall[len(fake.internalEntriesArgsForCall)]
+	fake.internalEntriesArgsForCall = append(fake.internalEntriesArgsForCall, struct{}{})
+	fake.recordInvocation("InternalEntries", []interface{}{})
+	fake.internalEntriesMutex.Unlock()
+	if fake.InternalEntriesStub != nil {
+		return fake.InternalEntriesStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.internalEntriesReturns.result1
+}
+
+func (fake *FakeRoutingTable) InternalEntriesCallCount() int {
+	fake.internalEntriesMutex.RLock()
+	defer fake.internalEntriesMutex.RUnlock()
+	return len(fake.internalEntriesArgsForCall)
+}
+
+func (fake *FakeRoutingTable) InternalEntriesReturns(result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints) {
+	fake.InternalEntriesStub = nil
+	fake.internalEntriesReturns = struct {
+		result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) InternalEntriesReturnsOnCall(i int, result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints) {
+	fake.InternalEntriesStub = nil
+	if fake.internalEntriesReturnsOnCall == nil {
+		fake.internalEntriesReturnsOnCall = make(map[int]struct {
+			result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+		})
+	}
+	fake.internalEntriesReturnsOnCall[i] = struct {
+		result1 map[routingtable.RoutingKey]routingtable.RoutableEndpoints
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) AddressEntries() map[routingtable.Address]routingtable.EndpointKey {
+	fake.addressEntriesMutex.Lock()
+	ret, specificReturn := fake.addressEntriesReturnsOnCall[len(fake.addressEntriesArgsForCall)]
+	fake.addressEntriesArgsForCall = append(fake.addressEntriesArgsForCall, struct{}{})
+	fake.recordInvocation("AddressEntries", []interface{}{})
+	fake.addressEntriesMutex.Unlock()
+	if fake.AddressEntriesStub != nil {
+		return fake.AddressEntriesStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.addressEntriesReturns.result1
+}
+
+func (fake *FakeRoutingTable) AddressEntriesCallCount() int {
+	fake.addressEntriesMutex.RLock()
+	defer fake.addressEntriesMutex.RUnlock()
+	return len(fake.addressEntriesArgsForCall)
+}
+
+func (fake *FakeRoutingTable) AddressEntriesReturns(result1 map[routingtable.Address]routingtable.EndpointKey) {
+	fake.AddressEntriesStub = nil
+	fake.addressEntriesReturns = struct {
+		result1 map[routingtable.Address]routingtable.EndpointKey
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) AddressEntriesReturnsOnCall(i int, result1 map[routingtable.Address]routingtable.EndpointKey) {
+	fake.AddressEntriesStub = nil
+	if fake.addressEntriesReturnsOnCall == nil {
+		fake.addressEntriesReturnsOnCall = make(map[int]struct {
+			result1 map[routingtable.Address]routingtable.EndpointKey
+		})
+	}
+	fake.addressEntriesReturnsOnCall[i] = struct {
+		result1 map[routingtable.Address]routingtable.EndpointKey
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) Snapshot() routingtable.Snapshot {
+	fake.snapshotMutex.Lock()
+	ret, specificReturn := fake.snapshotReturnsOnCall[len(fake.snapshotArgsForCall)]
+	fake.snapshotArgsForCall = append(fake.snapshotArgsForCall, struct{}{})
+	fake.recordInvocation("Snapshot", []interface{}{})
+	fake.snapshotMutex.Unlock()
+	if fake.SnapshotStub != nil {
+		return fake.SnapshotStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.snapshotReturns.result1
+}
+
+func (fake *FakeRoutingTable) SnapshotCallCount() int {
+	fake.snapshotMutex.RLock()
+	defer fake.snapshotMutex.RUnlock()
+	return len(fake.snapshotArgsForCall)
+}
+
+func (fake *FakeRoutingTable) SnapshotReturns(result1 routingtable.Snapshot) {
+	fake.SnapshotStub = nil
+	fake.snapshotReturns = struct {
+		result1 routingtable.Snapshot
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) SnapshotReturnsOnCall(i int, result1 routingtable.Snapshot) {
+	fake.SnapshotStub = nil
+	if fake.snapshotReturnsOnCall == nil {
+		fake.snapshotReturnsOnCall = make(map[int]struct {
+			result1 routingtable.Snapshot
+		})
+	}
+	fake.snapshotReturnsOnCall[i] = struct {
+		result1 routingtable.Snapshot
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) Restore(snapshot routingtable.Snapshot) error {
+	fake.restoreMutex.Lock()
+	ret, specificReturn := fake.restoreReturnsOnCall[len(fake.restoreArgsForCall)]
+	fake.restoreArgsForCall = append(fake.restoreArgsForCall, struct {
+		snapshot routingtable.Snapshot
+	}{snapshot})
+	fake.recordInvocation("Restore", []interface{}{snapshot})
+	fake.restoreMutex.Unlock()
+	if fake.RestoreStub != nil {
+		return fake.RestoreStub(snapshot)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.restoreReturns.result1
+}
+
+func (fake *FakeRoutingTable) RestoreCallCount() int {
+	fake.restoreMutex.RLock()
+	defer fake.restoreMutex.RUnlock()
+	return len(fake.restoreArgsForCall)
+}
+
+func (fake *FakeRoutingTable) RestoreArgsForCall(i int) routingtable.Snapshot {
+	fake.restoreMutex.RLock()
+	defer fake.restoreMutex.RUnlock()
+	return fake.restoreArgsForCall[i].snapshot
+}
+
+func (fake *FakeRoutingTable) RestoreReturns(result1 error) {
+	fake.RestoreStub = nil
+	fake.restoreReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) RestoreReturnsOnCall(i int, result1 error) {
+	fake.RestoreStub = nil
+	if fake.restoreReturnsOnCall == nil {
+		fake.restoreReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.restoreReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeRoutingTable) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -711,12 +1329,20 @@ func (fake *FakeRoutingTable) Invocations() map[string][][]interface{} {
 	defer fake.addEndpointMutex.RUnlock()
 	fake.removeEndpointMutex.RLock()
 	defer fake.removeEndpointMutex.RUnlock()
+	fake.drainEndpointMutex.RLock()
+	defer fake.drainEndpointMutex.RUnlock()
 	fake.swapMutex.RLock()
 	defer fake.swapMutex.RUnlock()
+	fake.swapProcessesMutex.RLock()
+	defer fake.swapProcessesMutex.RUnlock()
 	fake.getInternalRoutingEventsMutex.RLock()
 	defer fake.getInternalRoutingEventsMutex.RUnlock()
 	fake.getExternalRoutingEventsMutex.RLock()
 	defer fake.getExternalRoutingEventsMutex.RUnlock()
+	fake.unregisterDrainedEndpointsMutex.RLock()
+	defer fake.unregisterDrainedEndpointsMutex.RUnlock()
+	fake.removeEntriesMutex.RLock()
+	defer fake.removeEntriesMutex.RUnlock()
 	fake.hasExternalRoutesMutex.RLock()
 	defer fake.hasExternalRoutesMutex.RUnlock()
 	fake.hTTPAssociationsCountMutex.RLock()
@@ -727,6 +1353,20 @@ func (fake *FakeRoutingTable) Invocations() map[string][][]interface{} {
 	defer fake.tCPAssociationsCountMutex.RUnlock()
 	fake.tableSizeMutex.RLock()
 	defer fake.tableSizeMutex.RUnlock()
+	fake.hTTPEntriesMutex.RLock()
+	defer fake.hTTPEntriesMutex.RUnlock()
+	fake.hTTPEntriesForHostnamesMutex.RLock()
+	defer fake.hTTPEntriesForHostnamesMutex.RUnlock()
+	fake.tCPEntriesMutex.RLock()
+	defer fake.tCPEntriesMutex.RUnlock()
+	fake.internalEntriesMutex.RLock()
+	defer fake.internalEntriesMutex.RUnlock()
+	fake.addressEntriesMutex.RLock()
+	defer fake.addressEntriesMutex.RUnlock()
+	fake.snapshotMutex.RLock()
+	defer fake.snapshotMutex.RUnlock()
+	fake.restoreMutex.RLock()
+	defer fake.restoreMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
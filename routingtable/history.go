@@ -0,0 +1,259 @@
+package routingtable
+
+import (
+	"container/list"
+	"sort"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+)
+
+// The changes recorded for the registration of an endpoint for a route.
+const (
+	ChangeAdded    = "added"
+	ChangeModified = "modified"
+	ChangeRemoved  = "removed"
+)
+
+// The reasons of a change, the table operation that made it.
+const (
+	ReasonRoutesChanged   = "routes-changed"
+	ReasonEndpointAdded   = "endpoint-added"
+	ReasonEndpointRemoved = "endpoint-removed"
+	ReasonSync            = "sync"
+	ReasonEntriesRemoved  = "entries-removed"
+)
+
+// ChangeRecord is a change to the registration of a single endpoint for a
+// single route. Hostname is set for http and internal routes, RouterGroupGUID
+// and ExternalPort for tcp routes.
+type ChangeRecord struct {
+	Time            time.Time `json:"time"`
+	Table           string    `json:"table"`
+	ProcessGUID     string    `json:"process_guid"`
+	ContainerPort   uint32    `json:"container_port"`
+	Change          string    `json:"change"`
+	Reason          string    `json:"reason"`
+	Hostname        string    `json:"hostname,omitempty"`
+	RouterGroupGUID string    `json:"router_group_guid,omitempty"`
+	ExternalPort    uint32    `json:"external_port,omitempty"`
+	InstanceGUID    string    `json:"instance_guid"`
+	Index           int32     `json:"index"`
+	Host            string    `json:"host"`
+	Port            uint32    `json:"port"`
+	Evacuating      bool      `json:"evacuating,omitempty"`
+
+	seq uint64
+}
+
+// HistoryFilter selects change records, empty fields match every record.
+type HistoryFilter struct {
+	ProcessGUID     string
+	Hostname        string
+	RouterGroupGUID string
+	ExternalPort    uint32
+}
+
+func (f HistoryFilter) matches(record ChangeRecord) bool {
+	if f.ProcessGUID != "" && record.ProcessGUID != f.ProcessGUID {
+		return false
+	}
+	if f.Hostname != "" && record.Hostname != f.Hostname {
+		return false
+	}
+	if f.RouterGroupGUID != "" && record.RouterGroupGUID != f.RouterGroupGUID {
+		return false
+	}
+	if f.ExternalPort != 0 && record.ExternalPort != f.ExternalPort {
+		return false
+	}
+	return true
+}
+
+// History keeps the most recent change records of every routing key, at most
+// maxRecordsPerKey of them. Once more than maxKeys routing keys have a
+// history the least recently changed one is forgotten.
+type History struct {
+	clock            clock.Clock
+	maxRecordsPerKey int
+	maxKeys          int
+
+	lock  sync.Mutex
+	seq   uint64
+	keys  *list.List // of *keyHistory, least recently changed first
+	byKey map[historyKey]*list.Element
+}
+
+type historyKey struct {
+	table string
+	key   RoutingKey
+}
+
+type keyHistory struct {
+	key     historyKey
+	records []ChangeRecord
+	next    int
+	full    bool
+}
+
+func NewHistory(clock clock.Clock, maxRecordsPerKey, maxKeys int) *History {
+	return &History{
+		clock:            clock,
+		maxRecordsPerKey: maxRecordsPerKey,
+		maxKeys:          maxKeys,
+		keys:             list.New(),
+		byKey:            map[historyKey]*list.Element{},
+	}
+}
+
+// Records returns the records matching filter, oldest first.
+func (h *History) Records(filter HistoryFilter) []ChangeRecord {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	records := []ChangeRecord{}
+	for element := h.keys.Front(); element != nil; element = element.Next() {
+		for _, record := range element.Value.(*keyHistory).ordered() {
+			if filter.matches(record) {
+				records = append(records, record)
+			}
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].seq < records[j].seq
+	})
+	return records
+}
+
+func (h *History) record(table string, key RoutingKey, reason string, routesDiff routesDiff, endpointsDiff endpointsDiff, registrations registrationSet, unregistrations unregistrationSet) {
+	records := changeRecords(table, key, reason, routesDiff, endpointsDiff, registrations, unregistrations)
+	if len(records) == 0 || h.maxRecordsPerKey <= 0 || h.maxKeys <= 0 {
+		return
+	}
+
+	now := h.clock.Now()
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	hkey := historyKey{table: table, key: key}
+	element, ok := h.byKey[hkey]
+	if ok {
+		h.keys.MoveToBack(element)
+	} else {
+		element = h.keys.PushBack(&keyHistory{key: hkey, records: make([]ChangeRecord, h.maxRecordsPerKey)})
+		h.byKey[hkey] = element
+	}
+
+	history := element.Value.(*keyHistory)
+	for _, record := range records {
+		h.seq++
+		record.seq = h.seq
+		record.Time = now
+		history.add(record)
+	}
+
+	for h.keys.Len() > h.maxKeys {
+		oldest := h.keys.Front()
+		h.keys.Remove(oldest)
+		delete(h.byKey, oldest.Value.(*keyHistory).key)
+	}
+}
+
+func (k *keyHistory) add(record ChangeRecord) {
+	k.records[k.next] = record
+	k.next++
+	if k.next == len(k.records) {
+		k.next = 0
+		k.full = true
+	}
+}
+
+func (k *keyHistory) ordered() []ChangeRecord {
+	records := []ChangeRecord{}
+	if k.full {
+		records = append(records, k.records[k.next:]...)
+	}
+	return append(records, k.records[:k.next]...)
+}
+
+// changeRecords turns the registrations and unregistrations of a diff into
+// records. An endpoint unregistered and registered again for the same route
+// is modified, as is one registered again because its route changed.
+func changeRecords(table string, key RoutingKey, reason string, routesDiff routesDiff, endpointsDiff endpointsDiff, registrations registrationSet, unregistrations unregistrationSet) []ChangeRecord {
+	type pair struct {
+		route        routeMapping
+		instanceGUID string
+	}
+
+	registered := map[pair]struct{}{}
+	for route, endpoints := range registrations {
+		for endpoint := range endpoints {
+			registered[pair{routeIdentity(route), endpoint.InstanceGUID}] = struct{}{}
+		}
+	}
+
+	existed := func(route routeMapping, endpoint Endpoint) bool {
+		for _, before := range routesDiff.before {
+			if routeIdentity(before) != routeIdentity(route) {
+				continue
+			}
+			for _, beforeEndpoint := range endpointsDiff.before {
+				if beforeEndpoint.InstanceGUID == endpoint.InstanceGUID {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	records := []ChangeRecord{}
+	for route, endpoints := range unregistrations {
+		for endpoint := range endpoints {
+			if _, ok := registered[pair{routeIdentity(route), endpoint.InstanceGUID}]; ok {
+				continue
+			}
+			records = append(records, newChangeRecord(table, key, ChangeRemoved, reason, route, endpoint))
+		}
+	}
+	for route, endpoints := range registrations {
+		for endpoint := range endpoints {
+			change := ChangeAdded
+			if existed(route, endpoint) {
+				change = ChangeModified
+			}
+			records = append(records, newChangeRecord(table, key, change, reason, route, endpoint))
+		}
+	}
+
+	return records
+}
+
+func newChangeRecord(table string, key RoutingKey, change, reason string, route routeMapping, endpoint Endpoint) ChangeRecord {
+	record := ChangeRecord{
+		Table:         table,
+		ProcessGUID:   key.ProcessGUID,
+		ContainerPort: key.ContainerPort,
+		Change:        change,
+		Reason:        reason,
+		InstanceGUID:  endpoint.InstanceGUID,
+		Index:         endpoint.Index,
+		Host:          endpoint.Host,
+		Port:          endpoint.Port,
+		Evacuating:    endpoint.Evacuating,
+	}
+
+	switch route := route.(type) {
+	case Route:
+		record.Hostname = route.Hostname
+	case InternalRoute:
+		record.Hostname = route.Hostname
+	case ExternalEndpointInfo:
+		record.RouterGroupGUID = route.RouterGroupGUID
+		record.ExternalPort = route.Port
+	}
+
+	return record
+}
@@ -0,0 +1,205 @@
+package routingtable_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	metricsfakes "code.cloudfoundry.org/route-emitter/metrics/fakes"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("History", func() {
+	var (
+		clock            *fakeclock.FakeClock
+		history          *routingtable.History
+		table            routingtable.RoutingTable
+		maxRecordsPerKey int
+		maxKeys          int
+	)
+
+	key := routingtable.RoutingKey{ProcessGUID: "process-guid", ContainerPort: 8080}
+	otherKey := routingtable.RoutingKey{ProcessGUID: "other-process-guid", ContainerPort: 8080}
+	tag := models.ModificationTag{Epoch: "abc", Index: 1}
+
+	endpoint := routingtable.Endpoint{
+		InstanceGUID:    "instance-guid",
+		Host:            "1.1.1.1",
+		ContainerIP:     "1.2.3.4",
+		Index:           0,
+		Port:            61001,
+		ContainerPort:   8080,
+		ModificationTag: &tag,
+	}
+
+	desire := func(key routingtable.RoutingKey, hostnames ...string) {
+		table.SetRoutes(nil, createDesiredLRPSchedulingInfo(key.ProcessGUID, 1, key.ContainerPort, "log-guid", "", tag, hostnames...))
+	}
+
+	BeforeEach(func() {
+		maxRecordsPerKey = 10
+		maxKeys = 10
+	})
+
+	JustBeforeEach(func() {
+		clock = fakeclock.NewFakeClock(time.Now())
+		history = routingtable.NewHistory(clock, maxRecordsPerKey, maxKeys)
+		table = routingtable.NewDrainingRoutingTable(lagertest.NewTestLogger("test"), false, &metricsfakes.FakeSink{}, clock, 0, history)
+	})
+
+	It("records nothing for routes without endpoints", func() {
+		desire(key, "foo.example.com")
+		Expect(history.Records(routingtable.HistoryFilter{})).To(BeEmpty())
+	})
+
+	Context("when an endpoint is added", func() {
+		JustBeforeEach(func() {
+			desire(key, "foo.example.com", "bar.example.com")
+			table.AddEndpoint(createActualLRP(key, endpoint, "domain"))
+		})
+
+		It("records the endpoint as added for every route", func() {
+			records := history.Records(routingtable.HistoryFilter{})
+			Expect(records).To(HaveLen(2))
+			for _, record := range records {
+				Expect(record.Time).To(Equal(clock.Now()))
+				Expect(record.Table).To(Equal("http"))
+				Expect(record.ProcessGUID).To(Equal("process-guid"))
+				Expect(record.ContainerPort).To(BeEquivalentTo(8080))
+				Expect(record.Change).To(Equal(routingtable.ChangeAdded))
+				Expect(record.Reason).To(Equal(routingtable.ReasonEndpointAdded))
+				Expect(record.InstanceGUID).To(Equal("instance-guid"))
+				Expect(record.Host).To(Equal("1.1.1.1"))
+				Expect(record.Port).To(BeEquivalentTo(61001))
+			}
+			Expect([]string{records[0].Hostname, records[1].Hostname}).To(ConsistOf("foo.example.com", "bar.example.com"))
+		})
+
+		It("filters the records by hostname", func() {
+			records := history.Records(routingtable.HistoryFilter{Hostname: "bar.example.com"})
+			Expect(records).To(HaveLen(1))
+			Expect(records[0].Hostname).To(Equal("bar.example.com"))
+
+			Expect(history.Records(routingtable.HistoryFilter{Hostname: "baz.example.com"})).To(BeEmpty())
+		})
+
+		It("filters the records by process guid", func() {
+			Expect(history.Records(routingtable.HistoryFilter{ProcessGUID: "process-guid"})).To(HaveLen(2))
+			Expect(history.Records(routingtable.HistoryFilter{ProcessGUID: "other-process-guid"})).To(BeEmpty())
+		})
+
+		It("leaves broadcasts of the table out of the history", func() {
+			table.GetExternalRoutingEvents()
+			Expect(history.Records(routingtable.HistoryFilter{})).To(HaveLen(2))
+		})
+
+		Context("and then moved to another port", func() {
+			JustBeforeEach(func() {
+				clock.Increment(time.Second)
+
+				moved := endpoint
+				moved.Port = 61002
+				moved.ModificationTag = &models.ModificationTag{Epoch: "abc", Index: 2}
+				table.AddEndpoint(createActualLRP(key, moved, "domain"))
+			})
+
+			It("records the endpoint as modified", func() {
+				records := history.Records(routingtable.HistoryFilter{Hostname: "foo.example.com"})
+				Expect(records).To(HaveLen(2))
+				Expect(records[1].Time).To(Equal(clock.Now()))
+				Expect(records[1].Change).To(Equal(routingtable.ChangeModified))
+				Expect(records[1].Reason).To(Equal(routingtable.ReasonEndpointAdded))
+				Expect(records[1].Port).To(BeEquivalentTo(61002))
+			})
+		})
+
+		Context("and then removed", func() {
+			JustBeforeEach(func() {
+				table.RemoveEndpoint(createActualLRP(key, endpoint, "domain"))
+			})
+
+			It("records the endpoint as removed", func() {
+				records := history.Records(routingtable.HistoryFilter{Hostname: "foo.example.com"})
+				Expect(records).To(HaveLen(2))
+				Expect(records[0].Change).To(Equal(routingtable.ChangeAdded))
+				Expect(records[1].Change).To(Equal(routingtable.ChangeRemoved))
+				Expect(records[1].Reason).To(Equal(routingtable.ReasonEndpointRemoved))
+			})
+		})
+
+		Context("and then a route is removed", func() {
+			JustBeforeEach(func() {
+				before := createDesiredLRPSchedulingInfo(key.ProcessGUID, 1, key.ContainerPort, "log-guid", "", tag, "foo.example.com", "bar.example.com")
+				after := createDesiredLRPSchedulingInfo(key.ProcessGUID, 1, key.ContainerPort, "log-guid", "", models.ModificationTag{Epoch: "abc", Index: 2}, "foo.example.com")
+				table.SetRoutes(before, after)
+			})
+
+			It("records the endpoint as removed from that route only", func() {
+				records := history.Records(routingtable.HistoryFilter{})
+				Expect(records).To(HaveLen(3))
+				Expect(records[2].Hostname).To(Equal("bar.example.com"))
+				Expect(records[2].Change).To(Equal(routingtable.ChangeRemoved))
+				Expect(records[2].Reason).To(Equal(routingtable.ReasonRoutesChanged))
+			})
+		})
+	})
+
+	Context("when an endpoint is added to tcp routes", func() {
+		JustBeforeEach(func() {
+			routes := createRoutingInfo(key.ContainerPort, nil, nil, "", []uint32{5222}, "router-group-guid")
+			table.SetRoutes(nil, createSchedulingInfoWithRoutes(key.ProcessGUID, 1, routes, "log-guid", tag))
+			table.AddEndpoint(createActualLRP(key, endpoint, "domain"))
+		})
+
+		It("records the router group and external port", func() {
+			records := history.Records(routingtable.HistoryFilter{RouterGroupGUID: "router-group-guid", ExternalPort: 5222})
+			Expect(records).To(HaveLen(1))
+			Expect(records[0].Table).To(Equal("tcp"))
+			Expect(records[0].Hostname).To(BeEmpty())
+			Expect(records[0].Change).To(Equal(routingtable.ChangeAdded))
+
+			Expect(history.Records(routingtable.HistoryFilter{ExternalPort: 5223})).To(BeEmpty())
+		})
+	})
+
+	Context("when a routing key has more changes than are retained", func() {
+		BeforeEach(func() {
+			maxRecordsPerKey = 2
+		})
+
+		It("keeps the most recent ones", func() {
+			desire(key, "foo.example.com")
+			table.AddEndpoint(createActualLRP(key, endpoint, "domain"))
+			table.RemoveEndpoint(createActualLRP(key, endpoint, "domain"))
+			table.AddEndpoint(createActualLRP(key, endpoint, "domain"))
+
+			records := history.Records(routingtable.HistoryFilter{})
+			Expect(records).To(HaveLen(2))
+			Expect(records[0].Change).To(Equal(routingtable.ChangeRemoved))
+			Expect(records[1].Change).To(Equal(routingtable.ChangeAdded))
+		})
+	})
+
+	Context("when more routing keys changed than are retained", func() {
+		BeforeEach(func() {
+			maxKeys = 1
+		})
+
+		It("forgets the least recently changed one", func() {
+			desire(key, "foo.example.com")
+			table.AddEndpoint(createActualLRP(key, endpoint, "domain"))
+
+			other := endpoint
+			other.InstanceGUID = "other-instance-guid"
+			desire(otherKey, "bar.example.com")
+			table.AddEndpoint(createActualLRP(otherKey, other, "domain"))
+
+			records := history.Records(routingtable.HistoryFilter{})
+			Expect(records).To(HaveLen(1))
+			Expect(records[0].ProcessGUID).To(Equal("other-process-guid"))
+		})
+	})
+})
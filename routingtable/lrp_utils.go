@@ -3,7 +3,7 @@ package routingtable
 import (
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/lager"
-	"code.cloudfoundry.org/routing-info/cfroutes"
+	"code.cloudfoundry.org/route-emitter/cfroutes"
 	"code.cloudfoundry.org/routing-info/internalroutes"
 	"code.cloudfoundry.org/routing-info/tcp_routes"
 )
@@ -2,14 +2,17 @@ package routingtable_test
 
 import (
 	"fmt"
+	"time"
 
 	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
 
 	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
 	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/cfroutes"
+	"code.cloudfoundry.org/route-emitter/metrics"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	. "code.cloudfoundry.org/route-emitter/routingtable/matchers"
-	"code.cloudfoundry.org/routing-info/cfroutes"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gbytes"
@@ -21,6 +24,7 @@ var _ = Describe("RoutingTable", func() {
 		messagesToEmit   routingtable.MessagesToEmit
 		logger           *lagertest.TestLogger
 		fakeMetronClient *mfakes.FakeIngressClient
+		metricsSink      metrics.Sink
 	)
 
 	key := routingtable.RoutingKey{ProcessGUID: "some-process-guid", ContainerPort: 8080}
@@ -111,7 +115,8 @@ var _ = Describe("RoutingTable", func() {
 		logger = lagertest.NewTestLogger("test-route-emitter")
 
 		fakeMetronClient = &mfakes.FakeIngressClient{}
-		table = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+		metricsSink = metrics.NewLoggregatorSink(fakeMetronClient)
+		table = routingtable.NewRoutingTable(logger, false, metricsSink)
 	})
 
 	createSchedulingInfo := func(serviceURL string) *models.DesiredLRPSchedulingInfo {
@@ -199,9 +204,179 @@ var _ = Describe("RoutingTable", func() {
 		})
 	})
 
+	Describe("Draining endpoints", func() {
+		var (
+			clock     *fakeclock.FakeClock
+			actualLRP *routingtable.ActualLRPRoutingInfo
+		)
+
+		route := routingtable.Route{Hostname: hostname1, LogGUID: logGuid}
+
+		drainingRegistrationFor := func(endpoint routingtable.Endpoint) routingtable.RegistryMessage {
+			message := routingtable.RegistryMessageFor(endpoint, route, false)
+			message.Tags = map[string]string{"component": "route-emitter", routingtable.DrainingTag: "true"}
+			return message
+		}
+
+		BeforeEach(func() {
+			clock = fakeclock.NewFakeClock(time.Now())
+			table = routingtable.NewDrainingRoutingTable(logger, false, metricsSink, clock, 10*time.Second, nil)
+
+			schedulingInfo := createDesiredLRPSchedulingInfo(key.ProcessGUID, int32(3), key.ContainerPort, logGuid, "", *currentTag, hostname1)
+			table.SetRoutes(nil, schedulingInfo)
+
+			actualLRP = createActualLRP(key, endpoint1, domain)
+			table.AddEndpoint(actualLRP)
+
+			_, messagesToEmit = table.DrainEndpoint(actualLRP)
+		})
+
+		It("registers the removed endpoint again with the draining tag", func() {
+			expected := routingtable.MessagesToEmit{
+				RegistrationMessages: []routingtable.RegistryMessage{
+					drainingRegistrationFor(endpoint1),
+				},
+			}
+			Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+		})
+
+		It("keeps registering the endpoint on emits", func() {
+			_, messagesToEmit = table.GetExternalRoutingEvents()
+			Expect(messagesToEmit.RegistrationMessages).To(ConsistOf(drainingRegistrationFor(endpoint1)))
+		})
+
+		It("unregisters the endpoint once the drain window elapses", func() {
+			clock.Increment(9 * time.Second)
+			Expect(table.UnregisterDrainedEndpoints()).To(BeZero())
+
+			clock.Increment(time.Second)
+			expected := routingtable.MessagesToEmit{
+				UnregistrationMessages: []routingtable.RegistryMessage{
+					routingtable.RegistryMessageFor(endpoint1, route, false),
+				},
+			}
+			Expect(table.UnregisterDrainedEndpoints()).To(MatchMessagesToEmit(expected))
+			Expect(table.UnregisterDrainedEndpoints()).To(BeZero())
+		})
+
+		Context("when a replacement for the instance index comes up", func() {
+			BeforeEach(func() {
+				actualLRP = createActualLRP(key, newInstanceEndpointAfterEvacuation, domain)
+				_, messagesToEmit = table.AddEndpoint(actualLRP)
+			})
+
+			It("registers the replacement and unregisters the draining endpoint", func() {
+				expected := routingtable.MessagesToEmit{
+					RegistrationMessages: []routingtable.RegistryMessage{
+						routingtable.RegistryMessageFor(newInstanceEndpointAfterEvacuation, route, true),
+					},
+					UnregistrationMessages: []routingtable.RegistryMessage{
+						routingtable.RegistryMessageFor(endpoint1, route, false),
+					},
+				}
+				Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+			})
+
+			It("stops draining the endpoint", func() {
+				clock.Increment(10 * time.Second)
+				Expect(table.UnregisterDrainedEndpoints()).To(BeZero())
+			})
+		})
+
+		Context("when the replacement for the instance index came up before the instance was removed", func() {
+			BeforeEach(func() {
+				evacuatingLRP := createActualLRP(key, evacuating1, domain)
+				table.AddEndpoint(evacuatingLRP)
+				table.AddEndpoint(createActualLRP(key, newInstanceEndpointAfterEvacuation, domain))
+
+				_, messagesToEmit = table.DrainEndpoint(evacuatingLRP)
+			})
+
+			It("unregisters the removed instance right away", func() {
+				expected := routingtable.MessagesToEmit{
+					UnregistrationMessages: []routingtable.RegistryMessage{
+						routingtable.RegistryMessageFor(evacuating1, route, false),
+					},
+				}
+				Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+			})
+
+			It("does not drain it", func() {
+				_, messagesToEmit = table.GetExternalRoutingEvents()
+				Expect(messagesToEmit.RegistrationMessages).To(HaveLen(1))
+				Expect(messagesToEmit.RegistrationMessages[0].PrivateInstanceId).To(Equal(newInstanceEndpointAfterEvacuation.InstanceGUID))
+				Expect(messagesToEmit.RegistrationMessages[0].Tags).NotTo(HaveKey(routingtable.DrainingTag))
+
+				clock.Increment(10 * time.Second)
+				Expect(table.UnregisterDrainedEndpoints()).To(BeZero())
+			})
+		})
+
+		Context("when the entry of the endpoint is removed", func() {
+			BeforeEach(func() {
+				_, messagesToEmit = table.RemoveEntries(func(routingtable.RoutingKey) bool { return true })
+			})
+
+			It("unregisters the draining endpoint and stops draining it", func() {
+				expected := routingtable.MessagesToEmit{
+					UnregistrationMessages: []routingtable.RegistryMessage{
+						routingtable.RegistryMessageFor(endpoint1, route, false),
+					},
+				}
+				Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+
+				clock.Increment(10 * time.Second)
+				Expect(table.UnregisterDrainedEndpoints()).To(BeZero())
+			})
+		})
+
+		Context("when an endpoint is removed instead of drained", func() {
+			BeforeEach(func() {
+				crashedLRP := createActualLRP(key, endpoint2, domain)
+				table.AddEndpoint(crashedLRP)
+
+				_, messagesToEmit = table.RemoveEndpoint(crashedLRP)
+			})
+
+			It("unregisters it right away", func() {
+				expected := routingtable.MessagesToEmit{
+					UnregistrationMessages: []routingtable.RegistryMessage{
+						routingtable.RegistryMessageFor(endpoint2, route, false),
+					},
+				}
+				Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+			})
+
+			It("does not drain it", func() {
+				_, messagesToEmit = table.GetExternalRoutingEvents()
+				Expect(messagesToEmit.RegistrationMessages).To(ConsistOf(drainingRegistrationFor(endpoint1)))
+
+				clock.Increment(10 * time.Second)
+				expected := routingtable.MessagesToEmit{
+					UnregistrationMessages: []routingtable.RegistryMessage{
+						routingtable.RegistryMessageFor(endpoint1, route, false),
+					},
+				}
+				Expect(table.UnregisterDrainedEndpoints()).To(MatchMessagesToEmit(expected))
+			})
+		})
+
+		Context("when the same instance comes back", func() {
+			BeforeEach(func() {
+				_, messagesToEmit = table.AddEndpoint(actualLRP)
+			})
+
+			It("registers it again without unregistering it", func() {
+				Expect(messagesToEmit.UnregistrationMessages).To(BeEmpty())
+				clock.Increment(10 * time.Second)
+				Expect(table.UnregisterDrainedEndpoints()).To(BeZero())
+			})
+		})
+	})
+
 	Context("when internal address message builder is used", func() {
 		BeforeEach(func() {
-			table = routingtable.NewRoutingTable(logger, true, fakeMetronClient)
+			table = routingtable.NewRoutingTable(logger, true, metricsSink)
 			desiredLRP := createDesiredLRPSchedulingInfo(key.ProcessGUID, int32(3), key.ContainerPort, logGuid, "", *currentTag, hostname1)
 			table.SetRoutes(nil, desiredLRP)
 		})
@@ -271,7 +446,7 @@ var _ = Describe("RoutingTable", func() {
 	Describe("Swap", func() {
 		Context("when we have existing stuff in the table and an unfresh domain", func() {
 			BeforeEach(func() {
-				tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+				tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 
 				routes := createRoutingInfo(key.ContainerPort, []string{hostname1, hostname2}, []string{internalHostname1}, "", []uint32{}, "")
 				schedulingInfo := createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag)
@@ -281,7 +456,7 @@ var _ = Describe("RoutingTable", func() {
 
 				table.Swap(tempTable, domains)
 
-				tempTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+				tempTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 				routes = createRoutingInfo(key.ContainerPort, []string{hostname1, hostname3}, []string{internalHostname2}, "", []uint32{}, "")
 				schedulingInfo = createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag)
 				tempTable.SetRoutes(nil, schedulingInfo)
@@ -312,7 +487,7 @@ var _ = Describe("RoutingTable", func() {
 
 			Context("subsequent swaps with still not fresh domain", func() {
 				BeforeEach(func() {
-					tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 					schedulingInfo := createDesiredLRPSchedulingInfo(key.ProcessGUID, int32(3), key.ContainerPort, logGuid, "", *currentTag, hostname1, hostname3)
 					lrp := createActualLRP(key, endpoint1, domain)
 					tempTable.SetRoutes(nil, schedulingInfo)
@@ -329,7 +504,7 @@ var _ = Describe("RoutingTable", func() {
 
 			Context("subsequent swaps with fresh", func() {
 				BeforeEach(func() {
-					tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 					schedulingInfo := createDesiredLRPSchedulingInfo(key.ProcessGUID, int32(3), key.ContainerPort, logGuid, "", *currentTag, hostname1, hostname3)
 					lrp := createActualLRP(key, endpoint1, domain)
 					tempTable.SetRoutes(nil, schedulingInfo)
@@ -351,7 +526,7 @@ var _ = Describe("RoutingTable", func() {
 		Context("when a new routing key arrives", func() {
 			Context("when the routing key has both routes and endpoints", func() {
 				BeforeEach(func() {
-					tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 
 					routes := createRoutingInfo(key.ContainerPort, []string{hostname1, hostname2}, []string{internalHostname1}, "", []uint32{}, "")
 					schedulingInfo := createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag)
@@ -400,7 +575,7 @@ var _ = Describe("RoutingTable", func() {
 			Context("when the process only has routes", func() {
 				var schedulingInfo *models.DesiredLRPSchedulingInfo
 				BeforeEach(func() {
-					tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 					routes := createRoutingInfo(key.ContainerPort, []string{hostname1}, []string{internalHostname1}, "", []uint32{}, "")
 					schedulingInfo = createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag)
 					tempTable.SetRoutes(nil, schedulingInfo)
@@ -414,7 +589,7 @@ var _ = Describe("RoutingTable", func() {
 
 				Context("when the endpoints subsequently arrive", func() {
 					BeforeEach(func() {
-						tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+						tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 						lrp := createActualLRP(key, endpoint1, domain)
 						tempTable.SetRoutes(nil, schedulingInfo)
 						tempTable.AddEndpoint(lrp)
@@ -446,7 +621,7 @@ var _ = Describe("RoutingTable", func() {
 
 				Context("when the routing key subsequently disappears", func() {
 					BeforeEach(func() {
-						tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+						tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 						_, messagesToEmit = table.Swap(tempTable, domains)
 					})
 
@@ -458,7 +633,7 @@ var _ = Describe("RoutingTable", func() {
 
 			Context("when the process only has endpoints", func() {
 				BeforeEach(func() {
-					tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 					lrp := createActualLRP(key, endpoint1, domain)
 					tempTable.AddEndpoint(lrp)
 
@@ -471,7 +646,7 @@ var _ = Describe("RoutingTable", func() {
 
 				Context("when the routes subsequently arrive", func() {
 					BeforeEach(func() {
-						tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+						tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 						routes := createRoutingInfo(key.ContainerPort, []string{hostname1}, []string{internalHostname1}, "", []uint32{}, "")
 						schedulingInfo := createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag)
 						lrp := createActualLRP(key, endpoint1, domain)
@@ -504,7 +679,7 @@ var _ = Describe("RoutingTable", func() {
 
 				Context("when the endpoint subsequently disappears", func() {
 					BeforeEach(func() {
-						tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+						tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 						_, messagesToEmit = table.Swap(tempTable, domains)
 					})
 
@@ -521,7 +696,7 @@ var _ = Describe("RoutingTable", func() {
 			)
 
 			BeforeEach(func() {
-				tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+				tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 				schedulingInfo = createSchedulingInfoWithIS("isolation-segment-1")
 				tempTable.SetRoutes(nil, schedulingInfo)
 				lrp := createActualLRP(key, endpoint1, domain)
@@ -553,7 +728,7 @@ var _ = Describe("RoutingTable", func() {
 
 			Context("when the isolation segment changes in sync", func() {
 				BeforeEach(func() {
-					tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 					schedulingInfo := createSchedulingInfoWithIS("isolation-segment-2")
 					tempTable.SetRoutes(nil, schedulingInfo)
 					lrp := createActualLRP(key, endpoint1, domain)
@@ -583,7 +758,7 @@ var _ = Describe("RoutingTable", func() {
 			)
 
 			BeforeEach(func() {
-				tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+				tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 				schedulingInfo = createSchedulingInfo("https://rs.example.com")
 				tempTable.SetRoutes(nil, schedulingInfo)
 				lrp := createActualLRP(key, endpoint1, domain)
@@ -615,7 +790,7 @@ var _ = Describe("RoutingTable", func() {
 
 			Context("when the route service url changes during sync", func() {
 				BeforeEach(func() {
-					tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 					schedulingInfo := createSchedulingInfo("https://rs.new.example.com")
 					tempTable.SetRoutes(nil, schedulingInfo)
 					lrp1 := createActualLRP(key, endpoint1, domain)
@@ -641,7 +816,7 @@ var _ = Describe("RoutingTable", func() {
 
 		Context("when the routing key has an evacuating and instance endpoint", func() {
 			BeforeEach(func() {
-				tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+				tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 				routes := createRoutingInfo(key.ContainerPort, []string{hostname1, hostname2}, []string{internalHostname1}, "", []uint32{}, "")
 				schedulingInfo := createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag)
 				tempTable.SetRoutes(nil, schedulingInfo)
@@ -688,7 +863,7 @@ var _ = Describe("RoutingTable", func() {
 
 		Context("when there is an existing routing key", func() {
 			BeforeEach(func() {
-				tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+				tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 				routes := createRoutingInfo(key.ContainerPort, []string{hostname1, hostname2}, []string{internalHostname1}, "", []uint32{}, "")
 				schedulingInfo := createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag)
 				tempTable.SetRoutes(nil, schedulingInfo)
@@ -702,7 +877,7 @@ var _ = Describe("RoutingTable", func() {
 
 			Context("when nothing changes", func() {
 				BeforeEach(func() {
-					tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 					routes := createRoutingInfo(key.ContainerPort, []string{hostname1, hostname2}, []string{internalHostname1}, "", []uint32{}, "")
 					schedulingInfo := createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag)
 					tempTable.SetRoutes(nil, schedulingInfo)
@@ -721,7 +896,7 @@ var _ = Describe("RoutingTable", func() {
 
 			Context("when the routing key gets new routes", func() {
 				BeforeEach(func() {
-					tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 					routes := createRoutingInfo(key.ContainerPort, []string{hostname1, hostname2, hostname3}, []string{internalHostname1, internalHostname2}, "", []uint32{}, "")
 					schedulingInfo := createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag)
 					tempTable.SetRoutes(nil, schedulingInfo)
@@ -766,7 +941,7 @@ var _ = Describe("RoutingTable", func() {
 
 			Context("when the routing key without any route service url gets routes with a new route service url", func() {
 				BeforeEach(func() {
-					tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 					routes := createRoutingInfo(key.ContainerPort, []string{hostname1, hostname2}, []string{internalHostname1}, "https://rs.example.com", []uint32{}, "")
 					schedulingInfo := createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag)
 					tempTable.SetRoutes(nil, schedulingInfo)
@@ -799,7 +974,7 @@ var _ = Describe("RoutingTable", func() {
 
 			Context("when the routing key gets new endpoints", func() {
 				BeforeEach(func() {
-					tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 					routes := createRoutingInfo(key.ContainerPort, []string{hostname1, hostname2}, []string{internalHostname1}, "", []uint32{}, "")
 					schedulingInfo := createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag)
 					tempTable.SetRoutes(nil, schedulingInfo)
@@ -838,7 +1013,7 @@ var _ = Describe("RoutingTable", func() {
 
 			Context("when the routing key gets a new evacuating endpoint", func() {
 				BeforeEach(func() {
-					tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 					routes := createRoutingInfo(key.ContainerPort, []string{hostname1, hostname2}, []string{internalHostname1}, "", []uint32{}, "")
 					schedulingInfo := createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag)
 					tempTable.SetRoutes(nil, schedulingInfo)
@@ -858,7 +1033,7 @@ var _ = Describe("RoutingTable", func() {
 
 				Context("when running instance is removed", func() {
 					BeforeEach(func() {
-						tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+						tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 						routes := createRoutingInfo(key.ContainerPort, []string{hostname1, hostname2}, []string{internalHostname1}, "", []uint32{}, "")
 						schedulingInfo := createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag)
 						tempTable.SetRoutes(nil, schedulingInfo)
@@ -878,7 +1053,7 @@ var _ = Describe("RoutingTable", func() {
 
 			Context("when the routing key gets new routes and endpoints", func() {
 				BeforeEach(func() {
-					tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 					routes := createRoutingInfo(key.ContainerPort, []string{hostname1, hostname2, hostname3}, []string{internalHostname1, internalHostname2}, "", []uint32{}, "")
 					schedulingInfo := createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag)
 					tempTable.SetRoutes(nil, schedulingInfo)
@@ -947,7 +1122,7 @@ var _ = Describe("RoutingTable", func() {
 
 			Context("when the routing key loses routes", func() {
 				BeforeEach(func() {
-					tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 					routes := createRoutingInfo(key.ContainerPort, []string{hostname1}, []string{}, "", []uint32{}, "")
 					schedulingInfo := createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag)
 					tempTable.SetRoutes(nil, schedulingInfo)
@@ -992,7 +1167,7 @@ var _ = Describe("RoutingTable", func() {
 
 			Context("when the routing key loses endpoints", func() {
 				BeforeEach(func() {
-					tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 					routes := createRoutingInfo(key.ContainerPort, []string{hostname1, hostname2}, []string{internalHostname1}, "", []uint32{}, "")
 					schedulingInfo := createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag)
 					tempTable.SetRoutes(nil, schedulingInfo)
@@ -1026,7 +1201,7 @@ var _ = Describe("RoutingTable", func() {
 
 			Context("when the routing key loses http/internal routes and endpoints", func() {
 				BeforeEach(func() {
-					tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 					routes := createRoutingInfo(key.ContainerPort, []string{hostname1}, []string{}, "", []uint32{}, "")
 					schedulingInfo := createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag)
 					tempTable.SetRoutes(nil, schedulingInfo)
@@ -1070,7 +1245,7 @@ var _ = Describe("RoutingTable", func() {
 
 			Context("when the routing key gains routes but loses endpoints", func() {
 				BeforeEach(func() {
-					tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 					routes := createRoutingInfo(key.ContainerPort, []string{hostname1, hostname2, hostname3}, []string{internalHostname1, internalHostname2}, "", []uint32{}, "")
 					schedulingInfo := createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag)
 					tempTable.SetRoutes(nil, schedulingInfo)
@@ -1118,7 +1293,7 @@ var _ = Describe("RoutingTable", func() {
 
 			Context("when the routing key loses routes but gains endpoints", func() {
 				BeforeEach(func() {
-					tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 					routes := createRoutingInfo(key.ContainerPort, []string{hostname1}, []string{}, "", []uint32{}, "")
 					schedulingInfo := createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag)
 					tempTable.SetRoutes(nil, schedulingInfo)
@@ -1171,7 +1346,7 @@ var _ = Describe("RoutingTable", func() {
 				var domainSet models.DomainSet
 
 				BeforeEach(func() {
-					tempTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 				})
 
 				JustBeforeEach(func() {
@@ -1289,7 +1464,7 @@ var _ = Describe("RoutingTable", func() {
 				Context("when the original registration had no routes, and then the routing key loses endpoints", func() {
 					BeforeEach(func() {
 						//override previous set up
-						tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+						tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 						lrp1 := createActualLRP(key, endpoint1, domain)
 						tempTable.AddEndpoint(lrp1)
 						lrp2 := createActualLRP(key, endpoint2, domain)
@@ -1297,7 +1472,7 @@ var _ = Describe("RoutingTable", func() {
 						_, messagesToEmit = table.Swap(tempTable, domains)
 						Expect(messagesToEmit.InternalUnregistrationMessages).To(HaveLen(2))
 
-						tempTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+						tempTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 						lrp1 = createActualLRP(key, endpoint1, domain)
 						tempTable.AddEndpoint(lrp1)
 						_, messagesToEmit = table.Swap(tempTable, domains)
@@ -1311,12 +1486,12 @@ var _ = Describe("RoutingTable", func() {
 				Context("when the original registration had no endpoints, and then the routing key loses a route", func() {
 					BeforeEach(func() {
 						//override previous set up
-						tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+						tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 						schedulingInfo := createDesiredLRPSchedulingInfo(key.ProcessGUID, int32(3), key.ContainerPort, logGuid, "", *currentTag, hostname1, hostname2)
 						tempTable.SetRoutes(nil, schedulingInfo)
 						table.Swap(tempTable, domains)
 
-						tempTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+						tempTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 						schedulingInfo = createDesiredLRPSchedulingInfo(key.ProcessGUID, int32(3), key.ContainerPort, logGuid, "", *currentTag, hostname1)
 						tempTable.SetRoutes(nil, schedulingInfo)
 						_, messagesToEmit = table.Swap(tempTable, domains)
@@ -1330,6 +1505,214 @@ var _ = Describe("RoutingTable", func() {
 		})
 	})
 
+	Describe("SwapProcesses", func() {
+		otherKey := routingtable.RoutingKey{ProcessGUID: "other-process-guid", ContainerPort: 8080}
+
+		BeforeEach(func() {
+			schedulingInfo := createDesiredLRPSchedulingInfo(key.ProcessGUID, 1, key.ContainerPort, logGuid, "", *currentTag, hostname1)
+			table.SetRoutes(nil, schedulingInfo)
+			table.AddEndpoint(createActualLRP(key, endpoint1, domain))
+
+			otherSchedulingInfo := createDesiredLRPSchedulingInfo(otherKey.ProcessGUID, 1, otherKey.ContainerPort, logGuid, "", *currentTag, hostname2)
+			table.SetRoutes(nil, otherSchedulingInfo)
+			table.AddEndpoint(createActualLRP(otherKey, endpoint2, domain))
+		})
+
+		Context("when the routes of a swapped process changed", func() {
+			BeforeEach(func() {
+				tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
+				schedulingInfo := createDesiredLRPSchedulingInfo(key.ProcessGUID, 1, key.ContainerPort, logGuid, "", *currentTag, hostname3)
+				tempTable.SetRoutes(nil, schedulingInfo)
+				tempTable.AddEndpoint(createActualLRP(key, endpoint1, domain))
+
+				_, messagesToEmit = table.SwapProcesses(tempTable, []string{key.ProcessGUID}, domains)
+			})
+
+			It("emits the changes of the swapped process", func() {
+				expected := routingtable.MessagesToEmit{
+					RegistrationMessages: []routingtable.RegistryMessage{
+						routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname3, LogGUID: logGuid}, false),
+					},
+					UnregistrationMessages: []routingtable.RegistryMessage{
+						routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1, LogGUID: logGuid}, false),
+					},
+				}
+				Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+			})
+
+			It("keeps the entries of the other processes", func() {
+				entries := table.HTTPEntries()
+				Expect(entries).To(HaveKey(key))
+				Expect(entries).To(HaveKey(otherKey))
+				Expect(entries[otherKey].Endpoints).To(HaveLen(1))
+			})
+		})
+
+		Context("when a swapped process is missing from the other table", func() {
+			BeforeEach(func() {
+				tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
+				_, messagesToEmit = table.SwapProcesses(tempTable, []string{key.ProcessGUID}, domains)
+			})
+
+			It("unregisters its routes", func() {
+				expected := routingtable.MessagesToEmit{
+					UnregistrationMessages: []routingtable.RegistryMessage{
+						routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1, LogGUID: logGuid}, false),
+					},
+				}
+				Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+				Expect(table.HTTPEntries()).NotTo(HaveKey(key))
+			})
+		})
+
+		Context("when a process is not swapped", func() {
+			BeforeEach(func() {
+				tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
+				_, messagesToEmit = table.SwapProcesses(tempTable, []string{"unknown-process-guid"}, domains)
+			})
+
+			It("emits nothing", func() {
+				Expect(messagesToEmit).To(BeZero())
+				Expect(table.HTTPEntries()).To(HaveLen(2))
+			})
+		})
+
+		Context("when the other table holds processes that are not swapped", func() {
+			thirdKey := routingtable.RoutingKey{ProcessGUID: "third-process-guid", ContainerPort: 8080}
+
+			BeforeEach(func() {
+				tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
+				schedulingInfo := createDesiredLRPSchedulingInfo(key.ProcessGUID, 1, key.ContainerPort, logGuid, "", *currentTag, hostname1)
+				tempTable.SetRoutes(nil, schedulingInfo)
+				tempTable.AddEndpoint(createActualLRP(key, endpoint1, domain))
+
+				thirdSchedulingInfo := createDesiredLRPSchedulingInfo(thirdKey.ProcessGUID, 1, thirdKey.ContainerPort, logGuid, "", *currentTag, hostname3)
+				tempTable.SetRoutes(nil, thirdSchedulingInfo)
+				tempTable.AddEndpoint(createActualLRP(thirdKey, endpoint3, domain))
+
+				_, messagesToEmit = table.SwapProcesses(tempTable, []string{key.ProcessGUID}, domains)
+			})
+
+			It("leaves the other processes in place", func() {
+				Expect(messagesToEmit).To(BeZero())
+
+				entries := table.HTTPEntries()
+				Expect(entries).To(HaveLen(2))
+				Expect(entries).To(HaveKey(key))
+				Expect(entries).To(HaveKey(otherKey))
+			})
+
+			It("only takes the addresses of the swapped processes", func() {
+				addresses := table.AddressEntries()
+				Expect(addresses).To(HaveLen(2))
+				Expect(addresses).To(HaveKey(routingtable.Address{Host: endpoint1.Host, Port: endpoint1.Port}))
+				Expect(addresses).To(HaveKey(routingtable.Address{Host: endpoint2.Host, Port: endpoint2.Port}))
+			})
+		})
+
+		Context("when an endpoint of a swapped process is draining", func() {
+			var clock *fakeclock.FakeClock
+
+			route := routingtable.Route{Hostname: hostname1, LogGUID: logGuid}
+
+			BeforeEach(func() {
+				clock = fakeclock.NewFakeClock(time.Now())
+				table = routingtable.NewDrainingRoutingTable(logger, false, metricsSink, clock, 10*time.Second, nil)
+
+				schedulingInfo := createDesiredLRPSchedulingInfo(key.ProcessGUID, 1, key.ContainerPort, logGuid, "", *currentTag, hostname1)
+				table.SetRoutes(nil, schedulingInfo)
+				actualLRP := createActualLRP(key, endpoint1, domain)
+				table.AddEndpoint(actualLRP)
+				table.DrainEndpoint(actualLRP)
+			})
+
+			Context("and the other table holds its replacement", func() {
+				BeforeEach(func() {
+					tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
+					schedulingInfo := createDesiredLRPSchedulingInfo(key.ProcessGUID, 1, key.ContainerPort, logGuid, "", *currentTag, hostname1)
+					tempTable.SetRoutes(nil, schedulingInfo)
+					tempTable.AddEndpoint(createActualLRP(key, newInstanceEndpointAfterEvacuation, domain))
+
+					_, messagesToEmit = table.SwapProcesses(tempTable, []string{key.ProcessGUID}, domains)
+				})
+
+				It("registers the replacement and stops draining the endpoint", func() {
+					expected := routingtable.MessagesToEmit{
+						RegistrationMessages: []routingtable.RegistryMessage{
+							routingtable.RegistryMessageFor(newInstanceEndpointAfterEvacuation, route, false),
+						},
+						UnregistrationMessages: []routingtable.RegistryMessage{
+							routingtable.RegistryMessageFor(endpoint1, route, false),
+						},
+					}
+					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+
+					clock.Increment(10 * time.Second)
+					Expect(table.UnregisterDrainedEndpoints()).To(BeZero())
+				})
+			})
+
+			Context("and the process is missing from the other table", func() {
+				BeforeEach(func() {
+					tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
+					_, messagesToEmit = table.SwapProcesses(tempTable, []string{key.ProcessGUID}, domains)
+				})
+
+				It("unregisters the draining endpoint right away", func() {
+					expected := routingtable.MessagesToEmit{
+						UnregistrationMessages: []routingtable.RegistryMessage{
+							routingtable.RegistryMessageFor(endpoint1, route, false),
+						},
+					}
+					Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+
+					clock.Increment(10 * time.Second)
+					Expect(table.UnregisterDrainedEndpoints()).To(BeZero())
+				})
+			})
+		})
+	})
+
+	Describe("RemoveEntries", func() {
+		otherKey := routingtable.RoutingKey{ProcessGUID: "other-process-guid", ContainerPort: 8080}
+
+		BeforeEach(func() {
+			schedulingInfo := createDesiredLRPSchedulingInfo(key.ProcessGUID, 1, key.ContainerPort, logGuid, "", *currentTag, hostname1)
+			table.SetRoutes(nil, schedulingInfo)
+			table.AddEndpoint(createActualLRP(key, endpoint1, domain))
+
+			otherSchedulingInfo := createDesiredLRPSchedulingInfo(otherKey.ProcessGUID, 1, otherKey.ContainerPort, logGuid, "", *currentTag, hostname2)
+			table.SetRoutes(nil, otherSchedulingInfo)
+			table.AddEndpoint(createActualLRP(otherKey, endpoint2, domain))
+
+			_, messagesToEmit = table.RemoveEntries(func(k routingtable.RoutingKey) bool {
+				return k.ProcessGUID == key.ProcessGUID
+			})
+		})
+
+		It("returns the unregistrations of the removed entries", func() {
+			expected := routingtable.MessagesToEmit{
+				UnregistrationMessages: []routingtable.RegistryMessage{
+					routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1, LogGUID: logGuid}, false),
+				},
+			}
+			Expect(messagesToEmit).To(MatchMessagesToEmit(expected))
+		})
+
+		It("only keeps the other entries", func() {
+			entries := table.HTTPEntries()
+			Expect(entries).NotTo(HaveKey(key))
+			Expect(entries).To(HaveKey(otherKey))
+			Expect(table.AddressEntries()).To(HaveLen(1))
+		})
+
+		It("removes nothing when no key matches", func() {
+			_, messagesToEmit = table.RemoveEntries(func(routingtable.RoutingKey) bool { return false })
+			Expect(messagesToEmit).To(BeZero())
+			Expect(table.HTTPEntries()).To(HaveLen(1))
+		})
+	})
+
 	Describe("Processing deltas", func() {
 		Context("when the table is empty", func() {
 			Context("When setting routes", func() {
@@ -1368,7 +1751,7 @@ var _ = Describe("RoutingTable", func() {
 		Context("when there are both endpoints and routes in the table", func() {
 			var beforeLrpInfo *models.DesiredLRPSchedulingInfo
 			BeforeEach(func() {
-				tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+				tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 				routes := createRoutingInfo(key.ContainerPort, []string{hostname1, hostname2}, []string{internalHostname1}, "", []uint32{}, "")
 
 				beforeLrpInfo = createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag)
@@ -1754,7 +2137,7 @@ var _ = Describe("RoutingTable", func() {
 				Context("when there are internal routes", func() {
 					var internalHostname string
 					BeforeEach(func() {
-						tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+						tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 						internalHostname = "internal"
 						routes := createRoutingInfo(key.ContainerPort, []string{hostname1}, []string{internalHostname}, "", []uint32{}, "")
 
@@ -1903,7 +2286,7 @@ var _ = Describe("RoutingTable", func() {
 					)
 
 					BeforeEach(func() {
-						table = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+						table = routingtable.NewRoutingTable(logger, false, metricsSink)
 						routes := createRoutingInfo(key.ContainerPort, []string{hostname1}, []string{internalHostname1}, "", []uint32{}, "")
 
 						beforeLrpInfo = createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag)
@@ -2,21 +2,57 @@ package routingtable
 
 import (
 	"fmt"
+	"strings"
 )
 
+// DrainingTag is set on the registrations of endpoints that are being
+// drained: they still serve in-flight requests but are about to go away.
+const DrainingTag = "draining"
+
 type RegistryMessage struct {
-	Host                 string            `json:"host"`
-	Port                 uint32            `json:"port"`
-	TlsPort              uint32            `json:"tls_port,omitempty"`
-	URIs                 []string          `json:"uris"`
-	App                  string            `json:"app,omitempty"`
-	RouteServiceUrl      string            `json:"route_service_url,omitempty"`
-	PrivateInstanceId    string            `json:"private_instance_id,omitempty"`
-	PrivateInstanceIndex string            `json:"private_instance_index,omitempty"`
-	ServerCertDomainSAN  string            `json:"server_cert_domain_san,omitempty"`
-	IsolationSegment     string            `json:"isolation_segment,omitempty"`
-	EndpointUpdatedAtNs  int64             `json:"endpoint_updated_at_ns,omitempty"`
-	Tags                 map[string]string `json:"tags,omitempty"`
+	Host                 string               `json:"host"`
+	Port                 uint32               `json:"port"`
+	TlsPort              uint32               `json:"tls_port,omitempty"`
+	URIs                 []string             `json:"uris"`
+	App                  string               `json:"app,omitempty"`
+	RouteServiceUrl      string               `json:"route_service_url,omitempty"`
+	PrivateInstanceId    string               `json:"private_instance_id,omitempty"`
+	PrivateInstanceIndex string               `json:"private_instance_index,omitempty"`
+	ServerCertDomainSAN  string               `json:"server_cert_domain_san,omitempty"`
+	IsolationSegment     string               `json:"isolation_segment,omitempty"`
+	EndpointUpdatedAtNs  int64                `json:"endpoint_updated_at_ns,omitempty"`
+	Tags                 map[string]string    `json:"tags,omitempty"`
+	Weight               uint32               `json:"weight,omitempty"`
+	Options              *RouteOptionsMessage `json:"options,omitempty"`
+}
+
+type RouteOptionsMessage struct {
+	LoadBalancingAlgorithm   string   `json:"loadbalancing,omitempty"`
+	StickySessionCookieNames []string `json:"sticky_session_cookie_names,omitempty"`
+	RequestTimeoutMs         uint32   `json:"request_timeout_ms,omitempty"`
+}
+
+func drainingMessage(message RegistryMessage) RegistryMessage {
+	tags := map[string]string{DrainingTag: "true"}
+	for name, value := range message.Tags {
+		tags[name] = value
+	}
+	message.Tags = tags
+	return message
+}
+
+func routeOptionsMessageFor(options RouteOptions) *RouteOptionsMessage {
+	if options == (RouteOptions{}) {
+		return nil
+	}
+	msg := &RouteOptionsMessage{
+		LoadBalancingAlgorithm: options.LoadBalancingAlgorithm,
+		RequestTimeoutMs:       options.RequestTimeoutMs,
+	}
+	if options.StickySessionCookieNames != "" {
+		msg.StickySessionCookieNames = strings.Split(options.StickySessionCookieNames, ",")
+	}
+	return msg
 }
 
 func RegistryMessageFor(endpoint Endpoint, route Route, emitEndpointUpdatedAt bool) RegistryMessage {
@@ -42,6 +78,8 @@ func RegistryMessageFor(endpoint Endpoint, route Route, emitEndpointUpdatedAt bo
 		PrivateInstanceIndex: index,
 		ServerCertDomainSAN:  endpoint.InstanceGUID,
 		RouteServiceUrl:      route.RouteServiceUrl,
+		Weight:               route.Weight,
+		Options:              routeOptionsMessageFor(route.Options),
 	}
 }
 
@@ -69,6 +107,8 @@ func InternalAddressRegistryMessageFor(endpoint Endpoint, route Route, emitEndpo
 		PrivateInstanceIndex: index,
 		EndpointUpdatedAtNs:  since,
 		RouteServiceUrl:      route.RouteServiceUrl,
+		Weight:               route.Weight,
+		Options:              routeOptionsMessageFor(route.Options),
 	}
 }
 
@@ -93,7 +133,15 @@ func InternalEndpointRegistryMessageFor(endpoint Endpoint, route InternalRoute,
 	}
 }
 
+// RegistryMessageBatch is the payload of a batched registration, it is only
+// published to routers that set SupportsBatchedRegistration in their greeting.
+type RegistryMessageBatch struct {
+	Messages []RegistryMessage `json:"messages"`
+}
+
 type ExternalServiceGreetingMessage struct {
-	MinimumRegisterInterval int `json:"minimumRegisterIntervalInSeconds"`
-	PruneThresholdInSeconds int `json:"pruneThresholdInSeconds"`
+	ID                          string `json:"id,omitempty"`
+	MinimumRegisterInterval     int    `json:"minimumRegisterIntervalInSeconds"`
+	PruneThresholdInSeconds     int    `json:"pruneThresholdInSeconds"`
+	SupportsBatchedRegistration bool   `json:"supportsBatchedRegistration,omitempty"`
 }
@@ -138,6 +138,23 @@ var _ = Describe("RegistryMessage", func() {
 			message := routingtable.RegistryMessageFor(endpoint, route, true)
 			Expect(message).To(Equal(expectedMessage))
 		})
+
+		It("serializes the route options", func() {
+			route.Options = routingtable.RouteOptions{
+				LoadBalancingAlgorithm:   "least-connection",
+				StickySessionCookieNames: "JSESSIONID,SESSION",
+				RequestTimeoutMs:         5000,
+			}
+
+			message := routingtable.RegistryMessageFor(endpoint, route, true)
+			payload, err := json.Marshal(message.Options)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(payload).To(MatchJSON(`{
+				"loadbalancing": "least-connection",
+				"sticky_session_cookie_names": ["JSESSIONID", "SESSION"],
+				"request_timeout_ms": 5000
+			}`))
+		})
 	})
 
 	Describe("InternalAddressRegistryMessageFor", func() {
@@ -1,14 +1,17 @@
 package routingtable
 
 import (
+	"strings"
 	"sync"
+	"time"
 
 	tcpmodels "code.cloudfoundry.org/routing-api/models"
 
 	"code.cloudfoundry.org/bbs/models"
-	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/lager"
-	"code.cloudfoundry.org/routing-info/cfroutes"
+	"code.cloudfoundry.org/route-emitter/cfroutes"
+	"code.cloudfoundry.org/route-emitter/metrics"
 	"code.cloudfoundry.org/routing-info/internalroutes"
 	"code.cloudfoundry.org/routing-info/tcp_routes"
 )
@@ -34,9 +37,13 @@ type RoutingTable interface {
 	RemoveRoutes(desiredLRP *models.DesiredLRPSchedulingInfo) (TCPRouteMappings, MessagesToEmit)
 	AddEndpoint(actualLRP *ActualLRPRoutingInfo) (TCPRouteMappings, MessagesToEmit)
 	RemoveEndpoint(actualLRP *ActualLRPRoutingInfo) (TCPRouteMappings, MessagesToEmit)
+	DrainEndpoint(actualLRP *ActualLRPRoutingInfo) (TCPRouteMappings, MessagesToEmit) // remove the endpoint of an evacuating or stopping instance, its http routes are drained
 	Swap(t RoutingTable, domains models.DomainSet) (TCPRouteMappings, MessagesToEmit)
+	SwapProcesses(t RoutingTable, processGuids []string, domains models.DomainSet) (TCPRouteMappings, MessagesToEmit) // swap the entries of processGuids only
 	GetInternalRoutingEvents() (TCPRouteMappings, MessagesToEmit)
 	GetExternalRoutingEvents() (TCPRouteMappings, MessagesToEmit)
+	UnregisterDrainedEndpoints() MessagesToEmit                                    // return the unregistrations of the http endpoints whose drain window elapsed
+	RemoveEntries(remove func(RoutingKey) bool) (TCPRouteMappings, MessagesToEmit) // remove the entries whose key satisfies remove
 
 	// routes
 
@@ -45,6 +52,19 @@ type RoutingTable interface {
 	InternalAssociationsCount() int // return number of associations desired-lrp-internal-routes * 2 * actual-lrps
 	TCPAssociationsCount() int      // return number of associations desired-lrp-tcp-routes * actual-lrps
 	TableSize() int
+
+	// table inspection, all of these return copies of the underlying entries
+
+	HTTPEntries() map[RoutingKey]RoutableEndpoints
+	HTTPEntriesForHostnames(hostnames []string) map[RoutingKey]RoutableEndpoints // return the http entries routing any of hostnames
+	TCPEntries() map[RoutingKey]RoutableEndpoints
+	InternalEntries() map[RoutingKey]RoutableEndpoints
+	AddressEntries() map[Address]EndpointKey // return the address collision map of the http table
+
+	// snapshots
+
+	Snapshot() Snapshot
+	Restore(snapshot Snapshot) error
 }
 
 type internalRoutingTable struct {
@@ -55,11 +75,24 @@ type internalRoutingTable struct {
 	addressGenerator         func(endpoint Endpoint) Address
 	directInstanceRoute      bool
 	logger                   lager.Logger
-	metronClient             loggingclient.IngressClient
+	metricsSink              metrics.Sink
+	tableType                string
 	suppressAddressCollision bool
+	clock                    clock.Clock
+	drainWindow              time.Duration
+	draining                 []drainingEndpoint // ordered by deadline, the drain window being the same for every endpoint
+	history                  *History
 	sync.Locker
 }
 
+type drainingEndpoint struct {
+	deadline        time.Time
+	key             RoutingKey
+	endpoint        Endpoint
+	registrations   []RegistryMessage
+	unregistrations []RegistryMessage
+}
+
 type routingTable struct {
 	logger                     lager.Logger
 	tcpRoutesRoutingTable      *internalRoutingTable
@@ -67,7 +100,16 @@ type routingTable struct {
 	internalRoutesRoutingTable *internalRoutingTable
 }
 
-func NewRoutingTable(logger lager.Logger, directInstanceRoute bool, metronClient loggingclient.IngressClient) RoutingTable {
+func NewRoutingTable(logger lager.Logger, directInstanceRoute bool, metricsSink metrics.Sink) RoutingTable {
+	return NewDrainingRoutingTable(logger, directInstanceRoute, metricsSink, nil, 0, nil)
+}
+
+// NewDrainingRoutingTable returns a routing table keeping the http endpoints
+// drained from it registered with the DrainingTag for drainWindow, as told by
+// clock, or until a replacement for their index is added. A zero drainWindow
+// unregisters them right away, as RemoveEndpoint always does. Every change is recorded to history unless it
+// is nil.
+func NewDrainingRoutingTable(logger lager.Logger, directInstanceRoute bool, metricsSink metrics.Sink, clock clock.Clock, drainWindow time.Duration, history *History) RoutingTable {
 	addressGenerator := func(endpoint Endpoint) Address {
 		return Address{Host: endpoint.Host, Port: endpoint.Port}
 	}
@@ -86,7 +128,11 @@ func NewRoutingTable(logger lager.Logger, directInstanceRoute bool, metronClient
 		directInstanceRoute: directInstanceRoute,
 		addressGenerator:    addressGenerator,
 		logger:              logger.Session("http"),
-		metronClient:        metronClient,
+		metricsSink:         metricsSink,
+		tableType:           metrics.HTTPTable,
+		clock:               clock,
+		drainWindow:         drainWindow,
+		history:             history,
 		Locker:              &sync.Mutex{},
 	}
 	tcpRoutingTable := &internalRoutingTable{
@@ -97,8 +143,10 @@ func NewRoutingTable(logger lager.Logger, directInstanceRoute bool, metronClient
 		directInstanceRoute:      directInstanceRoute,
 		addressGenerator:         addressGenerator,
 		logger:                   logger.Session("tcp"),
-		metronClient:             metronClient,
+		metricsSink:              metricsSink,
+		tableType:                metrics.TCPTable,
 		suppressAddressCollision: true,
+		history:                  history,
 		Locker: &sync.Mutex{},
 	}
 	internalRoutingTable := &internalRoutingTable{
@@ -109,8 +157,10 @@ func NewRoutingTable(logger lager.Logger, directInstanceRoute bool, metronClient
 		directInstanceRoute:      directInstanceRoute,
 		addressGenerator:         addressGenerator,
 		logger:                   logger.Session("internal"),
-		metronClient:             metronClient,
+		metricsSink:              metricsSink,
+		tableType:                metrics.InternalTable,
 		suppressAddressCollision: true,
+		history:                  history,
 		Locker: &sync.Mutex{},
 	}
 
@@ -160,6 +210,16 @@ func (table *routingTable) RemoveEndpoint(actualLRP *ActualLRPRoutingInfo) (TCPR
 	return mappings, messages
 }
 
+func (table *routingTable) DrainEndpoint(actualLRP *ActualLRPRoutingInfo) (TCPRouteMappings, MessagesToEmit) {
+	httpMappings, httpMessages := table.httpRoutesRoutingTable.DrainEndpoint(actualLRP)
+	tcpMappings, tcpMessages := table.tcpRoutesRoutingTable.RemoveEndpoint(actualLRP)
+	internalMappings, internalMessages := table.internalRoutesRoutingTable.RemoveEndpoint(actualLRP)
+
+	mappings := httpMappings.Merge(tcpMappings).Merge(internalMappings)
+	messages := httpMessages.Merge(tcpMessages).Merge(internalMessages)
+	return mappings, messages
+}
+
 func (t *routingTable) Swap(other RoutingTable, domains models.DomainSet) (TCPRouteMappings, MessagesToEmit) {
 	table, ok := other.(*routingTable)
 	if !ok {
@@ -176,6 +236,29 @@ func (t *routingTable) Swap(other RoutingTable, domains models.DomainSet) (TCPRo
 	return mappings, messages
 }
 
+// SwapProcesses replaces the entries of processGuids with the ones of other,
+// the entries of every other process guid are kept as they are.
+func (t *routingTable) SwapProcesses(other RoutingTable, processGuids []string, domains models.DomainSet) (TCPRouteMappings, MessagesToEmit) {
+	table, ok := other.(*routingTable)
+	if !ok {
+		t.logger.Error("failed-to-convert-to-routing-table", nil)
+		return TCPRouteMappings{}, MessagesToEmit{}
+	}
+
+	guids := make(map[string]struct{}, len(processGuids))
+	for _, guid := range processGuids {
+		guids[guid] = struct{}{}
+	}
+
+	httpMappings, httpMessages := t.httpRoutesRoutingTable.SwapProcesses(table.httpRoutesRoutingTable, guids, domains)
+	tcpMappings, tcpMessages := t.tcpRoutesRoutingTable.SwapProcesses(table.tcpRoutesRoutingTable, guids, domains)
+	internalMappings, internalMessages := t.internalRoutesRoutingTable.SwapProcesses(table.internalRoutesRoutingTable, guids, domains)
+
+	mappings := httpMappings.Merge(tcpMappings).Merge(internalMappings)
+	messages := httpMessages.Merge(tcpMessages).Merge(internalMessages)
+	return mappings, messages
+}
+
 func (t *routingTable) GetExternalRoutingEvents() (TCPRouteMappings, MessagesToEmit) {
 	httpMappings, httpMessages := t.httpRoutesRoutingTable.GetRoutingEvents()
 	tcpMappings, tcpMessages := t.tcpRoutesRoutingTable.GetRoutingEvents()
@@ -189,6 +272,22 @@ func (t *routingTable) GetInternalRoutingEvents() (TCPRouteMappings, MessagesToE
 	return t.internalRoutesRoutingTable.GetRoutingEvents()
 }
 
+func (t *routingTable) UnregisterDrainedEndpoints() MessagesToEmit {
+	return t.httpRoutesRoutingTable.UnregisterDrained()
+}
+
+// RemoveEntries removes the entries whose key satisfies remove, along with
+// their draining endpoints, and returns their unregistrations.
+func (t *routingTable) RemoveEntries(remove func(RoutingKey) bool) (TCPRouteMappings, MessagesToEmit) {
+	httpMappings, httpMessages := t.httpRoutesRoutingTable.RemoveEntries(remove)
+	tcpMappings, tcpMessages := t.tcpRoutesRoutingTable.RemoveEntries(remove)
+	internalMappings, internalMessages := t.internalRoutesRoutingTable.RemoveEntries(remove)
+
+	mappings := httpMappings.Merge(tcpMappings).Merge(internalMappings)
+	messages := httpMessages.Merge(tcpMessages).Merge(internalMessages)
+	return mappings, messages
+}
+
 func (t *routingTable) SetRoutes(before, after *models.DesiredLRPSchedulingInfo) (TCPRouteMappings, MessagesToEmit) {
 	httpMappings, httpMessages := t.httpRoutesRoutingTable.SetRoutes(before, after)
 	tcpMappings, tcpMessages := t.tcpRoutesRoutingTable.SetRoutes(before, after)
@@ -227,7 +326,7 @@ func (table *internalRoutingTable) AddEndpoint(actualLRP *ActualLRPRoutingInfo)
 			address := table.addressGenerator(endpoint)
 			// if the address exists and the instance guid doesn't match then we have a collision
 			if existingEndpointKey, ok := table.addressEntries[address]; ok && existingEndpointKey.InstanceGUID != endpoint.InstanceGUID {
-				table.metronClient.IncrementCounter(addressCollisionsCounter)
+				table.metricsSink.IncrementCounter(addressCollisionsCounter, metrics.Labels{metrics.TableLabel: table.tableType})
 				existingInstanceGuid := existingEndpointKey.InstanceGUID
 				table.logger.Info("collision-detected-with-endpoint", lager.Data{
 					"instance_guid_a": existingInstanceGuid,
@@ -263,9 +362,12 @@ func (table *internalRoutingTable) AddEndpoint(actualLRP *ActualLRPRoutingInfo)
 		newEntry := currentEntry.copy()
 		newEntry.Endpoints[routingEndpoint.key()] = routingEndpoint
 		table.entries[key] = newEntry
-		mapping, message := table.emitDiffMessages(key, currentEntry, newEntry)
+		mapping, message := table.emitDiffMessages(key, currentEntry, newEntry, ReasonEndpointAdded)
 		mappings = mappings.Merge(mapping)
 		messagesToEmit = messagesToEmit.Merge(message)
+		if len(table.draining) > 0 {
+			messagesToEmit = messagesToEmit.Merge(table.releaseDraining(key, routingEndpoint))
+		}
 	}
 
 	return mappings, messagesToEmit
@@ -276,6 +378,20 @@ func (table *internalRoutingTable) RemoveEndpoint(actualLRP *ActualLRPRoutingInf
 	logger.Debug("starting")
 	defer logger.Debug("completed")
 
+	return table.removeEndpoint(actualLRP, false)
+}
+
+// DrainEndpoint removes the endpoint of an instance that is evacuating or
+// stopping gracefully, its unregistrations are held back for the drain window.
+func (table *internalRoutingTable) DrainEndpoint(actualLRP *ActualLRPRoutingInfo) (TCPRouteMappings, MessagesToEmit) {
+	logger := table.logger.Session("DrainEndpoint", lager.Data{"actual_lrp": actualLRP})
+	logger.Debug("starting")
+	defer logger.Debug("completed")
+
+	return table.removeEndpoint(actualLRP, true)
+}
+
+func (table *internalRoutingTable) removeEndpoint(actualLRP *ActualLRPRoutingInfo, drain bool) (TCPRouteMappings, MessagesToEmit) {
 	table.Lock()
 	defer table.Unlock()
 
@@ -327,7 +443,10 @@ func (table *internalRoutingTable) RemoveEndpoint(actualLRP *ActualLRPRoutingInf
 		table.entries[key] = newEntry
 		table.deleteEntryIfEmpty(key)
 
-		mapping, message := table.emitDiffMessages(key, currentEntry, newEntry)
+		mapping, message := table.emitDiffMessages(key, currentEntry, newEntry, ReasonEndpointRemoved)
+		if drain && table.drainWindow > 0 {
+			message = table.drain(key, currentEndpoint, message)
+		}
 		messagesToEmit = messagesToEmit.Merge(message)
 		mappings = mappings.Merge(mapping)
 	}
@@ -335,6 +454,87 @@ func (table *internalRoutingTable) RemoveEndpoint(actualLRP *ActualLRPRoutingInf
 	return mappings, messagesToEmit
 }
 
+// drain registers the endpoint again with the DrainingTag instead of
+// unregistering it, the unregistrations are held back until the drain window
+// elapses. An endpoint whose replacement for its index is already routed is
+// unregistered right away.
+func (table *internalRoutingTable) drain(key RoutingKey, endpoint Endpoint, messagesToEmit MessagesToEmit) MessagesToEmit {
+	if len(messagesToEmit.UnregistrationMessages) == 0 {
+		return messagesToEmit
+	}
+
+	for _, current := range table.entries[key].Endpoints {
+		if current.Index == endpoint.Index && current.InstanceGUID != endpoint.InstanceGUID {
+			table.logger.Info("skipping-drain-of-replaced-endpoint", lager.Data{
+				"process-guid":  key.ProcessGUID,
+				"instance-guid": endpoint.InstanceGUID,
+				"index":         endpoint.Index,
+			})
+			return messagesToEmit
+		}
+	}
+
+	pending := drainingEndpoint{
+		deadline:        table.clock.Now().Add(table.drainWindow),
+		key:             key,
+		endpoint:        endpoint,
+		unregistrations: messagesToEmit.UnregistrationMessages,
+	}
+	for _, message := range messagesToEmit.UnregistrationMessages {
+		pending.registrations = append(pending.registrations, drainingMessage(message))
+	}
+	table.draining = append(table.draining, pending)
+
+	table.logger.Info("draining-endpoint", lager.Data{
+		"process-guid":  key.ProcessGUID,
+		"instance-guid": endpoint.InstanceGUID,
+		"index":         endpoint.Index,
+		"deadline":      pending.deadline,
+	})
+
+	messagesToEmit.UnregistrationMessages = nil
+	messagesToEmit.RegistrationMessages = append(messagesToEmit.RegistrationMessages, pending.registrations...)
+	return messagesToEmit
+}
+
+// releaseDraining stops draining the endpoints of key with the index of the
+// endpoint just added. The endpoint coming back is already registered again,
+// any other instance it replaces is unregistered right away.
+func (table *internalRoutingTable) releaseDraining(key RoutingKey, endpoint Endpoint) MessagesToEmit {
+	var messagesToEmit MessagesToEmit
+	remaining := table.draining[:0]
+	for _, pending := range table.draining {
+		switch {
+		case pending.key != key || pending.endpoint.Index != endpoint.Index:
+			remaining = append(remaining, pending)
+		case pending.endpoint.InstanceGUID != endpoint.InstanceGUID:
+			messagesToEmit.UnregistrationMessages = append(messagesToEmit.UnregistrationMessages, pending.unregistrations...)
+		}
+	}
+	table.draining = remaining
+	return messagesToEmit
+}
+
+func (t *internalRoutingTable) UnregisterDrained() MessagesToEmit {
+	t.Lock()
+	defer t.Unlock()
+
+	var messagesToEmit MessagesToEmit
+	if len(t.draining) == 0 {
+		return messagesToEmit
+	}
+
+	now := t.clock.Now()
+	expired := 0
+	for expired < len(t.draining) && !t.draining[expired].deadline.After(now) {
+		messagesToEmit.UnregistrationMessages = append(messagesToEmit.UnregistrationMessages, t.draining[expired].unregistrations...)
+		expired++
+	}
+	t.draining = t.draining[expired:]
+
+	return messagesToEmit
+}
+
 func (t *internalRoutingTable) Swap(otherTable *internalRoutingTable, domains models.DomainSet) (TCPRouteMappings, MessagesToEmit) {
 	logger := t.logger.Session("swap", lager.Data{"received-domains": domains})
 	logger.Info("started")
@@ -361,7 +561,7 @@ func (t *internalRoutingTable) Swap(otherTable *internalRoutingTable, domains mo
 		newEntry := otherTable.entries[key]
 		if !ok {
 			// routing key only exist in the new table
-			mapping, message := t.emitDiffMessages(key, RoutableEndpoints{}, newEntry)
+			mapping, message := t.emitDiffMessages(key, RoutableEndpoints{}, newEntry, ReasonSync)
 			messagesToEmit = messagesToEmit.Merge(message)
 			mappings = mappings.Merge(mapping)
 			continue
@@ -371,7 +571,7 @@ func (t *internalRoutingTable) Swap(otherTable *internalRoutingTable, domains mo
 		merged := mergeUnfreshRoutes(existingEntry, newEntry, domains)
 		otherTable.entries[key] = merged
 		otherTable.deleteEntryIfEmpty(key)
-		mapping, message := t.emitDiffMessages(key, existingEntry, merged)
+		mapping, message := t.emitDiffMessages(key, existingEntry, merged, ReasonSync)
 		messagesToEmit = messagesToEmit.Merge(message)
 		mappings = mappings.Merge(mapping)
 	}
@@ -381,6 +581,138 @@ func (t *internalRoutingTable) Swap(otherTable *internalRoutingTable, domains mo
 	return mappings, messagesToEmit
 }
 
+func (t *internalRoutingTable) SwapProcesses(otherTable *internalRoutingTable, processGuids map[string]struct{}, domains models.DomainSet) (TCPRouteMappings, MessagesToEmit) {
+	logger := t.logger.Session("swap-processes", lager.Data{"received-domains": domains, "num-process-guids": len(processGuids)})
+	logger.Info("started")
+	defer logger.Info("finished")
+
+	t.Lock()
+	defer t.Unlock()
+
+	swappedRoutingKeys := map[RoutingKey]struct{}{}
+	for key := range otherTable.entries {
+		if _, ok := processGuids[key.ProcessGUID]; ok {
+			swappedRoutingKeys[key] = struct{}{}
+		}
+	}
+	for key, entry := range t.entries {
+		if _, ok := processGuids[key.ProcessGUID]; !ok {
+			continue
+		}
+		swappedRoutingKeys[key] = struct{}{}
+
+		// the addresses of the other table replace the ones of the swapped
+		// endpoints
+		for _, endpoint := range entry.Endpoints {
+			address := t.addressGenerator(endpoint)
+			if existingEndpointKey, ok := t.addressEntries[address]; ok && existingEndpointKey == endpoint.key() {
+				delete(t.addressEntries, address)
+			}
+		}
+	}
+	for key, entry := range otherTable.entries {
+		if _, ok := processGuids[key.ProcessGUID]; !ok {
+			continue
+		}
+		for _, endpoint := range entry.Endpoints {
+			address := t.addressGenerator(endpoint)
+			if endpointKey, ok := otherTable.addressEntries[address]; ok && endpointKey == endpoint.key() {
+				t.addressEntries[address] = endpointKey
+			}
+		}
+	}
+
+	var messagesToEmit MessagesToEmit
+	var mappings TCPRouteMappings
+
+	for key := range swappedRoutingKeys {
+		existingEntry, ok := t.entries[key]
+		newEntry := otherTable.entries[key]
+		if !ok {
+			t.entries[key] = newEntry
+			mapping, message := t.emitDiffMessages(key, RoutableEndpoints{}, newEntry, ReasonSync)
+			messagesToEmit = messagesToEmit.Merge(message)
+			mappings = mappings.Merge(mapping)
+			continue
+		}
+
+		merged := mergeUnfreshRoutes(existingEntry, newEntry, domains)
+		t.entries[key] = merged
+		t.deleteEntryIfEmpty(key)
+		mapping, message := t.emitDiffMessages(key, existingEntry, merged, ReasonSync)
+		messagesToEmit = messagesToEmit.Merge(message)
+		mappings = mappings.Merge(mapping)
+	}
+
+	// the endpoints draining for the swapped entries are released the way
+	// adding an endpoint or removing an entry releases them
+	if len(t.draining) > 0 {
+		for key := range swappedRoutingKeys {
+			entry, ok := t.entries[key]
+			if !ok {
+				removed := key
+				messagesToEmit = messagesToEmit.Merge(t.releaseDrainingEntries(func(key RoutingKey) bool { return key == removed }))
+				continue
+			}
+			for _, endpoint := range entry.Endpoints {
+				messagesToEmit = messagesToEmit.Merge(t.releaseDraining(key, endpoint))
+			}
+		}
+	}
+
+	return mappings, messagesToEmit
+}
+
+func (t *internalRoutingTable) RemoveEntries(remove func(RoutingKey) bool) (TCPRouteMappings, MessagesToEmit) {
+	logger := t.logger.Session("remove-entries")
+	logger.Debug("started")
+	defer logger.Debug("finished")
+
+	t.Lock()
+	defer t.Unlock()
+
+	var messagesToEmit MessagesToEmit
+	var mappings TCPRouteMappings
+
+	for key, entry := range t.entries {
+		if !remove(key) {
+			continue
+		}
+
+		for _, endpoint := range entry.Endpoints {
+			address := t.addressGenerator(endpoint)
+			if existingEndpointKey, ok := t.addressEntries[address]; ok && existingEndpointKey == endpoint.key() {
+				delete(t.addressEntries, address)
+			}
+		}
+		delete(t.entries, key)
+
+		mapping, message := t.emitDiffMessages(key, entry, RoutableEndpoints{}, ReasonEntriesRemoved)
+		messagesToEmit = messagesToEmit.Merge(message)
+		mappings = mappings.Merge(mapping)
+	}
+
+	messagesToEmit = messagesToEmit.Merge(t.releaseDrainingEntries(remove))
+
+	return mappings, messagesToEmit
+}
+
+// releaseDrainingEntries unregisters the endpoints draining for the entries
+// whose key satisfies remove right away.
+func (t *internalRoutingTable) releaseDrainingEntries(remove func(RoutingKey) bool) MessagesToEmit {
+	var messagesToEmit MessagesToEmit
+	draining := t.draining[:0]
+	for _, pending := range t.draining {
+		if remove(pending.key) {
+			messagesToEmit.UnregistrationMessages = append(messagesToEmit.UnregistrationMessages, pending.unregistrations...)
+			continue
+		}
+		draining = append(draining, pending)
+	}
+	t.draining = draining
+	return messagesToEmit
+}
+
 // merge the routes from both endpoints, ensuring that non-fresh routes aren't removed
 func mergeUnfreshRoutes(before, after RoutableEndpoints, domains models.DomainSet) RoutableEndpoints {
 	merged := after.copy()
@@ -417,12 +749,18 @@ func (t *internalRoutingTable) GetRoutingEvents() (TCPRouteMappings, MessagesToE
 	var messagesToEmit MessagesToEmit
 	var mappings TCPRouteMappings
 	for key, route := range t.entries {
-		mapping, message := t.emitDiffMessages(key, RoutableEndpoints{}, route)
+		// a broadcast does not change anything, it is left out of the history
+		mapping, message := t.messages(diffRegistrations(diffRoutes(nil, route.Routes), diffEndpoints(nil, route.Endpoints)))
 
 		mappings = mappings.Merge(mapping)
 		messagesToEmit = messagesToEmit.Merge(message)
 	}
 
+	// keep draining endpoints from being pruned by the router
+	for _, pending := range t.draining {
+		messagesToEmit.RegistrationMessages = append(messagesToEmit.RegistrationMessages, pending.registrations...)
+	}
+
 	return mappings, messagesToEmit
 }
 
@@ -447,6 +785,8 @@ func httpRoutesFromSchedulingInfo(lrp *models.DesiredLRPSchedulingInfo) map[Rout
 				LogGUID:          lrp.LogGuid,
 				RouteServiceUrl:  route.RouteServiceUrl,
 				IsolationSegment: route.IsolationSegment,
+				Weight:           route.Weight,
+				Options:          routeOptions(route.Options),
 			}
 			routes = append(routes, route)
 		}
@@ -455,6 +795,17 @@ func httpRoutesFromSchedulingInfo(lrp *models.DesiredLRPSchedulingInfo) map[Rout
 	return routeEntries
 }
 
+func routeOptions(options *cfroutes.Options) RouteOptions {
+	if options == nil {
+		return RouteOptions{}
+	}
+	return RouteOptions{
+		LoadBalancingAlgorithm:   options.LoadBalancingAlgorithm,
+		StickySessionCookieNames: strings.Join(options.StickySessionCookieNames, ","),
+		RequestTimeoutMs:         options.RequestTimeoutMs,
+	}
+}
+
 func tcpRoutesFromSchedulingInfo(lrp *models.DesiredLRPSchedulingInfo) map[RoutingKey][]routeMapping {
 	if lrp == nil {
 		return nil
@@ -535,7 +886,7 @@ func (table *internalRoutingTable) SetRoutes(before, after *models.DesiredLRPSch
 
 		table.entries[key] = newEntry
 
-		mapping, message := table.emitDiffMessages(key, currentEntry, newEntry)
+		mapping, message := table.emitDiffMessages(key, currentEntry, newEntry, ReasonRoutesChanged)
 		messagesToEmit = messagesToEmit.Merge(message)
 		mappings = mappings.Merge(mapping)
 	}
@@ -569,7 +920,7 @@ func (table *internalRoutingTable) SetRoutes(before, after *models.DesiredLRPSch
 
 		table.deleteEntryIfEmpty(key)
 
-		mapping, message := table.emitDiffMessages(key, currentEntry, newEntry)
+		mapping, message := table.emitDiffMessages(key, currentEntry, newEntry, ReasonRoutesChanged)
 		messagesToEmit = messagesToEmit.Merge(message)
 		mappings = mappings.Merge(mapping)
 	}
@@ -584,10 +935,14 @@ func (table *internalRoutingTable) deleteEntryIfEmpty(key RoutingKey) {
 	}
 }
 
-func (table *internalRoutingTable) emitDiffMessages(key RoutingKey, oldEntry, newEntry RoutableEndpoints) (TCPRouteMappings, MessagesToEmit) {
+func (table *internalRoutingTable) emitDiffMessages(key RoutingKey, oldEntry, newEntry RoutableEndpoints, reason string) (TCPRouteMappings, MessagesToEmit) {
 	routesDiff := diffRoutes(oldEntry.Routes, newEntry.Routes)
 	endpointsDiff := diffEndpoints(oldEntry.Endpoints, newEntry.Endpoints)
-	return table.messages(routesDiff, endpointsDiff)
+	registrations, unregistrations := diffRegistrations(routesDiff, endpointsDiff)
+	if table.history != nil {
+		table.history.record(table.tableType, key, reason, routesDiff, endpointsDiff, registrations, unregistrations)
+	}
+	return table.messages(registrations, unregistrations)
 }
 
 type routesDiff struct {
@@ -598,14 +953,19 @@ type endpointsDiff struct {
 	before, after, removed, added map[EndpointKey]Endpoint
 }
 
+// a route whose weight or options changed is only added again, so that its
+// endpoints are re-registered with the new settings without being
+// unregistered first
 func diffRoutes(before, after []routeMapping) routesDiff {
 	existingRoutes := map[routeMapping]struct{}{}
 	newRoutes := map[routeMapping]struct{}{}
+	newRouteIdentities := map[routeMapping]struct{}{}
 	for _, route := range before {
 		existingRoutes[route] = struct{}{}
 	}
 	for _, route := range after {
 		newRoutes[route] = struct{}{}
+		newRouteIdentities[routeIdentity(route)] = struct{}{}
 	}
 
 	diff := routesDiff{
@@ -614,7 +974,7 @@ func diffRoutes(before, after []routeMapping) routesDiff {
 	}
 	// generate the diff
 	for route := range existingRoutes {
-		if _, ok := newRoutes[route]; !ok {
+		if _, ok := newRouteIdentities[routeIdentity(route)]; !ok {
 			diff.removed = append(diff.removed, route)
 		}
 	}
@@ -628,6 +988,15 @@ func diffRoutes(before, after []routeMapping) routesDiff {
 	return diff
 }
 
+func routeIdentity(route routeMapping) routeMapping {
+	if httpRoute, ok := route.(Route); ok {
+		httpRoute.Weight = 0
+		httpRoute.Options = RouteOptions{}
+		return httpRoute
+	}
+	return route
+}
+
 // endpoints are different if any field is different other than the following:
 // 1. ModificationTag
 // 2. Evacuating
@@ -672,14 +1041,17 @@ func diffEndpoints(before, after map[EndpointKey]Endpoint) endpointsDiff {
 	return diff
 }
 
-func (table *internalRoutingTable) messages(routesDiff routesDiff, endpointDiff endpointsDiff) (TCPRouteMappings, MessagesToEmit) {
-	type registrationMetadata struct {
-		emitEndpointUpdatedAt bool
-	}
+type registrationMetadata struct {
+	emitEndpointUpdatedAt bool
+}
+
+type registrationSet map[routeMapping]map[Endpoint]*registrationMetadata
+type unregistrationSet map[routeMapping]map[Endpoint]bool
 
+func diffRegistrations(routesDiff routesDiff, endpointDiff endpointsDiff) (registrationSet, unregistrationSet) {
 	// maps used to remove duplicates
-	unregistrations := map[routeMapping]map[Endpoint]bool{}
-	registrations := map[routeMapping]map[Endpoint]*registrationMetadata{}
+	unregistrations := unregistrationSet{}
+	registrations := registrationSet{}
 
 	// for removed routes remove endpoints previously registered
 	for _, route := range routesDiff.removed {
@@ -737,6 +1109,10 @@ func (table *internalRoutingTable) messages(routesDiff routesDiff, endpointDiff
 		}
 	}
 
+	return registrations, unregistrations
+}
+
+func (table *internalRoutingTable) messages(registrations registrationSet, unregistrations unregistrationSet) (TCPRouteMappings, MessagesToEmit) {
 	messages := MessagesToEmit{}
 	mappings := TCPRouteMappings{}
 
@@ -798,7 +1174,62 @@ func (t *internalRoutingTable) TableSize() int {
 	return len(t.entries)
 }
 
+func (t *internalRoutingTable) Entries() map[RoutingKey]RoutableEndpoints {
+	t.Lock()
+	defer t.Unlock()
+
+	entries := make(map[RoutingKey]RoutableEndpoints, len(t.entries))
+	for key, entry := range t.entries {
+		entries[key] = entry.copy()
+	}
+
+	return entries
+}
+
+// EntriesForHostnames returns copies of the entries with a Route for any of
+// hostnames, without copying the rest of the table.
+func (t *internalRoutingTable) EntriesForHostnames(hostnames []string) map[RoutingKey]RoutableEndpoints {
+	wanted := make(map[string]struct{}, len(hostnames))
+	for _, hostname := range hostnames {
+		wanted[hostname] = struct{}{}
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	entries := map[RoutingKey]RoutableEndpoints{}
+	for key, entry := range t.entries {
+		for _, route := range entry.Routes {
+			httpRoute, ok := route.(Route)
+			if !ok {
+				continue
+			}
+			if _, ok := wanted[httpRoute.Hostname]; ok {
+				entries[key] = entry.copy()
+				break
+			}
+		}
+	}
+
+	return entries
+}
+
+func (t *internalRoutingTable) AddressEntries() map[Address]EndpointKey {
+	t.Lock()
+	defer t.Unlock()
+
+	addressEntries := make(map[Address]EndpointKey, len(t.addressEntries))
+	for address, endpointKey := range t.addressEntries {
+		addressEntries[address] = endpointKey
+	}
+
+	return addressEntries
+}
+
 func (t *internalRoutingTable) HasExternalRoutes(actual *ActualLRPRoutingInfo) bool {
+	t.Lock()
+	defer t.Unlock()
+
 	for _, key := range NewRoutingKeysFromActual(actual) {
 		if len(t.entries[key].Routes) > 0 {
 			return true
@@ -827,3 +1258,23 @@ func (t *routingTable) TableSize() int {
 func (t *routingTable) HasExternalRoutes(actual *ActualLRPRoutingInfo) bool {
 	return t.httpRoutesRoutingTable.HasExternalRoutes(actual) || t.tcpRoutesRoutingTable.HasExternalRoutes(actual)
 }
+
+func (t *routingTable) HTTPEntries() map[RoutingKey]RoutableEndpoints {
+	return t.httpRoutesRoutingTable.Entries()
+}
+
+func (t *routingTable) HTTPEntriesForHostnames(hostnames []string) map[RoutingKey]RoutableEndpoints {
+	return t.httpRoutesRoutingTable.EntriesForHostnames(hostnames)
+}
+
+func (t *routingTable) TCPEntries() map[RoutingKey]RoutableEndpoints {
+	return t.tcpRoutesRoutingTable.Entries()
+}
+
+func (t *routingTable) InternalEntries() map[RoutingKey]RoutableEndpoints {
+	return t.internalRoutesRoutingTable.Entries()
+}
+
+func (t *routingTable) AddressEntries() map[Address]EndpointKey {
+	return t.httpRoutesRoutingTable.AddressEntries()
+}
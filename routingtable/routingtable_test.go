@@ -6,10 +6,11 @@ import (
 	"code.cloudfoundry.org/bbs/models"
 	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
 	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/cfroutes"
+	"code.cloudfoundry.org/route-emitter/metrics"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	. "code.cloudfoundry.org/route-emitter/routingtable/matchers"
 	tcpmodels "code.cloudfoundry.org/routing-api/models"
-	"code.cloudfoundry.org/routing-info/cfroutes"
 	"code.cloudfoundry.org/routing-info/internalroutes"
 	"code.cloudfoundry.org/routing-info/tcp_routes"
 	. "github.com/onsi/ginkgo"
@@ -147,6 +148,7 @@ var _ = Describe("RoutingTable", func() {
 		endpoint1, endpoint2, endpoint3 routingtable.Endpoint
 		key                             routingtable.RoutingKey
 		fakeMetronClient                *mfakes.FakeIngressClient
+		metricsSink                     metrics.Sink
 	)
 
 	domain := "domain"
@@ -163,7 +165,8 @@ var _ = Describe("RoutingTable", func() {
 	BeforeEach(func() {
 		logger = lagertest.NewTestLogger("test-route-emitter")
 		fakeMetronClient = &mfakes.FakeIngressClient{}
-		table = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+		metricsSink = metrics.NewLoggregatorSink(fakeMetronClient)
+		table = routingtable.NewRoutingTable(logger, false, metricsSink)
 
 		endpoint1 = routingtable.Endpoint{
 			InstanceGUID:    "ig-1",
@@ -286,6 +289,63 @@ var _ = Describe("RoutingTable", func() {
 			})
 		})
 
+		Context("when the weight of a route changes", func() {
+			JustBeforeEach(func() {
+				routes := createRoutingInfo(key.ContainerPort, nil, []string{internalHostname}, "", []uint32{}, logGuid)
+				weightedRoutes := cfroutes.CFRoutes{{Hostnames: []string{hostname1}, Port: key.ContainerPort, Weight: 20}}.RoutingInfo()
+				routes[cfroutes.CF_ROUTER] = weightedRoutes[cfroutes.CF_ROUTER]
+
+				afterDesiredLRP := createSchedulingInfoWithRoutes(key.ProcessGUID, instances, routes, logGuid, *newerTag)
+				tcpRouteMappings, messagesToEmit = table.SetRoutes(beforeDesiredLRP, afterDesiredLRP)
+			})
+
+			It("registers the route again without unregistering it", func() {
+				expected := routingtable.MessagesToEmit{
+					RegistrationMessages: []routingtable.RegistryMessage{
+						routingtable.RegistryMessageFor(endpoint1, routingtable.Route{Hostname: hostname1, LogGUID: logGuid, Weight: 20}, false),
+					},
+				}
+				Expect(messagesToEmit).To(Equal(expected))
+				Expect(messagesToEmit.RegistrationMessages[0].Weight).To(BeEquivalentTo(20))
+			})
+		})
+
+		Context("when the options of a route change", func() {
+			JustBeforeEach(func() {
+				routes := createRoutingInfo(key.ContainerPort, nil, []string{internalHostname}, "", []uint32{}, logGuid)
+				routesWithOptions := cfroutes.CFRoutes{{
+					Hostnames: []string{hostname1},
+					Port:      key.ContainerPort,
+					Options: &cfroutes.Options{
+						LoadBalancingAlgorithm:   "least-connection",
+						StickySessionCookieNames: []string{"JSESSIONID", "SESSION"},
+					},
+				}}.RoutingInfo()
+				routes[cfroutes.CF_ROUTER] = routesWithOptions[cfroutes.CF_ROUTER]
+
+				afterDesiredLRP := createSchedulingInfoWithRoutes(key.ProcessGUID, instances, routes, logGuid, *newerTag)
+				tcpRouteMappings, messagesToEmit = table.SetRoutes(beforeDesiredLRP, afterDesiredLRP)
+			})
+
+			It("registers the route again with the options", func() {
+				route := routingtable.Route{
+					Hostname: hostname1,
+					LogGUID:  logGuid,
+					Options: routingtable.RouteOptions{
+						LoadBalancingAlgorithm:   "least-connection",
+						StickySessionCookieNames: "JSESSIONID,SESSION",
+					},
+				}
+				expected := routingtable.MessagesToEmit{
+					RegistrationMessages: []routingtable.RegistryMessage{
+						routingtable.RegistryMessageFor(endpoint1, route, false),
+					},
+				}
+				Expect(messagesToEmit).To(Equal(expected))
+				Expect(messagesToEmit.RegistrationMessages[0].Options.StickySessionCookieNames).To(Equal([]string{"JSESSIONID", "SESSION"}))
+			})
+		})
+
 		Context("when the internal route is removed", func() {
 			JustBeforeEach(func() {
 				afterDesiredLRP := createDesiredLRPSchedulingInfo(key.ProcessGUID, instances, key.ContainerPort, logGuid, "", *newerTag, hostname1)
@@ -432,13 +492,13 @@ var _ = Describe("RoutingTable", func() {
 			table.AddEndpoint(actualLRP)
 
 			By("removing the route and making the domains unfresh")
-			tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+			tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 			actualLRP = createActualLRP(key, endpoint1, domain)
 			tempTable.AddEndpoint(actualLRP)
 			table.Swap(tempTable, noFreshDomains)
 
 			By("making the domain fresh again")
-			tempTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+			tempTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 			actualLRP = createActualLRP(key, endpoint1, domain)
 			tempTable.AddEndpoint(actualLRP)
 			tcpRouteMappings, messagesToEmit = table.Swap(tempTable, freshDomains)
@@ -465,7 +525,7 @@ var _ = Describe("RoutingTable", func() {
 
 		Context("when there is internal routable endpoint", func() {
 			BeforeEach(func() {
-				// table = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+				// table = routingtable.NewRoutingTable(logger, false, metricsSink)
 				routingInfo := createRoutingInfo(key.ContainerPort, []string{}, []string{"internal"}, "", []uint32{5222}, "")
 				beforeDesiredLRP := createSchedulingInfoWithRoutes(key.ProcessGUID, 3, routingInfo, logGuid, *currentTag)
 				table.SetRoutes(nil, beforeDesiredLRP)
@@ -477,7 +537,7 @@ var _ = Describe("RoutingTable", func() {
 			Context("and the domain is not fresh", func() {
 				It("saves the previous tables routes and emits them when an endpoint is added", func() {
 					actualLRP := createActualLRP(key, endpoint1, domain)
-					tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 					tempTable.AddEndpoint(actualLRP)
 					_, messagesToEmit := table.Swap(tempTable, noFreshDomains)
 					Expect(messagesToEmit.InternalUnregistrationMessages).To(BeEmpty())
@@ -510,7 +570,7 @@ var _ = Describe("RoutingTable", func() {
 			Context("when the domain is not fresh", func() {
 				Context("and the new table has nothing in it", func() {
 					BeforeEach(func() {
-						tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+						tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 						tcpRouteMappings, messagesToEmit = table.Swap(tempTable, noFreshDomains)
 					})
 
@@ -537,7 +597,7 @@ var _ = Describe("RoutingTable", func() {
 
 					It("saves the previous tables routes and emits them when an endpoint is added", func() {
 						actualLRP := createActualLRP(key, endpoint1, domain)
-						tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+						tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 						tempTable.AddEndpoint(actualLRP)
 						tcpRouteMappings, messagesToEmit = table.Swap(tempTable, noFreshDomains)
 
@@ -625,7 +685,7 @@ var _ = Describe("RoutingTable", func() {
 
 		Context("when the table is swaped and the lrp is deleted", func() {
 			BeforeEach(func() {
-				tempTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+				tempTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 				table.Swap(tempTable, freshDomains)
 			})
 
@@ -635,6 +695,56 @@ var _ = Describe("RoutingTable", func() {
 		})
 	})
 
+	Describe("Entries", func() {
+		BeforeEach(func() {
+			routes := createRoutingInfo(key.ContainerPort, []string{hostname1}, []string{"internal-hostname"}, "", []uint32{5222}, "router-group-guid")
+			desiredLRP := createSchedulingInfoWithRoutes(key.ProcessGUID, 1, routes, logGuid, *currentTag)
+			table.SetRoutes(nil, desiredLRP)
+			table.AddEndpoint(createActualLRP(key, endpoint1, domain))
+		})
+
+		It("returns the http entries", func() {
+			entries := table.HTTPEntries()
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[key].Routes).To(ConsistOf(routingtable.Route{Hostname: hostname1, LogGUID: logGuid}))
+			Expect(entries[key].Endpoints).To(Equal(map[routingtable.EndpointKey]routingtable.Endpoint{
+				routingtable.NewEndpointKey(endpoint1.InstanceGUID, false): endpoint1,
+			}))
+			Expect(entries[key].DesiredInstances).To(BeEquivalentTo(1))
+			Expect(entries[key].ModificationTag).To(Equal(currentTag))
+		})
+
+		It("returns the http entries routing any of the given hostnames", func() {
+			Expect(table.HTTPEntriesForHostnames([]string{"unknown.example.com", hostname1})).To(Equal(table.HTTPEntries()))
+			Expect(table.HTTPEntriesForHostnames([]string{"internal-hostname"})).To(BeEmpty())
+		})
+
+		It("returns the tcp entries", func() {
+			entries := table.TCPEntries()
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[key].Routes).To(ConsistOf(routingtable.ExternalEndpointInfo{RouterGroupGUID: "router-group-guid", Port: 5222}))
+		})
+
+		It("returns the internal entries", func() {
+			entries := table.InternalEntries()
+			internalKey := routingtable.RoutingKey{ProcessGUID: key.ProcessGUID}
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[internalKey].Routes).To(ConsistOf(routingtable.InternalRoute{Hostname: "internal-hostname", LogGUID: logGuid}))
+		})
+
+		It("returns the address entries of the http table", func() {
+			Expect(table.AddressEntries()).To(Equal(map[routingtable.Address]routingtable.EndpointKey{
+				{Host: endpoint1.Host, Port: endpoint1.Port}: routingtable.NewEndpointKey(endpoint1.InstanceGUID, false),
+			}))
+		})
+
+		It("returns copies that do not modify the table", func() {
+			entries := table.HTTPEntries()
+			delete(entries[key].Endpoints, routingtable.NewEndpointKey(endpoint1.InstanceGUID, false))
+			Expect(table.HTTPEntries()[key].Endpoints).To(HaveLen(1))
+		})
+	})
+
 	Describe("RouteCounts", func() {
 		BeforeEach(func() {
 			internalHostname := "internal"
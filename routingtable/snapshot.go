@@ -0,0 +1,130 @@
+package routingtable
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/bbs/models"
+)
+
+// SnapshotVersion is bumped whenever the serialized layout of Snapshot
+// changes in a way older emitters cannot read.
+const SnapshotVersion = 1
+
+type ErrSnapshotVersionMismatch struct {
+	Version int
+}
+
+func (e ErrSnapshotVersionMismatch) Error() string {
+	return fmt.Sprintf("unsupported routing table snapshot version %d, expected %d", e.Version, SnapshotVersion)
+}
+
+type Snapshot struct {
+	Version  int             `json:"version"`
+	HTTP     []SnapshotEntry `json:"http"`
+	TCP      []SnapshotEntry `json:"tcp"`
+	Internal []SnapshotEntry `json:"internal"`
+}
+
+type SnapshotEntry struct {
+	Key              RoutingKey              `json:"key"`
+	Domain           string                  `json:"domain"`
+	DesiredInstances int32                   `json:"desired_instances"`
+	ModificationTag  *models.ModificationTag `json:"modification_tag,omitempty"`
+	HTTPRoutes       []Route                 `json:"http_routes,omitempty"`
+	TCPRoutes        []ExternalEndpointInfo  `json:"tcp_routes,omitempty"`
+	InternalRoutes   []InternalRoute         `json:"internal_routes,omitempty"`
+	Endpoints        []Endpoint              `json:"endpoints,omitempty"`
+}
+
+func (t *routingTable) Snapshot() Snapshot {
+	return Snapshot{
+		Version:  SnapshotVersion,
+		HTTP:     t.httpRoutesRoutingTable.snapshotEntries(),
+		TCP:      t.tcpRoutesRoutingTable.snapshotEntries(),
+		Internal: t.internalRoutesRoutingTable.snapshotEntries(),
+	}
+}
+
+// Restore replaces the content of the table with the entries in the
+// snapshot. No messages are generated, use GetExternalRoutingEvents and
+// GetInternalRoutingEvents to broadcast the restored state.
+func (t *routingTable) Restore(snapshot Snapshot) error {
+	if snapshot.Version != SnapshotVersion {
+		return ErrSnapshotVersionMismatch{Version: snapshot.Version}
+	}
+
+	t.httpRoutesRoutingTable.restore(snapshot.HTTP)
+	t.tcpRoutesRoutingTable.restore(snapshot.TCP)
+	t.internalRoutesRoutingTable.restore(snapshot.Internal)
+	return nil
+}
+
+func (t *internalRoutingTable) snapshotEntries() []SnapshotEntry {
+	t.Lock()
+	defer t.Unlock()
+
+	snapshotEntries := make([]SnapshotEntry, 0, len(t.entries))
+	for key, entry := range t.entries {
+		snapshotEntry := SnapshotEntry{
+			Key:              key,
+			Domain:           entry.Domain,
+			DesiredInstances: entry.DesiredInstances,
+			ModificationTag:  entry.ModificationTag,
+		}
+
+		for _, route := range entry.Routes {
+			switch route := route.(type) {
+			case Route:
+				snapshotEntry.HTTPRoutes = append(snapshotEntry.HTTPRoutes, route)
+			case ExternalEndpointInfo:
+				snapshotEntry.TCPRoutes = append(snapshotEntry.TCPRoutes, route)
+			case InternalRoute:
+				snapshotEntry.InternalRoutes = append(snapshotEntry.InternalRoutes, route)
+			}
+		}
+
+		for _, endpoint := range entry.Endpoints {
+			snapshotEntry.Endpoints = append(snapshotEntry.Endpoints, endpoint)
+		}
+
+		snapshotEntries = append(snapshotEntries, snapshotEntry)
+	}
+
+	return snapshotEntries
+}
+
+func (t *internalRoutingTable) restore(snapshotEntries []SnapshotEntry) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.entries = make(map[RoutingKey]RoutableEndpoints, len(snapshotEntries))
+	t.addressEntries = make(map[Address]EndpointKey)
+
+	for _, snapshotEntry := range snapshotEntries {
+		entry := RoutableEndpoints{
+			Domain:           snapshotEntry.Domain,
+			Endpoints:        make(map[EndpointKey]Endpoint, len(snapshotEntry.Endpoints)),
+			DesiredInstances: snapshotEntry.DesiredInstances,
+			ModificationTag:  snapshotEntry.ModificationTag,
+		}
+
+		for _, route := range snapshotEntry.HTTPRoutes {
+			entry.Routes = append(entry.Routes, route)
+		}
+		for _, route := range snapshotEntry.TCPRoutes {
+			entry.Routes = append(entry.Routes, route)
+		}
+		for _, route := range snapshotEntry.InternalRoutes {
+			entry.Routes = append(entry.Routes, route)
+		}
+
+		for _, endpoint := range snapshotEntry.Endpoints {
+			entry.Endpoints[endpoint.key()] = endpoint
+			if !t.suppressAddressCollision {
+				t.addressEntries[t.addressGenerator(endpoint)] = endpoint.key()
+			}
+		}
+
+		t.entries[snapshotEntry.Key] = entry
+	}
+}
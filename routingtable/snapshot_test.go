@@ -0,0 +1,108 @@
+package routingtable_test
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/bbs/models"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/metrics"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	. "code.cloudfoundry.org/route-emitter/routingtable/matchers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Snapshot", func() {
+	var (
+		logger           *lagertest.TestLogger
+		fakeMetronClient *mfakes.FakeIngressClient
+		metricsSink      metrics.Sink
+		table            routingtable.RoutingTable
+		restoredTable    routingtable.RoutingTable
+		key              routingtable.RoutingKey
+		endpoint         routingtable.Endpoint
+		currentTag       *models.ModificationTag
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		fakeMetronClient = &mfakes.FakeIngressClient{}
+		metricsSink = metrics.NewLoggregatorSink(fakeMetronClient)
+		table = routingtable.NewRoutingTable(logger, false, metricsSink)
+		restoredTable = routingtable.NewRoutingTable(logger, false, metricsSink)
+
+		currentTag = &models.ModificationTag{Epoch: "abc", Index: 1}
+		key = routingtable.RoutingKey{ProcessGUID: "some-process-guid", ContainerPort: 8080}
+		endpoint = routingtable.Endpoint{
+			InstanceGUID:    "ig-1",
+			Host:            "1.1.1.1",
+			ContainerIP:     "1.2.3.4",
+			Index:           0,
+			Port:            11,
+			ContainerPort:   8080,
+			Since:           1,
+			ModificationTag: currentTag,
+		}
+
+		routes := createRoutingInfo(key.ContainerPort, []string{"foo.example.com"}, []string{"internal-hostname"}, "https://rs.example.com", []uint32{5222}, "router-group-guid")
+		table.SetRoutes(nil, createSchedulingInfoWithRoutes(key.ProcessGUID, 1, routes, "some-log-guid", *currentTag))
+		table.AddEndpoint(createActualLRP(key, endpoint, "domain"))
+	})
+
+	Context("when a snapshot survives a JSON round trip", func() {
+		BeforeEach(func() {
+			payload, err := json.Marshal(table.Snapshot())
+			Expect(err).NotTo(HaveOccurred())
+
+			var snapshot routingtable.Snapshot
+			Expect(json.Unmarshal(payload, &snapshot)).To(Succeed())
+			Expect(snapshot.Version).To(Equal(routingtable.SnapshotVersion))
+
+			Expect(restoredTable.Restore(snapshot)).To(Succeed())
+		})
+
+		It("restores every sub-table", func() {
+			Expect(restoredTable.HTTPEntries()).To(Equal(table.HTTPEntries()))
+			Expect(restoredTable.TCPEntries()).To(Equal(table.TCPEntries()))
+			Expect(restoredTable.InternalEntries()).To(Equal(table.InternalEntries()))
+		})
+
+		It("rebuilds the address collision map", func() {
+			Expect(restoredTable.AddressEntries()).To(Equal(table.AddressEntries()))
+		})
+
+		It("generates the same external routing events", func() {
+			expectedMappings, expectedMessages := table.GetExternalRoutingEvents()
+			mappings, messages := restoredTable.GetExternalRoutingEvents()
+			Expect(messages).To(MatchMessagesToEmit(expectedMessages))
+			Expect(mappings.Registrations).To(ConsistOf(expectedMappings.Registrations))
+		})
+
+		It("generates the same internal routing events", func() {
+			_, expectedMessages := table.GetInternalRoutingEvents()
+			_, messages := restoredTable.GetInternalRoutingEvents()
+			Expect(messages).To(MatchMessagesToEmit(expectedMessages))
+		})
+
+		Context("and the restored table is swapped with a table without the lrp", func() {
+			It("unregisters the restored routes", func() {
+				emptyTable := routingtable.NewRoutingTable(logger, false, metricsSink)
+				_, messages := restoredTable.Swap(emptyTable, models.NewDomainSet([]string{"domain"}))
+				Expect(messages.UnregistrationMessages).To(HaveLen(1))
+				Expect(restoredTable.TableSize()).To(Equal(0))
+			})
+		})
+	})
+
+	Context("when the snapshot version is not supported", func() {
+		It("returns an error and leaves the table untouched", func() {
+			snapshot := table.Snapshot()
+			snapshot.Version = routingtable.SnapshotVersion + 1
+
+			err := restoredTable.Restore(snapshot)
+			Expect(err).To(Equal(routingtable.ErrSnapshotVersionMismatch{Version: routingtable.SnapshotVersion + 1}))
+			Expect(restoredTable.TableSize()).To(Equal(0))
+		})
+	})
+})
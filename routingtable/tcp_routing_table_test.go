@@ -8,6 +8,7 @@ import (
 	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/metrics"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/route-emitter/routingtable/fakeroutingtable"
 	tcpmodels "code.cloudfoundry.org/routing-api/models"
@@ -31,6 +32,7 @@ var _ = Describe("TCPRoutingTable", func() {
 		tcpRoutes        tcp_routes.TCPRoutes
 		logger           lager.Logger
 		fakeMetronClient *mfakes.FakeIngressClient
+		metricsSink      metrics.Sink
 	)
 
 	getDesiredLRP := func(
@@ -90,6 +92,7 @@ var _ = Describe("TCPRoutingTable", func() {
 	BeforeEach(func() {
 		logger = lagertest.NewTestLogger("test")
 		fakeMetronClient = &mfakes.FakeIngressClient{}
+		metricsSink = metrics.NewLoggregatorSink(fakeMetronClient)
 		tcpRoutes = tcp_routes.TCPRoutes{
 			tcp_routes.TCPRoute{
 				RouterGroupGuid: "router-group-guid",
@@ -101,7 +104,7 @@ var _ = Describe("TCPRoutingTable", func() {
 
 	Context("when no entry exist for route", func() {
 		BeforeEach(func() {
-			routingTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+			routingTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 			modificationTag = &models.ModificationTag{Epoch: "abc", Index: 0}
 		})
 
@@ -220,7 +223,7 @@ var _ = Describe("TCPRoutingTable", func() {
 
 			BeforeEach(func() {
 				logGuid = "log-guid-1"
-				tempRoutingTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+				tempRoutingTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 				beforeLRPSchedulingInfo := getDesiredLRP("process-guid-1", logGuid, tcpRoutes, modificationTag)
 				tempRoutingTable.SetRoutes(nil, beforeLRPSchedulingInfo)
 				tempRoutingTable.AddEndpoint(getActualLRP("process-guid-1", "instance-guid-1", "some-ip-1", "container-ip-1", 62004, 5222, false, modificationTag))
@@ -255,7 +258,7 @@ var _ = Describe("TCPRoutingTable", func() {
 
 			Context("when the table is configured to emit direct instance route", func() {
 				BeforeEach(func() {
-					routingTable = routingtable.NewRoutingTable(logger, true, fakeMetronClient)
+					routingTable = routingtable.NewRoutingTable(logger, true, metricsSink)
 				})
 
 				It("emits routing events for new routes", func() {
@@ -295,7 +298,7 @@ var _ = Describe("TCPRoutingTable", func() {
 
 		Context("when the routing tables are of different type", func() {
 			It("should not swap the tables", func() {
-				routingTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+				routingTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 				fakeTable := &fakeroutingtable.FakeRoutingTable{}
 				routingEvents, _ := routingTable.Swap(fakeTable, models.DomainSet{})
 				Expect(routingEvents.Registrations).To(HaveLen(0))
@@ -317,7 +320,7 @@ var _ = Describe("TCPRoutingTable", func() {
 
 		Describe("HasExternalRoutes", func() {
 			It("returns the associated desired state", func() {
-				routingTable = routingtable.NewRoutingTable(logger, true, fakeMetronClient)
+				routingTable = routingtable.NewRoutingTable(logger, true, metricsSink)
 				beforeLRPSchedulingInfo := getDesiredLRP("process-guid-1", logGuid, tcpRoutes, modificationTag)
 				routingTable.SetRoutes(nil, beforeLRPSchedulingInfo)
 				routingInfo := getActualLRP("process-guid-1", "instance-guid-2", "some-ip-2", "container-ip-2", 62004, 5222, false, modificationTag)
@@ -328,7 +331,7 @@ var _ = Describe("TCPRoutingTable", func() {
 
 		Describe("AddRoutes", func() {
 			BeforeEach(func() {
-				routingTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+				routingTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 				beforeLRPSchedulingInfo := getDesiredLRP("process-guid-1", "log-guid-1", tcpRoutes, modificationTag)
 				routingTable.SetRoutes(nil, beforeLRPSchedulingInfo)
 				routingTable.AddEndpoint(getActualLRP("process-guid-1", "instance-guid-1", "some-ip-1", "container-ip-1", 62004, 5222, false, modificationTag))
@@ -507,7 +510,7 @@ var _ = Describe("TCPRoutingTable", func() {
 					}
 
 					desiredLRP := getDesiredLRP("process-guid-1", "log-guid-1", currentTcpRoutes, modificationTag)
-					routingTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					routingTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 					routingTable.SetRoutes(nil, desiredLRP)
 					routingTable.AddEndpoint(getActualLRP("process-guid-1", "instance-guid-1", "some-ip-1", "container-ip-1", 62004, 5222, false, modificationTag))
 					routingTable.AddEndpoint(getActualLRP("process-guid-1", "instance-guid-2", "some-ip-2", "container-ip-2", 62004, 5222, false, modificationTag))
@@ -628,7 +631,7 @@ var _ = Describe("TCPRoutingTable", func() {
 			Context("when two disjoint (external port, container port) pairs are given", func() {
 				BeforeEach(func() {
 					beforeLRPSchedulingInfo := getDesiredLRP("process-guid-1", "log-guid-1", tcpRoutes, modificationTag)
-					routingTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					routingTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 					routingTable.SetRoutes(nil, beforeLRPSchedulingInfo)
 					routingTable.AddEndpoint(getActualLRP("process-guid-1", "instance-guid-1", "some-ip-1", "container-ip-1", 62004, 5222, false, modificationTag))
 					routingTable.AddEndpoint(getActualLRP("process-guid-1", "instance-guid-1", "some-ip-1", "container-ip-1", 63004, 5223, false, modificationTag))
@@ -718,7 +721,7 @@ var _ = Describe("TCPRoutingTable", func() {
 
 			BeforeEach(func() {
 				newModificationTag = &models.ModificationTag{Epoch: "abc", Index: 2}
-				routingTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+				routingTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 				beforeLRPSchedulingInfo = getDesiredLRP("process-guid-1", "log-guid-1", tcpRoutes, modificationTag)
 				routingTable.SetRoutes(nil, beforeLRPSchedulingInfo)
 				routingTable.AddEndpoint(getActualLRP("process-guid-1", "instance-guid-1", "some-ip-1", "container-ip-1", 62004, 5222, false, modificationTag))
@@ -1121,7 +1124,7 @@ var _ = Describe("TCPRoutingTable", func() {
 							ContainerPort:   5222,
 						},
 					}
-					routingTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					routingTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 					beforeLRPSchedulingInfo = getDesiredLRP("process-guid-1", "log-guid-1", newTcpRoutes, modificationTag)
 					routingTable.SetRoutes(nil, beforeLRPSchedulingInfo)
 					routingTable.AddEndpoint(getActualLRP("process-guid-1", "instance-guid-1", "some-ip-1", "container-ip-1", 62004, 5222, false, modificationTag))
@@ -1157,7 +1160,7 @@ var _ = Describe("TCPRoutingTable", func() {
 				)
 
 				BeforeEach(func() {
-					routingTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					routingTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 					desiredLRP = getDesiredLRP("process-guid-1", "log-guid-1", tcpRoutes, modificationTag)
 					routingTable.SetRoutes(nil, desiredLRP)
 					Expect(routingTable.TCPAssociationsCount()).Should(Equal(0))
@@ -1181,7 +1184,7 @@ var _ = Describe("TCPRoutingTable", func() {
 							ContainerPort:   5222,
 						},
 					}
-					routingTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					routingTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 					modificationTag := &models.ModificationTag{Epoch: "abc", Index: 1}
 					desiredLRP := getDesiredLRP("process-guid-1", "log-guid-1", tcpRoutes, modificationTag)
 					routingTable.SetRoutes(nil, desiredLRP)
@@ -1209,7 +1212,7 @@ var _ = Describe("TCPRoutingTable", func() {
 
 				Context("when there are no external endpoints", func() {
 					BeforeEach(func() {
-						routingTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+						routingTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 						modificationTag := &models.ModificationTag{Epoch: "abc", Index: 1}
 						desiredLRP := getDesiredLRP("process-guid-1", "log-guid-1", tcpRoutes, modificationTag)
 						routingTable.SetRoutes(nil, desiredLRP)
@@ -1231,7 +1234,7 @@ var _ = Describe("TCPRoutingTable", func() {
 		Describe("AddEndpoint", func() {
 			Context("with no existing endpoints", func() {
 				BeforeEach(func() {
-					routingTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					routingTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 					beforeLRPSchedulingInfo := getDesiredLRP("process-guid-1", "log-guid-1", tcpRoutes, modificationTag)
 					routingTable.SetRoutes(nil, beforeLRPSchedulingInfo)
 					Expect(routingTable.TCPAssociationsCount()).Should(Equal(0))
@@ -1258,7 +1261,7 @@ var _ = Describe("TCPRoutingTable", func() {
 
 			Context("with existing endpoints", func() {
 				BeforeEach(func() {
-					routingTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					routingTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 					beforeLRPSchedulingInfo := getDesiredLRP("process-guid-1", "log-guid-1", tcpRoutes, modificationTag)
 					routingTable.SetRoutes(nil, beforeLRPSchedulingInfo)
 					routingTable.AddEndpoint(getActualLRP("process-guid-1", "instance-guid-1", "some-ip-1", "container-ip-1", 62004, 5222, false, modificationTag))
@@ -1321,7 +1324,7 @@ var _ = Describe("TCPRoutingTable", func() {
 		Describe("RemoveEndpoint", func() {
 			Context("with no existing endpoints", func() {
 				BeforeEach(func() {
-					routingTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					routingTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 					beforeLRPSchedulingInfo := getDesiredLRP("process-guid-1", "log-guid-1", tcpRoutes, modificationTag)
 					routingTable.SetRoutes(nil, beforeLRPSchedulingInfo)
 					Expect(routingTable.TCPAssociationsCount()).Should(Equal(0))
@@ -1338,7 +1341,7 @@ var _ = Describe("TCPRoutingTable", func() {
 
 			Context("with existing endpoints", func() {
 				BeforeEach(func() {
-					routingTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					routingTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 					beforeLRPSchedulingInfo := getDesiredLRP("process-guid-1", "log-guid-1", tcpRoutes, modificationTag)
 					routingTable.SetRoutes(nil, beforeLRPSchedulingInfo)
 					routingTable.AddEndpoint(getActualLRP("process-guid-1", "instance-guid-1", "some-ip-1", "container-ip-1", 62004, 5222, false, modificationTag))
@@ -1409,7 +1412,7 @@ var _ = Describe("TCPRoutingTable", func() {
 
 		Describe("GetRoutingEvents", func() {
 			BeforeEach(func() {
-				routingTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+				routingTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 				beforeLRPSchedulingInfo := getDesiredLRP("process-guid-1", "log-guid-1", tcpRoutes, modificationTag)
 				routingTable.SetRoutes(nil, beforeLRPSchedulingInfo)
 				routingTable.AddEndpoint(getActualLRP("process-guid-1", "instance-guid-1", "some-ip-1", "container-ip-1", 62004, 5222, false, modificationTag))
@@ -1443,7 +1446,7 @@ var _ = Describe("TCPRoutingTable", func() {
 			BeforeEach(func() {
 				existingLogGuid = "log-guid-1"
 				newModificationTag = &models.ModificationTag{Epoch: "abc", Index: 2}
-				routingTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+				routingTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 				beforeLRPSchedulingInfo := getDesiredLRP("process-guid-1", existingLogGuid, tcpRoutes, modificationTag)
 				routingTable.SetRoutes(nil, beforeLRPSchedulingInfo)
 				routingTable.AddEndpoint(getActualLRP("process-guid-1", "instance-guid-1", "some-ip-1", "container-ip-1", 62004, 5222, false, modificationTag))
@@ -1455,7 +1458,7 @@ var _ = Describe("TCPRoutingTable", func() {
 
 				BeforeEach(func() {
 					logGuid = "log-guid-2"
-					tempRoutingTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempRoutingTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 					beforeLRPSchedulingInfo := getDesiredLRP("process-guid-2", logGuid, tcpRoutes, newModificationTag)
 					tempRoutingTable.SetRoutes(nil, beforeLRPSchedulingInfo)
 					tempRoutingTable.AddEndpoint(getActualLRP("process-guid-2", "instance-guid-1", "some-ip-3", "container-ip-3", 63004, 5222, false, newModificationTag))
@@ -1507,7 +1510,7 @@ var _ = Describe("TCPRoutingTable", func() {
 			Context("when updating an existing routing key (process-guid, container-port)", func() {
 				BeforeEach(func() {
 					logGuid = "log-guid-2"
-					tempRoutingTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempRoutingTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 					beforeLRPSchedulingInfo := getDesiredLRP("process-guid-1", logGuid, tcpRoutes, newModificationTag)
 					tempRoutingTable.SetRoutes(nil, beforeLRPSchedulingInfo)
 					tempRoutingTable.AddEndpoint(getActualLRP("process-guid-1", "instance-guid-1", "some-ip-3", "container-ip-3", 63004, 5222, false, newModificationTag))
@@ -1569,7 +1572,7 @@ var _ = Describe("TCPRoutingTable", func() {
 						},
 					}
 					beforeLRPSchedulingInfo := getDesiredLRP("process-guid-1", existingLogGuid, newTcpRoutes, newModificationTag)
-					tempRoutingTable = routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+					tempRoutingTable = routingtable.NewRoutingTable(logger, false, metricsSink)
 					tempRoutingTable.SetRoutes(nil, beforeLRPSchedulingInfo)
 					tempRoutingTable.AddEndpoint(getActualLRP("process-guid-1", "instance-guid-1", "some-ip-1", "container-ip-1", 62004, 5222, false, modificationTag))
 					tempRoutingTable.AddEndpoint(getActualLRP("process-guid-1", "instance-guid-2", "some-ip-2", "container-ip-2", 62004, 5222, false, modificationTag))
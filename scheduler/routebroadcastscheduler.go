@@ -15,26 +15,39 @@ import (
 	uuid "github.com/nu7hatch/gouuid"
 )
 
+// GreetingObserver is told about every greeting received from the external
+// service, such as the features each router instance supports.
+type GreetingObserver interface {
+	Observe(greeting routingtable.ExternalServiceGreetingMessage)
+}
+
 type RouteBroadcastScheduler struct {
 	natsClient           diegonats.NATSClient
 	externalServiceName  string
 	clock                clock.Clock
 	emitCh               chan struct{}
 	externalServiceStart chan time.Duration
+	greetingObserver     GreetingObserver
 
 	logger lager.Logger
 }
 
+// NewRouteBroadcastScheduler returns a scheduler signalling emitCh at the
+// interval requested by the external service. When greetingObserver is not
+// nil the external service is greeted again before every emit so that the
+// observer hears from all of its instances.
 func NewRouteBroadcastScheduler(
 	clock clock.Clock,
 	natsClient diegonats.NATSClient,
 	logger lager.Logger,
 	externalServiceName string,
 	emitCh chan struct{},
+	greetingObserver GreetingObserver,
 ) *RouteBroadcastScheduler {
 	return &RouteBroadcastScheduler{
 		natsClient:          natsClient,
 		externalServiceName: externalServiceName,
+		greetingObserver:    greetingObserver,
 
 		clock:  clock,
 		emitCh: emitCh,
@@ -57,6 +70,18 @@ func (s *RouteBroadcastScheduler) Run(signals <-chan os.Signal, ready chan<- str
 		return err
 	}
 
+	observeUuid, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+
+	if s.greetingObserver != nil {
+		_, err = s.natsClient.Subscribe(observeUuid.String(), s.handleGreetingReply)
+		if err != nil {
+			return err
+		}
+	}
+
 	close(ready)
 	s.logger.Info("started")
 
@@ -86,6 +111,10 @@ GREET_LOOP:
 	}
 	retryGreetingTicker.Stop()
 
+	// only the first reply to the initial greeting is received, greet again
+	// so that the observer hears from every instance before the first emit
+	s.observeExternalService(observeUuid.String())
+
 	// now keep emitting at the desired interval
 	emitTicker := s.clock.NewTicker(registerInterval)
 
@@ -103,6 +132,7 @@ GREET_LOOP:
 		case <-emitTicker.C():
 			s.logger.Info("emitting-routes")
 			s.emit()
+			s.observeExternalService(observeUuid.String())
 		case <-signals:
 			s.logger.Info("stopping")
 			emitTicker.Stop()
@@ -145,6 +175,31 @@ func (s *RouteBroadcastScheduler) greetExternalService(replyUUID string) error {
 	return nil
 }
 
+func (s *RouteBroadcastScheduler) observeExternalService(replyUUID string) {
+	if s.greetingObserver == nil {
+		return
+	}
+
+	err := s.greetExternalService(replyUUID)
+	if err != nil {
+		s.logger.Error("failed-to-greet-external-service", err)
+	}
+}
+
+func (s *RouteBroadcastScheduler) handleGreetingReply(msg *nats.Msg) {
+	var response routingtable.ExternalServiceGreetingMessage
+
+	err := json.Unmarshal(msg.Data, &response)
+	if err != nil {
+		s.logger.Error("received-invalid-external-service-greeting", err, lager.Data{
+			"payload": msg.Data,
+		})
+		return
+	}
+
+	s.greetingObserver.Observe(response)
+}
+
 func (s *RouteBroadcastScheduler) handleExternalServiceStart(msg *nats.Msg) {
 	var response routingtable.ExternalServiceGreetingMessage
 
@@ -156,6 +211,10 @@ func (s *RouteBroadcastScheduler) handleExternalServiceStart(msg *nats.Msg) {
 		return
 	}
 
+	if s.greetingObserver != nil {
+		s.greetingObserver.Observe(response)
+	}
+
 	greetInterval := response.MinimumRegisterInterval
 	s.externalServiceStart <- time.Duration(greetInterval) * time.Second
 }
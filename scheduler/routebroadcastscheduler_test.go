@@ -9,6 +9,7 @@ import (
 	"code.cloudfoundry.org/clock/fakeclock"
 	"code.cloudfoundry.org/lager/lagertest"
 	"code.cloudfoundry.org/route-emitter/diegonats"
+	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/route-emitter/scheduler"
 	"github.com/nats-io/nats"
 	"github.com/tedsuo/ifrit"
@@ -19,6 +20,12 @@ import (
 
 const logGuid = "some-log-guid"
 
+type greetingObserverFunc func(routingtable.ExternalServiceGreetingMessage)
+
+func (f greetingObserverFunc) Observe(greeting routingtable.ExternalServiceGreetingMessage) {
+	f(greeting)
+}
+
 var _ = Describe("RouteBroadcastScheduler", func() {
 	var (
 		bbsClient       *fake_bbs.FakeClient
@@ -28,6 +35,8 @@ var _ = Describe("RouteBroadcastScheduler", func() {
 		clock           *fakeclock.FakeClock
 		emitCh          chan struct{}
 
+		greetingObserver scheduler.GreetingObserver
+
 		shutdown chan struct{}
 
 		natsStartMessages chan<- *nats.Msg
@@ -42,6 +51,7 @@ var _ = Describe("RouteBroadcastScheduler", func() {
 				clock = fakeclock.NewFakeClock(time.Now())
 
 				emitCh = make(chan struct{}, 1)
+				greetingObserver = nil
 				startMessages := make(chan *nats.Msg)
 				natsStartMessages = startMessages
 
@@ -58,7 +68,7 @@ var _ = Describe("RouteBroadcastScheduler", func() {
 
 			JustBeforeEach(func() {
 				logger := lagertest.NewTestLogger("test")
-				schedulerRunner = scheduler.NewRouteBroadcastScheduler(clock, natsClient, logger, prefix, emitCh)
+				schedulerRunner = scheduler.NewRouteBroadcastScheduler(clock, natsClient, logger, prefix, emitCh, greetingObserver)
 
 				shutdown = make(chan struct{})
 
@@ -208,6 +218,58 @@ var _ = Describe("RouteBroadcastScheduler", func() {
 					})
 				})
 
+				Context("with a greeting observer", func() {
+					var observed chan routingtable.ExternalServiceGreetingMessage
+
+					BeforeEach(func() {
+						observed = make(chan routingtable.ExternalServiceGreetingMessage, 10)
+						greetingObserver = greetingObserverFunc(func(greeting routingtable.ExternalServiceGreetingMessage) {
+							observed <- greeting
+						})
+					})
+
+					JustBeforeEach(func() {
+						natsStartMessages <- &nats.Msg{
+							Data: []byte(`{
+						"id": "instance-1",
+						"minimumRegisterIntervalInSeconds": 2,
+						"pruneThresholdInSeconds": 3,
+						"supportsBatchedRegistration": true
+						}`),
+						}
+					})
+
+					It("observes the *.start message", func() {
+						var greeting routingtable.ExternalServiceGreetingMessage
+						Eventually(observed).Should(Receive(&greeting))
+						Expect(greeting.ID).To(Equal("instance-1"))
+						Expect(greeting.SupportsBatchedRegistration).To(BeTrue())
+					})
+
+					It("greets the external service again and observes every reply", func() {
+						Eventually(greetings).Should(Receive())
+
+						var msg *nats.Msg
+						Eventually(greetings).Should(Receive(&msg))
+						go natsClient.Publish(msg.Reply, []byte(`{"id": "instance-2", "minimumRegisterIntervalInSeconds": 2, "pruneThresholdInSeconds": 3}`))
+
+						Eventually(observed).Should(Receive())
+						var greeting routingtable.ExternalServiceGreetingMessage
+						Eventually(observed).Should(Receive(&greeting))
+						Expect(greeting.ID).To(Equal("instance-2"))
+						Expect(greeting.SupportsBatchedRegistration).To(BeFalse())
+					})
+
+					It("greets the external service on every emit", func() {
+						Eventually(greetings).Should(Receive())
+						Eventually(greetings).Should(Receive())
+
+						clock.WaitForWatcherAndIncrement(2 * time.Second)
+						Eventually(schedulerRunner.EmitCh()).Should(Receive())
+						Eventually(greetings).Should(Receive())
+					})
+				})
+
 				Context("if it never hears anything from a external service anywhere", func() {
 					It("should still be able to shutdown", func() {
 						process.Signal(os.Interrupt)
@@ -0,0 +1,154 @@
+package shard
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	locketmodels "code.cloudfoundry.org/locket/models"
+)
+
+// PresenceKeyPrefix prefixes the Locket presence keys of sharded route
+// emitters, other presences such as those of cells are ignored.
+const PresenceKeyPrefix = "route-emitter-"
+
+func PresenceKey(uuid string) string {
+	return PresenceKeyPrefix + uuid
+}
+
+// Membership keeps a ring of the route emitters registered in Locket and
+// tells whether a process guid belongs to the shard of this emitter.
+type Membership struct {
+	logger       lager.Logger
+	clock        clock.Clock
+	locketClient locketmodels.LocketClient
+	self         string
+	pollInterval time.Duration
+	rebalanceCh  chan<- struct{}
+
+	lock sync.RWMutex
+	ring *Ring
+}
+
+// NewMembership returns a membership for the emitter registered as self. A
+// signal is sent on rebalanceCh, without blocking, every time members join or
+// leave.
+func NewMembership(
+	logger lager.Logger,
+	clock clock.Clock,
+	locketClient locketmodels.LocketClient,
+	self string,
+	pollInterval time.Duration,
+	rebalanceCh chan<- struct{},
+) *Membership {
+	return &Membership{
+		logger:       logger.Session("shard-membership", lager.Data{"self": self}),
+		clock:        clock,
+		locketClient: locketClient,
+		self:         self,
+		pollInterval: pollInterval,
+		rebalanceCh:  rebalanceCh,
+		ring:         NewRing([]string{self}, DefaultReplicas),
+	}
+}
+
+// Run becomes ready once the members have been fetched from Locket, until
+// then this emitter would wrongly own every shard.
+func (m *Membership) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	m.logger.Info("starting")
+
+	ticker := m.clock.NewTicker(m.pollInterval)
+	defer ticker.Stop()
+
+	// the first fetch is covered by the initial sync, only later changes
+	// need a rebalance
+	fetched := m.refresh(false)
+	if fetched {
+		close(ready)
+		m.logger.Info("started")
+	}
+
+	for {
+		select {
+		case <-ticker.C():
+			if !m.refresh(fetched) || fetched {
+				continue
+			}
+			fetched = true
+			close(ready)
+			m.logger.Info("started")
+		case <-signals:
+			m.logger.Info("stopping")
+			return nil
+		}
+	}
+}
+
+// Owns returns true if processGuid belongs to the shard of this emitter.
+func (m *Membership) Owns(processGuid string) bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.ring.Owner(processGuid) == m.self
+}
+
+// refresh returns false if the members could not be fetched. A rebalance is
+// only signalled when notify is true.
+func (m *Membership) refresh(notify bool) bool {
+	members, err := m.fetchMembers()
+	if err != nil {
+		m.logger.Error("failed-to-fetch-members", err)
+		return false
+	}
+
+	m.lock.Lock()
+	changed := !reflect.DeepEqual(members, m.ring.Members())
+	if changed {
+		m.ring = NewRing(members, DefaultReplicas)
+	}
+	m.lock.Unlock()
+
+	if !changed {
+		return true
+	}
+
+	m.logger.Info("rebalanced", lager.Data{"members": members})
+	if notify {
+		select {
+		case m.rebalanceCh <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+// fetchMembers returns the sorted members, including this emitter even if
+// its own presence is not registered yet.
+func (m *Membership) fetchMembers() ([]string, error) {
+	response, err := m.locketClient.FetchAll(context.Background(), &locketmodels.FetchAllRequest{
+		Type:     locketmodels.PresenceType,
+		TypeCode: locketmodels.PRESENCE,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	members := map[string]struct{}{m.self: struct{}{}}
+	for _, resource := range response.Resources {
+		if strings.HasPrefix(resource.Key, PresenceKeyPrefix) {
+			members[resource.Owner] = struct{}{}
+		}
+	}
+
+	sorted := make([]string, 0, len(members))
+	for member := range members {
+		sorted = append(sorted, member)
+	}
+	sort.Strings(sorted)
+	return sorted, nil
+}
@@ -0,0 +1,146 @@
+package shard_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	locketmodels "code.cloudfoundry.org/locket/models"
+	"code.cloudfoundry.org/locket/models/modelsfakes"
+	"code.cloudfoundry.org/route-emitter/shard"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Membership", func() {
+	const pollInterval = 5 * time.Second
+
+	var (
+		clock        *fakeclock.FakeClock
+		locketClient *modelsfakes.FakeLocketClient
+		rebalanceCh  chan struct{}
+		membership   *shard.Membership
+		process      ifrit.Process
+	)
+
+	presences := func(uuids ...string) *locketmodels.FetchAllResponse {
+		response := &locketmodels.FetchAllResponse{}
+		for _, uuid := range uuids {
+			response.Resources = append(response.Resources, &locketmodels.Resource{
+				Key:      shard.PresenceKey(uuid),
+				Owner:    uuid,
+				Type:     locketmodels.PresenceType,
+				TypeCode: locketmodels.PRESENCE,
+			})
+		}
+		return response
+	}
+
+	// ownedBy returns a process guid owned by member in a ring of members
+	ownedBy := func(member string, members ...string) string {
+		ring := shard.NewRing(members, shard.DefaultReplicas)
+		for i := 0; ; i++ {
+			guid := fmt.Sprintf("process-guid-%d", i)
+			if ring.Owner(guid) == member {
+				return guid
+			}
+		}
+	}
+
+	BeforeEach(func() {
+		clock = fakeclock.NewFakeClock(time.Now())
+		locketClient = &modelsfakes.FakeLocketClient{}
+		rebalanceCh = make(chan struct{}, 1)
+		locketClient.FetchAllReturns(presences("emitter-1", "emitter-2"), nil)
+	})
+
+	JustBeforeEach(func() {
+		membership = shard.NewMembership(lagertest.NewTestLogger("test"), clock, locketClient, "emitter-1", pollInterval, rebalanceCh)
+		process = ifrit.Background(membership)
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	It("fetches the route emitter presences", func() {
+		Eventually(process.Ready()).Should(BeClosed())
+		_, request, _ := locketClient.FetchAllArgsForCall(0)
+		Expect(request).To(Equal(&locketmodels.FetchAllRequest{
+			Type:     locketmodels.PresenceType,
+			TypeCode: locketmodels.PRESENCE,
+		}))
+	})
+
+	It("owns the shards assigned to it by the ring", func() {
+		Eventually(process.Ready()).Should(BeClosed())
+		Expect(membership.Owns(ownedBy("emitter-1", "emitter-1", "emitter-2"))).To(BeTrue())
+		Expect(membership.Owns(ownedBy("emitter-2", "emitter-1", "emitter-2"))).To(BeFalse())
+	})
+
+	Context("when other components have registered their presence", func() {
+		BeforeEach(func() {
+			response := presences("emitter-2")
+			response.Resources = append(response.Resources, &locketmodels.Resource{
+				Key:   "cell-1",
+				Owner: "cell-1",
+			})
+			locketClient.FetchAllReturns(response, nil)
+		})
+
+		It("ignores them and includes itself", func() {
+			Eventually(process.Ready()).Should(BeClosed())
+			Expect(membership.Owns(ownedBy("emitter-1", "emitter-1", "emitter-2"))).To(BeTrue())
+		})
+	})
+
+	It("does not signal a rebalance for the first members", func() {
+		Eventually(process.Ready()).Should(BeClosed())
+		Consistently(rebalanceCh).ShouldNot(Receive())
+	})
+
+	Context("when a member leaves", func() {
+		It("rebalances", func() {
+			Eventually(process.Ready()).Should(BeClosed())
+			guid := ownedBy("emitter-2", "emitter-1", "emitter-2")
+			Expect(membership.Owns(guid)).To(BeFalse())
+
+			locketClient.FetchAllReturns(presences("emitter-1"), nil)
+			clock.WaitForWatcherAndIncrement(pollInterval)
+
+			Eventually(rebalanceCh).Should(Receive())
+			Expect(membership.Owns(guid)).To(BeTrue())
+		})
+	})
+
+	Context("when the members do not change", func() {
+		It("does not rebalance", func() {
+			Eventually(process.Ready()).Should(BeClosed())
+
+			clock.WaitForWatcherAndIncrement(pollInterval)
+			Eventually(locketClient.FetchAllCallCount).Should(Equal(2))
+			Consistently(rebalanceCh).ShouldNot(Receive())
+		})
+	})
+
+	Context("when the members cannot be fetched", func() {
+		BeforeEach(func() {
+			locketClient.FetchAllReturns(nil, errors.New("boom"))
+		})
+
+		It("does not become ready until they can", func() {
+			Consistently(process.Ready()).ShouldNot(BeClosed())
+
+			locketClient.FetchAllReturns(presences("emitter-1", "emitter-2"), nil)
+			clock.WaitForWatcherAndIncrement(pollInterval)
+			Eventually(process.Ready()).Should(BeClosed())
+			Consistently(rebalanceCh).ShouldNot(Receive())
+		})
+	})
+})
@@ -0,0 +1,70 @@
+package shard
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// DefaultReplicas is the number of points every member gets on the ring,
+// enough to spread process guids evenly over a handful of members.
+const DefaultReplicas = 128
+
+// Ring assigns keys to members by consistent hashing, so that a member
+// joining or leaving only moves the keys it gains or loses.
+type Ring struct {
+	members []string
+	hashes  []uint32
+	owners  map[uint32]string
+}
+
+func NewRing(members []string, replicas int) *Ring {
+	ring := &Ring{
+		members: append([]string{}, members...),
+		owners:  make(map[uint32]string, len(members)*replicas),
+	}
+	sort.Strings(ring.members)
+
+	for _, member := range ring.members {
+		for i := 0; i < replicas; i++ {
+			hash := hashKey(member + "#" + strconv.Itoa(i))
+			// the lowest member wins a collision so that every ring built from
+			// the same members agrees
+			if owner, ok := ring.owners[hash]; ok && owner < member {
+				continue
+			}
+			if _, ok := ring.owners[hash]; !ok {
+				ring.hashes = append(ring.hashes, hash)
+			}
+			ring.owners[hash] = member
+		}
+	}
+	sort.Slice(ring.hashes, func(i, j int) bool { return ring.hashes[i] < ring.hashes[j] })
+
+	return ring
+}
+
+// Owner returns the member owning key, or an empty string for an empty ring.
+func (r *Ring) Owner(key string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+
+	hash := hashKey(key)
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= hash })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.owners[r.hashes[i]]
+}
+
+// Members returns the sorted members of the ring.
+func (r *Ring) Members() []string {
+	return append([]string{}, r.members...)
+}
+
+func hashKey(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()
+}
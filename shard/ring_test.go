@@ -0,0 +1,62 @@
+package shard_test
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/route-emitter/shard"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Ring", func() {
+	var keys []string
+
+	BeforeEach(func() {
+		keys = nil
+		for i := 0; i < 1000; i++ {
+			keys = append(keys, fmt.Sprintf("process-guid-%d", i))
+		}
+	})
+
+	owners := func(ring *shard.Ring) map[string]string {
+		owners := map[string]string{}
+		for _, key := range keys {
+			owners[key] = ring.Owner(key)
+		}
+		return owners
+	}
+
+	It("has no owner when empty", func() {
+		Expect(shard.NewRing(nil, shard.DefaultReplicas).Owner("process-guid")).To(BeEmpty())
+	})
+
+	It("assigns the same owners regardless of the order of the members", func() {
+		ring := shard.NewRing([]string{"a", "b", "c"}, shard.DefaultReplicas)
+		other := shard.NewRing([]string{"c", "a", "b"}, shard.DefaultReplicas)
+		Expect(owners(ring)).To(Equal(owners(other)))
+	})
+
+	It("spreads the keys over every member", func() {
+		counts := map[string]int{}
+		for _, owner := range owners(shard.NewRing([]string{"a", "b", "c"}, shard.DefaultReplicas)) {
+			counts[owner]++
+		}
+
+		Expect(counts).To(HaveLen(3))
+		for _, count := range counts {
+			Expect(count).To(BeNumerically(">", 200))
+		}
+	})
+
+	It("only moves the keys of a member that leaves", func() {
+		before := owners(shard.NewRing([]string{"a", "b", "c"}, shard.DefaultReplicas))
+		after := owners(shard.NewRing([]string{"a", "b"}, shard.DefaultReplicas))
+
+		for key, owner := range before {
+			if owner != "c" {
+				Expect(after[key]).To(Equal(owner), key)
+			}
+		}
+	})
+})
@@ -0,0 +1 @@
+package snapshotter // import "code.cloudfoundry.org/route-emitter/snapshotter"
@@ -0,0 +1,135 @@
+package snapshotter
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+type Snapshotter struct {
+	logger      lager.Logger
+	clock       clock.Clock
+	table       routingtable.RoutingTable
+	natsEmitter emitter.NATSEmitter
+	path        string
+	interval    time.Duration
+}
+
+func NewSnapshotter(
+	logger lager.Logger,
+	clock clock.Clock,
+	table routingtable.RoutingTable,
+	natsEmitter emitter.NATSEmitter,
+	path string,
+	interval time.Duration,
+) *Snapshotter {
+	return &Snapshotter{
+		logger:      logger.Session("snapshotter"),
+		clock:       clock,
+		table:       table,
+		natsEmitter: natsEmitter,
+		path:        path,
+		interval:    interval,
+	}
+}
+
+// Run broadcasts whatever the table holds, normally the state restored from
+// the last snapshot, and then writes a new snapshot every interval and once
+// more before exiting.
+func (s *Snapshotter) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	s.logger.Info("starting")
+	s.broadcast()
+
+	close(ready)
+	s.logger.Info("started")
+
+	ticker := s.clock.NewTicker(s.interval)
+
+	for {
+		select {
+		case <-ticker.C():
+			s.save()
+		case <-signals:
+			s.logger.Info("stopping")
+			ticker.Stop()
+			s.save()
+			return nil
+		}
+	}
+}
+
+func (s *Snapshotter) broadcast() {
+	logger := s.logger.Session("broadcast")
+
+	if s.table.TableSize() == 0 {
+		logger.Info("nothing-to-broadcast")
+		return
+	}
+
+	_, externalMessages := s.table.GetExternalRoutingEvents()
+	_, internalMessages := s.table.GetInternalRoutingEvents()
+	messagesToEmit := externalMessages.Merge(internalMessages)
+
+	logger.Info("emitting-restored-routes", lager.Data{
+		"num-registration-messages":          len(messagesToEmit.RegistrationMessages),
+		"num-internal-registration-messages": len(messagesToEmit.InternalRegistrationMessages),
+	})
+	err := s.natsEmitter.Emit(messagesToEmit)
+	if err != nil {
+		logger.Error("failed-to-emit-restored-routes", err)
+	}
+}
+
+func (s *Snapshotter) save() {
+	logger := s.logger.Session("save", lager.Data{"path": s.path})
+
+	err := Save(s.path, s.table.Snapshot())
+	if err != nil {
+		logger.Error("failed-to-save-snapshot", err)
+		return
+	}
+
+	logger.Debug("saved-snapshot")
+}
+
+// Save atomically replaces the snapshot file at path.
+func Save(path string, snapshot routingtable.Snapshot) error {
+	tmpFile, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	err = json.NewEncoder(tmpFile).Encode(snapshot)
+	if err != nil {
+		tmpFile.Close()
+		return err
+	}
+
+	err = tmpFile.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), path)
+}
+
+func Load(path string) (routingtable.Snapshot, error) {
+	var snapshot routingtable.Snapshot
+
+	snapshotFile, err := os.Open(path)
+	if err != nil {
+		return snapshot, err
+	}
+	defer snapshotFile.Close()
+
+	err = json.NewDecoder(snapshotFile).Decode(&snapshot)
+	return snapshot, err
+}
@@ -0,0 +1,13 @@
+package snapshotter_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestSnapshotter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Snapshotter Suite")
+}
@@ -0,0 +1,136 @@
+package snapshotter_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/emitter/fakes"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/fakeroutingtable"
+	"code.cloudfoundry.org/route-emitter/snapshotter"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Snapshotter", func() {
+	var (
+		clock           *fakeclock.FakeClock
+		table           *fakeroutingtable.FakeRoutingTable
+		natsEmitter     *fakes.FakeNATSEmitter
+		tmpDir          string
+		snapshotPath    string
+		interval        time.Duration
+		snapshot        routingtable.Snapshot
+		process         ifrit.Process
+		externalMessage routingtable.RegistryMessage
+		internalMessage routingtable.RegistryMessage
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "snapshotter")
+		Expect(err).NotTo(HaveOccurred())
+		snapshotPath = filepath.Join(tmpDir, "routing-table.json")
+
+		clock = fakeclock.NewFakeClock(time.Now())
+		interval = 10 * time.Second
+		table = &fakeroutingtable.FakeRoutingTable{}
+		natsEmitter = &fakes.FakeNATSEmitter{}
+
+		snapshot = routingtable.Snapshot{
+			Version: routingtable.SnapshotVersion,
+			HTTP: []routingtable.SnapshotEntry{
+				{
+					Key:              routingtable.RoutingKey{ProcessGUID: "process-guid", ContainerPort: 8080},
+					DesiredInstances: 1,
+					HTTPRoutes:       []routingtable.Route{{Hostname: "foo.example.com"}},
+				},
+			},
+		}
+		table.SnapshotReturns(snapshot)
+
+		externalMessage = routingtable.RegistryMessage{Host: "1.1.1.1", Port: 61001, URIs: []string{"foo.example.com"}}
+		internalMessage = routingtable.RegistryMessage{Host: "10.0.0.1", URIs: []string{"foo.apps.internal"}}
+		table.GetExternalRoutingEventsReturns(routingtable.TCPRouteMappings{}, routingtable.MessagesToEmit{
+			RegistrationMessages: []routingtable.RegistryMessage{externalMessage},
+		})
+		table.GetInternalRoutingEventsReturns(routingtable.TCPRouteMappings{}, routingtable.MessagesToEmit{
+			InternalRegistrationMessages: []routingtable.RegistryMessage{internalMessage},
+		})
+		table.TableSizeReturns(1)
+	})
+
+	JustBeforeEach(func() {
+		logger := lagertest.NewTestLogger("test")
+		runner := snapshotter.NewSnapshotter(logger, clock, table, natsEmitter, snapshotPath, interval)
+		process = ifrit.Invoke(runner)
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+		os.RemoveAll(tmpDir)
+	})
+
+	It("broadcasts the restored registrations before becoming ready", func() {
+		Expect(natsEmitter.EmitCallCount()).To(Equal(1))
+		Expect(natsEmitter.EmitArgsForCall(0)).To(Equal(routingtable.MessagesToEmit{
+			RegistrationMessages:         []routingtable.RegistryMessage{externalMessage},
+			InternalRegistrationMessages: []routingtable.RegistryMessage{internalMessage},
+		}))
+	})
+
+	Context("when the table is empty", func() {
+		BeforeEach(func() {
+			table.TableSizeReturns(0)
+		})
+
+		It("does not broadcast anything", func() {
+			Consistently(natsEmitter.EmitCallCount).Should(Equal(0))
+		})
+	})
+
+	It("saves a snapshot every interval", func() {
+		clock.WaitForWatcherAndIncrement(interval)
+		Eventually(table.SnapshotCallCount).Should(Equal(1))
+
+		loaded, err := snapshotter.Load(snapshotPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded).To(Equal(snapshot))
+
+		clock.WaitForWatcherAndIncrement(interval)
+		Eventually(table.SnapshotCallCount).Should(Equal(2))
+	})
+
+	It("saves a snapshot when stopped", func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+
+		Expect(table.SnapshotCallCount()).To(Equal(1))
+		_, err := os.Stat(snapshotPath)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("Load", func() {
+		Context("when the snapshot file does not exist", func() {
+			It("returns an error", func() {
+				_, err := snapshotter.Load(filepath.Join(tmpDir, "missing.json"))
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when the snapshot file is corrupt", func() {
+			It("returns an error", func() {
+				Expect(ioutil.WriteFile(snapshotPath, []byte("{{"), 0600)).To(Succeed())
+				_, err := snapshotter.Load(snapshotPath)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
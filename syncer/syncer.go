@@ -2,6 +2,7 @@ package syncer
 
 import (
 	"os"
+	"sync"
 	"time"
 
 	"code.cloudfoundry.org/clock"
@@ -12,10 +13,13 @@ import (
 type NatsSyncer struct {
 	natsClient           diegonats.NATSClient
 	clock                clock.Clock
-	syncInterval         time.Duration
 	syncCh               chan struct{}
 	externalServiceStart chan time.Duration
 
+	intervalLock    sync.Mutex
+	syncInterval    time.Duration
+	intervalChanged chan struct{}
+
 	logger lager.Logger
 }
 
@@ -29,6 +33,8 @@ func NewSyncer(
 		syncInterval: syncInterval,
 		syncCh:       make(chan struct{}, 1),
 
+		intervalChanged: make(chan struct{}, 1),
+
 		externalServiceStart: make(chan time.Duration),
 
 		logger: logger.Session("syncer"),
@@ -42,13 +48,18 @@ func (s *NatsSyncer) Run(signals <-chan os.Signal, ready chan<- struct{}) error
 	s.sync()
 
 	// now keep emitting at the desired interval, syncing every syncInterval
-	syncTicker := s.clock.NewTicker(s.syncInterval)
+	syncTicker := s.clock.NewTicker(s.interval())
 
 	for {
 		select {
 		case <-syncTicker.C():
 			s.logger.Info("syncing")
 			s.sync()
+		case <-s.intervalChanged:
+			interval := s.interval()
+			syncTicker.Stop()
+			syncTicker = s.clock.NewTicker(interval)
+			s.logger.Info("changed-sync-interval", lager.Data{"interval": interval.String()})
 		case <-signals:
 			s.logger.Info("stopping")
 			syncTicker.Stop()
@@ -59,6 +70,25 @@ func (s *NatsSyncer) Run(signals <-chan os.Signal, ready chan<- struct{}) error
 	return nil
 }
 
+// SetSyncInterval changes the interval between syncs. A running syncer
+// restarts its ticker, the next sync happens one new interval from now.
+func (s *NatsSyncer) SetSyncInterval(interval time.Duration) {
+	s.intervalLock.Lock()
+	s.syncInterval = interval
+	s.intervalLock.Unlock()
+
+	select {
+	case s.intervalChanged <- struct{}{}:
+	default:
+	}
+}
+
+func (s *NatsSyncer) interval() time.Duration {
+	s.intervalLock.Lock()
+	defer s.intervalLock.Unlock()
+	return s.syncInterval
+}
+
 func (s *NatsSyncer) SyncCh() chan struct{} {
 	return s.syncCh
 }
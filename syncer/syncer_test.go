@@ -11,6 +11,7 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 )
 
 const logGuid = "some-log-guid"
@@ -20,6 +21,7 @@ var _ = Describe("NatsSyncer", func() {
 		syncerRunner *syncer.NatsSyncer
 		process      ifrit.Process
 		clock        *fakeclock.FakeClock
+		logger       *lagertest.TestLogger
 		syncInterval time.Duration
 
 		shutdown chan struct{}
@@ -31,7 +33,7 @@ var _ = Describe("NatsSyncer", func() {
 	})
 
 	JustBeforeEach(func() {
-		logger := lagertest.NewTestLogger("test")
+		logger = lagertest.NewTestLogger("test")
 		syncerRunner = syncer.NewSyncer(clock, syncInterval, logger)
 
 		shutdown = make(chan struct{})
@@ -58,4 +60,20 @@ var _ = Describe("NatsSyncer", func() {
 			Eventually(syncerRunner.SyncCh()).Should(Receive())
 		})
 	})
+
+	Context("when the sync interval is changed", func() {
+		It("syncs on the new interval", func() {
+			Eventually(syncerRunner.SyncCh()).Should(Receive())
+			Eventually(clock.WatcherCount).Should(Equal(1))
+
+			syncerRunner.SetSyncInterval(2 * syncInterval)
+			Eventually(logger).Should(gbytes.Say("changed-sync-interval"))
+
+			clock.Increment(syncInterval)
+			Consistently(syncerRunner.SyncCh()).ShouldNot(Receive())
+
+			clock.Increment(syncInterval)
+			Eventually(syncerRunner.SyncCh()).Should(Receive())
+		})
+	})
 })
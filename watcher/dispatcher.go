@@ -0,0 +1,116 @@
+package watcher
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/metrics"
+)
+
+const (
+	eventQueueLatency = "EventQueueLatency"
+	eventQueueSize    = 1000
+)
+
+type dispatchedEvent struct {
+	logger   lager.Logger
+	event    models.Event
+	queuedAt time.Time
+}
+
+// EventDispatcher handles events on a fixed number of workers. The events of
+// a process guid always go to the same worker, so they are handled in the
+// order they were dispatched while the events of other process guids are
+// handled in parallel.
+type EventDispatcher struct {
+	logger      lager.Logger
+	clock       clock.Clock
+	metricsSink metrics.Sink
+	handle      func(lager.Logger, models.Event)
+	queues      []chan dispatchedEvent
+
+	pending sync.WaitGroup // dispatched events not handled yet
+	stopped sync.WaitGroup // running workers
+}
+
+// NewEventDispatcher starts workers goroutines calling handle for the
+// dispatched events. With no workers Dispatch calls handle itself.
+func NewEventDispatcher(
+	logger lager.Logger,
+	clock clock.Clock,
+	metricsSink metrics.Sink,
+	workers int,
+	handle func(lager.Logger, models.Event),
+) *EventDispatcher {
+	d := &EventDispatcher{
+		logger:      logger.Session("event-dispatcher"),
+		clock:       clock,
+		metricsSink: metricsSink,
+		handle:      handle,
+	}
+
+	for i := 0; i < workers; i++ {
+		queue := make(chan dispatchedEvent, eventQueueSize)
+		d.queues = append(d.queues, queue)
+		d.stopped.Add(1)
+		go d.work(i, queue)
+	}
+	return d
+}
+
+// Dispatch queues event on the worker of its process guid, blocking while that
+// worker's queue is full. Dispatch, Wait and Stop must be called from the same
+// goroutine.
+func (d *EventDispatcher) Dispatch(logger lager.Logger, event models.Event) {
+	if len(d.queues) == 0 {
+		d.handle(logger, event)
+		return
+	}
+
+	d.pending.Add(1)
+	d.queues[d.worker(eventProcessGuid(event))] <- dispatchedEvent{
+		logger:   logger,
+		event:    event,
+		queuedAt: d.clock.Now(),
+	}
+}
+
+// Wait blocks until every dispatched event has been handled.
+func (d *EventDispatcher) Wait() {
+	d.pending.Wait()
+}
+
+// Stop handles the events still queued and stops the workers. Nothing may be
+// dispatched afterwards.
+func (d *EventDispatcher) Stop() {
+	for _, queue := range d.queues {
+		close(queue)
+	}
+	d.stopped.Wait()
+}
+
+func (d *EventDispatcher) work(index int, queue <-chan dispatchedEvent) {
+	defer d.stopped.Done()
+
+	labels := metrics.Labels{metrics.WorkerLabel: strconv.Itoa(index)}
+	for e := range queue {
+		err := d.metricsSink.SendDuration(eventQueueLatency, d.clock.Since(e.queuedAt), labels)
+		if err != nil {
+			d.logger.Error("failed-to-send-event-queue-latency-metric", err)
+		}
+
+		d.handle(e.logger, e.event)
+		d.pending.Done()
+	}
+}
+
+func (d *EventDispatcher) worker(processGuid string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(processGuid))
+	return int(hash.Sum32() % uint32(len(d.queues)))
+}
@@ -0,0 +1,133 @@
+package watcher_test
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/metrics"
+	metricsfakes "code.cloudfoundry.org/route-emitter/metrics/fakes"
+	"code.cloudfoundry.org/route-emitter/watcher"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EventDispatcher", func() {
+	var (
+		logger          *lagertest.TestLogger
+		clock           *fakeclock.FakeClock
+		fakeMetricsSink *metricsfakes.FakeSink
+		workers         int
+		dispatcher      *watcher.EventDispatcher
+
+		lock    sync.Mutex
+		handled []models.Event
+		blocked chan struct{}
+	)
+
+	// process-guid-1 and process-guid-2 are handled by different workers
+	event := func(processGuid string, index int32) models.Event {
+		return models.NewDesiredLRPCreatedEvent(&models.DesiredLRP{ProcessGuid: processGuid, Instances: index})
+	}
+
+	handledEvents := func() []models.Event {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]models.Event{}, handled...)
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		clock = fakeclock.NewFakeClock(time.Now())
+		fakeMetricsSink = &metricsfakes.FakeSink{}
+		workers = 2
+
+		lock.Lock()
+		handled = nil
+		lock.Unlock()
+		blocked = make(chan struct{})
+	})
+
+	JustBeforeEach(func() {
+		dispatcher = watcher.NewEventDispatcher(logger, clock, fakeMetricsSink, workers, func(_ lager.Logger, e models.Event) {
+			if e.(*models.DesiredLRPCreatedEvent).DesiredLrp.ProcessGuid == "process-guid-1" {
+				<-blocked
+			}
+			lock.Lock()
+			handled = append(handled, e)
+			lock.Unlock()
+		})
+	})
+
+	AfterEach(func() {
+		close(blocked)
+		dispatcher.Stop()
+	})
+
+	It("handles the events of a process guid in order", func() {
+		for i := int32(0); i < 5; i++ {
+			dispatcher.Dispatch(logger, event("process-guid-1", i))
+		}
+		for i := 0; i < 5; i++ {
+			blocked <- struct{}{}
+		}
+
+		dispatcher.Wait()
+		Expect(handledEvents()).To(HaveLen(5))
+		for i, e := range handledEvents() {
+			Expect(e.(*models.DesiredLRPCreatedEvent).DesiredLrp.Instances).To(BeEquivalentTo(i))
+		}
+	})
+
+	It("handles the events of other process guids while one is busy", func() {
+		dispatcher.Dispatch(logger, event("process-guid-1", 0))
+		dispatcher.Dispatch(logger, event("process-guid-2", 0))
+
+		Eventually(handledEvents).Should(ConsistOf(event("process-guid-2", 0)))
+	})
+
+	It("waits for every dispatched event to be handled", func() {
+		dispatcher.Dispatch(logger, event("process-guid-1", 0))
+
+		waited := make(chan struct{})
+		go func() {
+			dispatcher.Wait()
+			close(waited)
+		}()
+		Consistently(waited).ShouldNot(BeClosed())
+
+		blocked <- struct{}{}
+		Eventually(waited).Should(BeClosed())
+	})
+
+	It("sends how long every event was queued", func() {
+		dispatcher.Dispatch(logger, event("process-guid-1", 0))
+		dispatcher.Dispatch(logger, event("process-guid-1", 1))
+		Eventually(fakeMetricsSink.SendDurationCallCount).Should(Equal(1))
+
+		clock.Increment(time.Second)
+		blocked <- struct{}{}
+		Eventually(fakeMetricsSink.SendDurationCallCount).Should(Equal(2))
+
+		name, latency, labels := fakeMetricsSink.SendDurationArgsForCall(1)
+		Expect(name).To(Equal("EventQueueLatency"))
+		Expect(latency).To(Equal(time.Second))
+		Expect(labels).To(Equal(metrics.Labels{metrics.WorkerLabel: "0"}))
+	})
+
+	Context("without workers", func() {
+		BeforeEach(func() {
+			workers = 0
+		})
+
+		It("handles the events right away", func() {
+			dispatcher.Dispatch(logger, event("process-guid-2", 0))
+			Expect(handledEvents()).To(ConsistOf(event("process-guid-2", 0)))
+			Expect(fakeMetricsSink.SendDurationCallCount()).To(BeZero())
+		})
+	})
+})
@@ -26,6 +26,15 @@ type FakeRouteHandler struct {
 		domains       models.DomainSet
 		cachedEvents  map[string]models.Event
 	}
+	SyncProcessesStub        func(logger lager.Logger, processGuids []string, desired []*models.DesiredLRPSchedulingInfo, runningActual []*routingtable.ActualLRPRoutingInfo, domains models.DomainSet)
+	syncProcessesMutex       sync.RWMutex
+	syncProcessesArgsForCall []struct {
+		logger        lager.Logger
+		processGuids  []string
+		desired       []*models.DesiredLRPSchedulingInfo
+		runningActual []*routingtable.ActualLRPRoutingInfo
+		domains       models.DomainSet
+	}
 	EmitExternalStub        func(logger lager.Logger)
 	emitExternalMutex       sync.RWMutex
 	emitExternalArgsForCall []struct {
@@ -36,6 +45,11 @@ type FakeRouteHandler struct {
 	emitInternalArgsForCall []struct {
 		logger lager.Logger
 	}
+	EmitDrainedStub        func(logger lager.Logger)
+	emitDrainedMutex       sync.RWMutex
+	emitDrainedArgsForCall []struct {
+		logger lager.Logger
+	}
 	ShouldRefreshDesiredStub        func(*routingtable.ActualLRPRoutingInfo) bool
 	shouldRefreshDesiredMutex       sync.RWMutex
 	shouldRefreshDesiredArgsForCall []struct {
@@ -111,15 +125,62 @@ func (fake *FakeRouteHandler) Sync(logger lager.Logger, desired []*models.Desire
 func (fake *FakeRouteHandler) SyncCallCount() int {
 	fake.syncMutex.RLock()
 	defer fake.syncMutex.RUnlock()
+	fake.syncProcessesMutex.RLock()
+	defer fake.syncProcessesMutex.RUnlock()
 	return len(fake.syncArgsForCall)
 }
 
 func (fake *FakeRouteHandler) SyncArgsForCall(i int) (lager.Logger, []*models.DesiredLRPSchedulingInfo, []*routingtable.ActualLRPRoutingInfo, models.DomainSet, map[string]models.Event) {
 	fake.syncMutex.RLock()
 	defer fake.syncMutex.RUnlock()
+	fake.syncProcessesMutex.RLock()
+	defer fake.syncProcessesMutex.RUnlock()
 	return fake.syncArgsForCall[i].logger, fake.syncArgsForCall[i].desired, fake.syncArgsForCall[i].runningActual, fake.syncArgsForCall[i].domains, fake.syncArgsForCall[i].cachedEvents
 }
 
+func (fake *FakeRouteHandler) SyncProcesses(logger lager.Logger, processGuids []string, desired []*models.DesiredLRPSchedulingInfo, runningActual []*routingtable.ActualLRPRoutingInfo, domains models.DomainSet) {
+	var processGuidsCopy []string
+	if processGuids != nil {
+		processGuidsCopy = make([]string, len(processGuids))
+		copy(processGuidsCopy, processGuids)
+	}
+	var desiredCopy []*models.DesiredLRPSchedulingInfo
+	if desired != nil {
+		desiredCopy = make([]*models.DesiredLRPSchedulingInfo, len(desired))
+		copy(desiredCopy, desired)
+	}
+	var runningActualCopy []*routingtable.ActualLRPRoutingInfo
+	if runningActual != nil {
+		runningActualCopy = make([]*routingtable.ActualLRPRoutingInfo, len(runningActual))
+		copy(runningActualCopy, runningActual)
+	}
+	fake.syncProcessesMutex.Lock()
+	fake.syncProcessesArgsForCall = append(fake.syncProcessesArgsForCall, struct {
+		logger        lager.Logger
+		processGuids  []string
+		desired       []*models.DesiredLRPSchedulingInfo
+		runningActual []*routingtable.ActualLRPRoutingInfo
+		domains       models.DomainSet
+	}{logger, processGuidsCopy, desiredCopy, runningActualCopy, domains})
+	fake.recordInvocation("SyncProcesses", []interface{}{logger, processGuidsCopy, desiredCopy, runningActualCopy, domains})
+	fake.syncProcessesMutex.Unlock()
+	if fake.SyncProcessesStub != nil {
+		fake.SyncProcessesStub(logger, processGuids, desired, runningActual, domains)
+	}
+}
+
+func (fake *FakeRouteHandler) SyncProcessesCallCount() int {
+	fake.syncProcessesMutex.RLock()
+	defer fake.syncProcessesMutex.RUnlock()
+	return len(fake.syncProcessesArgsForCall)
+}
+
+func (fake *FakeRouteHandler) SyncProcessesArgsForCall(i int) (lager.Logger, []string, []*models.DesiredLRPSchedulingInfo, []*routingtable.ActualLRPRoutingInfo, models.DomainSet) {
+	fake.syncProcessesMutex.RLock()
+	defer fake.syncProcessesMutex.RUnlock()
+	return fake.syncProcessesArgsForCall[i].logger, fake.syncProcessesArgsForCall[i].processGuids, fake.syncProcessesArgsForCall[i].desired, fake.syncProcessesArgsForCall[i].runningActual, fake.syncProcessesArgsForCall[i].domains
+}
+
 func (fake *FakeRouteHandler) EmitExternal(logger lager.Logger) {
 	fake.emitExternalMutex.Lock()
 	fake.emitExternalArgsForCall = append(fake.emitExternalArgsForCall, struct {
@@ -168,6 +229,30 @@ func (fake *FakeRouteHandler) EmitInternalArgsForCall(i int) lager.Logger {
 	return fake.emitInternalArgsForCall[i].logger
 }
 
+func (fake *FakeRouteHandler) EmitDrained(logger lager.Logger) {
+	fake.emitDrainedMutex.Lock()
+	fake.emitDrainedArgsForCall = append(fake.emitDrainedArgsForCall, struct {
+		logger lager.Logger
+	}{logger})
+	fake.recordInvocation("EmitDrained", []interface{}{logger})
+	fake.emitDrainedMutex.Unlock()
+	if fake.EmitDrainedStub != nil {
+		fake.EmitDrainedStub(logger)
+	}
+}
+
+func (fake *FakeRouteHandler) EmitDrainedCallCount() int {
+	fake.emitDrainedMutex.RLock()
+	defer fake.emitDrainedMutex.RUnlock()
+	return len(fake.emitDrainedArgsForCall)
+}
+
+func (fake *FakeRouteHandler) EmitDrainedArgsForCall(i int) lager.Logger {
+	fake.emitDrainedMutex.RLock()
+	defer fake.emitDrainedMutex.RUnlock()
+	return fake.emitDrainedArgsForCall[i].logger
+}
+
 func (fake *FakeRouteHandler) ShouldRefreshDesired(arg1 *routingtable.ActualLRPRoutingInfo) bool {
 	fake.shouldRefreshDesiredMutex.Lock()
 	ret, specificReturn := fake.shouldRefreshDesiredReturnsOnCall[len(fake.shouldRefreshDesiredArgsForCall)]
@@ -253,10 +338,14 @@ func (fake *FakeRouteHandler) Invocations() map[string][][]interface{} {
 	defer fake.handleEventMutex.RUnlock()
 	fake.syncMutex.RLock()
 	defer fake.syncMutex.RUnlock()
+	fake.syncProcessesMutex.RLock()
+	defer fake.syncProcessesMutex.RUnlock()
 	fake.emitExternalMutex.RLock()
 	defer fake.emitExternalMutex.RUnlock()
 	fake.emitInternalMutex.RLock()
 	defer fake.emitInternalMutex.RUnlock()
+	fake.emitDrainedMutex.RLock()
+	defer fake.emitDrainedMutex.RUnlock()
 	fake.shouldRefreshDesiredMutex.RLock()
 	defer fake.shouldRefreshDesiredMutex.RUnlock()
 	fake.refreshDesiredMutex.RLock()
@@ -2,7 +2,10 @@ package watcher
 
 import (
 	"fmt"
+	"hash/fnv"
+	"math/rand"
 	"os"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -11,13 +14,14 @@ import (
 	"code.cloudfoundry.org/bbs/events"
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/clock"
-	loggingclient "code.cloudfoundry.org/diego-logging-client"
 	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/route-emitter/metrics"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 )
 
 const (
-	routeSyncDuration = "RouteEmitterSyncDuration"
+	routeSyncDuration          = "RouteEmitterSyncDuration"
+	eventStreamResubscriptions = "EventStreamResubscriptions"
 )
 
 //go:generate counterfeiter -o fakes/fake_routehandler.go . RouteHandler
@@ -30,53 +34,113 @@ type RouteHandler interface {
 		domains models.DomainSet,
 		cachedEvents map[string]models.Event,
 	)
+	SyncProcesses(
+		logger lager.Logger,
+		processGuids []string,
+		desired []*models.DesiredLRPSchedulingInfo,
+		runningActual []*routingtable.ActualLRPRoutingInfo,
+		domains models.DomainSet,
+	)
 	EmitExternal(logger lager.Logger)
 	EmitInternal(logger lager.Logger)
+	EmitDrained(logger lager.Logger)
 	ShouldRefreshDesired(*routingtable.ActualLRPRoutingInfo) bool
 	RefreshDesired(lager.Logger, []*models.DesiredLRPSchedulingInfo)
 }
 
+//go:generate counterfeiter -o fakes/fake_shard_filter.go . ShardFilter
+
+// ShardFilter restricts a global watcher to the process guids of the shards
+// owned by this route emitter.
+type ShardFilter interface {
+	Owns(processGuid string) bool
+}
+
 type Watcher struct {
-	cellID         string
-	bbsClient      bbs.Client
-	clock          clock.Clock
-	routeHandler   RouteHandler
-	syncCh         chan struct{}
-	emitExternalCh chan struct{}
-	emitInternalCh chan struct{}
-	logger         lager.Logger
-	metronClient   loggingclient.IngressClient
+	cellID             string
+	shardFilter        ShardFilter
+	ignoreRoutability  bool
+	bbsClient          bbs.Client
+	clock              clock.Clock
+	routeHandler       RouteHandler
+	syncCh             chan struct{}
+	emitExternalCh     chan struct{}
+	emitInternalCh     chan struct{}
+	fullSyncRequests   chan struct{}
+	drainCheckInterval time.Duration
+	fullSyncCycles     int
+	resubscribeMin     time.Duration
+	resubscribeMax     time.Duration
+	eventWorkers       int
+	logger             lager.Logger
+	metricsSink        metrics.Sink
+
+	// owned by the Run loop
+	checksums        map[string]uint64   // of the routes of every process guid, as of the last sync
+	touched          map[string]struct{} // process guids of the events seen since the last sync
+	incrementalSyncs int                 // since the last full sync
 }
 
+// NewWatcher returns a watcher for the routes of cellID, or of every cell
+// when cellID is empty. shardFilter may be nil, in which case every process
+// guid is watched. Unless ignoreRoutability is set, only the running
+// instances reported routable are synced. When drainCheckInterval is positive
+// the route handler unregisters drained endpoints at that interval. When
+// fullSyncCycles is greater than one, only every fullSyncCycles-th sync
+// rebuilds the whole routing table, the others only sync the process guids
+// whose desired routes or actual lrps changed. A failed event stream is resubscribed to after a
+// jittered backoff doubling from resubscribeMin up to resubscribeMax, and every
+// reconnection triggers a full sync for the events missed meanwhile. Events
+// are handled by eventWorkers workers, in order within a process guid, or
+// serially by the watcher itself when eventWorkers is zero.
 func NewWatcher(
 	cellID string,
+	shardFilter ShardFilter,
+	ignoreRoutability bool,
 	bbsClient bbs.Client,
 	clock clock.Clock,
 	routeHandler RouteHandler,
 	syncCh chan struct{},
 	emitExternalCh chan struct{},
 	emitInternalCh chan struct{},
+	drainCheckInterval time.Duration,
+	fullSyncCycles int,
+	resubscribeMin time.Duration,
+	resubscribeMax time.Duration,
+	eventWorkers int,
 	logger lager.Logger,
-	metronClient loggingclient.IngressClient,
+	metricsSink metrics.Sink,
 ) *Watcher {
 	return &Watcher{
-		cellID:         cellID,
-		bbsClient:      bbsClient,
-		clock:          clock,
-		routeHandler:   routeHandler,
-		syncCh:         syncCh,
-		emitExternalCh: emitExternalCh,
-		emitInternalCh: emitInternalCh,
-		logger:         logger.Session("watcher"),
-		metronClient:   metronClient,
+		cellID:             cellID,
+		shardFilter:        shardFilter,
+		ignoreRoutability:  ignoreRoutability,
+		bbsClient:          bbsClient,
+		clock:              clock,
+		routeHandler:       routeHandler,
+		syncCh:             syncCh,
+		emitExternalCh:     emitExternalCh,
+		emitInternalCh:     emitInternalCh,
+		fullSyncRequests:   make(chan struct{}, 1),
+		drainCheckInterval: drainCheckInterval,
+		fullSyncCycles:     fullSyncCycles,
+		resubscribeMin:     resubscribeMin,
+		resubscribeMax:     resubscribeMax,
+		eventWorkers:       eventWorkers,
+		logger:             logger.Session("watcher"),
+		metricsSink:        metricsSink,
+		touched:            map[string]struct{}{},
 	}
 }
 
 type syncEventResult struct {
 	startTime     time.Time
+	full          bool
+	processGuids  []string // synced by an incremental sync
 	desired       []*models.DesiredLRPSchedulingInfo
 	runningActual []*routingtable.ActualLRPRoutingInfo
 	domains       models.DomainSet
+	checksums     map[string]uint64
 	err           error
 }
 
@@ -86,11 +150,15 @@ func (watcher *Watcher) Run(signals <-chan os.Signal, ready chan<- struct{}) err
 
 	eventChan := make(chan models.Event)
 	resubscribeChannel := make(chan error)
+	subscribedChannel := make(chan struct{}, 1)
 
 	eventSource := &atomic.Value{}
 	var stopEventSource int32
 
-	go watcher.checkForEvents(resubscribeChannel, eventChan, eventSource, watcher.logger)
+	go watcher.checkForEvents(resubscribeChannel, subscribedChannel, eventChan, eventSource, watcher.logger)
+	dispatcher := NewEventDispatcher(watcher.logger, watcher.clock, watcher.metricsSink, watcher.eventWorkers, watcher.handleEvent)
+	defer dispatcher.Stop()
+
 	watcher.logger.Debug("listening-on-channels")
 	close(ready)
 	watcher.logger.Debug("started")
@@ -99,11 +167,40 @@ func (watcher *Watcher) Run(signals <-chan os.Signal, ready chan<- struct{}) err
 	syncEnd := make(chan *syncEventResult)
 	syncing := false
 
+	// failed subscriptions since the event stream was last subscribed to
+	resubscribeAttempts := 0
+	var resubscribeTimer clock.Timer
+	var resubscribeCh <-chan time.Time
+	defer func() {
+		if resubscribeTimer != nil {
+			resubscribeTimer.Stop()
+		}
+	}()
+
+	// a full sync to start as soon as no sync is in progress
+	fullSyncPending := false
+
+	var drainCh <-chan time.Time
+	if watcher.drainCheckInterval > 0 {
+		drainTicker := watcher.clock.NewTicker(watcher.drainCheckInterval)
+		defer drainTicker.Stop()
+		drainCh = drainTicker.C()
+	}
+
 	for {
+		if fullSyncPending && !syncing {
+			fullSyncPending = false
+			logger := watcher.logger.Session("sync")
+			logger.Info("starting", lager.Data{"full": true})
+			go watcher.sync(logger, syncEnd, true, watcher.checksums)
+			syncing = true
+		}
+
 		select {
 		case event := <-eventChan:
+			watcher.touched[eventProcessGuid(event)] = struct{}{}
 			if syncing {
-				if watcher.eventCellIDMatches(watcher.logger, event) {
+				if watcher.eventMatches(watcher.logger, event) {
 					watcher.logger.Info("caching-event", lager.Data{
 						"type": event.EventType(),
 					})
@@ -114,13 +211,16 @@ func (watcher *Watcher) Run(signals <-chan os.Signal, ready chan<- struct{}) err
 				continue
 			}
 			logger := watcher.logger.Session("handling-event")
-			watcher.handleEvent(logger, event)
+			dispatcher.Dispatch(logger, event)
 		case <-watcher.emitExternalCh:
 			logger := watcher.logger.Session("emit-external")
 			watcher.routeHandler.EmitExternal(logger)
 		case <-watcher.emitInternalCh:
 			logger := watcher.logger.Session("emit-internal")
 			watcher.routeHandler.EmitInternal(logger)
+		case <-drainCh:
+			logger := watcher.logger.Session("emit-drained")
+			watcher.routeHandler.EmitDrained(logger)
 		case syncEvent := <-syncEnd:
 			syncing = false
 			logger := watcher.logger.Session("sync")
@@ -129,41 +229,82 @@ func (watcher *Watcher) Run(signals <-chan os.Signal, ready chan<- struct{}) err
 				continue
 			}
 
-			var cachedDesired []*models.DesiredLRPSchedulingInfo
-			for _, e := range cachedEvents {
-				desired := watcher.retrieveDesiredWhileSyncing(logger, e, syncEvent.desired)
-				if len(desired) > 0 {
-					cachedDesired = append(cachedDesired, desired...)
+			// the events dispatched before the sync must not change the routing
+			// table while it is synced
+			dispatcher.Wait()
+
+			if syncEvent.full {
+				var cachedDesired []*models.DesiredLRPSchedulingInfo
+				for _, e := range cachedEvents {
+					desired := watcher.retrieveDesiredWhileSyncing(logger, e, syncEvent.desired)
+					if len(desired) > 0 {
+						cachedDesired = append(cachedDesired, desired...)
+					}
 				}
-			}
 
-			if len(cachedDesired) > 0 {
-				syncEvent.desired = append(syncEvent.desired, cachedDesired...)
-			}
+				if len(cachedDesired) > 0 {
+					syncEvent.desired = append(syncEvent.desired, cachedDesired...)
+				}
 
-			logger.Debug("calling-handler-sync")
-			watcher.routeHandler.Sync(logger,
-				syncEvent.desired,
-				syncEvent.runningActual,
-				syncEvent.domains,
-				cachedEvents,
-			)
+				logger.Debug("calling-handler-sync")
+				watcher.routeHandler.Sync(logger,
+					syncEvent.desired,
+					syncEvent.runningActual,
+					syncEvent.domains,
+					cachedEvents,
+				)
+				watcher.incrementalSyncs = 0
+			} else {
+				// the routes of a process changed without any event about it,
+				// events were missed and other changes may have been too
+				if diverged := watcher.untouched(syncEvent.processGuids); len(diverged) > 0 {
+					logger.Info("detected-divergence", lager.Data{"num-process-guids": len(diverged)})
+					go watcher.sync(logger, syncEnd, true, nil)
+					syncing = true
+					continue
+				}
+
+				logger.Debug("calling-handler-sync-processes", lager.Data{"num-process-guids": len(syncEvent.processGuids)})
+				if len(syncEvent.processGuids) > 0 {
+					watcher.routeHandler.SyncProcesses(logger,
+						syncEvent.processGuids,
+						syncEvent.desired,
+						syncEvent.runningActual,
+						syncEvent.domains,
+					)
+				}
+
+				// the rest of the table was left as it is, the cached events are
+				// handled as if they had just been received
+				for _, e := range cachedEvents {
+					dispatcher.Dispatch(logger, e)
+				}
+				watcher.incrementalSyncs++
+			}
 
 			after := watcher.clock.Now()
-			if err := watcher.metronClient.SendDuration(routeSyncDuration, after.Sub(syncEvent.startTime)); err != nil {
+			if err := watcher.metricsSink.SendDuration(routeSyncDuration, after.Sub(syncEvent.startTime), nil); err != nil {
 				watcher.logger.Error("failed-to-send-route-sync-duration-metric", err)
 			}
 
+			// the changes of the cached events may not be part of what was synced
+			watcher.checksums = syncEvent.checksums
+			watcher.touched = map[string]struct{}{}
+			for _, e := range cachedEvents {
+				watcher.touched[eventProcessGuid(e)] = struct{}{}
+			}
+
 			cachedEvents = make(map[string]models.Event)
-			logger.Info("complete")
+			logger.Info("complete", lager.Data{"full": syncEvent.full})
 		case <-watcher.syncCh:
 			if syncing {
 				watcher.logger.Debug("sync-already-in-progress")
 				continue
 			}
 			logger := watcher.logger.Session("sync")
-			logger.Info("starting")
-			go watcher.sync(logger, syncEnd)
+			full := watcher.fullSyncDue()
+			logger.Info("starting", lager.Data{"full": full})
+			go watcher.sync(logger, syncEnd, full, watcher.checksums)
 			syncing = true
 		case err := <-resubscribeChannel:
 			watcher.logger.Error("event-source-error", err)
@@ -173,7 +314,34 @@ func (watcher *Watcher) Run(signals <-chan os.Signal, ready chan<- struct{}) err
 					watcher.logger.Error("failed-closing-event-source", err)
 				}
 			}
-			go watcher.checkForEvents(resubscribeChannel, eventChan, eventSource, watcher.logger)
+			if err := watcher.metricsSink.IncrementCounter(eventStreamResubscriptions, nil); err != nil {
+				watcher.logger.Error("failed-to-send-event-stream-resubscriptions-metric", err)
+			}
+
+			resubscribeAttempts++
+			backoff := watcher.resubscribeBackoff(resubscribeAttempts)
+			if backoff <= 0 {
+				go watcher.checkForEvents(resubscribeChannel, subscribedChannel, eventChan, eventSource, watcher.logger)
+				continue
+			}
+			watcher.logger.Info("resubscribing-after-backoff", lager.Data{"attempt": resubscribeAttempts, "backoff": backoff.String()})
+			resubscribeTimer = watcher.clock.NewTimer(backoff)
+			resubscribeCh = resubscribeTimer.C()
+		case <-resubscribeCh:
+			resubscribeTimer = nil
+			resubscribeCh = nil
+			go watcher.checkForEvents(resubscribeChannel, subscribedChannel, eventChan, eventSource, watcher.logger)
+		case <-subscribedChannel:
+			// the events sent while unsubscribed are lost, sync them instead of
+			// waiting for the next sync interval
+			if resubscribeAttempts > 0 {
+				watcher.logger.Info("resubscribed-to-bbs-events", lager.Data{"attempts": resubscribeAttempts})
+				fullSyncPending = true
+			}
+			resubscribeAttempts = 0
+		case <-watcher.fullSyncRequests:
+			watcher.logger.Info("full-sync-requested")
+			fullSyncPending = true
 
 		case <-signals:
 			watcher.logger.Info("stopping")
@@ -189,6 +357,51 @@ func (watcher *Watcher) Run(signals <-chan os.Signal, ready chan<- struct{}) err
 	}
 }
 
+// RequestFullSync makes the watcher rebuild the whole routing table as soon as
+// no sync is in progress.
+func (watcher *Watcher) RequestFullSync() {
+	select {
+	case watcher.fullSyncRequests <- struct{}{}:
+	default:
+	}
+}
+
+// resubscribeBackoff doubles resubscribeMin for every attempt up to
+// resubscribeMax and picks a random duration between half of that and all of
+// it.
+func (w *Watcher) resubscribeBackoff(attempt int) time.Duration {
+	backoff := w.resubscribeMin
+	if backoff <= 0 {
+		return 0
+	}
+	for i := 1; i < attempt && backoff < w.resubscribeMax; i++ {
+		backoff *= 2
+	}
+	if backoff > w.resubscribeMax {
+		backoff = w.resubscribeMax
+	}
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(backoff-half)+1))
+}
+
+// fullSyncDue returns true when the next sync has to rebuild the whole
+// routing table.
+func (w *Watcher) fullSyncDue() bool {
+	return w.fullSyncCycles <= 1 || w.checksums == nil || w.incrementalSyncs+1 >= w.fullSyncCycles
+}
+
+// untouched returns the process guids no event was seen about since the last
+// sync.
+func (w *Watcher) untouched(processGuids []string) []string {
+	var untouched []string
+	for _, guid := range processGuids {
+		if _, ok := w.touched[guid]; !ok {
+			untouched = append(untouched, guid)
+		}
+	}
+	return untouched
+}
+
 func (w *Watcher) cacheIncomingEvents(
 	eventChan chan models.Event,
 	cachedEventsChan chan map[string]models.Event,
@@ -220,7 +433,7 @@ func (w *Watcher) retrieveDesiredInternal(logger lager.Logger, event models.Even
 	}
 	var desiredLRPs []*models.DesiredLRPSchedulingInfo
 	var err error
-	if routingInfo != nil && routingInfo.ActualLRP.State == models.ActualLRPStateRunning {
+	if routingInfo != nil && routingInfo.Routable(w.ignoreRoutability) {
 		if w.routeHandler.ShouldRefreshDesired(routingInfo) || (syncing && !foundInCurrentDesireds(routingInfo.ActualLRP.ProcessGuid, currentDesireds)) {
 			logger.Info("refreshing-desired-lrp-info", lager.Data{"process-guid": routingInfo.ActualLRP.ProcessGuid})
 			desiredLRPs, err = w.bbsClient.DesiredLRPSchedulingInfos(logger, models.DesiredLRPFilter{
@@ -254,7 +467,7 @@ func foundInCurrentDesireds(guid string, currentDesireds []*models.DesiredLRPSch
 }
 
 func (w *Watcher) handleEvent(logger lager.Logger, event models.Event) {
-	if !w.eventCellIDMatches(logger, event) {
+	if !w.eventMatches(logger, event) {
 		logSkippedEvent(logger, event)
 		return
 	}
@@ -266,6 +479,28 @@ func (w *Watcher) handleEvent(logger lager.Logger, event models.Event) {
 	w.routeHandler.HandleEvent(logger, event)
 }
 
+func eventProcessGuid(event models.Event) string {
+	switch event := event.(type) {
+	case *models.DesiredLRPCreatedEvent:
+		return event.DesiredLrp.ProcessGuid
+	case *models.DesiredLRPChangedEvent:
+		return event.After.ProcessGuid
+	case *models.DesiredLRPRemovedEvent:
+		return event.DesiredLrp.ProcessGuid
+	case *models.ActualLRPCreatedEvent:
+		lrp, _ := event.ActualLrpGroup.Resolve()
+		return lrp.ProcessGuid
+	case *models.ActualLRPChangedEvent:
+		lrp, _ := event.After.Resolve()
+		return lrp.ProcessGuid
+	case *models.ActualLRPRemovedEvent:
+		lrp, _ := event.ActualLrpGroup.Resolve()
+		return lrp.ProcessGuid
+	default:
+		return ""
+	}
+}
+
 func logSkippedEvent(logger lager.Logger, event models.Event) {
 	data := lager.Data{"event-type": event.EventType()}
 	switch e := event.(type) {
@@ -280,6 +515,37 @@ func logSkippedEvent(logger lager.Logger, event models.Event) {
 	logger.Debug("skipping-event", data)
 }
 
+func (watcher *Watcher) eventMatches(logger lager.Logger, event models.Event) bool {
+	return watcher.eventCellIDMatches(logger, event) && watcher.eventShardMatches(event)
+}
+
+// returns true if the event is about a process guid in an owned shard
+func (watcher *Watcher) eventShardMatches(event models.Event) bool {
+	if watcher.shardFilter == nil {
+		return true
+	}
+
+	switch event := event.(type) {
+	case *models.DesiredLRPCreatedEvent:
+		return watcher.shardFilter.Owns(event.DesiredLrp.ProcessGuid)
+	case *models.DesiredLRPChangedEvent:
+		return watcher.shardFilter.Owns(event.After.ProcessGuid)
+	case *models.DesiredLRPRemovedEvent:
+		return watcher.shardFilter.Owns(event.DesiredLrp.ProcessGuid)
+	case *models.ActualLRPCreatedEvent:
+		lrp, _ := event.ActualLrpGroup.Resolve()
+		return watcher.shardFilter.Owns(lrp.ProcessGuid)
+	case *models.ActualLRPChangedEvent:
+		lrp, _ := event.After.Resolve()
+		return watcher.shardFilter.Owns(lrp.ProcessGuid)
+	case *models.ActualLRPRemovedEvent:
+		lrp, _ := event.ActualLrpGroup.Resolve()
+		return watcher.shardFilter.Owns(lrp.ProcessGuid)
+	default:
+		return false
+	}
+}
+
 // returns true if the event is relevant to the local cell, e.g. an actual lrp
 // started or stopped on the local cell
 func (watcher *Watcher) eventCellIDMatches(logger lager.Logger, event models.Event) bool {
@@ -316,7 +582,10 @@ func (watcher *Watcher) eventCellIDMatches(logger lager.Logger, event models.Eve
 	}
 }
 
-func (w *Watcher) sync(logger lager.Logger, ch chan<- *syncEventResult) {
+// sync fetches the actual lrps, desired lrps and domains. Unless full is set,
+// only the lrps of the process guids whose desired routes or actual lrps
+// changed since previousChecksums were computed are returned.
+func (w *Watcher) sync(logger lager.Logger, ch chan<- *syncEventResult, full bool, previousChecksums map[string]uint64) {
 	var desiredSchedulingInfo []*models.DesiredLRPSchedulingInfo
 	var runningActualLRPs []*routingtable.ActualLRPRoutingInfo
 	var domains models.DomainSet
@@ -340,12 +609,9 @@ func (w *Watcher) sync(logger lager.Logger, ch chan<- *syncEventResult) {
 
 		runningActualLRPs = make([]*routingtable.ActualLRPRoutingInfo, 0, len(actualLRPGroups))
 		for _, actualLRPGroup := range actualLRPGroups {
-			actualLRP, evacuating := actualLRPGroup.Resolve()
-			if actualLRP.State == models.ActualLRPStateRunning {
-				runningActualLRPs = append(runningActualLRPs, &routingtable.ActualLRPRoutingInfo{
-					ActualLRP:  actualLRP,
-					Evacuating: evacuating,
-				})
+			routingInfo := routingtable.NewActualLRPRoutingInfo(actualLRPGroup)
+			if routingInfo.Routable(w.ignoreRoutability) {
+				runningActualLRPs = append(runningActualLRPs, routingInfo)
 			}
 		}
 
@@ -391,16 +657,59 @@ func (w *Watcher) sync(logger lager.Logger, ch chan<- *syncEventResult) {
 		err = fmt.Errorf("failed to sync: %s, %s, %s", actualErr, desiredErr, domainsErr)
 	}
 
+	if w.shardFilter != nil {
+		desiredSchedulingInfo, runningActualLRPs = filterOwned(w.shardFilter, desiredSchedulingInfo, runningActualLRPs)
+		logger.Debug("filtered-owned-shards", lager.Data{
+			"num-desired": len(desiredSchedulingInfo),
+			"num-actual":  len(runningActualLRPs),
+		})
+	}
+
+	var checksums map[string]uint64
+	if w.fullSyncCycles > 1 {
+		checksums = routingChecksums(desiredSchedulingInfo, runningActualLRPs)
+	}
+
+	var changedProcessGuids []string
+	if !full && err == nil {
+		changedProcessGuids = changedChecksums(previousChecksums, checksums)
+		logger.Debug("found-changed-process-guids", lager.Data{"num-process-guids": len(changedProcessGuids)})
+
+		changed := make(map[string]struct{}, len(changedProcessGuids))
+		for _, guid := range changedProcessGuids {
+			changed[guid] = struct{}{}
+		}
+
+		changedDesired := make([]*models.DesiredLRPSchedulingInfo, 0, len(changedProcessGuids))
+		for _, lrp := range desiredSchedulingInfo {
+			if _, ok := changed[lrp.ProcessGuid]; ok {
+				changedDesired = append(changedDesired, lrp)
+			}
+		}
+		desiredSchedulingInfo = changedDesired
+
+		changedActualLRPs := make([]*routingtable.ActualLRPRoutingInfo, 0, len(runningActualLRPs))
+		for _, lrp := range runningActualLRPs {
+			if _, ok := changed[lrp.ActualLRP.ProcessGuid]; ok {
+				changedActualLRPs = append(changedActualLRPs, lrp)
+			}
+		}
+		runningActualLRPs = changedActualLRPs
+	}
+
 	ch <- &syncEventResult{
 		startTime:     before,
+		full:          full,
+		processGuids:  changedProcessGuids,
 		desired:       desiredSchedulingInfo,
 		runningActual: runningActualLRPs,
 		domains:       domains,
+		checksums:     checksums,
 		err:           err,
 	}
 }
 
-func (w *Watcher) checkForEvents(resubscribeChannel chan error, eventChan chan models.Event, eventSource *atomic.Value, logger lager.Logger) {
+func (w *Watcher) checkForEvents(resubscribeChannel chan error, subscribedChannel chan struct{}, eventChan chan models.Event, eventSource *atomic.Value, logger lager.Logger) {
 	var err error
 	var es events.EventSource
 
@@ -413,6 +722,7 @@ func (w *Watcher) checkForEvents(resubscribeChannel chan error, eventChan chan m
 	logger.Info("subscribed-to-bbs-events")
 
 	eventSource.Store(es)
+	subscribedChannel <- struct{}{}
 
 	var event models.Event
 	for {
@@ -433,6 +743,95 @@ func (w *Watcher) checkForEvents(resubscribeChannel chan error, eventChan chan m
 	}
 }
 
+func filterOwned(
+	shardFilter ShardFilter,
+	desired []*models.DesiredLRPSchedulingInfo,
+	actuals []*routingtable.ActualLRPRoutingInfo,
+) ([]*models.DesiredLRPSchedulingInfo, []*routingtable.ActualLRPRoutingInfo) {
+	ownedDesired := make([]*models.DesiredLRPSchedulingInfo, 0, len(desired))
+	for _, lrp := range desired {
+		if shardFilter.Owns(lrp.ProcessGuid) {
+			ownedDesired = append(ownedDesired, lrp)
+		}
+	}
+
+	ownedActuals := make([]*routingtable.ActualLRPRoutingInfo, 0, len(actuals))
+	for _, lrp := range actuals {
+		if shardFilter.Owns(lrp.ActualLRP.ProcessGuid) {
+			ownedActuals = append(ownedActuals, lrp)
+		}
+	}
+
+	return ownedDesired, ownedActuals
+}
+
+// routingChecksums returns a checksum of the routes of the desired lrp and of
+// the routing state of the actual lrps of every process guid.
+func routingChecksums(desired []*models.DesiredLRPSchedulingInfo, actuals []*routingtable.ActualLRPRoutingInfo) map[string]uint64 {
+	states := map[string][]string{}
+	for _, lrp := range desired {
+		state := fmt.Sprintf("desired/%s/%d", lrp.ModificationTag.Epoch, lrp.ModificationTag.Index)
+		routerNames := make([]string, 0, len(lrp.Routes))
+		for routerName := range lrp.Routes {
+			routerNames = append(routerNames, routerName)
+		}
+		sort.Strings(routerNames)
+		for _, routerName := range routerNames {
+			state += "/" + routerName + ":"
+			if route := lrp.Routes[routerName]; route != nil {
+				state += string(*route)
+			}
+		}
+		states[lrp.ProcessGuid] = append(states[lrp.ProcessGuid], state)
+	}
+	for _, actual := range actuals {
+		lrp := actual.ActualLRP
+		state := fmt.Sprintf("%s/%d/%t/%s/%d/%s/%s",
+			lrp.InstanceGuid,
+			lrp.Index,
+			actual.Evacuating,
+			lrp.ModificationTag.Epoch,
+			lrp.ModificationTag.Index,
+			lrp.Address,
+			lrp.InstanceAddress,
+		)
+		for _, port := range lrp.Ports {
+			state += fmt.Sprintf("/%d:%d:%d:%d", port.ContainerPort, port.HostPort, port.ContainerTlsProxyPort, port.HostTlsProxyPort)
+		}
+		states[lrp.ProcessGuid] = append(states[lrp.ProcessGuid], state)
+	}
+
+	checksums := make(map[string]uint64, len(states))
+	for guid, lrpStates := range states {
+		sort.Strings(lrpStates)
+		hash := fnv.New64a()
+		for _, state := range lrpStates {
+			hash.Write([]byte(state))
+			hash.Write([]byte{'\n'})
+		}
+		checksums[guid] = hash.Sum64()
+	}
+	return checksums
+}
+
+// changedChecksums returns the process guids whose checksum differs, or which
+// only have one, sorted.
+func changedChecksums(before, after map[string]uint64) []string {
+	changed := []string{}
+	for guid, checksum := range after {
+		if previous, ok := before[guid]; !ok || previous != checksum {
+			changed = append(changed, guid)
+		}
+	}
+	for guid := range before {
+		if _, ok := after[guid]; !ok {
+			changed = append(changed, guid)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
 func getSchedulingInfos(logger lager.Logger, bbsClient bbs.Client, guids []string) ([]*models.DesiredLRPSchedulingInfo, error) {
 	logger.Debug("getting-scheduling-infos", lager.Data{"guids-length": len(guids)})
 	schedulingInfos, err := bbsClient.DesiredLRPSchedulingInfos(logger, models.DesiredLRPFilter{
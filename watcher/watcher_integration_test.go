@@ -12,13 +12,14 @@ import (
 	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/cfroutes"
 	"code.cloudfoundry.org/route-emitter/diegonats"
 	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/metrics"
 	"code.cloudfoundry.org/route-emitter/routehandlers"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/route-emitter/watcher"
 	"code.cloudfoundry.org/routing-api/fake_routing_api"
-	"code.cloudfoundry.org/routing-info/cfroutes"
 	uaaclient "code.cloudfoundry.org/uaa-go-client"
 	"code.cloudfoundry.org/workpool"
 	"github.com/nats-io/nats"
@@ -60,23 +61,31 @@ var _ = Describe("Watcher Integration", func() {
 		workPool, err := workpool.NewWorkPool(1)
 		Expect(err).NotTo(HaveOccurred())
 		fakeMetronClient = &mfakes.FakeIngressClient{}
-		natsEmitter := emitter.NewNATSEmitter(natsClient, workPool, logger, fakeMetronClient, false)
-		natsTable := routingtable.NewRoutingTable(logger, false, fakeMetronClient)
+		metricsSink := metrics.NewLoggregatorSink(fakeMetronClient)
+		natsEmitter := emitter.NewNATSEmitter(natsClient, workPool, logger, metricsSink, nil, nil, false)
+		natsTable := routingtable.NewRoutingTable(logger, false, metricsSink)
 
 		uaaClient := uaaclient.NewNoOpUaaClient()
-		routingAPIEmitter := emitter.NewRoutingAPIEmitter(logger, routingApiClient, uaaClient, 100)
-		handler := routehandlers.NewHandler(natsTable, natsEmitter, routingAPIEmitter, false, fakeMetronClient)
+		routingAPIEmitter := emitter.NewRoutingAPIEmitter(logger, routingApiClient, uaaClient, 100, nil)
+		handler := routehandlers.NewHandler(natsTable, natsEmitter, routingAPIEmitter, nil, false, false, nil, metricsSink, nil)
 		clock := fakeclock.NewFakeClock(time.Now())
 		testWatcher = watcher.NewWatcher(
 			cellID,
+			nil,
+			false,
 			bbsClient,
 			clock,
 			handler,
 			syncCh,
 			emitExternalCh,
 			emitInternalCh,
+			0,
+			0,
+			0,
+			0,
+			0,
 			logger,
-			fakeMetronClient,
+			metricsSink,
 		)
 	})
 
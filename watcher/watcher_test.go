@@ -13,10 +13,11 @@ import (
 	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/cfroutes"
+	"code.cloudfoundry.org/route-emitter/metrics"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/route-emitter/watcher"
 	"code.cloudfoundry.org/route-emitter/watcher/fakes"
-	"code.cloudfoundry.org/routing-info/cfroutes"
 	"code.cloudfoundry.org/routing-info/tcp_routes"
 	"github.com/tedsuo/ifrit"
 	"github.com/tedsuo/ifrit/ginkgomon"
@@ -85,18 +86,24 @@ var _ = Describe("Watcher", func() {
 	}
 
 	var (
-		logger           *lagertest.TestLogger
-		eventSource      *eventfakes.FakeEventSource
-		bbsClient        *fake_bbs.FakeClient
-		routeHandler     *fakes.FakeRouteHandler
-		testWatcher      *watcher.Watcher
-		clock            *fakeclock.FakeClock
-		process          ifrit.Process
-		cellID           string
-		syncCh           chan struct{}
-		emitExternalCh   chan struct{}
-		emitInternalCh   chan struct{}
-		fakeMetronClient *mfakes.FakeIngressClient
+		logger             *lagertest.TestLogger
+		eventSource        *eventfakes.FakeEventSource
+		bbsClient          *fake_bbs.FakeClient
+		routeHandler       *fakes.FakeRouteHandler
+		testWatcher        *watcher.Watcher
+		clock              *fakeclock.FakeClock
+		process            ifrit.Process
+		cellID             string
+		shardFilter        watcher.ShardFilter
+		syncCh             chan struct{}
+		emitExternalCh     chan struct{}
+		emitInternalCh     chan struct{}
+		drainCheckInterval time.Duration
+		fullSyncCycles     int
+		resubscribeMin     time.Duration
+		resubscribeMax     time.Duration
+		eventWorkers       int
+		fakeMetronClient   *mfakes.FakeIngressClient
 	)
 
 	BeforeEach(func() {
@@ -111,21 +118,34 @@ var _ = Describe("Watcher", func() {
 		syncCh = make(chan struct{})
 		emitExternalCh = make(chan struct{})
 		emitInternalCh = make(chan struct{})
+		drainCheckInterval = 0
+		fullSyncCycles = 0
+		resubscribeMin = 0
+		resubscribeMax = 0
+		eventWorkers = 0
 		cellID = ""
+		shardFilter = nil
 		fakeMetronClient = &mfakes.FakeIngressClient{}
 	})
 
 	JustBeforeEach(func() {
 		testWatcher = watcher.NewWatcher(
 			cellID,
+			shardFilter,
+			false,
 			bbsClient,
 			clock,
 			routeHandler,
 			syncCh,
 			emitExternalCh,
 			emitInternalCh,
+			drainCheckInterval,
+			fullSyncCycles,
+			resubscribeMin,
+			resubscribeMax,
+			eventWorkers,
 			logger,
-			fakeMetronClient,
+			metrics.NewLoggregatorSink(fakeMetronClient),
 		)
 		process = ifrit.Invoke(testWatcher)
 	})
@@ -177,6 +197,33 @@ var _ = Describe("Watcher", func() {
 			_, createEvent := routeHandler.HandleEventArgsForCall(0)
 			Expect(createEvent).Should(Equal(event))
 		})
+
+		Context("when sharded", func() {
+			var fakeShardFilter *fakes.FakeShardFilter
+
+			BeforeEach(func() {
+				fakeShardFilter = &fakes.FakeShardFilter{}
+				shardFilter = fakeShardFilter
+			})
+
+			Context("and the process guid is in another shard", func() {
+				It("ignores the event", func() {
+					Eventually(fakeShardFilter.OwnsCallCount).Should(BeNumerically(">=", 1))
+					Expect(fakeShardFilter.OwnsArgsForCall(0)).To(Equal("process-guid-1"))
+					Consistently(routeHandler.HandleEventCallCount).Should(Equal(0))
+				})
+			})
+
+			Context("and the process guid is in an owned shard", func() {
+				BeforeEach(func() {
+					fakeShardFilter.OwnsReturns(true)
+				})
+
+				It("handles the event", func() {
+					Eventually(routeHandler.HandleEventCallCount).Should(BeNumerically(">=", 1))
+				})
+			})
+		})
 	})
 
 	Context("handle DesiredLRPChangedEvent", func() {
@@ -375,6 +422,89 @@ var _ = Describe("Watcher", func() {
 			Eventually(bbsClient.SubscribeToEventsByCellIDCallCount, 5*time.Second, 300*time.Millisecond).Should(BeNumerically(">=", 2))
 			Eventually(logger).Should(gbytes.Say("event-source-error"))
 		})
+
+		It("counts the resubscriptions", func() {
+			Eventually(fakeMetronClient.IncrementCounterCallCount).Should(BeNumerically(">=", 1))
+			Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("EventStreamResubscriptions"))
+		})
+	})
+
+	Context("when the event stream is resubscribed to", func() {
+		var (
+			nextErrors chan error
+		)
+
+		BeforeEach(func() {
+			resubscribeMin = time.Second
+			resubscribeMax = 4 * time.Second
+
+			nextErrors = make(chan error, 10)
+			eventSource.NextStub = func() (models.Event, error) {
+				return nil, <-nextErrors
+			}
+		})
+
+		It("backs off before resubscribing", func() {
+			nextErrors <- errors.New("bazinga...")
+			Eventually(clock.WatcherCount).Should(Equal(1))
+			Consistently(bbsClient.SubscribeToEventsByCellIDCallCount).Should(Equal(1))
+
+			clock.WaitForWatcherAndIncrement(time.Second)
+			Eventually(bbsClient.SubscribeToEventsByCellIDCallCount).Should(Equal(2))
+		})
+
+		It("doubles the backoff for every failed attempt", func() {
+			bbsClient.SubscribeToEventsByCellIDReturnsOnCall(1, nil, errors.New("kaboom"))
+
+			nextErrors <- errors.New("bazinga...")
+			clock.WaitForWatcherAndIncrement(time.Second)
+			Eventually(bbsClient.SubscribeToEventsByCellIDCallCount).Should(Equal(2))
+
+			Eventually(clock.WatcherCount).Should(Equal(1))
+			clock.Increment(time.Second - time.Nanosecond)
+			Consistently(bbsClient.SubscribeToEventsByCellIDCallCount).Should(Equal(2))
+
+			clock.Increment(time.Second + time.Nanosecond)
+			Eventually(bbsClient.SubscribeToEventsByCellIDCallCount).Should(Equal(3))
+		})
+
+		It("syncs after resubscribing", func() {
+			nextErrors <- errors.New("bazinga...")
+			clock.WaitForWatcherAndIncrement(time.Second)
+
+			Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+			Eventually(logger).Should(gbytes.Say("resubscribed-to-bbs-events"))
+		})
+
+		Context("when a sync is in progress", func() {
+			var (
+				blockSync chan struct{}
+			)
+
+			BeforeEach(func() {
+				blockSync = make(chan struct{})
+				bbsClient.ActualLRPGroupsStub = func(lager.Logger, models.ActualLRPFilter) ([]*models.ActualLRPGroup, error) {
+					<-blockSync
+					return nil, nil
+				}
+			})
+
+			It("syncs again once it completes", func() {
+				syncCh <- struct{}{}
+				Eventually(bbsClient.ActualLRPGroupsCallCount).Should(Equal(1))
+
+				nextErrors <- errors.New("bazinga...")
+				clock.WaitForWatcherAndIncrement(time.Second)
+				Eventually(bbsClient.SubscribeToEventsByCellIDCallCount).Should(Equal(2))
+
+				blockSync <- struct{}{}
+				Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+				Eventually(bbsClient.ActualLRPGroupsCallCount).Should(Equal(2))
+
+				blockSync <- struct{}{}
+				Eventually(routeHandler.SyncCallCount).Should(Equal(2))
+			})
+		})
 	})
 
 	Context("when subscribe to events fails", func() {
@@ -406,6 +536,94 @@ var _ = Describe("Watcher", func() {
 		})
 	})
 
+	Context("when events are handled by workers", func() {
+		var (
+			events  chan models.Event
+			release chan struct{}
+		)
+
+		desiredCreated := func(processGuid string) models.Event {
+			return models.NewDesiredLRPCreatedEvent(getDesiredLRP(processGuid, "log-guid", 5222, 61000))
+		}
+
+		handledProcessGuids := func() []string {
+			var guids []string
+			for i := 0; i < routeHandler.HandleEventCallCount(); i++ {
+				_, event := routeHandler.HandleEventArgsForCall(i)
+				guids = append(guids, event.(*models.DesiredLRPCreatedEvent).DesiredLrp.ProcessGuid)
+			}
+			return guids
+		}
+
+		BeforeEach(func() {
+			eventWorkers = 2
+
+			events = make(chan models.Event, 10)
+			eventSource.NextStub = func() (models.Event, error) {
+				return <-events, nil
+			}
+
+			// process-guid-1 and process-guid-2 are handled by different workers
+			release = make(chan struct{})
+			routeHandler.HandleEventStub = func(_ lager.Logger, event models.Event) {
+				if event.(*models.DesiredLRPCreatedEvent).DesiredLrp.ProcessGuid == "process-guid-1" {
+					<-release
+				}
+			}
+		})
+
+		AfterEach(func() {
+			close(release)
+		})
+
+		It("handles the events of other process guids while one is busy", func() {
+			events <- desiredCreated("process-guid-1")
+			events <- desiredCreated("process-guid-1")
+			events <- desiredCreated("process-guid-2")
+
+			Eventually(handledProcessGuids).Should(ConsistOf("process-guid-1", "process-guid-2"))
+			Consistently(handledProcessGuids).Should(HaveLen(2))
+
+			release <- struct{}{}
+			Eventually(handledProcessGuids).Should(ConsistOf("process-guid-1", "process-guid-1", "process-guid-2"))
+		})
+
+		It("sends the queue latency of every worker", func() {
+			events <- desiredCreated("process-guid-2")
+
+			Eventually(fakeMetronClient.SendDurationCallCount).Should(Equal(1))
+			metric, _, _ := fakeMetronClient.SendDurationArgsForCall(0)
+			Expect(metric).To(Equal("EventQueueLatency"))
+		})
+
+		It("syncs once the dispatched events are handled", func() {
+			events <- desiredCreated("process-guid-1")
+			Eventually(routeHandler.HandleEventCallCount).Should(Equal(1))
+
+			syncCh <- struct{}{}
+			Eventually(bbsClient.ActualLRPGroupsCallCount).Should(Equal(1))
+			Consistently(routeHandler.SyncCallCount).Should(BeZero())
+
+			release <- struct{}{}
+			Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+		})
+	})
+
+	Describe("full sync requests", func() {
+		BeforeEach(func() {
+			fullSyncCycles = 3
+		})
+
+		It("syncs the whole routing table even when an incremental sync is due", func() {
+			syncCh <- struct{}{}
+			Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+
+			testWatcher.RequestFullSync()
+			Eventually(logger).Should(gbytes.Say("full-sync-requested"))
+			Eventually(routeHandler.SyncCallCount).Should(Equal(2))
+		})
+	})
+
 	Describe("emit external event", func() {
 		It("emits registrations", func() {
 			emitExternalCh <- struct{}{}
@@ -420,6 +638,29 @@ var _ = Describe("Watcher", func() {
 		})
 	})
 
+	Describe("drain checks", func() {
+		Context("when the drain check interval is set", func() {
+			BeforeEach(func() {
+				drainCheckInterval = time.Second
+			})
+
+			It("emits the drained endpoints at every interval", func() {
+				Eventually(clock.WatcherCount).Should(Equal(1))
+				clock.IncrementBySeconds(1)
+				Eventually(routeHandler.EmitDrainedCallCount).Should(Equal(1))
+				clock.IncrementBySeconds(1)
+				Eventually(routeHandler.EmitDrainedCallCount).Should(Equal(2))
+			})
+		})
+
+		Context("when the drain check interval is not set", func() {
+			It("never emits the drained endpoints", func() {
+				clock.IncrementBySeconds(1)
+				Consistently(routeHandler.EmitDrainedCallCount).Should(Equal(0))
+			})
+		})
+	})
+
 	Describe("Sync Events", func() {
 		var (
 			errCh   chan error
@@ -748,6 +989,202 @@ var _ = Describe("Watcher", func() {
 				_, filter := bbsClient.DesiredLRPSchedulingInfosArgsForCall(0)
 				Expect(filter.ProcessGuids).To(BeEmpty())
 			})
+
+			Context("when sharded", func() {
+				BeforeEach(func() {
+					fakeShardFilter := &fakes.FakeShardFilter{}
+					fakeShardFilter.OwnsStub = func(processGuid string) bool {
+						return processGuid != "pg-2"
+					}
+					shardFilter = fakeShardFilter
+				})
+
+				It("only syncs the lrps of owned shards", func() {
+					Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+					_, desired, actuals, _, _ := routeHandler.SyncArgsForCall(0)
+
+					Expect(desired).To(Equal([]*models.DesiredLRPSchedulingInfo{schedulingInfo1}))
+					Expect(actuals).To(Equal([]*routingtable.ActualLRPRoutingInfo{
+						routingtable.NewActualLRPRoutingInfo(actualLRPGroup1),
+						routingtable.NewActualLRPRoutingInfo(actualLRPGroup3),
+					}))
+				})
+			})
+
+			Context("when a running actual lrp is not routable", func() {
+				var unroutableActualLRPGroup *models.ActualLRPGroup
+
+				BeforeEach(func() {
+					unroutableActualLRP := *actualLRPGroup2.Instance
+					unroutableActualLRP.SetRoutable(false)
+					unroutableActualLRPGroup = &models.ActualLRPGroup{Instance: &unroutableActualLRP}
+
+					bbsClient.ActualLRPGroupsReturns([]*models.ActualLRPGroup{
+						actualLRPGroup1,
+						unroutableActualLRPGroup,
+					}, nil)
+					bbsClient.ActualLRPGroupsStub = nil
+				})
+
+				It("does not sync it", func() {
+					Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+					_, _, actuals, _, _ := routeHandler.SyncArgsForCall(0)
+
+					Expect(actuals).To(Equal([]*routingtable.ActualLRPRoutingInfo{
+						routingtable.NewActualLRPRoutingInfo(actualLRPGroup1),
+					}))
+				})
+			})
+		})
+
+		Context("when incremental syncs are enabled", func() {
+			var changedActualLRPGroup1 *models.ActualLRPGroup
+
+			BeforeEach(func() {
+				fullSyncCycles = 3
+
+				changedActualLRP1 := *actualLRPGroup1.Instance
+				changedActualLRP1.ModificationTag = models.ModificationTag{Epoch: "abc", Index: 2}
+				changedActualLRPGroup1 = &models.ActualLRPGroup{Instance: &changedActualLRP1}
+
+				bbsClient.ActualLRPGroupsReturns([]*models.ActualLRPGroup{
+					actualLRPGroup1,
+					actualLRPGroup2,
+					actualLRPGroup3,
+				}, nil)
+				bbsClient.DesiredLRPSchedulingInfosReturns([]*models.DesiredLRPSchedulingInfo{schedulingInfo1, schedulingInfo2, schedulingInfo3}, nil)
+			})
+
+			JustBeforeEach(func() {
+				Eventually(routeHandler.SyncCallCount).Should(Equal(1))
+			})
+
+			It("rebuilds the whole routing table on the first sync", func() {
+				Expect(bbsClient.DesiredLRPSchedulingInfosCallCount()).To(Equal(1))
+				_, filter := bbsClient.DesiredLRPSchedulingInfosArgsForCall(0)
+				Expect(filter.ProcessGuids).To(BeEmpty())
+			})
+
+			Context("when nothing changed since the last sync", func() {
+				It("does not sync any process", func() {
+					syncCh <- struct{}{}
+					Eventually(logger).Should(gbytes.Say(`sync.complete.*"full":false`))
+
+					Expect(routeHandler.SyncCallCount()).To(Equal(1))
+					Expect(routeHandler.SyncProcessesCallCount()).To(Equal(0))
+				})
+
+				It("rebuilds the whole routing table every full sync cycles", func() {
+					syncCh <- struct{}{}
+					Eventually(logger).Should(gbytes.Say(`sync.complete.*"full":false`))
+					syncCh <- struct{}{}
+					Eventually(logger).Should(gbytes.Say(`sync.complete.*"full":false`))
+					Expect(routeHandler.SyncCallCount()).To(Equal(1))
+
+					syncCh <- struct{}{}
+					Eventually(routeHandler.SyncCallCount).Should(Equal(2))
+				})
+			})
+
+			Context("when the actual lrps of a process changed", func() {
+				JustBeforeEach(func() {
+					bbsClient.ActualLRPGroupsReturns([]*models.ActualLRPGroup{
+						changedActualLRPGroup1,
+						actualLRPGroup2,
+						actualLRPGroup3,
+					}, nil)
+				})
+
+				Context("and an event about the process was seen", func() {
+					JustBeforeEach(func() {
+						sendEvent()
+						Eventually(routeHandler.HandleEventCallCount).Should(Equal(1))
+						syncCh <- struct{}{}
+					})
+
+					It("only syncs that process", func() {
+						Eventually(routeHandler.SyncProcessesCallCount).Should(Equal(1))
+						_, processGuids, desired, actuals, domains := routeHandler.SyncProcessesArgsForCall(0)
+						Expect(processGuids).To(Equal([]string{"pg-1"}))
+						Expect(desired).To(Equal([]*models.DesiredLRPSchedulingInfo{schedulingInfo1}))
+						Expect(actuals).To(Equal([]*routingtable.ActualLRPRoutingInfo{
+							routingtable.NewActualLRPRoutingInfo(changedActualLRPGroup1),
+						}))
+						Expect(domains).To(Equal(models.DomainSet{}))
+
+						Expect(routeHandler.SyncCallCount()).To(Equal(1))
+					})
+				})
+
+				Context("and no event about the process was seen", func() {
+					JustBeforeEach(func() {
+						syncCh <- struct{}{}
+					})
+
+					It("detects the divergence and rebuilds the whole routing table", func() {
+						Eventually(logger).Should(gbytes.Say("detected-divergence"))
+						Eventually(routeHandler.SyncCallCount).Should(Equal(2))
+						Expect(routeHandler.SyncProcessesCallCount()).To(Equal(0))
+
+						_, _, actuals, _, _ := routeHandler.SyncArgsForCall(1)
+						Expect(actuals).To(ContainElement(routingtable.NewActualLRPRoutingInfo(changedActualLRPGroup1)))
+					})
+				})
+			})
+
+			Context("when only the desired routes of a process changed", func() {
+				var changedSchedulingInfo2 *models.DesiredLRPSchedulingInfo
+
+				JustBeforeEach(func() {
+					changed := *schedulingInfo2
+					changed.Routes = cfroutes.CFRoutes{
+						cfroutes.CFRoute{
+							Hostnames: []string{hostname2, "new.example.com"},
+							Port:      8080,
+						},
+					}.RoutingInfo()
+					changedSchedulingInfo2 = &changed
+					bbsClient.DesiredLRPSchedulingInfosReturns([]*models.DesiredLRPSchedulingInfo{schedulingInfo1, changedSchedulingInfo2, schedulingInfo3}, nil)
+				})
+
+				Context("and an event about the process was seen", func() {
+					JustBeforeEach(func() {
+						Eventually(eventCh).Should(BeSent(EventHolder{models.NewDesiredLRPChangedEvent(
+							&models.DesiredLRP{ProcessGuid: "pg-2", Routes: &schedulingInfo2.Routes},
+							&models.DesiredLRP{ProcessGuid: "pg-2", Routes: &changedSchedulingInfo2.Routes},
+						)}))
+						Eventually(routeHandler.HandleEventCallCount).Should(Equal(1))
+						syncCh <- struct{}{}
+					})
+
+					It("syncs the new routes of that process", func() {
+						Eventually(routeHandler.SyncProcessesCallCount).Should(Equal(1))
+						_, processGuids, desired, actuals, _ := routeHandler.SyncProcessesArgsForCall(0)
+						Expect(processGuids).To(Equal([]string{"pg-2"}))
+						Expect(desired).To(Equal([]*models.DesiredLRPSchedulingInfo{changedSchedulingInfo2}))
+						Expect(actuals).To(Equal([]*routingtable.ActualLRPRoutingInfo{
+							routingtable.NewActualLRPRoutingInfo(actualLRPGroup2),
+						}))
+
+						Expect(routeHandler.SyncCallCount()).To(Equal(1))
+					})
+				})
+
+				Context("and no event about the process was seen", func() {
+					JustBeforeEach(func() {
+						syncCh <- struct{}{}
+					})
+
+					It("detects the divergence and rebuilds the whole routing table", func() {
+						Eventually(logger).Should(gbytes.Say("detected-divergence"))
+						Eventually(routeHandler.SyncCallCount).Should(Equal(2))
+						Expect(routeHandler.SyncProcessesCallCount()).To(Equal(0))
+
+						_, desired, _, _, _ := routeHandler.SyncArgsForCall(1)
+						Expect(desired).To(ContainElement(changedSchedulingInfo2))
+					})
+				})
+			})
 		})
 
 		Context("when the cell id is set", func() {
@@ -0,0 +1 @@
+package xdsserver // import "code.cloudfoundry.org/route-emitter/xdsserver"
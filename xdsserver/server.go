@@ -0,0 +1,67 @@
+package xdsserver
+
+import (
+	"net"
+	"os"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v2"
+	"github.com/envoyproxy/go-control-plane/pkg/cache"
+	xds "github.com/envoyproxy/go-control-plane/pkg/server"
+	"google.golang.org/grpc"
+)
+
+type Server struct {
+	logger        lager.Logger
+	listenAddress string
+	snapshotCache cache.SnapshotCache
+}
+
+// NewServer returns a runner serving the snapshots in snapshotCache over the
+// aggregated and the individual EDS/CDS/RDS/LDS gRPC services.
+func NewServer(logger lager.Logger, listenAddress string, snapshotCache cache.SnapshotCache) *Server {
+	return &Server{
+		logger:        logger.Session("xds-server"),
+		listenAddress: listenAddress,
+		snapshotCache: snapshotCache,
+	}
+}
+
+func (s *Server) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	s.logger.Info("starting", lager.Data{"listen-address": s.listenAddress})
+
+	listener, err := net.Listen("tcp", s.listenAddress)
+	if err != nil {
+		s.logger.Error("failed-to-listen", err)
+		return err
+	}
+
+	xdsServer := xds.NewServer(s.snapshotCache, nil)
+	grpcServer := grpc.NewServer()
+	discovery.RegisterAggregatedDiscoveryServiceServer(grpcServer, xdsServer)
+	v2.RegisterEndpointDiscoveryServiceServer(grpcServer, xdsServer)
+	v2.RegisterClusterDiscoveryServiceServer(grpcServer, xdsServer)
+	v2.RegisterRouteDiscoveryServiceServer(grpcServer, xdsServer)
+	v2.RegisterListenerDiscoveryServiceServer(grpcServer, xdsServer)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- grpcServer.Serve(listener)
+	}()
+
+	close(ready)
+	s.logger.Info("started")
+
+	select {
+	case <-signals:
+		s.logger.Info("stopping")
+		// xDS streams are long lived, a graceful stop would wait for every
+		// connected Envoy to hang up
+		grpcServer.Stop()
+		return nil
+	case err := <-errCh:
+		s.logger.Error("failed-to-serve", err)
+		return err
+	}
+}
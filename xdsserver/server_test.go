@@ -0,0 +1,135 @@
+package xdsserver_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/xdsserver"
+	"github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	"github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v2"
+	"github.com/envoyproxy/go-control-plane/pkg/cache"
+	"github.com/gogo/protobuf/types"
+	"github.com/tedsuo/ifrit"
+	"google.golang.org/grpc"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// envoy stands in for an Envoy proxy speaking ADS: it subscribes to a
+// resource type and ACKs every response it receives.
+type envoy struct {
+	stream discovery.AggregatedDiscoveryService_StreamAggregatedResourcesClient
+}
+
+func (e *envoy) subscribe(typeURL string, resourceNames ...string) {
+	Expect(e.stream.Send(&v2.DiscoveryRequest{
+		Node:          &core.Node{Id: "envoy"},
+		TypeUrl:       typeURL,
+		ResourceNames: resourceNames,
+	})).To(Succeed())
+}
+
+func (e *envoy) receive() *v2.DiscoveryResponse {
+	response, err := e.stream.Recv()
+	Expect(err).NotTo(HaveOccurred())
+
+	Expect(e.stream.Send(&v2.DiscoveryRequest{
+		Node:          &core.Node{Id: "envoy"},
+		TypeUrl:       response.TypeUrl,
+		VersionInfo:   response.VersionInfo,
+		ResponseNonce: response.Nonce,
+	})).To(Succeed())
+
+	return response
+}
+
+var _ = Describe("Server", func() {
+	var (
+		xdsEmitter emitter.XDSEmitter
+		process    ifrit.Process
+		conn       *grpc.ClientConn
+		cancel     context.CancelFunc
+		proxy      *envoy
+	)
+
+	BeforeEach(func() {
+		logger := lagertest.NewTestLogger("test")
+		snapshotCache := cache.NewSnapshotCache(true, emitter.XDSNodeHash{}, nil)
+		xdsEmitter = emitter.NewXDSEmitter(logger, snapshotCache, 8080)
+
+		err := xdsEmitter.Emit(routingtable.MessagesToEmit{
+			RegistrationMessages: []routingtable.RegistryMessage{
+				{Host: "1.1.1.1", Port: 61001, URIs: []string{"foo.example.com"}},
+			},
+		}, routingtable.TCPRouteMappings{})
+		Expect(err).NotTo(HaveOccurred())
+
+		listenAddress := fmt.Sprintf("127.0.0.1:%d", 18000+GinkgoParallelNode())
+		process = ifrit.Invoke(xdsserver.NewServer(logger, listenAddress, snapshotCache))
+
+		conn, err = grpc.Dial(listenAddress, grpc.WithInsecure())
+		Expect(err).NotTo(HaveOccurred())
+
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		stream, err := discovery.NewAggregatedDiscoveryServiceClient(conn).StreamAggregatedResources(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		proxy = &envoy{stream: stream}
+	})
+
+	AfterEach(func() {
+		cancel()
+		conn.Close()
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	It("serves the current clusters", func() {
+		proxy.subscribe(cache.ClusterType)
+
+		response := proxy.receive()
+		Expect(response.VersionInfo).To(Equal("1"))
+		Expect(response.Resources).To(HaveLen(1))
+
+		var cluster v2.Cluster
+		Expect(types.UnmarshalAny(&response.Resources[0], &cluster)).To(Succeed())
+		Expect(cluster.Name).To(Equal(emitter.XDSHTTPClusterName("foo.example.com")))
+	})
+
+	It("serves the endpoints of a cluster", func() {
+		proxy.subscribe(cache.EndpointType, emitter.XDSHTTPClusterName("foo.example.com"))
+
+		response := proxy.receive()
+		Expect(response.Resources).To(HaveLen(1))
+
+		var assignment v2.ClusterLoadAssignment
+		Expect(types.UnmarshalAny(&response.Resources[0], &assignment)).To(Succeed())
+		address := assignment.Endpoints[0].LbEndpoints[0].Endpoint.Address.GetSocketAddress()
+		Expect(address.Address).To(Equal("1.1.1.1"))
+		Expect(address.GetPortValue()).To(BeEquivalentTo(61001))
+	})
+
+	Context("when new routes are emitted", func() {
+		It("pushes the next version to the connected proxy", func() {
+			proxy.subscribe(cache.ClusterType)
+			Expect(proxy.receive().VersionInfo).To(Equal("1"))
+
+			err := xdsEmitter.Emit(routingtable.MessagesToEmit{
+				RegistrationMessages: []routingtable.RegistryMessage{
+					{Host: "2.2.2.2", Port: 61002, URIs: []string{"bar.example.com"}},
+				},
+			}, routingtable.TCPRouteMappings{})
+			Expect(err).NotTo(HaveOccurred())
+
+			response := proxy.receive()
+			Expect(response.VersionInfo).To(Equal("2"))
+			Expect(response.Resources).To(HaveLen(2))
+		})
+	})
+})
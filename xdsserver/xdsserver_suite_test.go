@@ -0,0 +1,13 @@
+package xdsserver_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestXdsserver(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Xdsserver Suite")
+}